@@ -7,9 +7,11 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -25,6 +27,8 @@ var (
 	apiKeysFile  string
 	adminKey     string
 	lastPersist  time.Time
+	keysDirty    bool
+	persistMutex sync.Mutex
 	corsOrigins  map[string]struct{}
 	allowAllCORS bool
 )
@@ -192,14 +196,51 @@ func persistAPIKeys(path string, entries []apiKeyEntry) error {
 	if path == "" {
 		return nil
 	}
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return err
 	}
 	payload, err := json.MarshalIndent(entries, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, payload, 0o600)
+
+	// Write to a temp file and rename it, so a crash never leaves a
+	// truncated key file behind.
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+	if _, err := tmp.Write(payload); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
+
+// savePersistedAPIKeys writes the key file and clears the pending flag.
+func savePersistedAPIKeys() error {
+	persistMutex.Lock()
+	defer persistMutex.Unlock()
+	if err := persistAPIKeys(apiKeysFile, snapshotAPIKeys()); err != nil {
+		return err
+	}
+	lastPersist = time.Now().UTC()
+	keysDirty = false
+	return nil
 }
 
 func hydrateAPIKeys(entries []apiKeyEntry) {
@@ -256,11 +297,27 @@ func maybePersistAPIKeys() {
 	if apiKeysFile == "" {
 		return
 	}
-	if time.Since(lastPersist) < 30*time.Second {
+	persistMutex.Lock()
+	keysDirty = true
+	due := time.Since(lastPersist) >= 30*time.Second
+	persistMutex.Unlock()
+	if !due {
 		return
 	}
-	lastPersist = time.Now().UTC()
-	if err := persistAPIKeys(apiKeysFile, snapshotAPIKeys()); err != nil {
+	if err := savePersistedAPIKeys(); err != nil {
+		log.Printf("[WARN] API-Key-Datei konnte nicht gespeichert werden: %v", err)
+	}
+}
+
+// flushAPIKeys writes changes skipped by the debounce in maybePersistAPIKeys.
+func flushAPIKeys() {
+	persistMutex.Lock()
+	dirty := keysDirty
+	persistMutex.Unlock()
+	if apiKeysFile == "" || !dirty {
+		return
+	}
+	if err := savePersistedAPIKeys(); err != nil {
 		log.Printf("[WARN] API-Key-Datei konnte nicht gespeichert werden: %v", err)
 	}
 }
@@ -598,7 +655,23 @@ func main() {
 	log.Printf("[INFO] Rate limiting enabled")
 	log.Printf("[INFO] Available API keys: %d", len(apiKeys))
 
-	if err := http.ListenAndServe(PORT, r); err != nil {
-		log.Fatal(err)
+	server := &http.Server{Addr: PORT, Handler: r}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigC
+	log.Printf("[INFO] Signal empfangen: %s", sig)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("[WARN] Graceful Shutdown fehlgeschlagen: %v", err)
 	}
+	flushAPIKeys()
+	log.Printf("[INFO] JARVIS Auth Service gestoppt")
 }
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"jarviscore/go/internal/command"
+	"jarviscore/go/internal/security"
 )
 
 func main() {
@@ -21,10 +22,11 @@ func main() {
 	svc := command.NewService(cfg, logger)
 	mux := http.NewServeMux()
 	svc.Routes(mux)
+	guard := security.NewMiddleware(security.LoadMiddlewareConfig("JARVIS_COMMANDD"), logger)
 
 	server := &http.Server{
 		Addr:         cfg.ListenAddr,
-		Handler:      withLogging(logger, mux),
+		Handler:      withLogging(logger, guard.Handler(mux)),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 20 * time.Second,
 	}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"jarviscore/go/internal/gateway"
+	"jarviscore/go/internal/security"
 )
 
 func main() {
@@ -20,10 +21,11 @@ func main() {
 	srv := gateway.NewServer(cfg, logger)
 	mux := http.NewServeMux()
 	srv.Routes(mux)
+	guard := security.NewMiddleware(security.LoadMiddlewareConfig("JARVIS_GATEWAYD"), logger)
 
 	httpServer := &http.Server{
 		Addr:         cfg.ListenAddr,
-		Handler:      withLogging(logger, mux),
+		Handler:      withLogging(logger, guard.Handler(mux)),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 15 * time.Second,
 	}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -40,13 +41,30 @@ func main() {
 		}
 	}()
 
+	var grpcServer interface{ GracefulStop() }
+	if cfg.GRPCAddr != "" {
+		lis, err := net.Listen("tcp", cfg.GRPCAddr)
+		if err != nil {
+			logger.Fatalf("gRPC-Listener konnte nicht geöffnet werden: %v", err)
+		}
+		logger.Printf("memoryd gRPC lauscht auf %s", sanitizeForLog(cfg.GRPCAddr))
+		grpcServer = svc.ServeGRPC(lis)
+	}
+
 	waitForSignal(logger)
 
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Printf("Graceful Shutdown fehlgeschlagen: %v", err)
 	}
+	if err := svc.Close(); err != nil {
+		logger.Printf("Memories konnten nicht gespeichert werden: %v", err)
+	}
 	logger.Println("memoryd gestoppt")
 }
 
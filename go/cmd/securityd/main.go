@@ -46,6 +46,7 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Printf("graceful shutdown failed: %v", err)
 	}
+	svc.Close()
 	logger.Println("securityd stopped")
 }
 
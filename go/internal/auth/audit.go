@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Audit event types.
+const (
+	AuditTokenIssued   = "token_issued"
+	AuditVerifyFailed  = "verify_failed"
+	AuditKeyCreated    = "key_created"
+	AuditKeyRotated    = "key_rotated"
+	AuditKeyDeleted    = "key_deleted"
+	AuditKeyExtended   = "key_extended"
+	AuditTOTPEnrolled  = "totp_enrolled"
+	AuditRateLimited   = "rate_limited"
+	AuditQuotaExceeded = "quota_exceeded"
+	AuditLockout       = "lockout"
+)
+
+const defaultAuditRetention = 10000
+
+// AuditEvent is a single entry of the auth audit log.
+type AuditEvent struct {
+	ID       int64     `json:"id"`
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	Key      string    `json:"key,omitempty"`
+	RemoteIP string    `json:"remote_ip,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	Detail   string    `json:"detail,omitempty"`
+}
+
+// AuditQuery filters and paginates audit events. Results are newest first.
+type AuditQuery struct {
+	Type   string
+	Key    string
+	Since  time.Time
+	Offset int
+	Limit  int
+}
+
+// AuditLog appends events to a JSON-lines file and keeps the most recent
+// entries in memory for querying.
+type AuditLog struct {
+	path      string
+	retention int
+	events    []AuditEvent
+	nextID    int64
+	mu        sync.RWMutex
+}
+
+func NewAuditLog(path string) (*AuditLog, error) {
+	a := &AuditLog{path: path, retention: defaultAuditRetention, nextID: 1}
+	if path == "" {
+		return a, nil
+	}
+	if err := a.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *AuditLog) load() error {
+	file, err := os.Open(a.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		a.events = append(a.events, event)
+		if event.ID >= a.nextID {
+			a.nextID = event.ID + 1
+		}
+	}
+	a.trim()
+	return scanner.Err()
+}
+
+func (a *AuditLog) trim() {
+	if len(a.events) > a.retention {
+		a.events = append([]AuditEvent(nil), a.events[len(a.events)-a.retention:]...)
+	}
+}
+
+// Record stores an event and appends it to the audit file.
+func (a *AuditLog) Record(event AuditEvent) error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	event.ID = a.nextID
+	a.nextID++
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	a.events = append(a.events, event)
+	a.trim()
+
+	if a.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(payload, '\n'))
+	return err
+}
+
+// Query returns the matching events for the requested page and the total
+// number of matches.
+func (a *AuditLog) Query(q AuditQuery) ([]AuditEvent, int) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	matches := []AuditEvent{}
+	for i := len(a.events) - 1; i >= 0; i-- {
+		event := a.events[i]
+		if q.Type != "" && event.Type != q.Type {
+			continue
+		}
+		if q.Key != "" && event.Key != q.Key {
+			continue
+		}
+		if !q.Since.IsZero() && event.Time.Before(q.Since) {
+			continue
+		}
+		matches = append(matches, event)
+	}
+
+	total := len(matches)
+	if q.Offset >= total {
+		return []AuditEvent{}, total
+	}
+	end := total
+	if q.Limit > 0 && q.Offset+q.Limit < end {
+		end = q.Offset + q.Limit
+	}
+	return matches[q.Offset:end], total
+}
+
+func (s *Service) recordAudit(r *http.Request, eventType string, key string, detail string) {
+	event := AuditEvent{
+		Type:   eventType,
+		Detail: detail,
+	}
+	if key != "" {
+		event.Key = maskAPIKey(key)
+	}
+	if r != nil {
+		if ip := s.clientIP(r); ip != nil {
+			event.RemoteIP = ip.String()
+		}
+		event.Path = r.URL.Path
+	}
+	if err := s.audit.Record(event); err != nil {
+		s.logger.Printf("[WARN] Audit-Eintrag konnte nicht gespeichert werden: %v", err)
+	}
+}
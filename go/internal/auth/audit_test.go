@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuditLogQuery(t *testing.T) {
+	audit, err := NewAuditLog("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []AuditEvent{
+		{Type: AuditTokenIssued, Key: "****aaaa", Time: base},
+		{Type: AuditVerifyFailed, Key: "****bbbb", Time: base.Add(time.Minute)},
+		{Type: AuditTokenIssued, Key: "****bbbb", Time: base.Add(2 * time.Minute)},
+		{Type: AuditLockout, Time: base.Add(3 * time.Minute)},
+	}
+	for _, event := range events {
+		if err := audit.Record(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		query   AuditQuery
+		wantIDs []int64
+		total   int
+	}{
+		{"all newest first", AuditQuery{}, []int64{4, 3, 2, 1}, 4},
+		{"by type", AuditQuery{Type: AuditTokenIssued}, []int64{3, 1}, 2},
+		{"by key", AuditQuery{Key: "****bbbb"}, []int64{3, 2}, 2},
+		{"since", AuditQuery{Since: base.Add(2 * time.Minute)}, []int64{4, 3}, 2},
+		{"page", AuditQuery{Offset: 1, Limit: 2}, []int64{3, 2}, 4},
+		{"offset past end", AuditQuery{Offset: 10}, nil, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total := audit.Query(tt.query)
+			if total != tt.total {
+				t.Errorf("total = %d, want %d", total, tt.total)
+			}
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("got %d events, want %d", len(got), len(tt.wantIDs))
+			}
+			for i, event := range got {
+				if event.ID != tt.wantIDs[i] {
+					t.Errorf("event %d has id %d, want %d", i, event.ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAuditLogPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	first, err := NewAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, eventType := range []string{AuditKeyCreated, AuditKeyDeleted} {
+		if err := first.Record(AuditEvent{Type: eventType}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened, err := NewAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Record(AuditEvent{Type: AuditLockout}); err != nil {
+		t.Fatal(err)
+	}
+	events, total := reopened.Query(AuditQuery{})
+	if total != 3 {
+		t.Fatalf("total = %d, want 3", total)
+	}
+	if events[0].ID != 3 || events[0].Type != AuditLockout || events[2].Type != AuditKeyCreated {
+		t.Errorf("unexpected events after reload: %+v", events)
+	}
+}
+
+func TestAuditRecordsAuthentication(t *testing.T) {
+	svc := newTestService(t, Config{})
+
+	serve(svc, http.MethodPost, "/api/auth/token", map[string]string{"api_key": testKey}, nil)
+	serve(svc, http.MethodPost, "/api/auth/token", map[string]string{"api_key": "wrong-key-0123456789"}, nil)
+
+	tests := []struct {
+		eventType string
+		key       string
+	}{
+		{AuditTokenIssued, maskAPIKey(testKey)},
+		{AuditVerifyFailed, maskAPIKey("wrong-key-0123456789")},
+	}
+	for _, tt := range tests {
+		events, total := svc.audit.Query(AuditQuery{Type: tt.eventType})
+		if total != 1 {
+			t.Fatalf("%s: %d events, want 1", tt.eventType, total)
+		}
+		if events[0].Key != tt.key || events[0].Path != "/api/auth/token" {
+			t.Errorf("%s: got %+v", tt.eventType, events[0])
+		}
+	}
+}
+
+func TestAuditHandlerRequiresAdmin(t *testing.T) {
+	svc := newTestService(t, Config{})
+	svc.recordAudit(nil, AuditKeyCreated, testKey, "")
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+	}{
+		{"no key", nil, http.StatusForbidden},
+		{"api key", map[string]string{"X-Admin-Key": testKey}, http.StatusForbidden},
+		{"admin key", map[string]string{"X-Admin-Key": testAdminKey}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(svc, http.MethodGet, "/api/auth/audit?type="+AuditKeyCreated, nil, tt.headers)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusOK && decodeBody(t, rec)["total"] != float64(1) {
+				t.Errorf("unexpected body %s", rec.Body.String())
+			}
+		})
+	}
+}
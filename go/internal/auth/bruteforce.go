@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxFailures = 5
+	defaultLockout     = time.Minute
+	maxLockout         = time.Hour
+	failureWindow      = 15 * time.Minute
+	maxTrackedSources  = 10000
+)
+
+type failureEntry struct {
+	failures    int
+	lockouts    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// FailureTracker counts failed authentication attempts per source and locks
+// a source out once it exceeds the threshold. Each further lockout doubles
+// the duration up to maxLockout.
+type FailureTracker struct {
+	maxFailures int
+	lockout     time.Duration
+	entries     map[string]*failureEntry
+	mu          sync.Mutex
+}
+
+func NewFailureTracker(maxFailures int, lockout time.Duration) *FailureTracker {
+	if maxFailures <= 0 {
+		maxFailures = defaultMaxFailures
+	}
+	if lockout <= 0 {
+		lockout = defaultLockout
+	}
+	return &FailureTracker{
+		maxFailures: maxFailures,
+		lockout:     lockout,
+		entries:     make(map[string]*failureEntry),
+	}
+}
+
+// Locked returns the remaining lockout for source, if any.
+func (t *FailureTracker) Locked(source string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.entries[source]
+	if !ok {
+		return 0, false
+	}
+	remaining := time.Until(entry.lockedUntil)
+	return remaining, remaining > 0
+}
+
+// Failure registers a failed attempt and returns the lockout it triggered.
+func (t *FailureTracker) Failure(source string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if len(t.entries) >= maxTrackedSources {
+		t.pruneLocked(now)
+	}
+	entry, ok := t.entries[source]
+	if !ok || now.Sub(entry.lastFailure) > failureWindow && now.After(entry.lockedUntil) {
+		entry = &failureEntry{}
+		t.entries[source] = entry
+	}
+	entry.failures++
+	entry.lastFailure = now
+	if entry.failures < t.maxFailures {
+		return 0
+	}
+
+	lockout := t.lockout << entry.lockouts
+	if lockout <= 0 || lockout > maxLockout {
+		lockout = maxLockout
+	}
+	entry.lockouts++
+	entry.failures = 0
+	entry.lockedUntil = now.Add(lockout)
+	return lockout
+}
+
+// Success clears the failure history of source.
+func (t *FailureTracker) Success(source string) {
+	t.mu.Lock()
+	delete(t.entries, source)
+	t.mu.Unlock()
+}
+
+func (t *FailureTracker) pruneLocked(now time.Time) {
+	for source, entry := range t.entries {
+		if now.Sub(entry.lastFailure) > failureWindow && now.After(entry.lockedUntil) {
+			delete(t.entries, source)
+		}
+	}
+}
+
+func (s *Service) failureSource(r *http.Request) string {
+	if ip := s.clientIP(r); ip != nil {
+		return ip.String()
+	}
+	return r.RemoteAddr
+}
+
+// rejectIfLocked answers with 429 when the caller is currently locked out.
+func (s *Service) rejectIfLocked(w http.ResponseWriter, r *http.Request) bool {
+	remaining, locked := s.failures.Locked(s.failureSource(r))
+	if !locked {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(remaining.Seconds())+1))
+	http.Error(w, `{"error":"Too many failed attempts. Try again later."}`, http.StatusTooManyRequests)
+	return true
+}
+
+// registerFailure records a failed attempt in the audit log and the
+// brute-force tracker.
+func (s *Service) registerFailure(r *http.Request, key string, detail string) {
+	s.recordAudit(r, AuditVerifyFailed, key, detail)
+	source := s.failureSource(r)
+	if lockout := s.failures.Failure(source); lockout > 0 {
+		s.recordAudit(r, AuditLockout, key, fmt.Sprintf("locked for %s", lockout))
+		s.logger.Printf("[WARN] Zu viele Fehlversuche von %s, gesperrt für %s", source, lockout)
+	}
+}
+
+func (s *Service) registerSuccess(r *http.Request) {
+	s.failures.Success(s.failureSource(r))
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFailureTrackerLockout(t *testing.T) {
+	// Steps are "fail" or "ok"; want holds the lockout of every "fail".
+	tests := []struct {
+		name  string
+		steps []string
+		want  []time.Duration
+	}{
+		{"locks at threshold", []string{"fail", "fail", "fail"}, []time.Duration{0, 0, time.Minute}},
+		{"repeat doubles", []string{"fail", "fail", "fail", "fail", "fail", "fail"}, []time.Duration{0, 0, time.Minute, 0, 0, 2 * time.Minute}},
+		{"success resets", []string{"fail", "fail", "ok", "fail", "fail"}, []time.Duration{0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewFailureTracker(3, time.Minute)
+			var got []time.Duration
+			for _, step := range tt.steps {
+				if step == "ok" {
+					tracker.Success("192.0.2.1")
+					continue
+				}
+				got = append(got, tracker.Failure("192.0.2.1"))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lockouts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailureTrackerCapsLockout(t *testing.T) {
+	tracker := NewFailureTracker(1, 45*time.Minute)
+	tracker.Failure("a")
+	if got := tracker.Failure("a"); got != maxLockout {
+		t.Errorf("second lockout = %s, want %s", got, maxLockout)
+	}
+	if _, locked := tracker.Locked("a"); !locked {
+		t.Error("source not locked")
+	}
+	if _, locked := tracker.Locked("b"); locked {
+		t.Error("unrelated source locked")
+	}
+}
+
+func TestLockedOutCallerGets429(t *testing.T) {
+	svc := newTestService(t, Config{MaxFailures: 2, Lockout: time.Minute})
+	wrong := map[string]string{"api_key": "wrong-key-0123456789"}
+
+	statuses := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+	for i, want := range statuses {
+		if rec := serve(svc, http.MethodPost, "/api/auth/token", wrong, nil); rec.Code != want {
+			t.Fatalf("attempt %d: status %d, want %d", i, rec.Code, want)
+		}
+	}
+	rec := serve(svc, http.MethodPost, "/api/auth/token", map[string]string{"api_key": testKey}, nil)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("valid key during lockout: status %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if _, total := svc.audit.Query(AuditQuery{Type: AuditLockout}); total != 1 {
+		t.Errorf("%d lockout events, want 1", total)
+	}
+}
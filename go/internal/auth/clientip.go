@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const defaultClientIPHeader = "X-Forwarded-For"
+
+// parseCIDRs accepts plain IPs as well as CIDR notation.
+func parseCIDRs(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("ungültige IP-Adresse: %q", value)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			value = fmt.Sprintf("%s/%d", ip.String(), bits)
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("ungültiger CIDR-Bereich: %q", value)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func splitList(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	return strings.Split(raw, ",")
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the real caller. Forwarding headers are
+// only honoured when the direct peer is a configured trusted proxy; the
+// right-most untrusted hop of the header is used.
+func (s *Service) clientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer := net.ParseIP(host)
+	if peer == nil || len(s.proxies) == 0 || !containsIP(s.proxies, peer) {
+		return peer
+	}
+
+	header := strings.TrimSpace(r.Header.Get(s.cfg.ClientIPHeader))
+	if header == "" {
+		return peer
+	}
+	hops := strings.Split(header, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		if !containsIP(s.proxies, ip) {
+			return ip
+		}
+		peer = ip
+	}
+	return peer
+}
+
+// keyAllowsIP reports whether the key may be used from the given address.
+// Keys without an allowlist are usable from anywhere.
+func keyAllowsIP(info *APIKeyInfo, ip net.IP) bool {
+	if len(info.AllowedNets) == 0 {
+		return true
+	}
+	return ip != nil && containsIP(info.AllowedNets, ip)
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	tests := []struct {
+		values  []string
+		want    []string
+		wantErr bool
+	}{
+		{values: []string{"10.0.0.0/8", " 192.168.1.5 "}, want: []string{"10.0.0.0/8", "192.168.1.5/32"}},
+		{values: []string{"::1", ""}, want: []string{"::1/128"}},
+		{values: []string{"not-an-ip"}, wantErr: true},
+		{values: []string{"10.0.0.0/33"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		networks, err := parseCIDRs(tt.values)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCIDRs(%q) error = %v, wantErr %v", tt.values, err, tt.wantErr)
+			continue
+		}
+		if len(networks) != len(tt.want) {
+			t.Errorf("parseCIDRs(%q) = %v, want %v", tt.values, networks, tt.want)
+			continue
+		}
+		for i, network := range networks {
+			if network.String() != tt.want[i] {
+				t.Errorf("parseCIDRs(%q)[%d] = %s, want %s", tt.values, i, network, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	svc := newTestService(t, Config{TrustedProxies: "10.0.0.1, 172.16.0.0/12"})
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		want      string
+	}{
+		{"direct peer", "203.0.113.7:1234", "", "203.0.113.7"},
+		{"untrusted peer ignores header", "203.0.113.7:1234", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:1234", "198.51.100.1", "198.51.100.1"},
+		{"rightmost untrusted hop", "10.0.0.1:1234", "1.1.1.1, 198.51.100.1, 172.16.0.9", "198.51.100.1"},
+		{"only proxies", "10.0.0.1:1234", "172.16.0.9", "172.16.0.9"},
+		{"garbage hop stops", "10.0.0.1:1234", "198.51.100.1, junk", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := svc.clientIP(req); got.String() != tt.want {
+				t.Errorf("clientIP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyAllowsIP(t *testing.T) {
+	nets, _ := parseCIDRs([]string{"192.168.0.0/16"})
+	restricted := &APIKeyInfo{AllowedNets: nets}
+	tests := []struct {
+		info *APIKeyInfo
+		ip   net.IP
+		want bool
+	}{
+		{&APIKeyInfo{}, net.ParseIP("203.0.113.7"), true},
+		{restricted, net.ParseIP("192.168.4.2"), true},
+		{restricted, net.ParseIP("203.0.113.7"), false},
+		{restricted, nil, false},
+	}
+	for _, tt := range tests {
+		if got := keyAllowsIP(tt.info, tt.ip); got != tt.want {
+			t.Errorf("keyAllowsIP(%v, %s) = %v, want %v", tt.info.AllowedNets, tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestTokenRejectsKeyOutsideAllowlist(t *testing.T) {
+	info := testKeyInfo(testKey)
+	info.AllowedNets, _ = parseCIDRs([]string{"192.0.2.0/24"})
+	svc := newTestService(t, Config{}, info)
+
+	// httptest requests come from 192.0.2.1.
+	if rec := serve(svc, http.MethodPost, "/api/auth/token", map[string]string{"api_key": testKey}, nil); rec.Code != http.StatusOK {
+		t.Errorf("allowed address: status %d", rec.Code)
+	}
+	other := testKeyInfo(testKey)
+	other.AllowedNets, _ = parseCIDRs([]string{"198.51.100.0/24"})
+	svc = newTestService(t, Config{}, other)
+	if rec := serve(svc, http.MethodPost, "/api/auth/token", map[string]string{"api_key": testKey}, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("other address: status %d, want 403", rec.Code)
+	}
+}
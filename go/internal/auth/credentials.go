@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"fmt"
+	"sync"
+
+	"jarviscore/go/internal/secrets"
+)
+
+// credentials holds the rotatable secrets of the service. After a rotation
+// the previous signing secret stays valid for verification so tokens issued
+// before the rotation keep working until they expire.
+type credentials struct {
+	secret         string
+	previousSecret string
+	adminKey       string
+	mu             sync.RWMutex
+}
+
+func (c *credentials) signingSecret() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.secret
+}
+
+func (c *credentials) verificationSecrets() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.previousSecret == "" {
+		return []string{c.secret}
+	}
+	return []string{c.secret, c.previousSecret}
+}
+
+func (c *credentials) admin() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.adminKey
+}
+
+// watchSecrets loads the initial secrets from an external source and applies
+// later rotations.
+func (s *Service) watchSecrets() error {
+	manager, err := secrets.NewManager(s.cfg.Secrets, s.logger)
+	if err != nil {
+		return fmt.Errorf("Secrets konnten nicht geladen werden: %w", err)
+	}
+	s.creds.mu.Lock()
+	if value := manager.Get("JARVIS_AUTH_SECRET"); value != "" {
+		s.creds.secret = value
+	}
+	if value := manager.Get("JARVIS_AUTH_ADMIN_KEY"); value != "" {
+		s.creds.adminKey = value
+	}
+	s.creds.mu.Unlock()
+
+	manager.Watch(s.applySecret)
+	manager.Start(s.stop)
+	return nil
+}
+
+func (s *Service) applySecret(name, value string) {
+	if value == "" {
+		return
+	}
+	switch name {
+	case "JARVIS_AUTH_SECRET":
+		s.creds.mu.Lock()
+		if s.creds.secret == value {
+			s.creds.mu.Unlock()
+			return
+		}
+		s.creds.previousSecret = s.creds.secret
+		s.creds.secret = value
+		s.creds.mu.Unlock()
+		s.recordAudit(nil, AuditKeyRotated, "", "JARVIS_AUTH_SECRET rotated")
+		s.logger.Printf("[INFO] Signing-Secret rotiert")
+	case "JARVIS_AUTH_ADMIN_KEY":
+		s.creds.mu.Lock()
+		s.creds.adminKey = value
+		s.creds.mu.Unlock()
+		s.recordAudit(nil, AuditKeyRotated, "", "JARVIS_AUTH_ADMIN_KEY rotated")
+		s.logger.Printf("[INFO] Admin-Key rotiert")
+	}
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	expiryWarning       = 7 * 24 * time.Hour
+	expiryCheckInterval = time.Hour
+)
+
+// Expired reports whether the key has an expiry date that lies before now.
+func (info *APIKeyInfo) Expired(now time.Time) bool {
+	return !info.ExpiresAt.IsZero() && !now.Before(info.ExpiresAt)
+}
+
+// Usable reports whether the key is enabled and not expired.
+func (info *APIKeyInfo) Usable(now time.Time) bool {
+	return info.Enabled && !info.Expired(now)
+}
+
+// tokenTTL caps the token lifetime at the expiry of the key.
+func tokenTTL(info *APIKeyInfo, now time.Time) time.Duration {
+	ttl := 24 * time.Hour
+	if !info.ExpiresAt.IsZero() {
+		if remaining := info.ExpiresAt.Sub(now); remaining < ttl {
+			ttl = remaining
+		}
+	}
+	return ttl
+}
+
+// Extend moves the expiry of key to expiresAt (zero removes the expiry).
+func (k *KeyStore) Extend(key string, expiresAt time.Time) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	info, exists := k.keys[key]
+	if !exists {
+		return false
+	}
+	info.ExpiresAt = expiresAt
+	return true
+}
+
+// watchExpiry periodically announces keys that expire within expiryWarning.
+// Every key is announced once per expiry date.
+func (s *Service) watchExpiry() {
+	notified := map[string]time.Time{}
+	check := func() {
+		now := time.Now()
+		for _, info := range s.keys.List() {
+			if info.ExpiresAt.IsZero() || !info.Enabled || info.ExpiresAt.Sub(now) > expiryWarning {
+				continue
+			}
+			if last, ok := notified[info.Key]; ok && last.Equal(info.ExpiresAt) {
+				continue
+			}
+			notified[info.Key] = info.ExpiresAt
+			s.notifyExpiry(info, now)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(expiryCheckInterval)
+		defer ticker.Stop()
+		check()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				check()
+			}
+		}
+	}()
+}
+
+func (s *Service) notifyExpiry(info APIKeyInfo, now time.Time) {
+	expired := info.Expired(now)
+	s.logger.Printf("[WARN] API-Key %s läuft ab am %s", maskAPIKey(info.Key), info.ExpiresAt.UTC().Format(time.RFC3339))
+	if s.cfg.GatewayURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"type":      "auth.key_expiring",
+		"timestamp": float64(now.UnixNano()) / 1e9,
+		"payload": map[string]interface{}{
+			"key":        maskAPIKey(info.Key),
+			"expires_at": info.ExpiresAt.UTC().Format(time.RFC3339),
+			"expired":    expired,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(s.cfg.GatewayURL, "/")+"/api/events", bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.GatewayToken != "" {
+		req.Header.Set("X-API-Key", s.cfg.GatewayToken)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		s.logger.Printf("[WARN] gatewayd nicht erreichbar: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		s.logger.Printf("[WARN] gatewayd Event fehlgeschlagen: %d", resp.StatusCode)
+	}
+}
+
+// extendAPIKeyHandler sets the expiry of a key to expires_at, moves it by
+// extend_by, or removes it if no_expiry is set. Exactly one of them is
+// required.
+func (s *Service) extendAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Key       string `json:"key"`
+		ExpiresAt string `json:"expires_at"`
+		ExtendBy  string `json:"extend_by"`
+		NoExpiry  bool   `json:"no_expiry"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	options := 0
+	for _, set := range []bool{req.ExpiresAt != "", req.ExtendBy != "", req.NoExpiry} {
+		if set {
+			options++
+		}
+	}
+	if options != 1 {
+		http.Error(w, `{"error":"Exactly one of expires_at, extend_by or no_expiry required"}`, http.StatusBadRequest)
+		return
+	}
+	key := strings.TrimSpace(req.Key)
+	info, exists := s.keys.Get(key)
+	if !exists {
+		http.Error(w, `{"error":"API key not found"}`, http.StatusNotFound)
+		return
+	}
+
+	// With no_expiry expiresAt stays zero.
+	var expiresAt time.Time
+	switch {
+	case req.ExpiresAt != "":
+		parsed, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			http.Error(w, `{"error":"Invalid expires_at"}`, http.StatusBadRequest)
+			return
+		}
+		expiresAt = parsed
+	case req.ExtendBy != "":
+		duration, err := time.ParseDuration(req.ExtendBy)
+		if err != nil || duration <= 0 {
+			http.Error(w, `{"error":"Invalid extend_by"}`, http.StatusBadRequest)
+			return
+		}
+		base := info.ExpiresAt
+		if base.IsZero() || base.Before(time.Now()) {
+			base = time.Now()
+		}
+		expiresAt = base.Add(duration)
+	}
+
+	s.keys.Extend(key, expiresAt)
+	detail := "no expiry"
+	if !expiresAt.IsZero() {
+		detail = fmt.Sprintf("expires %s", expiresAt.UTC().Format(time.RFC3339))
+	}
+	s.recordAudit(r, AuditKeyExtended, key, detail)
+
+	if err := s.keys.Persist(); err != nil {
+		s.logger.Printf("[WARN] API-Key-Datei konnte nicht gespeichert werden: %v", err)
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "API key expiry updated",
+	}
+	if !expiresAt.IsZero() {
+		response["expires_at"] = expiresAt.UTC().Format(time.RFC3339)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
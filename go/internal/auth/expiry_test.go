@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestKeyExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		enabled bool
+		expires time.Time
+		expired bool
+		usable  bool
+		ttl     time.Duration
+	}{
+		{"no expiry", true, time.Time{}, false, true, 24 * time.Hour},
+		{"expires later", true, now.Add(72 * time.Hour), false, true, 24 * time.Hour},
+		{"expires within a day", true, now.Add(time.Hour), false, true, time.Hour},
+		{"expires now", true, now, true, false, 0},
+		{"expired", true, now.Add(-time.Hour), true, false, -time.Hour},
+		{"disabled", false, time.Time{}, false, false, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &APIKeyInfo{Enabled: tt.enabled, ExpiresAt: tt.expires}
+			if got := info.Expired(now); got != tt.expired {
+				t.Errorf("Expired = %v, want %v", got, tt.expired)
+			}
+			if got := info.Usable(now); got != tt.usable {
+				t.Errorf("Usable = %v, want %v", got, tt.usable)
+			}
+			if got := tokenTTL(info, now); got != tt.ttl {
+				t.Errorf("tokenTTL = %v, want %v", got, tt.ttl)
+			}
+		})
+	}
+}
+
+func TestExpiredKeyCannotIssueTokens(t *testing.T) {
+	expired := testKeyInfo("expired-key-0123456789")
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	soon := testKeyInfo("soon-key-0123456789")
+	soon.ExpiresAt = time.Now().Add(time.Hour)
+	svc := newTestService(t, Config{}, expired, soon)
+
+	if rec := serve(svc, http.MethodPost, "/api/auth/token", map[string]string{"api_key": expired.Key}, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expired key: status %d, want 401", rec.Code)
+	}
+	rec := serve(svc, http.MethodPost, "/api/auth/token", map[string]string{"api_key": soon.Key}, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("valid key: status %d", rec.Code)
+	}
+	if expiresIn := decodeBody(t, rec)["expires_in"].(float64); expiresIn > time.Hour.Seconds() {
+		t.Errorf("expires_in = %v, want at most the key lifetime", expiresIn)
+	}
+}
+
+func TestExtendAPIKeyHandler(t *testing.T) {
+	fixed := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name    string
+		expires time.Duration // relative to now, 0 = no expiry
+		body    map[string]interface{}
+		status  int
+		want    func(now time.Time) time.Time
+	}{
+		{"set date", time.Hour, map[string]interface{}{"expires_at": fixed.Format(time.RFC3339)}, http.StatusOK,
+			func(time.Time) time.Time { return fixed }},
+		{"extend running key", time.Hour, map[string]interface{}{"extend_by": "48h"}, http.StatusOK,
+			func(now time.Time) time.Time { return now.Add(49 * time.Hour) }},
+		{"extend expired key from now", -time.Hour, map[string]interface{}{"extend_by": "24h"}, http.StatusOK,
+			func(now time.Time) time.Time { return now.Add(24 * time.Hour) }},
+		{"remove expiry", time.Hour, map[string]interface{}{"no_expiry": true}, http.StatusOK,
+			func(time.Time) time.Time { return time.Time{} }},
+		{"no option", time.Hour, map[string]interface{}{}, http.StatusBadRequest, nil},
+		{"two options", time.Hour, map[string]interface{}{"extend_by": "1h", "no_expiry": true}, http.StatusBadRequest, nil},
+		{"invalid date", time.Hour, map[string]interface{}{"expires_at": "tomorrow"}, http.StatusBadRequest, nil},
+		{"negative duration", time.Hour, map[string]interface{}{"extend_by": "-1h"}, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := testKeyInfo(testKey)
+			now := time.Now()
+			info.ExpiresAt = now.Add(tt.expires)
+			original := info.ExpiresAt
+			svc := newTestService(t, Config{}, info)
+
+			tt.body["key"] = testKey
+			rec := serve(svc, http.MethodPost, "/api/auth/keys/extend", tt.body, map[string]string{"X-Admin-Key": testAdminKey})
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.status, rec.Body)
+			}
+			stored, _ := svc.keys.Get(testKey)
+			if tt.want == nil {
+				if !stored.ExpiresAt.Equal(original) {
+					t.Errorf("expiry changed to %v on a rejected request", stored.ExpiresAt)
+				}
+				return
+			}
+			if want := tt.want(now); stored.ExpiresAt.Sub(want).Abs() > time.Second {
+				t.Errorf("expires_at = %v, want %v", stored.ExpiresAt, want)
+			}
+		})
+	}
+
+	svc := newTestService(t, Config{})
+	rec := serve(svc, http.MethodPost, "/api/auth/keys/extend", map[string]interface{}{"key": "unknown", "extend_by": "1h"}, map[string]string{"X-Admin-Key": testAdminKey})
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown key: status %d, want 404", rec.Code)
+	}
+}
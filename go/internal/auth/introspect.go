@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"jarviscore/go/internal/authmw"
+)
+
+// trustedCaller reports whether a service may use the introspection and
+// ticket redemption endpoints (RFC 7662 section 2.1 requires the protected
+// resource to authenticate): admin key, verified client certificate, or a
+// valid API key.
+func (s *Service) trustedCaller(r *http.Request) bool {
+	if s.isAdminRequest(r) {
+		return true
+	}
+	if _, ok := clientCertInfo(r); ok {
+		return true
+	}
+	apiKey := authmw.APIKeyFromRequest(r)
+	if apiKey == "" {
+		return false
+	}
+	keyInfo, exists := s.keys.Get(apiKey)
+	return exists && keyInfo.Usable(time.Now()) && keyAllowsIP(keyInfo, s.clientIP(r))
+}
+
+func introspectionToken(r *http.Request) (string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return "", err
+		}
+		return req.Token, nil
+	}
+	if err := r.ParseForm(); err != nil {
+		return "", err
+	}
+	return r.PostForm.Get("token"), nil
+}
+
+// introspectHandler implements RFC 7662 token introspection. Unknown,
+// expired or revoked tokens yield {"active": false}.
+func (s *Service) introspectHandler(w http.ResponseWriter, r *http.Request) {
+	if s.rejectIfLocked(w, r) {
+		return
+	}
+	if !s.trustedCaller(r) {
+		s.registerFailure(r, r.Header.Get("X-API-Key"), "introspection caller not authenticated")
+		w.Header().Set("WWW-Authenticate", `Bearer realm="jarvis-auth"`)
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+
+	token, err := introspectionToken(r)
+	if err != nil || strings.TrimSpace(token) == "" {
+		http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{"active": false}
+	if claims, err := s.VerifyToken(token); err == nil {
+		keyInfo, exists := s.keys.Get(claims.APIKey)
+		subject := KeyID(claims.APIKey)
+		if claims.APIKey == "" && claims.Subject != "" {
+			keyInfo, exists = s.keys.GetByID(claims.Subject)
+			subject = claims.Subject
+		}
+		if exists && keyInfo.Usable(time.Now()) {
+			response = map[string]interface{}{
+				"active":     true,
+				"token_type": "Bearer",
+				"client_id":  subject,
+				"sub":        subject,
+			}
+			if len(claims.Audience) > 0 {
+				response["aud"] = claims.Audience
+			}
+			if claims.Scope != "" {
+				response["scope"] = claims.Scope
+			}
+			if claims.ExpiresAt != nil {
+				response["exp"] = claims.ExpiresAt.Unix()
+			}
+			if claims.IssuedAt != nil {
+				response["iat"] = claims.IssuedAt.Unix()
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"jarviscore/go/internal/authmw"
+)
+
+func TestIntrospect(t *testing.T) {
+	expired := testKeyInfo("expired-key-0123456789")
+	expired.ExpiresAt = time.Now().Add(-time.Hour)
+	svc := newTestService(t, Config{}, testKeyInfo(testKey), expired)
+
+	token, _ := authmw.GenerateToken(testSecret, testKey, time.Hour, "memory:read")
+	serviceToken, _ := authmw.GenerateServiceToken(testSecret, KeyID(testKey), "gatewayd", time.Minute)
+	expiredToken, _ := authmw.GenerateToken(testSecret, expired.Key, time.Hour)
+	foreignToken, _ := authmw.GenerateToken("other-secret", testKey, time.Hour)
+	admin := map[string]string{"X-Admin-Key": testAdminKey}
+
+	tests := []struct {
+		name    string
+		token   string
+		headers map[string]string
+		status  int
+		active  bool
+		sub     string
+	}{
+		{"api key token", token, admin, http.StatusOK, true, KeyID(testKey)},
+		{"service token", serviceToken, admin, http.StatusOK, true, KeyID(testKey)},
+		{"caller with api key", token, map[string]string{"X-API-Key": testKey}, http.StatusOK, true, KeyID(testKey)},
+		{"expired key", expiredToken, admin, http.StatusOK, false, ""},
+		{"foreign signature", foreignToken, admin, http.StatusOK, false, ""},
+		{"garbage", "not-a-token", admin, http.StatusOK, false, ""},
+		{"unauthenticated caller", token, nil, http.StatusUnauthorized, false, ""},
+		{"expired caller key", token, map[string]string{"X-API-Key": expired.Key}, http.StatusUnauthorized, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(svc, http.MethodPost, "/api/auth/introspect", map[string]string{"token": tt.token}, tt.headers)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			body := decodeBody(t, rec)
+			if body["active"] != tt.active {
+				t.Fatalf("active = %v, want %v", body["active"], tt.active)
+			}
+			if tt.active && (body["sub"] != tt.sub || body["client_id"] != tt.sub) {
+				t.Errorf("sub %v, client_id %v, want %s", body["sub"], body["client_id"], tt.sub)
+			}
+			if !tt.active && len(body) != 1 {
+				t.Errorf("inactive response leaks claims: %v", body)
+			}
+		})
+	}
+}
+
+func TestIntrospectForm(t *testing.T) {
+	svc := newTestService(t, Config{})
+	token, _ := authmw.GenerateToken(testSecret, testKey, time.Hour, "memory:read", "memory:write")
+
+	form := url.Values{"token": {token}}
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Admin-Key", testAdminKey)
+	mux := http.NewServeMux()
+	svc.Routes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	body := decodeBody(t, rec)
+	if body["active"] != true || body["scope"] != "memory:read memory:write" || body["exp"] == nil {
+		t.Errorf("unexpected response %v", body)
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Error("response may be cached")
+	}
+}
@@ -0,0 +1,349 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"jarviscore/go/internal/fsutil"
+)
+
+const persistInterval = 30 * time.Second
+
+// KeyID derives the public identifier of a key, used for signed requests and
+// wherever a key is referenced without revealing it.
+func KeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+type apiKeyEntry struct {
+	Key       string `json:"key"`
+	RateLimit int    `json:"rate_limit"`
+	Burst     int    `json:"burst"`
+	Enabled   bool   `json:"enabled"`
+	CreatedAt string `json:"created_at"`
+	LastUsed  string `json:"last_used,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+
+	MonthlyQuota int `json:"monthly_quota,omitempty"`
+
+	AllowedCIDRs []string `json:"allowed_cidrs,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+	Audiences    []string `json:"audiences,omitempty"`
+}
+
+// KeyStore holds the API keys of a Service and persists them to a JSON file.
+type KeyStore struct {
+	path        string
+	keys        map[string]*APIKeyInfo
+	mu          sync.RWMutex
+	persistMu   sync.Mutex
+	lastPersist time.Time
+	dirty       bool
+}
+
+// NewKeyStore creates an empty store persisting to path ("" disables persistence).
+func NewKeyStore(path string) *KeyStore {
+	return &KeyStore{
+		path: path,
+		keys: make(map[string]*APIKeyInfo),
+	}
+}
+
+// LoadKeyStore builds the store from JARVIS_AUTH_KEYS or, if unset, the keys file.
+func LoadKeyStore(cfg Config) (*KeyStore, error) {
+	store := NewKeyStore(cfg.KeysFile)
+
+	entries, err := parseAPIKeysFromEnv(cfg.KeysEnv)
+	if err != nil {
+		return nil, fmt.Errorf("ungültiges JARVIS_AUTH_KEYS Format: %w", err)
+	}
+
+	if len(entries) == 0 {
+		fileEntries, fileErr := loadAPIKeysFromFile(cfg.KeysFile)
+		if fileErr == nil {
+			entries = fileEntries
+		} else if !os.IsNotExist(fileErr) {
+			return nil, fmt.Errorf("API-Key-Datei konnte nicht gelesen werden: %w", fileErr)
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("keine API-Keys konfiguriert. Setze JARVIS_AUTH_KEYS oder eine config/auth_keys.json")
+	}
+
+	if err := store.hydrate(entries); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func generateAPIKey() (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return "jarvis_" + hex.EncodeToString(raw), nil
+}
+
+func parseTime(value string, fallback time.Time) time.Time {
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+func loadAPIKeysFromFile(path string) ([]apiKeyEntry, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []apiKeyEntry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func parseAPIKeysFromEnv(raw string) ([]apiKeyEntry, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var entries []apiKeyEntry
+	if err := json.Unmarshal([]byte(raw), &entries); err == nil {
+		return entries, nil
+	}
+	keys := strings.Split(raw, ",")
+	entries = make([]apiKeyEntry, 0, len(keys))
+	for _, key := range keys {
+		trimmed := strings.TrimSpace(key)
+		if trimmed == "" {
+			continue
+		}
+		entries = append(entries, apiKeyEntry{
+			Key:       trimmed,
+			RateLimit: 60,
+			Burst:     10,
+			Enabled:   true,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+	return entries, nil
+}
+
+func persistAPIKeys(path string, entries []apiKeyEntry) error {
+	if path == "" {
+		return nil
+	}
+	payload, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(path, payload, 0o600)
+}
+
+func (k *KeyStore) hydrate(entries []apiKeyEntry) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = map[string]*APIKeyInfo{}
+	now := time.Now().UTC()
+	for _, entry := range entries {
+		if strings.TrimSpace(entry.Key) == "" {
+			continue
+		}
+		rateLimit := entry.RateLimit
+		if rateLimit <= 0 {
+			rateLimit = 60
+		}
+		burst := entry.Burst
+		if burst <= 0 {
+			burst = 10
+		}
+		allowedNets, err := parseCIDRs(entry.AllowedCIDRs)
+		if err != nil {
+			return fmt.Errorf("API-Key %s: %w", maskAPIKey(entry.Key), err)
+		}
+		createdAt := parseTime(entry.CreatedAt, now)
+		lastUsed := parseTime(entry.LastUsed, time.Time{})
+		k.keys[entry.Key] = &APIKeyInfo{
+			Key:          entry.Key,
+			RateLimit:    rateLimit,
+			Burst:        burst,
+			Enabled:      entry.Enabled,
+			CreatedAt:    createdAt,
+			LastUsed:     lastUsed,
+			ExpiresAt:    parseTime(entry.ExpiresAt, time.Time{}),
+			MonthlyQuota: entry.MonthlyQuota,
+			AllowedCIDRs: entry.AllowedCIDRs,
+			AllowedNets:  allowedNets,
+			Scopes:       entry.Scopes,
+			Audiences:    entry.Audiences,
+		}
+	}
+	return nil
+}
+
+// Get returns a copy of the key info for key. Changes go through the store,
+// so the copy can be read without holding its lock.
+func (k *KeyStore) Get(key string) (*APIKeyInfo, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	info, ok := k.keys[key]
+	if !ok {
+		return nil, false
+	}
+	copied := *info
+	return &copied, true
+}
+
+// Add inserts a copy of info; it returns false if the key already exists.
+func (k *KeyStore) Add(info *APIKeyInfo) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, exists := k.keys[info.Key]; exists {
+		return false
+	}
+	copied := *info
+	k.keys[info.Key] = &copied
+	return true
+}
+
+// Remove deletes key; it returns false if the key does not exist.
+func (k *KeyStore) Remove(key string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, exists := k.keys[key]; !exists {
+		return false
+	}
+	delete(k.keys, key)
+	return true
+}
+
+// Rotate replaces oldKey with newKey, keeping limits, allowlist and scopes.
+func (k *KeyStore) Rotate(oldKey, newKey string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	info, exists := k.keys[oldKey]
+	if !exists {
+		return fmt.Errorf("API key not found")
+	}
+	if _, taken := k.keys[newKey]; taken {
+		return fmt.Errorf("API key already exists")
+	}
+	rotated := *info
+	rotated.Key = newKey
+	rotated.CreatedAt = time.Now()
+	rotated.LastUsed = time.Time{}
+	delete(k.keys, oldKey)
+	k.keys[newKey] = &rotated
+	return nil
+}
+
+// Touch records a use of the key in the store and in info.
+func (k *KeyStore) Touch(info *APIKeyInfo) {
+	now := time.Now()
+	k.mu.Lock()
+	if stored, ok := k.keys[info.Key]; ok {
+		stored.LastUsed = now
+	}
+	k.mu.Unlock()
+	info.LastUsed = now
+	k.persistMu.Lock()
+	k.dirty = true
+	k.persistMu.Unlock()
+}
+
+func (k *KeyStore) Len() int {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return len(k.keys)
+}
+
+// List returns copies of all key infos.
+func (k *KeyStore) List() []APIKeyInfo {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	infos := make([]APIKeyInfo, 0, len(k.keys))
+	for _, info := range k.keys {
+		infos = append(infos, *info)
+	}
+	return infos
+}
+
+func (k *KeyStore) snapshot() []apiKeyEntry {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	entries := make([]apiKeyEntry, 0, len(k.keys))
+	for _, info := range k.keys {
+		entry := apiKeyEntry{
+			Key:       info.Key,
+			RateLimit: info.RateLimit,
+			Burst:     info.Burst,
+			Enabled:   info.Enabled,
+			CreatedAt: info.CreatedAt.UTC().Format(time.RFC3339),
+
+			AllowedCIDRs: info.AllowedCIDRs,
+			Scopes:       info.Scopes,
+			Audiences:    info.Audiences,
+			MonthlyQuota: info.MonthlyQuota,
+		}
+		if !info.LastUsed.IsZero() {
+			entry.LastUsed = info.LastUsed.UTC().Format(time.RFC3339)
+		}
+		if !info.ExpiresAt.IsZero() {
+			entry.ExpiresAt = info.ExpiresAt.UTC().Format(time.RFC3339)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Persist writes all keys to the keys file.
+func (k *KeyStore) Persist() error {
+	k.persistMu.Lock()
+	defer k.persistMu.Unlock()
+	if err := persistAPIKeys(k.path, k.snapshot()); err != nil {
+		return err
+	}
+	k.lastPersist = time.Now().UTC()
+	k.dirty = false
+	return nil
+}
+
+// MaybePersist persists at most once per persistInterval. Skipped changes
+// stay pending until the next call or Flush.
+func (k *KeyStore) MaybePersist() error {
+	if k.path == "" {
+		return nil
+	}
+	k.persistMu.Lock()
+	due := time.Since(k.lastPersist) >= persistInterval
+	k.persistMu.Unlock()
+	if !due {
+		return nil
+	}
+	return k.Persist()
+}
+
+// Flush writes pending changes, ignoring the persist interval.
+func (k *KeyStore) Flush() error {
+	k.persistMu.Lock()
+	dirty := k.dirty
+	k.persistMu.Unlock()
+	if k.path == "" || !dirty {
+		return nil
+	}
+	return k.Persist()
+}
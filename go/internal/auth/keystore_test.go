@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyStorePersistRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth_keys.json")
+	store := NewKeyStore(path)
+	info := testKeyInfo(testKey)
+	info.Scopes = []string{"memory:read"}
+	info.ExpiresAt = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.Add(info)
+	if err := store.Persist(); err != nil {
+		t.Fatalf("Persist: %v", err)
+	}
+
+	loaded, err := LoadKeyStore(Config{KeysFile: path})
+	if err != nil {
+		t.Fatalf("LoadKeyStore: %v", err)
+	}
+	got, ok := loaded.Get(testKey)
+	if !ok {
+		t.Fatal("key missing after reload")
+	}
+	if !got.ExpiresAt.Equal(info.ExpiresAt) || len(got.Scopes) != 1 || got.RateLimit != info.RateLimit {
+		t.Errorf("reloaded %+v, want %+v", got, info)
+	}
+	if stat, _ := os.Stat(path); stat.Mode().Perm() != 0o600 {
+		t.Errorf("perm = %v, want 0600", stat.Mode().Perm())
+	}
+}
+
+func TestKeyStoreAddCopies(t *testing.T) {
+	store := NewKeyStore("")
+	info := testKeyInfo(testKey)
+	store.Add(info)
+	info.Enabled = false
+	info.Scopes = append(info.Scopes, "admin")
+
+	got, _ := store.Get(testKey)
+	if !got.Enabled || len(got.Scopes) != 0 {
+		t.Errorf("stored key changed through the caller's pointer: %+v", got)
+	}
+}
+
+func TestKeyStoreFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth_keys.json")
+	store := NewKeyStore(path)
+	store.Add(testKeyInfo(testKey))
+
+	steps := []struct {
+		name   string
+		action func() error
+		writes bool
+	}{
+		{"first MaybePersist", store.MaybePersist, true},
+		{"clean Flush", store.Flush, false},
+		{"MaybePersist within interval", func() error {
+			info, _ := store.Get(testKey)
+			store.Touch(info)
+			return store.MaybePersist()
+		}, false},
+		{"Flush with pending change", store.Flush, true},
+		{"Flush after flush", store.Flush, false},
+	}
+	for _, step := range steps {
+		os.Remove(path)
+		if err := step.action(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if _, err := os.Stat(path); (err == nil) != step.writes {
+			t.Errorf("%s: file written = %v, want %v", step.name, err == nil, step.writes)
+		}
+	}
+}
+
+func TestServiceCloseFlushesKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth_keys.json")
+	keys := NewKeyStore(path)
+	keys.Add(testKeyInfo(testKey))
+	svc, err := NewServiceWithStores(Config{SecretKey: testSecret}, log.New(io.Discard, "", 0), Stores{Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caller := map[string]string{"X-API-Key": testKey}
+	serve(svc, http.MethodGet, "/api/protected/test", nil, caller)
+	os.Remove(path)
+	if rec := serve(svc, http.MethodGet, "/api/protected/test", nil, caller); rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Fatal("key file written within the persist interval")
+	}
+
+	svc.Close()
+	svc.Close()
+	if _, err := LoadKeyStore(Config{KeysFile: path}); err != nil {
+		t.Errorf("key file not flushed on Close: %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"jarviscore/go/internal/authmw"
+)
+
+const (
+	defaultServiceTokenTTL = 5 * time.Minute
+	maxServiceTokenTTL     = time.Hour
+)
+
+type clientCredentialsRequest struct {
+	GrantType    string `json:"grant_type"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	Audience     string `json:"audience"`
+	Scope        string `json:"scope"`
+}
+
+func parseClientCredentials(r *http.Request) (clientCredentialsRequest, error) {
+	var req clientCredentialsRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return req, err
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return req, err
+		}
+		req = clientCredentialsRequest{
+			GrantType:    r.PostForm.Get("grant_type"),
+			ClientID:     r.PostForm.Get("client_id"),
+			ClientSecret: r.PostForm.Get("client_secret"),
+			Audience:     r.PostForm.Get("audience"),
+			Scope:        r.PostForm.Get("scope"),
+		}
+	}
+	if id, secret, ok := r.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = id, secret
+	}
+	return req, nil
+}
+
+func oauthError(w http.ResponseWriter, code string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+// serviceTokenHandler implements the OAuth 2.0 client credentials grant for
+// internal services. The client id is the KeyID of an API key, the secret the
+// key itself; the key must list the requested audience.
+func (s *Service) serviceTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if s.rejectIfLocked(w, r) {
+		return
+	}
+	req, err := parseClientCredentials(r)
+	if err != nil {
+		oauthError(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	if req.GrantType != "client_credentials" {
+		oauthError(w, "unsupported_grant_type", http.StatusBadRequest)
+		return
+	}
+
+	keyInfo, exists := s.keys.Get(strings.TrimSpace(req.ClientSecret))
+	if !exists || KeyID(keyInfo.Key) != strings.TrimSpace(req.ClientID) ||
+		!keyInfo.Usable(time.Now()) || !keyAllowsIP(keyInfo, s.clientIP(r)) {
+		s.registerFailure(r, req.ClientSecret, "invalid client credentials")
+		w.Header().Set("WWW-Authenticate", `Basic realm="jarvis-auth"`)
+		oauthError(w, "invalid_client", http.StatusUnauthorized)
+		return
+	}
+	s.registerSuccess(r)
+
+	audience := strings.TrimSpace(req.Audience)
+	if audience == "" || !slices.Contains(keyInfo.Audiences, audience) {
+		oauthError(w, "invalid_target", http.StatusBadRequest)
+		return
+	}
+
+	scopes := keyInfo.Scopes
+	if requested := strings.Fields(req.Scope); len(requested) > 0 {
+		for _, scope := range requested {
+			if !slices.Contains(keyInfo.Scopes, scope) {
+				oauthError(w, "invalid_scope", http.StatusBadRequest)
+				return
+			}
+		}
+		scopes = requested
+	}
+
+	ttl := min(s.cfg.ServiceTokenTTL, tokenTTL(keyInfo, time.Now()))
+	token, err := authmw.GenerateServiceToken(s.creds.signingSecret(), KeyID(keyInfo.Key), audience, ttl, scopes...)
+	if err != nil {
+		oauthError(w, "server_error", http.StatusInternalServerError)
+		return
+	}
+	s.recordAudit(r, AuditTokenIssued, keyInfo.Key, "aud="+audience)
+
+	response := map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(ttl.Seconds()),
+	}
+	if len(scopes) > 0 {
+		response["scope"] = strings.Join(scopes, " ")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"jarviscore/go/internal/authmw"
+)
+
+func TestServiceTokenHandler(t *testing.T) {
+	clientID := KeyID(testKey)
+	tests := []struct {
+		name    string
+		body    map[string]string
+		status  int
+		errCode string
+		scope   string
+	}{
+		{"all scopes", map[string]string{"grant_type": "client_credentials", "client_id": clientID, "client_secret": testKey, "audience": "memoryd"}, http.StatusOK, "", "memory:read memory:write"},
+		{"requested scope", map[string]string{"grant_type": "client_credentials", "client_id": clientID, "client_secret": testKey, "audience": "memoryd", "scope": "memory:read"}, http.StatusOK, "", "memory:read"},
+		{"unsupported grant", map[string]string{"grant_type": "password", "client_id": clientID, "client_secret": testKey, "audience": "memoryd"}, http.StatusBadRequest, "unsupported_grant_type", ""},
+		{"wrong client id", map[string]string{"grant_type": "client_credentials", "client_id": KeyID("other"), "client_secret": testKey, "audience": "memoryd"}, http.StatusUnauthorized, "invalid_client", ""},
+		{"unknown secret", map[string]string{"grant_type": "client_credentials", "client_id": clientID, "client_secret": "unknown-key-0123456789", "audience": "memoryd"}, http.StatusUnauthorized, "invalid_client", ""},
+		{"audience not allowed", map[string]string{"grant_type": "client_credentials", "client_id": clientID, "client_secret": testKey, "audience": "securityd"}, http.StatusBadRequest, "invalid_target", ""},
+		{"missing audience", map[string]string{"grant_type": "client_credentials", "client_id": clientID, "client_secret": testKey}, http.StatusBadRequest, "invalid_target", ""},
+		{"scope not granted", map[string]string{"grant_type": "client_credentials", "client_id": clientID, "client_secret": testKey, "audience": "memoryd", "scope": "admin"}, http.StatusBadRequest, "invalid_scope", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(t, Config{}, m2mKeyInfo())
+			rec := serve(svc, http.MethodPost, "/api/auth/oauth/token", tt.body, nil)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.status, rec.Body)
+			}
+			if rec.Header().Get("Cache-Control") != "no-store" {
+				t.Error("token response may be cached")
+			}
+			body := decodeBody(t, rec)
+			if tt.errCode != "" {
+				if body["error"] != tt.errCode {
+					t.Errorf("error = %v, want %s", body["error"], tt.errCode)
+				}
+				return
+			}
+			if body["scope"] != tt.scope || body["token_type"] != "Bearer" {
+				t.Errorf("body = %v", body)
+			}
+			claims, err := authmw.ParseToken(testSecret, body["access_token"].(string))
+			if err != nil {
+				t.Fatalf("ParseToken: %v", err)
+			}
+			if claims.Subject != clientID || !claims.VerifyAudience("memoryd", true) {
+				t.Errorf("sub %q, aud %v", claims.Subject, claims.Audience)
+			}
+			if ttl := time.Until(claims.ExpiresAt.Time); ttl > defaultServiceTokenTTL {
+				t.Errorf("token lives %v, want at most %v", ttl, defaultServiceTokenTTL)
+			}
+		})
+	}
+}
+
+func TestServiceTokenFormAndBasicAuth(t *testing.T) {
+	svc := newTestService(t, Config{}, m2mKeyInfo())
+	form := url.Values{"grant_type": {"client_credentials"}, "audience": {"memoryd"}}
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(KeyID(testKey), testKey)
+
+	if rec := serveRequest(svc, req); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s)", rec.Code, rec.Body)
+	}
+}
+
+func TestServiceTokenRespectsKeyExpiry(t *testing.T) {
+	info := m2mKeyInfo()
+	info.ExpiresAt = time.Now().Add(time.Minute)
+	svc := newTestService(t, Config{}, info)
+	rec := serve(svc, http.MethodPost, "/api/auth/oauth/token", map[string]string{
+		"grant_type": "client_credentials", "client_id": KeyID(testKey), "client_secret": testKey, "audience": "memoryd",
+	}, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if expiresIn := decodeBody(t, rec)["expires_in"].(float64); expiresIn > time.Minute.Seconds() {
+		t.Errorf("expires_in = %v, want capped at the key expiry", expiresIn)
+	}
+}
+
+func m2mKeyInfo() *APIKeyInfo {
+	info := testKeyInfo(testKey)
+	info.Audiences = []string{"memoryd", "gatewayd"}
+	info.Scopes = []string{"memory:read", "memory:write"}
+	return info
+}
@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"jarviscore/go/internal/authmw"
+	"jarviscore/go/internal/fsutil"
+)
+
+// QuotaStore counts requests per key id (see KeyID) and calendar month (UTC)
+// and persists the counters so they survive restarts.
+type QuotaStore struct {
+	path        string
+	month       string
+	counts      map[string]int
+	mu          sync.Mutex
+	persistMu   sync.Mutex
+	lastPersist time.Time
+	dirty       bool
+}
+
+// quotaFileVersion is written to the quota file. Files without a version
+// count by the key itself instead of its id.
+const quotaFileVersion = 2
+
+type quotaFile struct {
+	Version int            `json:"version,omitempty"`
+	Month   string         `json:"month"`
+	Counts  map[string]int `json:"counts"`
+}
+
+func quotaMonth(now time.Time) string {
+	return now.UTC().Format("2006-01")
+}
+
+// quotaReset returns the start of the next month, when counters reset.
+func quotaReset(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func NewQuotaStore(path string) (*QuotaStore, error) {
+	q := &QuotaStore{path: path, month: quotaMonth(time.Now()), counts: map[string]int{}}
+	if path == "" {
+		return q, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return q, nil
+		}
+		return nil, err
+	}
+	var stored quotaFile
+	if err := json.Unmarshal(raw, &stored); err != nil {
+		return nil, err
+	}
+	if stored.Month == q.month {
+		for key, count := range stored.Counts {
+			if stored.Version < quotaFileVersion {
+				key = KeyID(key)
+			}
+			q.counts[key] += count
+		}
+	}
+	return q, nil
+}
+
+func (q *QuotaStore) rollLocked(now time.Time) {
+	if month := quotaMonth(now); month != q.month {
+		q.month = month
+		q.counts = map[string]int{}
+	}
+}
+
+// Consume counts one request for key id unless limit is reached. It returns
+// whether the request is allowed and the usage after the call.
+func (q *QuotaStore) Consume(key string, limit int, now time.Time) (bool, int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollLocked(now)
+	used := q.counts[key]
+	if limit > 0 && used >= limit {
+		return false, used
+	}
+	used++
+	q.counts[key] = used
+	q.dirty = true
+	return true, used
+}
+
+// Used returns the usage of key id in the current month.
+func (q *QuotaStore) Used(key string, now time.Time) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollLocked(now)
+	return q.counts[key]
+}
+
+// Rotate moves the counters of oldID to newID, so rotating a key does not
+// reset its quota.
+func (q *QuotaStore) Rotate(oldID, newID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if used, ok := q.counts[oldID]; ok {
+		delete(q.counts, oldID)
+		q.counts[newID] += used
+		q.dirty = true
+	}
+}
+
+// Persist writes the counters to the quota file.
+func (q *QuotaStore) Persist() error {
+	if q.path == "" {
+		return nil
+	}
+	q.persistMu.Lock()
+	defer q.persistMu.Unlock()
+	q.mu.Lock()
+	payload, err := json.MarshalIndent(quotaFile{Version: quotaFileVersion, Month: q.month, Counts: q.counts}, "", "  ")
+	q.dirty = false
+	q.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(q.path, payload, 0o600); err != nil {
+		q.mu.Lock()
+		q.dirty = true
+		q.mu.Unlock()
+		return err
+	}
+	q.lastPersist = time.Now().UTC()
+	return nil
+}
+
+// MaybePersist persists at most once per persistInterval.
+func (q *QuotaStore) MaybePersist() error {
+	if q.path == "" {
+		return nil
+	}
+	q.persistMu.Lock()
+	due := time.Since(q.lastPersist) >= persistInterval
+	q.persistMu.Unlock()
+	if !due {
+		return nil
+	}
+	return q.Persist()
+}
+
+// Flush writes pending counters, ignoring the persist interval.
+func (q *QuotaStore) Flush() error {
+	q.mu.Lock()
+	dirty := q.dirty
+	q.mu.Unlock()
+	if q.path == "" || !dirty {
+		return nil
+	}
+	return q.Persist()
+}
+
+func setQuotaHeaders(w http.ResponseWriter, limit int, used int, now time.Time) {
+	remaining := limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	w.Header().Set("X-Quota-Limit", strconv.Itoa(limit))
+	w.Header().Set("X-Quota-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-Quota-Reset", strconv.FormatInt(quotaReset(now).Unix(), 10))
+}
+
+// enforceQuota counts the request against the monthly quota of the key and
+// answers 429 once it is used up. It returns false if the request was rejected.
+func (s *Service) enforceQuota(w http.ResponseWriter, r *http.Request, keyInfo *APIKeyInfo) bool {
+	if keyInfo.MonthlyQuota <= 0 {
+		return true
+	}
+	now := time.Now()
+	allowed, used := s.quotas.Consume(KeyID(keyInfo.Key), keyInfo.MonthlyQuota, now)
+	setQuotaHeaders(w, keyInfo.MonthlyQuota, used, now)
+	if !allowed {
+		s.recordAudit(r, AuditQuotaExceeded, keyInfo.Key, "")
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(quotaReset(now)).Seconds())))
+		http.Error(w, `{"error":"Monthly quota exceeded"}`, http.StatusTooManyRequests)
+		return false
+	}
+	if err := s.quotas.MaybePersist(); err != nil {
+		s.logger.Printf("[WARN] Quota-Datei konnte nicht gespeichert werden: %v", err)
+	}
+	return true
+}
+
+// quotaHandler reports the quota of the calling key. Admins may query any
+// key with ?key=.
+func (s *Service) quotaHandler(w http.ResponseWriter, r *http.Request) {
+	key := authmw.APIKeyFromRequest(r)
+	if requested := strings.TrimSpace(r.URL.Query().Get("key")); requested != "" {
+		if !s.isAdminRequest(r) {
+			http.Error(w, `{"error":"Admin access required"}`, http.StatusForbidden)
+			return
+		}
+		key = requested
+	} else if s.rejectIfLocked(w, r) {
+		return
+	}
+
+	keyInfo, exists := s.keys.Get(key)
+	if key == "" || !exists {
+		if !s.isAdminRequest(r) {
+			s.registerFailure(r, key, "invalid api key")
+		}
+		http.Error(w, `{"error":"Invalid API key"}`, http.StatusUnauthorized)
+		return
+	}
+
+	now := time.Now()
+	used := s.quotas.Used(KeyID(key), now)
+	response := map[string]interface{}{
+		"key":      maskAPIKey(key),
+		"month":    quotaMonth(now),
+		"used":     used,
+		"limit":    keyInfo.MonthlyQuota,
+		"reset_at": quotaReset(now).Unix(),
+	}
+	if keyInfo.MonthlyQuota > 0 {
+		setQuotaHeaders(w, keyInfo.MonthlyQuota, used, now)
+		response["remaining"] = max(keyInfo.MonthlyQuota-used, 0)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
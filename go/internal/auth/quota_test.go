@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestQuotaConsume(t *testing.T) {
+	q, _ := NewQuotaStore("")
+	march := time.Date(2026, 3, 31, 23, 59, 0, 0, time.UTC)
+	april := march.Add(2 * time.Minute)
+
+	steps := []struct {
+		key     string
+		limit   int
+		now     time.Time
+		allowed bool
+		used    int
+	}{
+		{"a", 2, march, true, 1},
+		{"a", 2, march, true, 2},
+		{"a", 2, march, false, 2},
+		{"b", 2, march, true, 1},
+		{"a", 0, march, true, 3},
+		{"a", 2, april, true, 1},
+		{"b", 2, april, true, 1},
+	}
+	for i, step := range steps {
+		allowed, used := q.Consume(step.key, step.limit, step.now)
+		if allowed != step.allowed || used != step.used {
+			t.Errorf("step %d: Consume(%s) = %v, %d, want %v, %d", i, step.key, allowed, used, step.allowed, step.used)
+		}
+	}
+}
+
+func TestQuotaReset(t *testing.T) {
+	tests := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 4, 1, 0, 30, 0, 0, time.FixedZone("CEST", 2*3600)), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := quotaReset(tt.now); !got.Equal(tt.want) {
+			t.Errorf("quotaReset(%v) = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestQuotaRotate(t *testing.T) {
+	q, _ := NewQuotaStore("")
+	now := time.Now()
+	q.Consume("old", 0, now)
+	q.Consume("old", 0, now)
+	q.Consume("new", 0, now)
+	q.Rotate("old", "new")
+	q.Rotate("missing", "new")
+
+	if used := q.Used("old", now); used != 0 {
+		t.Errorf("old id used = %d, want 0", used)
+	}
+	if used := q.Used("new", now); used != 3 {
+		t.Errorf("new id used = %d, want 3", used)
+	}
+}
+
+func TestQuotaFileMigration(t *testing.T) {
+	// A legacy key of 16 hex characters looks like a key id.
+	legacyKey := "0123456789abcdef"
+	month := quotaMonth(time.Now())
+
+	tests := []struct {
+		name string
+		file quotaFile
+		want map[string]int
+	}{
+		{
+			name: "legacy file counts by key",
+			file: quotaFile{Month: month, Counts: map[string]int{legacyKey: 3, testKey: 2}},
+			want: map[string]int{KeyID(legacyKey): 3, KeyID(testKey): 2},
+		},
+		{
+			name: "current file counts by id",
+			file: quotaFile{Version: quotaFileVersion, Month: month, Counts: map[string]int{KeyID(testKey): 4}},
+			want: map[string]int{KeyID(testKey): 4},
+		},
+		{
+			name: "previous month is discarded",
+			file: quotaFile{Version: quotaFileVersion, Month: "2000-01", Counts: map[string]int{KeyID(testKey): 4}},
+			want: map[string]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "quota.json")
+			raw, _ := json.Marshal(tt.file)
+			if err := os.WriteFile(path, raw, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			q, err := NewQuotaStore(path)
+			if err != nil {
+				t.Fatalf("NewQuotaStore: %v", err)
+			}
+			if len(q.counts) != len(tt.want) {
+				t.Errorf("counts = %v, want %v", q.counts, tt.want)
+			}
+			for id, count := range tt.want {
+				if q.counts[id] != count {
+					t.Errorf("counts[%s] = %d, want %d", id, q.counts[id], count)
+				}
+			}
+
+			// A persisted file is read back unchanged.
+			if err := q.Persist(); err != nil {
+				t.Fatalf("Persist: %v", err)
+			}
+			reloaded, _ := NewQuotaStore(path)
+			for id, count := range q.counts {
+				if reloaded.counts[id] != count {
+					t.Errorf("after reload counts[%s] = %d, want %d", id, reloaded.counts[id], count)
+				}
+			}
+		})
+	}
+}
+
+func TestQuotaEnforcement(t *testing.T) {
+	info := testKeyInfo(testKey)
+	info.MonthlyQuota = 2
+	svc := newTestService(t, Config{}, info)
+	caller := map[string]string{"X-API-Key": testKey}
+
+	for i, status := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		rec := serve(svc, http.MethodGet, "/api/protected/test", nil, caller)
+		if rec.Code != status {
+			t.Fatalf("request %d: status %d, want %d", i, rec.Code, status)
+		}
+		if want := []string{"1", "0", "0"}[i]; rec.Header().Get("X-Quota-Remaining") != want {
+			t.Errorf("request %d: X-Quota-Remaining = %q, want %s", i, rec.Header().Get("X-Quota-Remaining"), want)
+		}
+		if status == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+			t.Error("429 without Retry-After")
+		}
+	}
+
+	rotated := serve(svc, http.MethodPost, "/api/auth/keys/rotate", map[string]string{"key": testKey, "new_key": "rotated-key-0123456789"}, map[string]string{"X-Admin-Key": testAdminKey})
+	if rotated.Code != http.StatusOK {
+		t.Fatalf("rotate: status %d", rotated.Code)
+	}
+	if rec := serve(svc, http.MethodGet, "/api/protected/test", nil, map[string]string{"X-API-Key": "rotated-key-0123456789"}); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("rotated key: status %d, want quota carried over", rec.Code)
+	}
+
+	rec := serve(svc, http.MethodGet, "/api/auth/quota", nil, map[string]string{"X-API-Key": "rotated-key-0123456789"})
+	body := decodeBody(t, rec)
+	if body["used"] != float64(2) || body["remaining"] != float64(0) {
+		t.Errorf("quota = %v", body)
+	}
+}
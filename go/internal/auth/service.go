@@ -5,39 +5,102 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/gorilla/mux"
 	"golang.org/x/time/rate"
+
+	"jarviscore/go/internal/authmw"
+	"jarviscore/go/internal/cors"
+	"jarviscore/go/internal/netutil"
+	"jarviscore/go/internal/secrets"
 )
 
 const defaultListenAddr = ":8080"
 
+// Limits applied to callers authenticated by client certificate.
+const (
+	certRateLimit = 600
+	certBurst     = 50
+)
+
 // Configuration
 
 type Config struct {
-	ListenAddr  string
-	SecretKey   string
-	KeysFile    string
-	KeysEnv     string
-	AdminKey    string
-	CORSOrigins string
+	ListenAddr string
+	SecretKey  string
+	KeysFile   string
+	KeysEnv    string
+	AdminKey   string
+	CORS       cors.Config
+	AuditFile  string
+
+	// TrustedProxies lists proxy addresses (IP or CIDR, comma separated)
+	// whose ClientIPHeader is trusted to carry the real client address.
+	TrustedProxies string
+	ClientIPHeader string
+
+	// MaxFailures failed attempts from one address trigger a lockout that
+	// starts at Lockout and doubles on every repeat.
+	MaxFailures int
+	Lockout     time.Duration
+
+	// TicketTTL is the lifetime of one-time WebSocket tickets.
+	TicketTTL time.Duration
+
+	// TOTPFile stores the admin TOTP enrollment. With RequireTOTP admin key
+	// operations need a code even before a secret has been enrolled.
+	TOTPFile    string
+	RequireTOTP bool
+
+	// ServiceTokenTTL is the lifetime of machine-to-machine tokens.
+	ServiceTokenTTL time.Duration
+
+	// QuotaFile persists the monthly request counters of keys with a quota.
+	QuotaFile string
+
+	// GatewayURL receives key expiry events (POST /api/events).
+	GatewayURL   string
+	GatewayToken string
+
+	// TLS enables HTTPS and, with a client CA, mutual TLS for internal callers.
+	TLS netutil.TLSConfig
+
+	// Secrets selects an external secret source (Vault, AWS, encrypted
+	// file) for JARVIS_AUTH_SECRET and JARVIS_AUTH_ADMIN_KEY.
+	Secrets secrets.Config
 }
 
 func LoadConfig() (Config, error) {
 	cfg := Config{
-		ListenAddr:  defaultListenAddr,
-		KeysFile:    filepath.Join("config", "auth_keys.json"),
-		KeysEnv:     strings.TrimSpace(os.Getenv("JARVIS_AUTH_KEYS")),
-		SecretKey:   strings.TrimSpace(os.Getenv("JARVIS_AUTH_SECRET")),
-		AdminKey:    strings.TrimSpace(os.Getenv("JARVIS_AUTH_ADMIN_KEY")),
-		CORSOrigins: strings.TrimSpace(os.Getenv("JARVIS_AUTH_CORS_ORIGINS")),
+		ListenAddr: defaultListenAddr,
+		KeysFile:   filepath.Join("config", "auth_keys.json"),
+		KeysEnv:    strings.TrimSpace(os.Getenv("JARVIS_AUTH_KEYS")),
+		SecretKey:  strings.TrimSpace(os.Getenv("JARVIS_AUTH_SECRET")),
+		AdminKey:   strings.TrimSpace(os.Getenv("JARVIS_AUTH_ADMIN_KEY")),
+		CORS:       cors.LoadConfig("JARVIS_AUTH_CORS_ORIGINS"),
+		AuditFile:  filepath.Join("data", "auth", "audit.jsonl"),
+		TOTPFile:   filepath.Join("config", "auth_totp.json"),
+		QuotaFile:  filepath.Join("data", "auth", "quota.json"),
+
+		GatewayURL:   strings.TrimSpace(os.Getenv("JARVIS_GATEWAYD_URL")),
+		GatewayToken: strings.TrimSpace(os.Getenv("JARVIS_GATEWAYD_TOKEN")),
+
+		TrustedProxies:  strings.TrimSpace(os.Getenv("JARVIS_AUTH_TRUSTED_PROXIES")),
+		ClientIPHeader:  defaultClientIPHeader,
+		TLS:             netutil.LoadTLSConfig("JARVIS_AUTH"),
+		MaxFailures:     defaultMaxFailures,
+		Lockout:         defaultLockout,
+		TicketTTL:       defaultTicketTTL,
+		ServiceTokenTTL: defaultServiceTokenTTL,
+		Secrets:         secrets.LoadConfig(),
 	}
 
 	if value := strings.TrimSpace(os.Getenv("JARVIS_AUTH_ADDR")); value != "" {
@@ -46,8 +109,43 @@ func LoadConfig() (Config, error) {
 	if value := strings.TrimSpace(os.Getenv("JARVIS_AUTH_KEYS_FILE")); value != "" {
 		cfg.KeysFile = value
 	}
+	if value, ok := os.LookupEnv("JARVIS_AUTH_AUDIT_FILE"); ok {
+		cfg.AuditFile = strings.TrimSpace(value)
+	}
+	if value, ok := os.LookupEnv("JARVIS_AUTH_QUOTA_FILE"); ok {
+		cfg.QuotaFile = strings.TrimSpace(value)
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_AUTH_TOTP_FILE")); value != "" {
+		cfg.TOTPFile = value
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_AUTH_REQUIRE_TOTP")); value != "" {
+		cfg.RequireTOTP, _ = strconv.ParseBool(value)
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_AUTH_CLIENT_IP_HEADER")); value != "" {
+		cfg.ClientIPHeader = value
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_AUTH_MAX_FAILURES")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			cfg.MaxFailures = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_AUTH_TICKET_TTL")); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			cfg.TicketTTL = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_AUTH_SERVICE_TOKEN_TTL")); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			cfg.ServiceTokenTTL = min(parsed, maxServiceTokenTTL)
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_AUTH_LOCKOUT")); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			cfg.Lockout = parsed
+		}
+	}
 
-	if cfg.SecretKey == "" {
+	if cfg.SecretKey == "" && !cfg.Secrets.External() {
 		return cfg, fmt.Errorf("JARVIS_AUTH_SECRET ist nicht gesetzt")
 	}
 
@@ -62,24 +160,27 @@ type APIKeyInfo struct {
 	Enabled   bool
 	CreatedAt time.Time
 	LastUsed  time.Time
+	ExpiresAt time.Time // zero = never
+
+	// AllowedCIDRs restricts the key to the listed networks (empty = any).
+	AllowedCIDRs []string
+	AllowedNets  []*net.IPNet
+
+	// Scopes are embedded into tokens issued for this key.
+	Scopes []string
+
+	// Audiences lists the services (e.g. "gatewayd", "database") this key
+	// may request machine-to-machine tokens for.
+	Audiences []string
+
+	// MonthlyQuota limits the requests per calendar month (0 = unlimited).
+	MonthlyQuota int
 }
 
 type contextKey string
 
 const apiKeyInfoKey contextKey = "api_key_info"
 
-var (
-	secretKey    string
-	apiKeysFile  string
-	adminKey     string
-	lastPersist  time.Time
-	apiKeys      = map[string]*APIKeyInfo{}
-	apiKeysMu    sync.RWMutex
-	persistMu    sync.Mutex
-	corsOrigins  map[string]struct{}
-	allowAllCORS bool
-)
-
 // Rate Limiter Store
 
 type RateLimiterStore struct {
@@ -108,60 +209,19 @@ func (s *RateLimiterStore) GetLimiter(key string, rateLimit int, burst int) *rat
 	return limiter
 }
 
-var rateLimiterStore = NewRateLimiterStore()
-
-type apiKeyEntry struct {
-	Key       string `json:"key"`
-	RateLimit int    `json:"rate_limit"`
-	Burst     int    `json:"burst"`
-	Enabled   bool   `json:"enabled"`
-	CreatedAt string `json:"created_at"`
-	LastUsed  string `json:"last_used,omitempty"`
-}
-
-func parseTime(value string, fallback time.Time) time.Time {
-	if value == "" {
-		return fallback
-	}
-	parsed, err := time.Parse(time.RFC3339, value)
-	if err != nil {
-		return fallback
-	}
-	return parsed
-}
-
-func loadCORSOrigins(raw string) {
-	corsOrigins = map[string]struct{}{}
-	allowAllCORS = false
-	raw = strings.TrimSpace(raw)
-	if raw == "" {
-		return
-	}
-	for _, entry := range strings.Split(raw, ",") {
-		origin := strings.TrimSpace(entry)
-		if origin == "" {
-			continue
-		}
-		if origin == "*" {
-			allowAllCORS = true
-		} else {
-			corsOrigins[origin] = struct{}{}
-		}
-	}
+// Remove drops the limiter of a deleted key.
+func (s *RateLimiterStore) Remove(key string) {
+	s.mu.Lock()
+	delete(s.limiters, key)
+	s.mu.Unlock()
 }
 
-func isAllowedOrigin(origin string) bool {
-	if origin == "" {
-		return false
-	}
-	if allowAllCORS {
-		return true
-	}
-	_, ok := corsOrigins[origin]
-	return ok
+func maskAPIKey(key string) string {
+	return authmw.MaskKey(key)
 }
 
-func isAdminRequest(r *http.Request) bool {
+func (s *Service) isAdminRequest(r *http.Request) bool {
+	adminKey := s.creds.admin()
 	if adminKey == "" {
 		return false
 	}
@@ -175,182 +235,71 @@ func isAdminRequest(r *http.Request) bool {
 	return headerKey != "" && headerKey == adminKey
 }
 
-func loadAPIKeysFromFile(path string) ([]apiKeyEntry, error) {
-	raw, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	var entries []apiKeyEntry
-	if err := json.Unmarshal(raw, &entries); err != nil {
-		return nil, err
-	}
-	return entries, nil
-}
-
-func parseAPIKeysFromEnv(raw string) ([]apiKeyEntry, error) {
-	raw = strings.TrimSpace(raw)
-	if raw == "" {
-		return nil, nil
-	}
-	var entries []apiKeyEntry
-	if err := json.Unmarshal([]byte(raw), &entries); err == nil {
-		return entries, nil
-	}
-	keys := strings.Split(raw, ",")
-	entries = make([]apiKeyEntry, 0, len(keys))
-	for _, key := range keys {
-		trimmed := strings.TrimSpace(key)
-		if trimmed == "" {
-			continue
-		}
-		entries = append(entries, apiKeyEntry{
-			Key:       trimmed,
-			RateLimit: 60,
-			Burst:     10,
-			Enabled:   true,
-			CreatedAt: time.Now().UTC().Format(time.RFC3339),
-		})
-	}
-	return entries, nil
-}
-
-func persistAPIKeys(path string, entries []apiKeyEntry) error {
-	if path == "" {
-		return nil
-	}
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return err
-	}
-	payload, err := json.MarshalIndent(entries, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(path, payload, 0o600)
-}
-
-func hydrateAPIKeys(entries []apiKeyEntry) {
-	apiKeysMu.Lock()
-	defer apiKeysMu.Unlock()
-	apiKeys = map[string]*APIKeyInfo{}
-	now := time.Now().UTC()
-	for _, entry := range entries {
-		if strings.TrimSpace(entry.Key) == "" {
-			continue
-		}
-		rateLimit := entry.RateLimit
-		if rateLimit <= 0 {
-			rateLimit = 60
-		}
-		burst := entry.Burst
-		if burst <= 0 {
-			burst = 10
-		}
-		createdAt := parseTime(entry.CreatedAt, now)
-		lastUsed := parseTime(entry.LastUsed, time.Time{})
-		apiKeys[entry.Key] = &APIKeyInfo{
-			Key:       entry.Key,
-			RateLimit: rateLimit,
-			Burst:     burst,
-			Enabled:   entry.Enabled,
-			CreatedAt: createdAt,
-			LastUsed:  lastUsed,
-		}
-	}
-}
-
-func snapshotAPIKeys() []apiKeyEntry {
-	apiKeysMu.RLock()
-	defer apiKeysMu.RUnlock()
-	entries := make([]apiKeyEntry, 0, len(apiKeys))
-	for _, info := range apiKeys {
-		entry := apiKeyEntry{
-			Key:       info.Key,
-			RateLimit: info.RateLimit,
-			Burst:     info.Burst,
-			Enabled:   info.Enabled,
-			CreatedAt: info.CreatedAt.UTC().Format(time.RFC3339),
-		}
-		if !info.LastUsed.IsZero() {
-			entry.LastUsed = info.LastUsed.UTC().Format(time.RFC3339)
-		}
-		entries = append(entries, entry)
-	}
-	return entries
-}
-
-func maybePersistAPIKeys(logger *log.Logger) {
-	if apiKeysFile == "" {
-		return
-	}
-	persistMu.Lock()
-	if time.Since(lastPersist) < 30*time.Second {
-		persistMu.Unlock()
-		return
-	}
-	lastPersist = time.Now().UTC()
-	persistMu.Unlock()
-	if err := persistAPIKeys(apiKeysFile, snapshotAPIKeys()); err != nil {
-		logger.Printf("[WARN] API-Key-Datei konnte nicht gespeichert werden: %v", err)
-	}
-}
-
-func loadAPIKeys(logger *log.Logger, cfg Config) error {
-	apiKeysFile = cfg.KeysFile
-
-	entries, err := parseAPIKeysFromEnv(cfg.KeysEnv)
-	if err != nil {
-		return fmt.Errorf("ungültiges JARVIS_AUTH_KEYS Format: %w", err)
-	}
-
-	if len(entries) == 0 {
-		fileEntries, fileErr := loadAPIKeysFromFile(apiKeysFile)
-		if fileErr == nil {
-			entries = fileEntries
-		} else if !os.IsNotExist(fileErr) {
-			return fmt.Errorf("API-Key-Datei konnte nicht gelesen werden: %w", fileErr)
-		}
-	}
-
-	if len(entries) == 0 {
-		return fmt.Errorf("keine API-Keys konfiguriert. Setze JARVIS_AUTH_KEYS oder eine config/auth_keys.json")
-	}
-
-	hydrateAPIKeys(entries)
-	return nil
-}
-
 // JWT Claims
 
-type Claims struct {
-	APIKey string `json:"api_key"`
-	jwt.RegisteredClaims
-}
+type Claims = authmw.Claims
 
 // Middleware: Verify API Key
-func VerifyAPIKey(logger *log.Logger) mux.MiddlewareFunc {
+func (s *Service) VerifyAPIKey() mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			apiKey := r.Header.Get("X-API-Key")
+			if certInfo, ok := clientCertInfo(r); ok {
+				ctx := context.WithValue(r.Context(), apiKeyInfoKey, certInfo)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
 
-			if apiKey == "" {
-				http.Error(w, `{"error":"API key required"}`, http.StatusUnauthorized)
+			if s.rejectIfLocked(w, r) {
 				return
 			}
 
-			apiKeysMu.RLock()
-			keyInfo, exists := apiKeys[apiKey]
-			apiKeysMu.RUnlock()
+			apiKey := authmw.APIKeyFromRequest(r)
+			var keyInfo *APIKeyInfo
+			var exists bool
+
+			if isSignedRequest(r) {
+				signedInfo, err := s.verifySignedRequest(r)
+				if err != nil {
+					key := ""
+					if signedInfo != nil {
+						key = signedInfo.Key
+					}
+					s.registerFailure(r, key, err.Error())
+					http.Error(w, `{"error":"Invalid request signature"}`, http.StatusUnauthorized)
+					return
+				}
+				apiKey, keyInfo, exists = signedInfo.Key, signedInfo, true
+			} else {
+				if apiKey == "" {
+					http.Error(w, `{"error":"API key required"}`, http.StatusUnauthorized)
+					return
+				}
+				keyInfo, exists = s.keys.Get(apiKey)
+			}
 
 			if !exists || !keyInfo.Enabled {
+				s.registerFailure(r, apiKey, "invalid api key")
 				http.Error(w, `{"error":"Invalid API key"}`, http.StatusUnauthorized)
 				return
 			}
+			if keyInfo.Expired(time.Now()) {
+				s.registerFailure(r, apiKey, "api key expired")
+				http.Error(w, `{"error":"API key expired"}`, http.StatusUnauthorized)
+				return
+			}
+			if !keyAllowsIP(keyInfo, s.clientIP(r)) {
+				s.registerFailure(r, apiKey, "client ip not allowed")
+				http.Error(w, `{"error":"API key not allowed from this address"}`, http.StatusForbidden)
+				return
+			}
+
+			s.registerSuccess(r)
 
 			// Update last used
-			apiKeysMu.Lock()
-			keyInfo.LastUsed = time.Now()
-			apiKeysMu.Unlock()
-			maybePersistAPIKeys(logger)
+			s.keys.Touch(keyInfo)
+			if err := s.keys.MaybePersist(); err != nil {
+				s.logger.Printf("[WARN] API-Key-Datei konnte nicht gespeichert werden: %v", err)
+			}
 
 			// Add key info to context
 			ctx := context.WithValue(r.Context(), apiKeyInfoKey, keyInfo)
@@ -359,6 +308,26 @@ func VerifyAPIKey(logger *log.Logger) mux.MiddlewareFunc {
 	}
 }
 
+// clientCertInfo authenticates callers that presented a verified client
+// certificate on an mTLS listener. The certificate subject becomes the key id.
+func clientCertInfo(r *http.Request) (*APIKeyInfo, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	name := cert.Subject.CommonName
+	if name == "" {
+		name = cert.SerialNumber.String()
+	}
+	return &APIKeyInfo{
+		Key:       "cert:" + name,
+		RateLimit: certRateLimit,
+		Burst:     certBurst,
+		Enabled:   true,
+		CreatedAt: cert.NotBefore,
+	}, true
+}
+
 func apiKeyInfoFromContext(ctx context.Context) (*APIKeyInfo, bool) {
 	info, ok := ctx.Value(apiKeyInfoKey).(*APIKeyInfo)
 	if !ok || info == nil {
@@ -368,7 +337,7 @@ func apiKeyInfoFromContext(ctx context.Context) (*APIKeyInfo, bool) {
 }
 
 // Middleware: Rate Limiting
-func RateLimitMiddleware(next http.Handler) http.Handler {
+func (s *Service) RateLimitMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		keyInfo, ok := apiKeyInfoFromContext(r.Context())
 		if !ok {
@@ -376,9 +345,10 @@ func RateLimitMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		limiter := rateLimiterStore.GetLimiter(keyInfo.Key, keyInfo.RateLimit, keyInfo.Burst)
+		limiter := s.limiters.GetLimiter(keyInfo.Key, keyInfo.RateLimit, keyInfo.Burst)
 
 		if !limiter.Allow() {
+			s.recordAudit(r, AuditRateLimited, keyInfo.Key, "")
 			w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", keyInfo.RateLimit))
 			w.Header().Set("X-RateLimit-Remaining", "0")
 			w.Header().Set("Retry-After", "60")
@@ -387,90 +357,196 @@ func RateLimitMiddleware(next http.Handler) http.Handler {
 		}
 
 		w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", keyInfo.RateLimit))
+		if !s.enforceQuota(w, r, keyInfo) {
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
 
 // JWT Token Generation
-func GenerateToken(apiKey string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &Claims{
-		APIKey: apiKey,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secretKey))
+func (s *Service) GenerateToken(apiKey string, scopes ...string) (string, error) {
+	return authmw.GenerateToken(s.creds.signingSecret(), apiKey, 24*time.Hour, scopes...)
 }
 
 // JWT Token Verification
-func VerifyToken(tokenString string) (*Claims, error) {
-	claims := &Claims{}
-
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
-			return nil, fmt.Errorf("unexpected signing method: %s", token.Method.Alg())
+func (s *Service) VerifyToken(tokenString string) (*Claims, error) {
+	var lastErr error
+	for _, secret := range s.creds.verificationSecrets() {
+		claims, err := authmw.ParseToken(secret, tokenString)
+		if err == nil {
+			return claims, nil
 		}
-		return []byte(secretKey), nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	if !token.Valid {
-		return nil, fmt.Errorf("invalid token")
+		lastErr = err
 	}
+	return nil, lastErr
+}
 
-	return claims, nil
+// Stores bundles the state a Service operates on. Nil stores are created
+// from the Config.
+type Stores struct {
+	Keys     *KeyStore
+	Limiters *RateLimiterStore
+	Audit    *AuditLog
+	Failures *FailureTracker
+	Tickets  *TicketStore
+	TOTP     *TOTPStore
+	Quotas   *QuotaStore
 }
 
 type Service struct {
-	cfg    Config
-	logger *log.Logger
+	cfg        Config
+	logger     *log.Logger
+	keys       *KeyStore
+	limiters   *RateLimiterStore
+	audit      *AuditLog
+	failures   *FailureTracker
+	tickets    *TicketStore
+	totp       *TOTPStore
+	quotas     *QuotaStore
+	cors       *cors.Policy
+	signatures *signatureCache
+	proxies    []*net.IPNet
+	creds      credentials
+	stop       chan struct{}
 }
 
 func NewService(cfg Config, logger *log.Logger) (*Service, error) {
+	return NewServiceWithStores(cfg, logger, Stores{})
+}
+
+func NewServiceWithStores(cfg Config, logger *log.Logger, stores Stores) (*Service, error) {
 	if logger == nil {
 		logger = log.New(os.Stdout, "[auth] ", log.LstdFlags|log.LUTC)
 	}
+	if cfg.ClientIPHeader == "" {
+		cfg.ClientIPHeader = defaultClientIPHeader
+	}
+	if cfg.ServiceTokenTTL <= 0 {
+		cfg.ServiceTokenTTL = defaultServiceTokenTTL
+	}
+
+	proxies, err := parseCIDRs(splitList(cfg.TrustedProxies))
+	if err != nil {
+		return nil, fmt.Errorf("ungültiges JARVIS_AUTH_TRUSTED_PROXIES Format: %w", err)
+	}
+
+	if stores.Keys == nil {
+		if stores.Keys, err = LoadKeyStore(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if stores.Limiters == nil {
+		stores.Limiters = NewRateLimiterStore()
+	}
+	if stores.Audit == nil {
+		if stores.Audit, err = NewAuditLog(cfg.AuditFile); err != nil {
+			return nil, fmt.Errorf("Audit-Log konnte nicht geladen werden: %w", err)
+		}
+	}
+	if stores.Failures == nil {
+		stores.Failures = NewFailureTracker(cfg.MaxFailures, cfg.Lockout)
+	}
+	if stores.Tickets == nil {
+		stores.Tickets = NewTicketStore(cfg.TicketTTL)
+	}
+	if stores.Quotas == nil {
+		if stores.Quotas, err = NewQuotaStore(cfg.QuotaFile); err != nil {
+			return nil, fmt.Errorf("Quota-Datei konnte nicht gelesen werden: %w", err)
+		}
+	}
+	if stores.TOTP == nil {
+		if stores.TOTP, err = NewTOTPStore(cfg.TOTPFile); err != nil {
+			return nil, fmt.Errorf("TOTP-Datei konnte nicht gelesen werden: %w", err)
+		}
+	}
 
-	secretKey = cfg.SecretKey
-	adminKey = cfg.AdminKey
-	loadCORSOrigins(cfg.CORSOrigins)
-	if err := loadAPIKeys(logger, cfg); err != nil {
-		return nil, err
+	svc := &Service{
+		cfg:        cfg,
+		logger:     logger,
+		keys:       stores.Keys,
+		limiters:   stores.Limiters,
+		audit:      stores.Audit,
+		failures:   stores.Failures,
+		tickets:    stores.Tickets,
+		totp:       stores.TOTP,
+		quotas:     stores.Quotas,
+		cors:       cors.New(cfg.CORS),
+		signatures: newSignatureCache(),
+		proxies:    proxies,
+		creds:      credentials{secret: cfg.SecretKey, adminKey: cfg.AdminKey},
+		stop:       make(chan struct{}),
+	}
+	if cfg.Secrets.External() {
+		if err := svc.watchSecrets(); err != nil {
+			return nil, err
+		}
+		logger.Printf("[INFO] Secrets loaded from %s (refresh %s)", cfg.Secrets.Source, cfg.Secrets.RefreshInterval)
 	}
+	if svc.creds.signingSecret() == "" {
+		return nil, fmt.Errorf("JARVIS_AUTH_SECRET ist nicht gesetzt")
+	}
+
+	svc.watchExpiry()
 
 	logger.Printf("[INFO] Rate limiting enabled")
-	logger.Printf("[INFO] Available API keys: %d", len(apiKeys))
+	if cfg.TLS.Enabled() {
+		logger.Printf("[INFO] TLS enabled (client CA: %t)", cfg.TLS.ClientCAFile != "")
+	}
+	logger.Printf("[INFO] Available API keys: %d", stores.Keys.Len())
+
+	return svc, nil
+}
+
+// Close stops background work such as the secret refresh and flushes
+// pending key and quota changes. Call it on shutdown.
+func (s *Service) Close() {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	if err := s.keys.Flush(); err != nil {
+		s.logger.Printf("[WARN] API-Key-Datei konnte nicht gespeichert werden: %v", err)
+	}
+	if err := s.quotas.Flush(); err != nil {
+		s.logger.Printf("[WARN] Quota-Datei konnte nicht gespeichert werden: %v", err)
+	}
+}
 
-	return &Service{cfg: cfg, logger: logger}, nil
+// Listen opens the service listener, using (mutual) TLS when configured.
+func (s *Service) Listen() (net.Listener, error) {
+	return netutil.Listen(s.cfg.ListenAddr, s.cfg.TLS)
 }
 
-func (s *Service) Routes(mux *http.ServeMux) {
+func (s *Service) Routes(serveMux *http.ServeMux) {
 	router := mux.NewRouter()
 
 	// Public endpoints
 	router.HandleFunc("/health", s.healthHandler).Methods(http.MethodGet)
 	router.HandleFunc("/api/auth/token", s.generateTokenHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/auth/oauth/token", s.serviceTokenHandler).Methods(http.MethodPost)
 	router.HandleFunc("/api/auth/verify", s.verifyTokenHandler).Methods(http.MethodPost)
-	router.HandleFunc("/api/auth/keys/create", s.createAPIKeyHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/auth/introspect", s.introspectHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/auth/ws-ticket", s.issueTicketHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/auth/ws-ticket/redeem", s.redeemTicketHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/auth/2fa/enroll", s.enrollTOTPHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/auth/2fa/verify", s.verifyTOTPHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/auth/keys/create", s.requireAdmin2FA(s.createAPIKeyHandler)).Methods(http.MethodPost)
+	router.HandleFunc("/api/auth/keys/rotate", s.requireAdmin2FA(s.rotateAPIKeyHandler)).Methods(http.MethodPost)
+	router.HandleFunc("/api/auth/keys/extend", s.requireAdmin2FA(s.extendAPIKeyHandler)).Methods(http.MethodPost)
+	router.HandleFunc("/api/auth/keys/delete", s.requireAdmin2FA(s.deleteAPIKeyHandler)).Methods(http.MethodPost)
 	router.HandleFunc("/api/auth/keys", s.listAPIKeysHandler).Methods(http.MethodGet)
+	router.HandleFunc("/api/auth/audit", s.auditHandler).Methods(http.MethodGet)
+	router.HandleFunc("/api/auth/quota", s.quotaHandler).Methods(http.MethodGet)
 
 	// Protected endpoints (with auth + rate limiting)
 	protected := router.PathPrefix("/api/protected").Subrouter()
-	protected.Use(VerifyAPIKey(s.logger))
-	protected.Use(RateLimitMiddleware)
+	protected.Use(s.VerifyAPIKey())
+	protected.Use(s.RateLimitMiddleware)
 	protected.HandleFunc("/test", s.protectedHandler).Methods(http.MethodGet)
 
-	// CORS middleware
-	router.Use(corsMiddleware)
-
-	mux.Handle("/", router)
+	serveMux.Handle("/", s.cors.Handler(router))
 }
 
 // Handlers
@@ -486,6 +562,10 @@ func (s *Service) healthHandler(w http.ResponseWriter, _ *http.Request) {
 }
 
 func (s *Service) generateTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if s.rejectIfLocked(w, r) {
+		return
+	}
+
 	var req struct {
 		APIKey string `json:"api_key"`
 	}
@@ -495,29 +575,46 @@ func (s *Service) generateTokenHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	apiKeysMu.RLock()
-	keyInfo, exists := apiKeys[req.APIKey]
-	apiKeysMu.RUnlock()
+	keyInfo, exists := s.keys.Get(req.APIKey)
 
 	if !exists || !keyInfo.Enabled {
+		s.registerFailure(r, req.APIKey, "invalid api key")
 		http.Error(w, `{"error":"Invalid API key"}`, http.StatusUnauthorized)
 		return
 	}
+	now := time.Now()
+	if keyInfo.Expired(now) {
+		s.registerFailure(r, req.APIKey, "api key expired")
+		http.Error(w, `{"error":"API key expired"}`, http.StatusUnauthorized)
+		return
+	}
+	if !keyAllowsIP(keyInfo, s.clientIP(r)) {
+		s.registerFailure(r, req.APIKey, "client ip not allowed")
+		http.Error(w, `{"error":"API key not allowed from this address"}`, http.StatusForbidden)
+		return
+	}
 
-	token, err := GenerateToken(req.APIKey)
+	ttl := tokenTTL(keyInfo, now)
+	token, err := authmw.GenerateToken(s.creds.signingSecret(), req.APIKey, ttl, keyInfo.Scopes...)
 	if err != nil {
 		http.Error(w, `{"error":"Failed to generate token"}`, http.StatusInternalServerError)
 		return
 	}
+	s.registerSuccess(r)
+	s.recordAudit(r, AuditTokenIssued, req.APIKey, "")
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"token":      token,
-		"expires_in": 86400,
+		"expires_in": int(ttl.Seconds()),
 	})
 }
 
 func (s *Service) verifyTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if s.rejectIfLocked(w, r) {
+		return
+	}
+
 	var req struct {
 		Token string `json:"token"`
 	}
@@ -527,11 +624,13 @@ func (s *Service) verifyTokenHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	claims, err := VerifyToken(req.Token)
+	claims, err := s.VerifyToken(req.Token)
 	if err != nil {
+		s.registerFailure(r, "", "invalid token")
 		http.Error(w, `{"error":"Invalid token"}`, http.StatusUnauthorized)
 		return
 	}
+	s.registerSuccess(r)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -541,14 +640,15 @@ func (s *Service) verifyTokenHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Service) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
-	if !isAdminRequest(r) {
-		http.Error(w, `{"error":"Admin access required"}`, http.StatusForbidden)
-		return
-	}
 	var req struct {
-		Key       string `json:"key"`
-		RateLimit int    `json:"rate_limit"`
-		Burst     int    `json:"burst"`
+		Key          string   `json:"key"`
+		RateLimit    int      `json:"rate_limit"`
+		Burst        int      `json:"burst"`
+		AllowedCIDRs []string `json:"allowed_cidrs"`
+		Scopes       []string `json:"scopes"`
+		ExpiresAt    string   `json:"expires_at"`
+		MonthlyQuota int      `json:"monthly_quota"`
+		Audiences    []string `json:"audiences"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -567,23 +667,39 @@ func (s *Service) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
 	if req.Burst <= 0 {
 		req.Burst = 10
 	}
+	allowedNets, err := parseCIDRs(req.AllowedCIDRs)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid allowed_cidrs"}`, http.StatusBadRequest)
+		return
+	}
+	var expiresAt time.Time
+	if req.ExpiresAt != "" {
+		if expiresAt, err = time.Parse(time.RFC3339, req.ExpiresAt); err != nil {
+			http.Error(w, `{"error":"Invalid expires_at"}`, http.StatusBadRequest)
+			return
+		}
+	}
 
-	apiKeysMu.Lock()
-	if _, exists := apiKeys[key]; exists {
-		apiKeysMu.Unlock()
+	created := s.keys.Add(&APIKeyInfo{
+		Key:          key,
+		RateLimit:    req.RateLimit,
+		Burst:        req.Burst,
+		Enabled:      true,
+		CreatedAt:    time.Now(),
+		AllowedCIDRs: req.AllowedCIDRs,
+		AllowedNets:  allowedNets,
+		Scopes:       req.Scopes,
+		ExpiresAt:    expiresAt,
+		MonthlyQuota: max(req.MonthlyQuota, 0),
+		Audiences:    req.Audiences,
+	})
+	if !created {
 		http.Error(w, `{"error":"API key already exists"}`, http.StatusConflict)
 		return
 	}
-	apiKeys[key] = &APIKeyInfo{
-		Key:       key,
-		RateLimit: req.RateLimit,
-		Burst:     req.Burst,
-		Enabled:   true,
-		CreatedAt: time.Now(),
-	}
-	apiKeysMu.Unlock()
+	s.recordAudit(r, AuditKeyCreated, key, "")
 
-	if err := persistAPIKeys(apiKeysFile, snapshotAPIKeys()); err != nil {
+	if err := s.keys.Persist(); err != nil {
 		s.logger.Printf("[WARN] API-Key-Datei konnte nicht gespeichert werden: %v", err)
 	}
 
@@ -592,25 +708,98 @@ func (s *Service) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
 		"success": true,
 		"message": "API key created",
 		"key":     key,
+		"key_id":  KeyID(key),
 	})
 }
 
-func (s *Service) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
-	if !isAdminRequest(r) {
-		http.Error(w, `{"error":"Admin access required"}`, http.StatusForbidden)
+// rotateAPIKeyHandler replaces a key with a new one that keeps its limits,
+// allowlist and scopes. The new key is generated unless one is supplied.
+func (s *Service) rotateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Key    string `json:"key"`
+		NewKey string `json:"new_key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
 		return
 	}
-	apiKeysMu.RLock()
-	defer apiKeysMu.RUnlock()
 
-	keys := make([]map[string]interface{}, 0, len(apiKeys))
-	for _, info := range apiKeys {
-		maskedKey := info.Key
-		if len(maskedKey) > 4 {
-			maskedKey = fmt.Sprintf("****%s", maskedKey[len(maskedKey)-4:])
+	newKey := strings.TrimSpace(req.NewKey)
+	if newKey == "" {
+		generated, err := generateAPIKey()
+		if err != nil {
+			http.Error(w, `{"error":"Failed to generate key"}`, http.StatusInternalServerError)
+			return
 		}
+		newKey = generated
+	} else if len(newKey) < 16 {
+		http.Error(w, `{"error":"API key must be at least 16 characters"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := s.keys.Rotate(strings.TrimSpace(req.Key), newKey); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	s.limiters.Remove(strings.TrimSpace(req.Key))
+	s.quotas.Rotate(KeyID(strings.TrimSpace(req.Key)), KeyID(newKey))
+	if err := s.quotas.Persist(); err != nil {
+		s.logger.Printf("[WARN] Quota-Datei konnte nicht gespeichert werden: %v", err)
+	}
+	s.recordAudit(r, AuditKeyRotated, newKey, "replaces "+maskAPIKey(req.Key))
+
+	if err := s.keys.Persist(); err != nil {
+		s.logger.Printf("[WARN] API-Key-Datei konnte nicht gespeichert werden: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "API key rotated",
+		"key":     newKey,
+		"key_id":  KeyID(newKey),
+	})
+}
+
+func (s *Service) deleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Key string `json:"key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	key := strings.TrimSpace(req.Key)
+	if !s.keys.Remove(key) {
+		http.Error(w, `{"error":"API key not found"}`, http.StatusNotFound)
+		return
+	}
+	s.limiters.Remove(key)
+	s.recordAudit(r, AuditKeyDeleted, key, "")
+
+	if err := s.keys.Persist(); err != nil {
+		s.logger.Printf("[WARN] API-Key-Datei konnte nicht gespeichert werden: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "API key deleted",
+	})
+}
+
+func (s *Service) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdminRequest(r) {
+		http.Error(w, `{"error":"Admin access required"}`, http.StatusForbidden)
+		return
+	}
+	infos := s.keys.List()
+	keys := make([]map[string]interface{}, 0, len(infos))
+	for _, info := range infos {
 		entry := map[string]interface{}{
-			"key":        maskedKey,
+			"key":        maskAPIKey(info.Key),
+			"key_id":     KeyID(info.Key),
 			"rate_limit": info.RateLimit,
 			"burst":      info.Burst,
 			"enabled":    info.Enabled,
@@ -619,6 +808,22 @@ func (s *Service) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
 		if !info.LastUsed.IsZero() {
 			entry["last_used"] = info.LastUsed.Unix()
 		}
+		if len(info.AllowedCIDRs) > 0 {
+			entry["allowed_cidrs"] = info.AllowedCIDRs
+		}
+		if len(info.Scopes) > 0 {
+			entry["scopes"] = info.Scopes
+		}
+		if len(info.Audiences) > 0 {
+			entry["audiences"] = info.Audiences
+		}
+		if info.MonthlyQuota > 0 {
+			entry["monthly_quota"] = info.MonthlyQuota
+		}
+		if !info.ExpiresAt.IsZero() {
+			entry["expires_at"] = info.ExpiresAt.Unix()
+			entry["expired"] = info.Expired(time.Now())
+		}
 		keys = append(keys, entry)
 	}
 
@@ -626,6 +831,49 @@ func (s *Service) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(keys)
 }
 
+func (s *Service) auditHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdminRequest(r) {
+		http.Error(w, `{"error":"Admin access required"}`, http.StatusForbidden)
+		return
+	}
+
+	params := r.URL.Query()
+	query := AuditQuery{
+		Type:  params.Get("type"),
+		Limit: 50,
+	}
+	if value := params.Get("key"); value != "" {
+		query.Key = maskAPIKey(value)
+	}
+	if value, err := strconv.Atoi(params.Get("offset")); err == nil && value > 0 {
+		query.Offset = value
+	}
+	if value, err := strconv.Atoi(params.Get("limit")); err == nil && value > 0 {
+		query.Limit = value
+	}
+	if query.Limit > 500 {
+		query.Limit = 500
+	}
+	if value := params.Get("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, `{"error":"since must be RFC3339"}`, http.StatusBadRequest)
+			return
+		}
+		query.Since = since
+	}
+
+	events, total := s.audit.Query(query)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"events": events,
+		"total":  total,
+		"offset": query.Offset,
+		"limit":  query.Limit,
+	})
+}
+
 func (s *Service) protectedHandler(w http.ResponseWriter, r *http.Request) {
 	keyInfo, ok := apiKeyInfoFromContext(r.Context())
 	if !ok {
@@ -640,24 +888,3 @@ func (s *Service) protectedHandler(w http.ResponseWriter, r *http.Request) {
 		"rate_limit": keyInfo.RateLimit,
 	})
 }
-
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		origin := r.Header.Get("Origin")
-		if isAllowedOrigin(origin) {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Add("Vary", "Origin")
-		} else if allowAllCORS {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-		}
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-API-Key, Authorization, X-Admin-Key")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const (
+	testSecret   = "test-signing-secret"
+	testAdminKey = "test-admin-key-0123456789"
+	testKey      = "test-api-key-0123456789"
+)
+
+// newTestService returns a service without persistence. Without infos it
+// holds testKey.
+func newTestService(t *testing.T, cfg Config, infos ...*APIKeyInfo) *Service {
+	t.Helper()
+	if cfg.SecretKey == "" {
+		cfg.SecretKey = testSecret
+	}
+	if cfg.AdminKey == "" {
+		cfg.AdminKey = testAdminKey
+	}
+	if len(infos) == 0 {
+		infos = []*APIKeyInfo{testKeyInfo(testKey)}
+	}
+	keys := NewKeyStore("")
+	for _, info := range infos {
+		keys.Add(info)
+	}
+	svc, err := NewServiceWithStores(cfg, log.New(io.Discard, "", 0), Stores{Keys: keys})
+	if err != nil {
+		t.Fatalf("NewServiceWithStores: %v", err)
+	}
+	t.Cleanup(svc.Close)
+	return svc
+}
+
+func testKeyInfo(key string) *APIKeyInfo {
+	return &APIKeyInfo{Key: key, RateLimit: 60, Burst: 10, Enabled: true, CreatedAt: time.Now()}
+}
+
+// serve sends a request with a JSON body (unless body is nil) through the
+// service routes.
+func serve(svc *Service, method, path string, body interface{}, headers map[string]string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		payload, _ := json.Marshal(body)
+		reader = bytes.NewReader(payload)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return serveRequest(svc, req)
+}
+
+func serveRequest(svc *Service, req *http.Request) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	svc.Routes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	return body
+}
+
+func TestVerifyAPIKeyAcceptsClientCertificate(t *testing.T) {
+	svc := newTestService(t, Config{})
+	var seen *APIKeyInfo
+	handler := svc.VerifyAPIKey()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = apiKeyInfoFromContext(r.Context())
+	}))
+
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "memoryd"}, SerialNumber: big.NewInt(7)}
+	tests := []struct {
+		name    string
+		state   *tls.ConnectionState
+		status  int
+		subject string
+	}{
+		{"verified certificate", &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}, http.StatusOK, "cert:memoryd"},
+		{"unverified connection", &tls.ConnectionState{}, http.StatusUnauthorized, ""},
+		{"plain http", nil, http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = nil
+			req := httptest.NewRequest(http.MethodGet, "/api/protected/test", nil)
+			req.TLS = tt.state
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.subject != "" && (seen == nil || seen.Key != tt.subject) {
+				t.Errorf("caller = %+v, want %s", seen, tt.subject)
+			}
+		})
+	}
+}
+
+func TestServicesDoNotShareState(t *testing.T) {
+	first := newTestService(t, Config{MaxFailures: 1})
+	second := newTestService(t, Config{})
+	admin := map[string]string{"X-Admin-Key": testAdminKey}
+
+	created := serve(first, http.MethodPost, "/api/auth/keys/create", map[string]string{"key": "first-only-key-0123"}, admin)
+	if created.Code != http.StatusOK {
+		t.Fatalf("create: status %d", created.Code)
+	}
+	serve(first, http.MethodPost, "/api/auth/token", map[string]string{"api_key": "wrong-key-0123456789"}, nil)
+	if _, total := second.audit.Query(AuditQuery{}); total != 0 {
+		t.Errorf("second service has %d audit events, want 0", total)
+	}
+
+	tests := []struct {
+		name   string
+		svc    *Service
+		key    string
+		status int
+	}{
+		{"new key on first", first, "first-only-key-0123", http.StatusTooManyRequests},
+		{"new key on second", second, "first-only-key-0123", http.StatusUnauthorized},
+		{"shared test key on second", second, testKey, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(tt.svc, http.MethodPost, "/api/auth/token", map[string]string{"api_key": tt.key}, nil)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestNewServiceWithInjectedStores(t *testing.T) {
+	keys := NewKeyStore("")
+	keys.Add(testKeyInfo(testKey))
+	audit, _ := NewAuditLog("")
+	tickets := NewTicketStore(time.Minute)
+	svc, err := NewServiceWithStores(Config{SecretKey: testSecret}, log.New(io.Discard, "", 0), Stores{Keys: keys, Audit: audit, Tickets: tickets})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Close()
+
+	if svc.keys != keys || svc.audit != audit || svc.tickets != tickets {
+		t.Error("injected stores not used")
+	}
+	if svc.limiters == nil || svc.failures == nil || svc.quotas == nil || svc.totp == nil {
+		t.Error("missing stores not created")
+	}
+}
+
+func TestLoadKeyStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		keys    []string
+		wantErr bool
+	}{
+		{"comma separated", "key-a-0123456789, key-b-0123456789", []string{"key-a-0123456789", "key-b-0123456789"}, false},
+		{"json", `[{"key":"key-a-0123456789","rate_limit":5,"enabled":true,"allowed_cidrs":["10.0.0.0/8"]}]`, []string{"key-a-0123456789"}, false},
+		{"invalid cidr", `[{"key":"key-a-0123456789","allowed_cidrs":["nope"]}]`, nil, true},
+		{"no keys", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := LoadKeyStore(Config{KeysEnv: tt.env, KeysFile: t.TempDir() + "/missing.json"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if store.Len() != len(tt.keys) {
+				t.Fatalf("%d keys, want %d", store.Len(), len(tt.keys))
+			}
+			for _, key := range tt.keys {
+				if _, ok := store.Get(key); !ok {
+					t.Errorf("key %s missing", key)
+				}
+			}
+		})
+	}
+}
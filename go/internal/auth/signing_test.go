@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRequestSignature(t *testing.T) {
+	base := requestSignature("secret", "post", "/api/x?a=1", "1700000000", []byte("body"))
+	if base != requestSignature("secret", "POST", "/api/x?a=1", "1700000000", []byte("body")) {
+		t.Error("method case changes the signature")
+	}
+	variants := map[string]string{
+		"key":       requestSignature("other", "POST", "/api/x?a=1", "1700000000", []byte("body")),
+		"method":    requestSignature("secret", "GET", "/api/x?a=1", "1700000000", []byte("body")),
+		"uri":       requestSignature("secret", "POST", "/api/x?a=2", "1700000000", []byte("body")),
+		"timestamp": requestSignature("secret", "POST", "/api/x?a=1", "1700000001", []byte("body")),
+		"body":      requestSignature("secret", "POST", "/api/x?a=1", "1700000000", []byte("bodY")),
+	}
+	for name, signature := range variants {
+		if signature == base {
+			t.Errorf("changing the %s keeps the signature", name)
+		}
+	}
+}
+
+// signedRequest signs a request to /api/protected/test with key at ts.
+func signedRequest(key string, ts time.Time, body []byte) *http.Request {
+	const uri = "/api/protected/test?probe=1"
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	req := httptest.NewRequest(http.MethodGet, uri, bytes.NewReader(body))
+	req.Header.Set(headerKeyID, KeyID(key))
+	req.Header.Set(headerTimestamp, timestamp)
+	req.Header.Set(headerSignature, requestSignature(key, http.MethodGet, uri, timestamp, body))
+	return req
+}
+
+func TestSignedRequests(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		build  func() *http.Request
+		status int
+	}{
+		{"valid", func() *http.Request { return signedRequest(testKey, now, []byte("payload")) }, http.StatusOK},
+		{"without body", func() *http.Request { return signedRequest(testKey, now, nil) }, http.StatusOK},
+		{"clock slightly ahead", func() *http.Request { return signedRequest(testKey, now.Add(time.Minute), nil) }, http.StatusOK},
+		{"too old", func() *http.Request { return signedRequest(testKey, now.Add(-signatureWindow-time.Minute), nil) }, http.StatusUnauthorized},
+		{"too far ahead", func() *http.Request { return signedRequest(testKey, now.Add(signatureWindow+time.Minute), nil) }, http.StatusUnauthorized},
+		{"unknown key", func() *http.Request { return signedRequest("unknown-key-0123456789", now, nil) }, http.StatusUnauthorized},
+		{"tampered body", func() *http.Request {
+			req := signedRequest(testKey, now, []byte("payload"))
+			req.Body = httptest.NewRequest(http.MethodGet, "/", bytes.NewReader([]byte("Payload"))).Body
+			return req
+		}, http.StatusUnauthorized},
+		{"tampered uri", func() *http.Request {
+			req := signedRequest(testKey, now, nil)
+			req.URL.RawQuery = "probe=2"
+			return req
+		}, http.StatusUnauthorized},
+		{"missing key id", func() *http.Request {
+			req := signedRequest(testKey, now, nil)
+			req.Header.Del(headerKeyID)
+			return req
+		}, http.StatusUnauthorized},
+		{"invalid timestamp", func() *http.Request {
+			req := signedRequest(testKey, now, nil)
+			req.Header.Set(headerTimestamp, "yesterday")
+			return req
+		}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(t, Config{})
+			if status := serveRequest(svc, tt.build()).Code; status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestSignedRequestReplay(t *testing.T) {
+	svc := newTestService(t, Config{})
+	first := signedRequest(testKey, time.Now(), []byte("payload"))
+	replay := first.Clone(first.Context())
+	replay.Body = httptest.NewRequest(http.MethodGet, "/", bytes.NewReader([]byte("payload"))).Body
+
+	if status := serveRequest(svc, first).Code; status != http.StatusOK {
+		t.Fatalf("first request: status %d", status)
+	}
+	if status := serveRequest(svc, replay).Code; status != http.StatusUnauthorized {
+		t.Errorf("replay: status %d, want 401", status)
+	}
+}
+
+func TestSignatureCacheExpires(t *testing.T) {
+	cache := newSignatureCache()
+	now := time.Now()
+	if !cache.Add("sig", now) || cache.Add("sig", now.Add(signatureWindow)) {
+		t.Fatal("signature not remembered within the window")
+	}
+	if !cache.Add("sig", now.Add(2*signatureWindow+time.Second)) {
+		t.Error("signature still blocked after the window")
+	}
+}
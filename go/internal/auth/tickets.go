@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"jarviscore/go/internal/authmw"
+)
+
+const defaultTicketTTL = 30 * time.Second
+
+type wsTicket struct {
+	subject string
+	scopes  []string
+	expires time.Time
+}
+
+// TicketStore holds one-time WebSocket tickets. A ticket is removed on its
+// first redemption, so it cannot be replayed from logs or browser history.
+type TicketStore struct {
+	ttl     time.Duration
+	tickets map[string]wsTicket
+	mu      sync.Mutex
+}
+
+func NewTicketStore(ttl time.Duration) *TicketStore {
+	if ttl <= 0 {
+		ttl = defaultTicketTTL
+	}
+	return &TicketStore{ttl: ttl, tickets: make(map[string]wsTicket)}
+}
+
+// Issue mints a ticket for subject.
+func (t *TicketStore) Issue(subject string, scopes []string) (string, time.Time, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, err
+	}
+	id := base64.RawURLEncoding.EncodeToString(raw)
+	expires := time.Now().Add(t.ttl)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pruneLocked()
+	t.tickets[id] = wsTicket{subject: subject, scopes: scopes, expires: expires}
+	return id, expires, nil
+}
+
+// Redeem consumes a ticket; expired or unknown tickets are rejected.
+func (t *TicketStore) Redeem(id string) (wsTicket, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ticket, ok := t.tickets[id]
+	if !ok {
+		return wsTicket{}, false
+	}
+	delete(t.tickets, id)
+	if time.Now().After(ticket.expires) {
+		return wsTicket{}, false
+	}
+	return ticket, true
+}
+
+func (t *TicketStore) pruneLocked() {
+	now := time.Now()
+	for id, ticket := range t.tickets {
+		if now.After(ticket.expires) {
+			delete(t.tickets, id)
+		}
+	}
+}
+
+// ticketCaller authenticates the holder of an API key or a bearer JWT.
+func (s *Service) ticketCaller(r *http.Request) (string, []string, bool) {
+	if apiKey := authmw.APIKeyFromRequest(r); apiKey != "" {
+		keyInfo, exists := s.keys.Get(apiKey)
+		if !exists || !keyInfo.Usable(time.Now()) || !keyAllowsIP(keyInfo, s.clientIP(r)) {
+			s.registerFailure(r, apiKey, "ws ticket: invalid api key")
+			return "", nil, false
+		}
+		return maskAPIKey(apiKey), keyInfo.Scopes, true
+	}
+	if token := authmw.BearerToken(r); token != "" {
+		claims, err := s.VerifyToken(token)
+		if err != nil {
+			s.registerFailure(r, "", "ws ticket: invalid token")
+			return "", nil, false
+		}
+		return maskAPIKey(claims.APIKey), claims.Scopes(), true
+	}
+	return "", nil, false
+}
+
+func (s *Service) issueTicketHandler(w http.ResponseWriter, r *http.Request) {
+	if s.rejectIfLocked(w, r) {
+		return
+	}
+	subject, scopes, ok := s.ticketCaller(r)
+	if !ok {
+		http.Error(w, `{"error":"API key or token required"}`, http.StatusUnauthorized)
+		return
+	}
+	s.registerSuccess(r)
+
+	ticket, expires, err := s.tickets.Issue(subject, scopes)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to issue ticket"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ticket":     ticket,
+		"expires_in": int(time.Until(expires).Seconds()),
+	})
+}
+
+// redeemTicketHandler is called by gatewayd to validate the ticket passed on /ws.
+func (s *Service) redeemTicketHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.trustedCaller(r) {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		Ticket string `json:"ticket"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Ticket) == "" {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{"valid": false}
+	if ticket, ok := s.tickets.Redeem(strings.TrimSpace(req.Ticket)); ok {
+		response = map[string]interface{}{
+			"valid":   true,
+			"subject": ticket.subject,
+			"scopes":  ticket.scopes,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
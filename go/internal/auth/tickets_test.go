@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"jarviscore/go/internal/authmw"
+)
+
+func TestTicketStoreRedeemsOnce(t *testing.T) {
+	tickets := NewTicketStore(time.Minute)
+	id, expires, err := tickets.Issue("subject", []string{"ws"})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if until := time.Until(expires); until <= 0 || until > time.Minute {
+		t.Errorf("expires in %v", until)
+	}
+
+	ticket, ok := tickets.Redeem(id)
+	if !ok || ticket.subject != "subject" || len(ticket.scopes) != 1 {
+		t.Fatalf("Redeem = %+v, %v", ticket, ok)
+	}
+	if _, ok := tickets.Redeem(id); ok {
+		t.Error("ticket was redeemed twice")
+	}
+	if _, ok := tickets.Redeem("unknown"); ok {
+		t.Error("unknown ticket was accepted")
+	}
+}
+
+func TestTicketStoreExpires(t *testing.T) {
+	tickets := NewTicketStore(time.Nanosecond)
+	id, _, _ := tickets.Issue("subject", nil)
+	time.Sleep(time.Millisecond)
+	if _, ok := tickets.Redeem(id); ok {
+		t.Error("expired ticket was accepted")
+	}
+
+	tickets.Issue("other", nil)
+	if len(tickets.tickets) != 1 {
+		t.Errorf("%d tickets stored, want expired ones pruned on issue", len(tickets.tickets))
+	}
+}
+
+func TestTicketHandlers(t *testing.T) {
+	svc := newTestService(t, Config{})
+	token, _ := authmw.GenerateToken(testSecret, testKey, time.Hour, "ws")
+
+	issueTests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+	}{
+		{"api key", map[string]string{"X-API-Key": testKey}, http.StatusOK},
+		{"bearer token", map[string]string{"Authorization": "Bearer " + token}, http.StatusOK},
+		{"unknown key", map[string]string{"X-API-Key": "unknown-key-0123456789"}, http.StatusUnauthorized},
+		{"invalid token", map[string]string{"Authorization": "Bearer garbage"}, http.StatusUnauthorized},
+		{"no credentials", nil, http.StatusUnauthorized},
+	}
+	var issued []string
+	for _, tt := range issueTests {
+		t.Run("issue "+tt.name, func(t *testing.T) {
+			rec := serve(svc, http.MethodPost, "/api/auth/ws-ticket", nil, tt.headers)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if rec.Header().Get("Cache-Control") != "no-store" {
+				t.Error("ticket response may be cached")
+			}
+			body := decodeBody(t, rec)
+			ticket, _ := body["ticket"].(string)
+			if ticket == "" || body["expires_in"].(float64) <= 0 {
+				t.Fatalf("body = %v", body)
+			}
+			issued = append(issued, ticket)
+		})
+	}
+	if len(issued) != 2 {
+		t.Fatalf("issued %d tickets, want 2", len(issued))
+	}
+
+	service := map[string]string{"X-API-Key": testKey}
+	redeemTests := []struct {
+		name    string
+		ticket  string
+		headers map[string]string
+		status  int
+		valid   bool
+	}{
+		{"untrusted caller", issued[0], nil, http.StatusUnauthorized, false},
+		{"service key", issued[0], service, http.StatusOK, true},
+		{"replayed ticket", issued[0], service, http.StatusOK, false},
+		{"admin key", issued[1], map[string]string{"X-Admin-Key": testAdminKey}, http.StatusOK, true},
+		{"unknown ticket", "unknown", service, http.StatusOK, false},
+		{"empty ticket", " ", service, http.StatusBadRequest, false},
+	}
+	for _, tt := range redeemTests {
+		t.Run("redeem "+tt.name, func(t *testing.T) {
+			rec := serve(svc, http.MethodPost, "/api/auth/ws-ticket/redeem", map[string]string{"ticket": tt.ticket}, tt.headers)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			body := decodeBody(t, rec)
+			if body["valid"] != tt.valid {
+				t.Fatalf("valid = %v, want %v", body["valid"], tt.valid)
+			}
+			if tt.valid && body["subject"] != maskAPIKey(testKey) {
+				t.Errorf("subject = %v, want %s", body["subject"], maskAPIKey(testKey))
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"jarviscore/go/internal/fsutil"
+)
+
+const (
+	totpDigits = 6
+	totpPeriod = 30
+	totpSkew   = 1
+	totpIssuer = "JarvisCore"
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// totpCode computes the RFC 6238 code (HMAC-SHA1) for the given counter.
+func totpCode(secret []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+type totpState struct {
+	Secret      string `json:"secret"`
+	Active      bool   `json:"active"`
+	LastCounter uint64 `json:"last_counter"`
+	// Pending is a replacement for the active secret awaiting confirmation.
+	Pending string `json:"pending,omitempty"`
+}
+
+// TOTPStore keeps the admin TOTP enrollment. A secret becomes active once
+// the first code generated from it has been verified; a re-enrollment only
+// replaces the active secret once confirmed.
+type TOTPStore struct {
+	path  string
+	state totpState
+	mu    sync.Mutex
+}
+
+func NewTOTPStore(path string) (*TOTPStore, error) {
+	store := &TOTPStore{path: path}
+	if path == "" {
+		return store, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &store.state); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (t *TOTPStore) persistLocked() error {
+	if t.path == "" {
+		return nil
+	}
+	payload, err := json.MarshalIndent(t.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(t.path, payload, 0o600)
+}
+
+// Active reports whether a confirmed secret is enrolled.
+func (t *TOTPStore) Active() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state.Active
+}
+
+// Enroll creates a new, not yet confirmed secret. While a secret is active
+// the new one is kept pending and the active one stays in force until
+// Confirm accepts a code from the new one.
+func (t *TOTPStore) Enroll() (string, error) {
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	secret := totpEncoding.EncodeToString(raw)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state.Active {
+		t.state.Pending = secret
+	} else {
+		t.state = totpState{Secret: secret}
+	}
+	return secret, t.persistLocked()
+}
+
+// Verify checks code against the enrolled secret, allowing one step of clock
+// skew. Codes cannot be reused; the first valid code activates the secret.
+// A pending secret is not accepted.
+func (t *TOTPStore) Verify(code string, now time.Time) bool {
+	code = strings.TrimSpace(code)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.verifyLocked(code, now)
+}
+
+// Confirm is Verify that also accepts a code from a pending secret, which
+// then replaces the active one.
+func (t *TOTPStore) Confirm(code string, now time.Time) bool {
+	code = strings.TrimSpace(code)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state.Pending != "" {
+		if counter, ok := matchTOTP(t.state.Pending, code, now, 0); ok {
+			t.state = totpState{Secret: t.state.Pending, Active: true, LastCounter: counter}
+			t.persistLocked()
+			return true
+		}
+	}
+	return t.verifyLocked(code, now)
+}
+
+func (t *TOTPStore) verifyLocked(code string, now time.Time) bool {
+	counter, ok := matchTOTP(t.state.Secret, code, now, t.state.LastCounter)
+	if !ok {
+		return false
+	}
+	t.state.LastCounter = counter
+	t.state.Active = true
+	t.persistLocked()
+	return true
+}
+
+// matchTOTP returns the counter after last at which encoded generates code.
+func matchTOTP(encoded, code string, now time.Time, last uint64) (uint64, bool) {
+	if encoded == "" || len(code) != totpDigits {
+		return 0, false
+	}
+	secret, err := totpEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, false
+	}
+	current := uint64(now.Unix()) / totpPeriod
+	for delta := -totpSkew; delta <= totpSkew; delta++ {
+		counter := current + uint64(int64(delta))
+		if counter <= last {
+			continue
+		}
+		if hmac.Equal([]byte(totpCode(secret, counter)), []byte(code)) {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+func totpURI(secret string) string {
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", totpIssuer)
+	values.Set("digits", fmt.Sprintf("%d", totpDigits))
+	values.Set("period", fmt.Sprintf("%d", totpPeriod))
+	return fmt.Sprintf("otpauth://totp/%s:admin?%s", totpIssuer, values.Encode())
+}
+
+// requireAdmin2FA guards admin handlers: the admin key is always required,
+// and once a TOTP secret is active a valid X-TOTP-Code header as well.
+func (s *Service) requireAdmin2FA(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.isAdminRequest(r) {
+			http.Error(w, `{"error":"Admin access required"}`, http.StatusForbidden)
+			return
+		}
+		if s.totp.Active() || s.cfg.RequireTOTP {
+			if s.rejectIfLocked(w, r) {
+				return
+			}
+			if !s.totp.Verify(r.Header.Get("X-TOTP-Code"), time.Now()) {
+				s.registerFailure(r, "", "invalid totp code")
+				http.Error(w, `{"error":"Valid X-TOTP-Code required"}`, http.StatusUnauthorized)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// enrollTOTPHandler creates a new TOTP secret. Replacing an active secret
+// requires a valid code from the current one, which stays active until the
+// new one is confirmed.
+func (s *Service) enrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdminRequest(r) {
+		http.Error(w, `{"error":"Admin access required"}`, http.StatusForbidden)
+		return
+	}
+	if s.totp.Active() && !s.totp.Verify(r.Header.Get("X-TOTP-Code"), time.Now()) {
+		s.registerFailure(r, "", "invalid totp code")
+		http.Error(w, `{"error":"Valid X-TOTP-Code required"}`, http.StatusUnauthorized)
+		return
+	}
+
+	secret, err := s.totp.Enroll()
+	if err != nil {
+		http.Error(w, `{"error":"Failed to enroll TOTP"}`, http.StatusInternalServerError)
+		return
+	}
+	s.recordAudit(r, AuditTOTPEnrolled, "", "")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"secret":      secret,
+		"otpauth_uri": totpURI(secret),
+		"message":     "Confirm with POST /api/auth/2fa/verify",
+	})
+}
+
+// verifyTOTPHandler confirms an enrollment or checks a code.
+func (s *Service) verifyTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdminRequest(r) {
+		http.Error(w, `{"error":"Admin access required"}`, http.StatusForbidden)
+		return
+	}
+	if s.rejectIfLocked(w, r) {
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if !s.totp.Confirm(req.Code, time.Now()) {
+		s.registerFailure(r, "", "invalid totp code")
+		http.Error(w, `{"error":"Invalid code"}`, http.StatusUnauthorized)
+		return
+	}
+	s.registerSuccess(r)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"valid":  true,
+		"active": true,
+	})
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B (SHA1), truncated to six digits.
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(secret, uint64(tt.unix)/totpPeriod); got != tt.want {
+			t.Errorf("totpCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+// codeAt returns the code of the enrolled secret for now shifted by steps periods.
+func codeAt(t *testing.T, store *TOTPStore, now time.Time, steps int) string {
+	t.Helper()
+	return codeFor(t, store.state.Secret, now, steps)
+}
+
+func codeFor(t *testing.T, encoded string, now time.Time, steps int) string {
+	t.Helper()
+	secret, err := totpEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return totpCode(secret, uint64(now.Unix()/totpPeriod+int64(steps)))
+}
+
+func TestTOTPVerify(t *testing.T) {
+	store, _ := NewTOTPStore("")
+	if store.Verify("000000", time.Now()) {
+		t.Fatal("code accepted without enrollment")
+	}
+	if _, err := store.Enroll(); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+
+	steps := []struct {
+		name   string
+		code   string
+		valid  bool
+		active bool
+	}{
+		{"wrong length", "12345", false, false},
+		{"two steps ahead", codeAt(t, store, now, 2), false, false},
+		{"previous step", codeAt(t, store, now, -1), true, true},
+		{"current step", " " + codeAt(t, store, now, 0) + " ", true, true},
+		{"replayed code", codeAt(t, store, now, 0), false, true},
+		{"older than last code", codeAt(t, store, now, -1), false, true},
+		{"next step", codeAt(t, store, now, 1), true, true},
+	}
+	for _, step := range steps {
+		if got := store.Verify(step.code, now); got != step.valid {
+			t.Errorf("%s: Verify = %v, want %v", step.name, got, step.valid)
+		}
+		if store.Active() != step.active {
+			t.Errorf("%s: Active = %v, want %v", step.name, store.Active(), step.active)
+		}
+	}
+}
+
+func TestTOTPReEnrollKeepsActiveSecret(t *testing.T) {
+	store, _ := NewTOTPStore(filepath.Join(t.TempDir(), "totp.json"))
+	store.Enroll()
+	now := time.Unix(1_700_000_000, 0)
+	if !store.Verify(codeAt(t, store, now, -1), now) {
+		t.Fatal("enrollment code rejected")
+	}
+	old := store.state.Secret
+
+	pending, err := store.Enroll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		name    string
+		check   func(string, time.Time) bool
+		code    string
+		valid   bool
+		current string
+	}{
+		{"active secret still valid", store.Verify, codeFor(t, old, now, 0), true, old},
+		{"pending secret not valid for admin requests", store.Verify, codeFor(t, pending, now, 1), false, old},
+		{"wrong confirmation", store.Confirm, "000000", false, old},
+		{"confirm pending secret", store.Confirm, codeFor(t, pending, now, 0), true, pending},
+		{"old secret replaced", store.Verify, codeFor(t, old, now, 1), false, pending},
+		{"new secret valid", store.Verify, codeFor(t, pending, now, 1), true, pending},
+	}
+	for _, step := range steps {
+		if got := step.check(step.code, now); got != step.valid {
+			t.Errorf("%s: got %v, want %v", step.name, got, step.valid)
+		}
+		if !store.Active() || store.state.Secret != step.current {
+			t.Errorf("%s: active %v, secret replaced = %v", step.name, store.Active(), store.state.Secret != old)
+		}
+	}
+	if store.state.Pending != "" {
+		t.Error("pending secret kept after confirmation")
+	}
+}
+
+func TestTOTPStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "totp.json")
+	store, _ := NewTOTPStore(path)
+	store.Enroll()
+	now := time.Now()
+	code := codeAt(t, store, now, 0)
+	if !store.Verify(code, now) {
+		t.Fatal("enrollment code rejected")
+	}
+
+	reloaded, err := NewTOTPStore(path)
+	if err != nil {
+		t.Fatalf("NewTOTPStore: %v", err)
+	}
+	if !reloaded.Active() {
+		t.Error("activation was not persisted")
+	}
+	if reloaded.Verify(code, now) {
+		t.Error("code accepted again after restart")
+	}
+}
+
+func TestRequireAdmin2FA(t *testing.T) {
+	svc := newTestService(t, Config{})
+	admin := map[string]string{"X-Admin-Key": testAdminKey}
+	withCode := func(code string) map[string]string {
+		return map[string]string{"X-Admin-Key": testAdminKey, "X-TOTP-Code": code}
+	}
+
+	if rec := serve(svc, http.MethodPost, "/api/auth/keys/create", map[string]string{"key": "before-2fa-key-0123"}, admin); rec.Code != http.StatusOK {
+		t.Fatalf("create without enrollment: status %d", rec.Code)
+	}
+	if rec := serve(svc, http.MethodPost, "/api/auth/2fa/enroll", nil, map[string]string{"X-Admin-Key": "wrong"}); rec.Code != http.StatusForbidden {
+		t.Fatalf("enroll without admin key: status %d", rec.Code)
+	}
+	enrolled := serve(svc, http.MethodPost, "/api/auth/2fa/enroll", nil, admin)
+	if enrolled.Code != http.StatusOK || decodeBody(t, enrolled)["secret"] != svc.totp.state.Secret {
+		t.Fatalf("enroll: status %d, body %s", enrolled.Code, enrolled.Body)
+	}
+
+	now := time.Now()
+	current, next := codeAt(t, svc.totp, now, 0), codeAt(t, svc.totp, now, 1)
+	tests := []struct {
+		name    string
+		path    string
+		body    interface{}
+		headers map[string]string
+		status  int
+	}{
+		{"pending enrollment allows admin key", "/api/auth/keys/create", map[string]string{"key": "pending-2fa-key-0123"}, admin, http.StatusOK},
+		{"wrong confirmation", "/api/auth/2fa/verify", map[string]string{"code": "000000"}, admin, http.StatusUnauthorized},
+		{"confirm enrollment", "/api/auth/2fa/verify", map[string]string{"code": current}, admin, http.StatusOK},
+		{"admin key alone", "/api/auth/keys/create", map[string]string{"key": "no-code-key-0123"}, admin, http.StatusUnauthorized},
+		{"replayed code", "/api/auth/keys/create", map[string]string{"key": "replay-key-0123"}, withCode(current), http.StatusUnauthorized},
+		{"fresh code", "/api/auth/keys/create", map[string]string{"key": "with-code-key-0123"}, withCode(next), http.StatusOK},
+		{"code without admin key", "/api/auth/keys/create", map[string]string{"key": "no-admin-key-0123"}, map[string]string{"X-TOTP-Code": next}, http.StatusForbidden},
+		{"re-enroll without code", "/api/auth/2fa/enroll", nil, admin, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(svc, http.MethodPost, tt.path, tt.body, tt.headers); rec.Code != tt.status {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+// Package authmw provides API key and JWT verification shared by the Go
+// services, so every daemon enforces the same credentials as the auth service.
+package authmw
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Claims are the JWT claims issued by the auth service.
+type Claims struct {
+	APIKey string `json:"api_key"`
+	Scope  string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Scopes returns the space-delimited scope claim as a slice.
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// GenerateToken signs an HS256 token for apiKey.
+func GenerateToken(secret string, apiKey string, ttl time.Duration, scopes ...string) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		APIKey: apiKey,
+		Scope:  strings.Join(scopes, " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// GenerateServiceToken signs a short-lived machine-to-machine token for
+// clientID that is only valid for audience.
+func GenerateServiceToken(secret string, clientID string, audience string, ttl time.Duration, scopes ...string) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		Scope: strings.Join(scopes, " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   clientID,
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// ParseToken validates an HS256 token signed with secret.
+func ParseToken(secret string, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %s", token.Method.Alg())
+		}
+		return []byte(secret), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	return claims, nil
+}
+
+// APIKeyFromRequest returns the X-API-Key header value.
+func APIKeyFromRequest(r *http.Request) string {
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// BearerToken returns the token of an "Authorization: Bearer" header.
+func BearerToken(r *http.Request) string {
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// MaskKey hides all but the last four characters of a key.
+func MaskKey(key string) string {
+	if len(key) > 4 {
+		return fmt.Sprintf("****%s", key[len(key)-4:])
+	}
+	return key
+}
+
+// Configuration
+
+type Config struct {
+	// Secret is the HS256 secret shared with the auth service (JARVIS_AUTH_SECRET).
+	Secret string
+	// APIKeys are accepted via X-API-Key (JARVIS_AUTH_KEYS).
+	APIKeys []string
+	// Audience is the name of this service. Tokens carrying an aud claim
+	// are only accepted if it contains Audience.
+	Audience string
+}
+
+// LoadConfig reads JARVIS_AUTH_SECRET and JARVIS_AUTH_KEYS. JARVIS_AUTH_KEYS
+// may be a comma separated list or the JSON format used by the auth service.
+func LoadConfig() Config {
+	return Config{
+		Secret:  strings.TrimSpace(os.Getenv("JARVIS_AUTH_SECRET")),
+		APIKeys: parseKeys(os.Getenv("JARVIS_AUTH_KEYS")),
+	}
+}
+
+// ForService returns a copy of c that accepts tokens for audience.
+// JARVIS_AUTH_AUDIENCE overrides the name.
+func (c Config) ForService(audience string) Config {
+	c.Audience = audience
+	if value := strings.TrimSpace(os.Getenv("JARVIS_AUTH_AUDIENCE")); value != "" {
+		c.Audience = value
+	}
+	return c
+}
+
+// Enabled reports whether any credential is configured.
+func (c Config) Enabled() bool {
+	return c.Secret != "" || len(c.APIKeys) > 0
+}
+
+func parseKeys(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	var entries []struct {
+		Key     string `json:"key"`
+		Enabled *bool  `json:"enabled"`
+	}
+	if err := json.Unmarshal([]byte(raw), &entries); err == nil {
+		keys := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			if strings.TrimSpace(entry.Key) == "" || (entry.Enabled != nil && !*entry.Enabled) {
+				continue
+			}
+			keys = append(keys, strings.TrimSpace(entry.Key))
+		}
+		return keys
+	}
+	keys := []string{}
+	for _, key := range strings.Split(raw, ",") {
+		if trimmed := strings.TrimSpace(key); trimmed != "" {
+			keys = append(keys, trimmed)
+		}
+	}
+	return keys
+}
+
+// Identity describes an authenticated caller.
+type Identity struct {
+	Subject string
+	Method  string // "api_key", "jwt" or "ticket"
+	Scopes  []string
+	Claims  *Claims
+}
+
+type contextKey string
+
+const identityKey contextKey = "authmw_identity"
+
+// FromContext returns the identity stored by the middleware.
+func FromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey).(*Identity)
+	if !ok || identity == nil {
+		return nil, false
+	}
+	return identity, true
+}
+
+// WithIdentity stores identity in ctx.
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey, identity)
+}
+
+// Verifier checks API keys and bearer tokens against a Config.
+type Verifier struct {
+	secret   string
+	audience string
+	keys     [][]byte
+}
+
+func NewVerifier(cfg Config) *Verifier {
+	keys := make([][]byte, 0, len(cfg.APIKeys))
+	for _, key := range cfg.APIKeys {
+		keys = append(keys, []byte(key))
+	}
+	return &Verifier{secret: cfg.Secret, audience: cfg.Audience, keys: keys}
+}
+
+// Authenticate validates the X-API-Key header or a bearer JWT.
+func (v *Verifier) Authenticate(r *http.Request) (*Identity, error) {
+	if apiKey := APIKeyFromRequest(r); apiKey != "" {
+		if !v.validKey(apiKey) {
+			return nil, fmt.Errorf("invalid api key")
+		}
+		return &Identity{Subject: MaskKey(apiKey), Method: "api_key"}, nil
+	}
+	if token := BearerToken(r); token != "" {
+		if v.secret == "" {
+			return nil, fmt.Errorf("token authentication not configured")
+		}
+		claims, err := ParseToken(v.secret, token)
+		if err != nil {
+			return nil, err
+		}
+		if len(claims.Audience) > 0 && (v.audience == "" || !claims.VerifyAudience(v.audience, true)) {
+			return nil, fmt.Errorf("token not issued for this service")
+		}
+		subject := claims.Subject
+		if subject == "" {
+			subject = MaskKey(claims.APIKey)
+		}
+		return &Identity{Subject: subject, Method: "jwt", Scopes: claims.Scopes(), Claims: claims}, nil
+	}
+	return nil, fmt.Errorf("credentials required")
+}
+
+func (v *Verifier) validKey(candidate string) bool {
+	valid := false
+	for _, key := range v.keys {
+		if subtle.ConstantTimeCompare(key, []byte(candidate)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// Middleware rejects unauthenticated requests with 401 and stores the
+// identity in the request context. CORS preflight requests pass through.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		identity, err := v.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="jarvis"`)
+			http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+	})
+}
@@ -0,0 +1,154 @@
+package authmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const testSecret = "test-signing-secret"
+
+func TestParseToken(t *testing.T) {
+	valid, _ := GenerateToken(testSecret, "key-1234", time.Hour, "a", "b")
+	expired, _ := GenerateToken(testSecret, "key-1234", -time.Minute)
+	foreign, _ := GenerateToken("other-secret", "key-1234", time.Hour)
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, &Claims{APIKey: "key-1234"}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid", valid, false},
+		{"expired", expired, true},
+		{"other secret", foreign, true},
+		{"alg none", unsigned, true},
+		{"garbage", "a.b.c", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ParseToken(testSecret, tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (claims.APIKey != "key-1234" || !reflect.DeepEqual(claims.Scopes(), []string{"a", "b"})) {
+				t.Errorf("claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{"", nil},
+		{" key-a , key-b,, ", []string{"key-a", "key-b"}},
+		{`[{"key":"key-a"},{"key":"key-b","enabled":false},{"key":" "}]`, []string{"key-a"}},
+	}
+	for _, tt := range tests {
+		if got := parseKeys(tt.raw); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseKeys(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestMaskKey(t *testing.T) {
+	tests := map[string]string{"": "", "abcd": "abcd", "secret-key-1234": "****1234"}
+	for key, want := range tests {
+		if got := MaskKey(key); got != want {
+			t.Errorf("MaskKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestVerifierAuthenticate(t *testing.T) {
+	verifier := NewVerifier(Config{Secret: testSecret, APIKeys: []string{"service-key-1234"}, Audience: "memory"})
+	userToken, _ := GenerateToken(testSecret, "user-key-5678", time.Hour, "memory:read")
+	memoryToken, _ := GenerateServiceToken(testSecret, "client-1", "memory", time.Minute)
+	gatewayToken, _ := GenerateServiceToken(testSecret, "client-1", "gatewayd", time.Minute)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		subject string
+		method  string
+		wantErr bool
+	}{
+		{"api key", map[string]string{"X-API-Key": "service-key-1234"}, "****1234", "api_key", false},
+		{"unknown api key", map[string]string{"X-API-Key": "other-key-1234"}, "", "", true},
+		{"user token", map[string]string{"Authorization": "Bearer " + userToken}, "****5678", "jwt", false},
+		{"service token for this service", map[string]string{"Authorization": "bearer " + memoryToken}, "client-1", "jwt", false},
+		{"service token for another service", map[string]string{"Authorization": "Bearer " + gatewayToken}, "", "", true},
+		{"no credentials", nil, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			identity, err := verifier.Authenticate(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (identity.Subject != tt.subject || identity.Method != tt.method) {
+				t.Errorf("identity = %+v", identity)
+			}
+		})
+	}
+}
+
+func TestVerifierRejectsTokensWithoutSecret(t *testing.T) {
+	token, _ := GenerateToken("", "key", time.Hour)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if _, err := NewVerifier(Config{APIKeys: []string{"key"}}).Authenticate(req); err == nil {
+		t.Error("token accepted without a configured secret")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	verifier := NewVerifier(Config{APIKeys: []string{"service-key-1234"}})
+	var identity *Identity
+	handler := verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ = FromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		method   string
+		key      string
+		status   int
+		identity bool
+	}{
+		{"valid key", http.MethodGet, "service-key-1234", http.StatusOK, true},
+		{"missing key", http.MethodGet, "", http.StatusUnauthorized, false},
+		{"preflight", http.MethodOptions, "", http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity = nil
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if (identity != nil) != tt.identity {
+				t.Errorf("identity = %+v", identity)
+			}
+			if tt.status == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("missing WWW-Authenticate header")
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package authmw
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// TicketClient redeems one-time WebSocket tickets at the auth service.
+// gatewayd uses it to validate the ?ticket= parameter on /ws instead of
+// accepting long-lived API keys in query strings.
+type TicketClient struct {
+	AuthURL string
+	APIKey  string
+	Client  *http.Client
+}
+
+// LoadTicketClient reads JARVIS_AUTH_URL and JARVIS_AUTH_SERVICE_KEY.
+func LoadTicketClient() *TicketClient {
+	authURL := strings.TrimRight(strings.TrimSpace(os.Getenv("JARVIS_AUTH_URL")), "/")
+	if authURL == "" {
+		return nil
+	}
+	return &TicketClient{
+		AuthURL: authURL,
+		APIKey:  strings.TrimSpace(os.Getenv("JARVIS_AUTH_SERVICE_KEY")),
+		Client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Redeem consumes ticket and returns the identity it was issued for.
+func (c *TicketClient) Redeem(ctx context.Context, ticket string) (*Identity, error) {
+	body, err := json.Marshal(map[string]string{"ticket": ticket})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.AuthURL+"/api/auth/ws-ticket/redeem", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("auth service unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("auth service returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Valid   bool     `json:"valid"`
+		Subject string   `json:"subject"`
+		Scopes  []string `json:"scopes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if !result.Valid {
+		return nil, fmt.Errorf("invalid or expired ticket")
+	}
+	return &Identity{Subject: result.Subject, Method: "ticket", Scopes: result.Scopes}, nil
+}
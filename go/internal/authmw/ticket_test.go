@@ -0,0 +1,71 @@
+package authmw
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTicketClientRedeem(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/auth/ws-ticket/redeem" || r.Header.Get("X-API-Key") != "service-key" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Ticket string `json:"ticket"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req.Ticket {
+		case "good":
+			w.Write([]byte(`{"valid":true,"subject":"jarv...1234","scopes":["ws"]}`))
+		case "broken":
+			w.Write([]byte(`{`))
+		default:
+			w.Write([]byte(`{"valid":false}`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		apiKey  string
+		ticket  string
+		want    *Identity
+		wantErr bool
+	}{
+		{name: "valid ticket", apiKey: "service-key", ticket: "good", want: &Identity{Subject: "jarv...1234", Method: "ticket", Scopes: []string{"ws"}}},
+		{name: "invalid ticket", apiKey: "service-key", ticket: "used", wantErr: true},
+		{name: "malformed response", apiKey: "service-key", ticket: "broken", wantErr: true},
+		{name: "missing service key", ticket: "good", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &TicketClient{AuthURL: server.URL, APIKey: tt.apiKey}
+			identity, err := client.Redeem(context.Background(), tt.ticket)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(identity, tt.want) {
+				t.Errorf("identity = %+v, want %+v", identity, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTicketClient(t *testing.T) {
+	t.Setenv("JARVIS_AUTH_URL", "")
+	if LoadTicketClient() != nil {
+		t.Error("client created without JARVIS_AUTH_URL")
+	}
+
+	t.Setenv("JARVIS_AUTH_URL", " http://auth:8081/ ")
+	t.Setenv("JARVIS_AUTH_SERVICE_KEY", "service-key")
+	client := LoadTicketClient()
+	if client == nil || client.AuthURL != "http://auth:8081" || client.APIKey != "service-key" {
+		t.Errorf("client = %+v", client)
+	}
+}
@@ -0,0 +1,190 @@
+// Package cors implements the CORS policy shared by the Go services.
+//
+// Allowed origins come from JARVIS_ALLOWED_ORIGINS (comma separated). Entries
+// are matched exactly, or as patterns: "https://*.example.com" matches any
+// subdomain of example.com, "http://localhost:*" matches any port and "*"
+// allows every origin (without credentials).
+package cors
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxAge  = 10 * time.Minute
+	defaultMethods = "GET, POST, PUT, DELETE, OPTIONS"
+	defaultHeaders = "Content-Type, X-API-Key, Authorization, X-Admin-Key"
+)
+
+// Used when JARVIS_ALLOWED_ORIGINS is not set: only local frontends.
+var defaultOrigins = []string{"http://localhost:*", "http://127.0.0.1:*"}
+
+type Config struct {
+	AllowedOrigins []string
+	AllowedMethods string
+	AllowedHeaders string
+	MaxAge         time.Duration
+}
+
+// LoadConfig reads JARVIS_ALLOWED_ORIGINS and JARVIS_CORS_MAX_AGE. A
+// non-empty serviceEnv variable (e.g. JARVIS_AUTH_CORS_ORIGINS) takes
+// precedence over the global origin list.
+func LoadConfig(serviceEnv string) Config {
+	cfg := Config{
+		AllowedOrigins: defaultOrigins,
+		AllowedMethods: defaultMethods,
+		AllowedHeaders: defaultHeaders,
+		MaxAge:         defaultMaxAge,
+	}
+
+	raw := strings.TrimSpace(os.Getenv("JARVIS_ALLOWED_ORIGINS"))
+	if serviceEnv != "" {
+		if value := strings.TrimSpace(os.Getenv(serviceEnv)); value != "" {
+			raw = value
+		}
+	}
+	if raw != "" {
+		cfg.AllowedOrigins = splitOrigins(raw)
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_CORS_MAX_AGE")); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed >= 0 {
+			cfg.MaxAge = parsed
+		}
+	}
+
+	return cfg
+}
+
+func splitOrigins(raw string) []string {
+	origins := []string{}
+	for _, entry := range strings.Split(raw, ",") {
+		if origin := strings.TrimSpace(entry); origin != "" {
+			origins = append(origins, strings.TrimRight(origin, "/"))
+		}
+	}
+	return origins
+}
+
+type pattern struct {
+	scheme string // "" matches any scheme
+	host   string // leading "*." matches subdomains
+	port   string // "*" matches any port
+}
+
+func parsePattern(value string) (pattern, bool) {
+	p := pattern{}
+	rest := value
+	if idx := strings.Index(rest, "://"); idx >= 0 {
+		p.scheme = strings.ToLower(rest[:idx])
+		rest = rest[idx+3:]
+	}
+	if idx := strings.LastIndex(rest, ":"); idx >= 0 && !strings.HasSuffix(rest, "]") {
+		p.port = rest[idx+1:]
+		rest = rest[:idx]
+	}
+	p.host = strings.ToLower(rest)
+	return p, strings.Contains(value, "*")
+}
+
+func (p pattern) matches(scheme, host, port string) bool {
+	if p.scheme != "" && p.scheme != scheme {
+		return false
+	}
+	if p.port != "*" && p.port != port {
+		return false
+	}
+	if strings.HasPrefix(p.host, "*.") {
+		suffix := p.host[1:]
+		return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+	}
+	return p.host == host
+}
+
+// Policy decides which origins may access a service.
+type Policy struct {
+	cfg      Config
+	exact    map[string]struct{}
+	patterns []pattern
+	allowAll bool
+}
+
+func New(cfg Config) *Policy {
+	if cfg.AllowedMethods == "" {
+		cfg.AllowedMethods = defaultMethods
+	}
+	if cfg.AllowedHeaders == "" {
+		cfg.AllowedHeaders = defaultHeaders
+	}
+	p := &Policy{cfg: cfg, exact: map[string]struct{}{}}
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			p.allowAll = true
+			continue
+		}
+		if parsed, wildcard := parsePattern(origin); wildcard {
+			p.patterns = append(p.patterns, parsed)
+		} else {
+			p.exact[strings.ToLower(origin)] = struct{}{}
+		}
+	}
+	return p
+}
+
+// Allowed reports whether origin is explicitly allowed.
+func (p *Policy) Allowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if _, ok := p.exact[strings.ToLower(origin)]; ok {
+		return true
+	}
+	if len(p.patterns) == 0 {
+		return false
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	scheme := strings.ToLower(parsed.Scheme)
+	host := strings.ToLower(parsed.Hostname())
+	port := parsed.Port()
+	for _, pattern := range p.patterns {
+		if pattern.matches(scheme, host, port) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler wraps next with the CORS policy and answers preflight requests.
+func (p *Policy) Handler(next http.Handler) http.Handler {
+	maxAge := strconv.Itoa(int(p.cfg.MaxAge.Seconds()))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		header := w.Header()
+		header.Add("Vary", "Origin")
+		if p.Allowed(origin) {
+			header.Set("Access-Control-Allow-Origin", origin)
+			header.Set("Access-Control-Allow-Credentials", "true")
+		} else if p.allowAll && origin != "" {
+			header.Set("Access-Control-Allow-Origin", "*")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			header.Set("Access-Control-Allow-Methods", p.cfg.AllowedMethods)
+			header.Set("Access-Control-Allow-Headers", p.cfg.AllowedHeaders)
+			if p.cfg.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,111 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAllowed(t *testing.T) {
+	policy := New(Config{AllowedOrigins: []string{
+		"https://app.example.com",
+		"https://*.jarvis.dev",
+		"http://localhost:*",
+	}})
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"HTTPS://APP.EXAMPLE.COM", true},
+		{"http://app.example.com", false},
+		{"https://evil.example.com", false},
+		{"https://ui.jarvis.dev", true},
+		{"https://a.b.jarvis.dev", true},
+		{"https://jarvis.dev", false},
+		{"https://eviljarvis.dev", false},
+		{"http://ui.jarvis.dev", false},
+		{"http://localhost:5173", true},
+		{"http://localhost", true},
+		{"http://localhost.evil.com:80", false},
+		{"", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		if got := policy.Allowed(tt.origin); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		origins     []string
+		origin      string
+		preflight   bool
+		allowOrigin string
+		credentials string
+		status      int
+	}{
+		{"allowed origin", []string{"https://app.example.com"}, "https://app.example.com", false, "https://app.example.com", "true", http.StatusOK},
+		{"other origin", []string{"https://app.example.com"}, "https://evil.com", false, "", "", http.StatusOK},
+		{"wildcard without credentials", []string{"*"}, "https://evil.com", false, "*", "", http.StatusOK},
+		{"preflight", []string{"https://app.example.com"}, "https://app.example.com", true, "https://app.example.com", "true", http.StatusNoContent},
+		{"preflight of other origin", []string{"https://app.example.com"}, "https://evil.com", true, "", "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(Config{AllowedOrigins: tt.origins, MaxAge: time.Minute}).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			method := http.MethodGet
+			if tt.preflight {
+				method = http.MethodOptions
+			}
+			req := httptest.NewRequest(method, "/", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+				t.Errorf("Allow-Origin = %q, want %q", got, tt.allowOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != tt.credentials {
+				t.Errorf("Allow-Credentials = %q, want %q", got, tt.credentials)
+			}
+			if tt.preflight && rec.Header().Get("Access-Control-Max-Age") != "60" {
+				t.Errorf("Max-Age = %q", rec.Header().Get("Access-Control-Max-Age"))
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		global  string
+		service string
+		want    []string
+	}{
+		{"defaults", "", "", defaultOrigins},
+		{"global list", "https://a.com/, https://b.com", "", []string{"https://a.com", "https://b.com"}},
+		{"service overrides global", "https://a.com", "https://c.com", []string{"https://c.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JARVIS_ALLOWED_ORIGINS", tt.global)
+			t.Setenv("JARVIS_TEST_CORS_ORIGINS", tt.service)
+			if got := LoadConfig("JARVIS_TEST_CORS_ORIGINS").AllowedOrigins; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("origins = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -12,7 +12,10 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+
+	"jarviscore/go/internal/authmw"
+	"jarviscore/go/internal/cors"
 )
 
 const (
@@ -23,12 +26,16 @@ const (
 type Config struct {
 	ListenAddr  string
 	DatabaseURL string
+	Auth        authmw.Config
+	CORS        cors.Config
 }
 
 func LoadConfig() Config {
 	cfg := Config{
 		ListenAddr:  defaultListenAddr,
 		DatabaseURL: defaultDatabaseURL,
+		Auth:        authmw.LoadConfig().ForService("database"),
+		CORS:        cors.LoadConfig("JARVIS_DATABASE_CORS_ORIGINS"),
 	}
 	if value := strings.TrimSpace(os.Getenv("JARVIS_DATABASE_ADDR")); value != "" {
 		cfg.ListenAddr = value
@@ -154,12 +161,24 @@ func (s *Service) createTables() error {
 	CREATE TABLE IF NOT EXISTS memories (
 		id VARCHAR(36) PRIMARY KEY,
 		content TEXT NOT NULL,
-		type VARCHAR(50) NOT NULL,
+		type VARCHAR(255) NOT NULL,
 		tags TEXT[],
 		importance INTEGER DEFAULT 5 CHECK (importance >= 1 AND importance <= 10),
 		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 	);
+	-- Hierarchical category paths from the memory service exceed the old
+	-- limit; widen the column once instead of rewriting it on every start
+	DO $$
+	BEGIN
+		IF EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'memories'
+				AND column_name = 'type' AND character_maximum_length < 255
+		) THEN
+			ALTER TABLE memories ALTER COLUMN type TYPE VARCHAR(255);
+		END IF;
+	END $$;
 	CREATE INDEX IF NOT EXISTS idx_memories_type ON memories(type);
 	CREATE INDEX IF NOT EXISTS idx_memories_importance ON memories(importance DESC);
 
@@ -205,32 +224,37 @@ func (s *Service) createTables() error {
 	return nil
 }
 
-func (s *Service) Routes(mux *http.ServeMux) {
+func (s *Service) Routes(serveMux *http.ServeMux) {
 	router := mux.NewRouter()
 
 	router.HandleFunc("/health", s.healthHandler).Methods(http.MethodGet)
 
-	router.HandleFunc("/api/database/sessions", s.createChatSessionHandler).Methods(http.MethodPost)
-	router.HandleFunc("/api/database/sessions", s.getChatSessionsHandler).Methods(http.MethodGet)
-	router.HandleFunc("/api/database/sessions/{id}", s.getChatSessionHandler).Methods(http.MethodGet)
-	router.HandleFunc("/api/database/sessions/{id}", s.deleteChatSessionHandler).Methods(http.MethodDelete)
-	router.HandleFunc("/api/database/sessions/{id}/messages", s.addMessageHandler).Methods(http.MethodPost)
-	router.HandleFunc("/api/database/sessions/{id}/messages", s.getSessionMessagesHandler).Methods(http.MethodGet)
+	api := router.PathPrefix("/api/database").Subrouter()
+	if s.cfg.Auth.Enabled() {
+		api.Use(authmw.NewVerifier(s.cfg.Auth).Middleware)
+	} else {
+		s.logger.Println("[WARN] No JARVIS_AUTH_SECRET/JARVIS_AUTH_KEYS configured, database API is unauthenticated")
+	}
 
-	router.HandleFunc("/api/database/memories", s.addMemoryHandler).Methods(http.MethodPost)
-	router.HandleFunc("/api/database/memories", s.searchMemoriesHandler).Methods(http.MethodGet)
-	router.HandleFunc("/api/database/memories/{id}", s.getMemoryHandler).Methods(http.MethodGet)
-	router.HandleFunc("/api/database/memories/{id}", s.updateMemoryHandler).Methods(http.MethodPut)
-	router.HandleFunc("/api/database/memories/{id}", s.deleteMemoryHandler).Methods(http.MethodDelete)
+	api.HandleFunc("/sessions", s.createChatSessionHandler).Methods(http.MethodPost)
+	api.HandleFunc("/sessions", s.getChatSessionsHandler).Methods(http.MethodGet)
+	api.HandleFunc("/sessions/{id}", s.getChatSessionHandler).Methods(http.MethodGet)
+	api.HandleFunc("/sessions/{id}", s.deleteChatSessionHandler).Methods(http.MethodDelete)
+	api.HandleFunc("/sessions/{id}/messages", s.addMessageHandler).Methods(http.MethodPost)
+	api.HandleFunc("/sessions/{id}/messages", s.getSessionMessagesHandler).Methods(http.MethodGet)
 
-	router.HandleFunc("/api/database/models", s.addModelHandler).Methods(http.MethodPost)
-	router.HandleFunc("/api/database/models", s.getModelsHandler).Methods(http.MethodGet)
-	router.HandleFunc("/api/database/models/{id}", s.updateModelStatusHandler).Methods(http.MethodPut)
-	router.HandleFunc("/api/database/models/{id}", s.deleteModelHandler).Methods(http.MethodDelete)
+	api.HandleFunc("/memories", s.addMemoryHandler).Methods(http.MethodPost)
+	api.HandleFunc("/memories", s.searchMemoriesHandler).Methods(http.MethodGet)
+	api.HandleFunc("/memories/{id}", s.getMemoryHandler).Methods(http.MethodGet)
+	api.HandleFunc("/memories/{id}", s.updateMemoryHandler).Methods(http.MethodPut)
+	api.HandleFunc("/memories/{id}", s.deleteMemoryHandler).Methods(http.MethodDelete)
 
-	router.Use(corsMiddleware)
+	api.HandleFunc("/models", s.addModelHandler).Methods(http.MethodPost)
+	api.HandleFunc("/models", s.getModelsHandler).Methods(http.MethodGet)
+	api.HandleFunc("/models/{id}", s.updateModelStatusHandler).Methods(http.MethodPut)
+	api.HandleFunc("/models/{id}", s.deleteModelHandler).Methods(http.MethodDelete)
 
-	mux.Handle("/", router)
+	serveMux.Handle("/", cors.New(s.cfg.CORS).Handler(router))
 }
 
 // Handlers
@@ -391,14 +415,26 @@ func (s *Service) addMemoryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	memory.ID = uuid.New().String()
+	// A supplied ID upserts the entry, so the memory service can mirror its
+	// own memories into this table.
 	now := time.Now()
-	memory.CreatedAt = now
-	memory.UpdatedAt = now
+	if memory.ID == "" {
+		memory.ID = uuid.New().String()
+		memory.CreatedAt = now
+		memory.UpdatedAt = now
+	}
+	if memory.CreatedAt.IsZero() {
+		memory.CreatedAt = now
+	}
+	if memory.UpdatedAt.IsZero() {
+		memory.UpdatedAt = now
+	}
 
 	_, err := s.db.Exec(
-		"INSERT INTO memories (id, content, type, tags, importance, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
-		memory.ID, memory.Content, memory.Type, memory.Tags, memory.Importance, memory.CreatedAt, memory.UpdatedAt,
+		`INSERT INTO memories (id, content, type, tags, importance, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET content = EXCLUDED.content, type = EXCLUDED.type, tags = EXCLUDED.tags,
+			importance = EXCLUDED.importance, updated_at = EXCLUDED.updated_at`,
+		memory.ID, memory.Content, memory.Type, pq.Array(memory.Tags), memory.Importance, memory.CreatedAt, memory.UpdatedAt,
 	)
 
 	if err != nil {
@@ -427,7 +463,7 @@ func (s *Service) searchMemoriesHandler(w http.ResponseWriter, r *http.Request)
 	var memories []MemoryEntry
 	for rows.Next() {
 		var memory MemoryEntry
-		if err := rows.Scan(&memory.ID, &memory.Content, &memory.Type, &memory.Tags, &memory.Importance, &memory.CreatedAt, &memory.UpdatedAt); err != nil {
+		if err := rows.Scan(&memory.ID, &memory.Content, &memory.Type, pq.Array(&memory.Tags), &memory.Importance, &memory.CreatedAt, &memory.UpdatedAt); err != nil {
 			http.Error(w, fmt.Sprintf(`{"error":"Scan failed: %s"}`, err), http.StatusInternalServerError)
 			return
 		}
@@ -443,7 +479,7 @@ func (s *Service) getMemoryHandler(w http.ResponseWriter, r *http.Request) {
 
 	var memory MemoryEntry
 	row := s.db.QueryRow("SELECT id, content, type, tags, importance, created_at, updated_at FROM memories WHERE id = $1", id)
-	if err := row.Scan(&memory.ID, &memory.Content, &memory.Type, &memory.Tags, &memory.Importance, &memory.CreatedAt, &memory.UpdatedAt); err != nil {
+	if err := row.Scan(&memory.ID, &memory.Content, &memory.Type, pq.Array(&memory.Tags), &memory.Importance, &memory.CreatedAt, &memory.UpdatedAt); err != nil {
 		http.Error(w, `{"error":"Memory not found"}`, http.StatusNotFound)
 		return
 	}
@@ -468,7 +504,7 @@ func (s *Service) updateMemoryHandler(w http.ResponseWriter, r *http.Request) {
 
 	_, err := s.db.Exec(
 		"UPDATE memories SET content = $1, tags = $2, importance = $3, updated_at = $4 WHERE id = $5",
-		updates.Content, updates.Tags, updates.Importance, time.Now(), id,
+		updates.Content, pq.Array(updates.Tags), updates.Importance, time.Now(), id,
 	)
 	if err != nil {
 		http.Error(w, fmt.Sprintf(`{"error":"Failed to update memory: %s"}`, err), http.StatusInternalServerError)
@@ -584,18 +620,3 @@ func (s *Service) deleteModelHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
 }
-
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
@@ -0,0 +1,61 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		perm     os.FileMode
+	}{
+		{name: "new file in new directory", perm: 0o600},
+		{name: "replace existing file", existing: "old contents that are longer", perm: 0o644},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "nested", "dir")
+			path := filepath.Join(dir, "data.json")
+			if tt.existing != "" {
+				os.MkdirAll(dir, 0o755)
+				if err := os.WriteFile(path, []byte(tt.existing), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := WriteFileAtomic(path, []byte(`{"ok":true}`), tt.perm); err != nil {
+				t.Fatalf("WriteFileAtomic: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil || string(data) != `{"ok":true}` {
+				t.Fatalf("contents = %q, %v", data, err)
+			}
+			info, _ := os.Stat(path)
+			if info.Mode().Perm() != tt.perm {
+				t.Errorf("perm = %v, want %v", info.Mode().Perm(), tt.perm)
+			}
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != 1 {
+				t.Errorf("directory holds %d entries, want no leftover temp files", len(entries))
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomicRemovesTempFileOnError(t *testing.T) {
+	dir := t.TempDir()
+	// A non-empty directory in place of the target makes the rename fail.
+	target := filepath.Join(dir, "blocked")
+	os.MkdirAll(filepath.Join(target, "child"), 0o755)
+
+	if err := WriteFileAtomic(target, []byte("new"), 0o600); err == nil {
+		t.Fatal("rename over a non-empty directory succeeded")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want temp file removed", len(entries))
+	}
+}
@@ -0,0 +1,306 @@
+package memory
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Backend names for JARVIS_MEMORY_BACKEND.
+const (
+	BackendJSON   = "json"
+	BackendBolt   = "bbolt"
+	BackendSQLite = "sqlite"
+)
+
+// metaFlushInterval is how often flushMeta writes memories whose bookkeeping
+// fields changed.
+const metaFlushInterval = time.Minute
+
+// Backend persists individual memories. Unlike the JSON file, which is
+// rewritten as a whole, backends store every change as it happens.
+type Backend interface {
+	Load() (map[string]*Memory, error)
+	Put(memory *Memory) error
+	Delete(id string) error
+	Close() error
+}
+
+// OpenBackend opens the backend selected in cfg. It returns nil for the JSON
+// file backend, which is handled by SaveToFile/LoadFromFile.
+func OpenBackend(cfg Config, sealer *Sealer) (Backend, error) {
+	path := cfg.BackendPath
+	switch cfg.Backend {
+	case "", BackendJSON:
+		return nil, nil
+	case BackendBolt:
+		if path == "" {
+			path = filepath.Join(cfg.StorageDir, "memories.db")
+		}
+		return OpenBoltBackend(path, sealer)
+	case BackendSQLite:
+		if path == "" {
+			path = filepath.Join(cfg.StorageDir, "memories.sqlite")
+		}
+		return OpenSQLiteBackend(path, sealer)
+	default:
+		return nil, fmt.Errorf("unbekanntes Memory-Backend: %q", cfg.Backend)
+	}
+}
+
+// bbolt
+
+var boltBucket = []byte("memories")
+
+type BoltBackend struct {
+	db     *bolt.DB
+	sealer *Sealer
+}
+
+func OpenBoltBackend(path string, sealer *Sealer) (*BoltBackend, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltBackend{db: db, sealer: sealer}, nil
+}
+
+func (b *BoltBackend) Load() (map[string]*Memory, error) {
+	memories := make(map[string]*Memory)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).ForEach(func(k, v []byte) error {
+			data, err := b.sealer.open(v)
+			if err != nil {
+				return fmt.Errorf("memory %s: %w", k, err)
+			}
+			var memory Memory
+			if err := json.Unmarshal(data, &memory); err != nil {
+				return fmt.Errorf("memory %s: %w", k, err)
+			}
+			memories[memory.ID] = &memory
+			return nil
+		})
+	})
+	return memories, err
+}
+
+func (b *BoltBackend) Put(memory *Memory) error {
+	data, err := json.Marshal(memory)
+	if err == nil {
+		data, err = b.sealer.seal(data)
+	}
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(memory.ID), data)
+	})
+}
+
+func (b *BoltBackend) Delete(id string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete([]byte(id))
+	})
+}
+
+func (b *BoltBackend) Close() error {
+	return b.db.Close()
+}
+
+// SQLite
+
+type SQLiteBackend struct {
+	db     *sql.DB
+	sealer *Sealer
+}
+
+func OpenSQLiteBackend(path string, sealer *Sealer) (*SQLiteBackend, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS memories (
+		id TEXT PRIMARY KEY,
+		data TEXT NOT NULL,
+		updated_at INTEGER NOT NULL
+	)`); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteBackend{db: db, sealer: sealer}, nil
+}
+
+func (b *SQLiteBackend) Load() (map[string]*Memory, error) {
+	rows, err := b.db.Query(`SELECT id, data FROM memories`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memories := make(map[string]*Memory)
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		plaintext, err := b.sealer.open([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("memory %s: %w", id, err)
+		}
+		var memory Memory
+		if err := json.Unmarshal(plaintext, &memory); err != nil {
+			return nil, fmt.Errorf("memory %s: %w", id, err)
+		}
+		memories[memory.ID] = &memory
+	}
+	return memories, rows.Err()
+}
+
+func (b *SQLiteBackend) Put(memory *Memory) error {
+	data, err := json.Marshal(memory)
+	if err == nil {
+		data, err = b.sealer.seal(data)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = b.db.Exec(`INSERT INTO memories (id, data, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`,
+		memory.ID, string(data), memory.UpdatedAt.Unix())
+	return err
+}
+
+func (b *SQLiteBackend) Delete(id string) error {
+	_, err := b.db.Exec(`DELETE FROM memories WHERE id = ?`, id)
+	return err
+}
+
+func (b *SQLiteBackend) Close() error {
+	return b.db.Close()
+}
+
+// persist is registered as store observer for non-JSON backends. MetaOnly
+// changes, made on every read, are only marked and written by flushMeta.
+func (s *Service) persist(change Change) {
+	s.metaLock.Lock()
+	if change.MetaOnly {
+		s.metaDirty[change.ID] = struct{}{}
+		s.metaLock.Unlock()
+		return
+	}
+	delete(s.metaDirty, change.ID)
+	s.metaLock.Unlock()
+
+	var err error
+	if change.Deleted {
+		err = s.backend.Delete(change.ID)
+	} else {
+		err = s.backend.Put(change.Memory)
+	}
+	if err != nil {
+		s.logger.Printf("[ERROR] Memory %s konnte nicht gespeichert werden: %v", change.ID, err)
+	}
+}
+
+// flushMeta writes the memories whose bookkeeping fields changed since the
+// last flush. It holds the store's read lock, so no newer write made through
+// persist can be overwritten.
+func (s *Service) flushMeta() {
+	s.metaLock.Lock()
+	dirty := s.metaDirty
+	s.metaDirty = make(map[string]struct{})
+	s.metaLock.Unlock()
+	if len(dirty) == 0 {
+		return
+	}
+
+	s.store.mu.RLock()
+	defer s.store.mu.RUnlock()
+	for id := range dirty {
+		memory, exists := s.store.memories[id]
+		if !exists {
+			continue
+		}
+		if err := s.backend.Put(memory); err != nil {
+			s.logger.Printf("[ERROR] Memory %s konnte nicht gespeichert werden: %v", id, err)
+		}
+	}
+}
+
+func (s *Service) startMetaFlush() {
+	go func() {
+		ticker := time.NewTicker(metaFlushInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			s.flushMeta()
+		}
+	}()
+}
+
+// Replace swaps the store contents for memories. Memories that are not in
+// the new set are reported to the observers as deleted.
+func (s *MemoryStore) Replace(memories map[string]*Memory) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replaceLocked(memories)
+}
+
+// replaceLocked swaps the store contents for memories and reports only the
+// difference, so reloading an unchanged set doesn't journal, sync or embed
+// every memory again.
+func (s *MemoryStore) replaceLocked(memories map[string]*Memory) {
+	previous := s.memories
+	s.memories = memories
+	s.rebuildKeys()
+	for id, memory := range previous {
+		if _, kept := memories[id]; !kept {
+			s.notifyLocked(memory, true)
+		}
+	}
+	for id, memory := range memories {
+		if old, exists := previous[id]; !exists || !sameMemory(old, memory) {
+			s.notifyLocked(memory, false)
+		}
+	}
+}
+
+// sameMemory reports whether a and b would be stored identically.
+func sameMemory(a, b *Memory) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
+
+func backendName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "bolt" {
+		return BackendBolt
+	}
+	if name == "sqlite3" {
+		return BackendSQLite
+	}
+	return name
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var backendOpeners = map[string]func(path string, sealer *Sealer) (Backend, error){
+	BackendBolt: func(path string, sealer *Sealer) (Backend, error) {
+		return OpenBoltBackend(filepath.Join(path, "memories.db"), sealer)
+	},
+	BackendSQLite: func(path string, sealer *Sealer) (Backend, error) {
+		return OpenSQLiteBackend(filepath.Join(path, "memories.sqlite"), sealer)
+	},
+}
+
+func TestBackends(t *testing.T) {
+	for name, open := range backendOpeners {
+		for _, sealer := range []*Sealer{nil, NewSealer("passphrase")} {
+			t.Run(name+map[bool]string{true: "/sealed", false: "/plain"}[sealer != nil], func(t *testing.T) {
+				dir := t.TempDir()
+				backend, err := open(dir, sealer)
+				if err != nil {
+					t.Fatalf("open: %v", err)
+				}
+				now := time.Now().UTC().Truncate(time.Second)
+				for _, memory := range []*Memory{
+					{ID: "a", Content: "first", UpdatedAt: now},
+					{ID: "b", Content: "second", UpdatedAt: now},
+					{ID: "a", Content: "first, updated", Tags: []string{"x"}, UpdatedAt: now},
+				} {
+					if err := backend.Put(memory); err != nil {
+						t.Fatalf("Put: %v", err)
+					}
+				}
+				if err := backend.Delete("b"); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+				if err := backend.Delete("missing"); err != nil {
+					t.Errorf("Delete of a missing id: %v", err)
+				}
+				backend.Close()
+
+				reopened, err := open(dir, sealer)
+				if err != nil {
+					t.Fatalf("reopen: %v", err)
+				}
+				defer reopened.Close()
+				memories, err := reopened.Load()
+				if err != nil {
+					t.Fatalf("Load: %v", err)
+				}
+				if len(memories) != 1 || memories["a"].Content != "first, updated" || memories["a"].Tags[0] != "x" {
+					t.Errorf("loaded %v", memories)
+				}
+			})
+		}
+	}
+}
+
+func TestBackendRejectsWrongKey(t *testing.T) {
+	for name, open := range backendOpeners {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			backend, _ := open(dir, NewSealer("right"))
+			backend.Put(&Memory{ID: "a", Content: "secret"})
+			backend.Close()
+
+			reopened, _ := open(dir, NewSealer("wrong"))
+			defer reopened.Close()
+			if _, err := reopened.Load(); err == nil {
+				t.Error("Load with the wrong key succeeded")
+			}
+		})
+	}
+}
+
+func TestServiceWithBackend(t *testing.T) {
+	for _, backend := range []string{BackendBolt, BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			legacy, _ := json.Marshal(map[string]*Memory{"old": {ID: "old", Content: "aus memories.json", Importance: 5}})
+			if err := os.WriteFile(filepath.Join(dir, "memories.json"), legacy, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := Config{StorageDir: dir, Backend: backend}
+			first, err := NewService(cfg, log.New(io.Discard, "", 0))
+			if err != nil {
+				t.Fatalf("NewService: %v", err)
+			}
+			if _, ok := first.store.Get("old"); !ok {
+				t.Fatal("memories.json was not imported into the empty backend")
+			}
+			id := first.store.Add(&Memory{Content: "neu", Importance: 5})
+			first.store.Delete("old")
+			first.store.Touch(id, 0, time.Now())
+			if err := first.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			second := newTestService(t, cfg)
+			if _, ok := second.store.Get("old"); ok {
+				t.Error("memories.json imported again into a non-empty backend")
+			}
+			memory, ok := second.store.Get(id)
+			if !ok || memory.Hits != 1 {
+				t.Errorf("after restart %+v, %v; want the pending access flushed on Close", memory, ok)
+			}
+		})
+	}
+}
+
+func TestOpenBackendUnknown(t *testing.T) {
+	if _, err := OpenBackend(Config{Backend: "mongodb"}, nil); err == nil {
+		t.Error("unknown backend accepted")
+	}
+	for _, name := range []string{"bolt", " BBolt ", "sqlite3"} {
+		if got := backendName(name); got != BackendBolt && got != BackendSQLite {
+			t.Errorf("backendName(%q) = %q", name, got)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"jarviscore/go/internal/fsutil"
+)
+
+const (
+	backupDir              = "backups"
+	backupTimeLayout       = "20060102T150405.000Z"
+	defaultBackupRetention = 10
+	defaultBackupInterval  = time.Hour
+)
+
+var backupNamePattern = regexp.MustCompile(`^memories-\d{8}T\d{6}\.\d{3}Z\.json$`)
+
+// BackupInfo describes one timestamped snapshot in the backups directory.
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size"`
+}
+
+// Backups keeps the newest retention snapshots of the store as
+// memories-<timestamp>.json files.
+type Backups struct {
+	dir       string
+	retention int
+	sealer    *Sealer
+}
+
+func NewBackups(storageDir string, retention int, sealer *Sealer) *Backups {
+	return &Backups{dir: filepath.Join(storageDir, backupDir), retention: retention, sealer: sealer}
+}
+
+// List returns the backups, newest first.
+func (b *Backups) List() ([]BackupInfo, error) {
+	entries, err := os.ReadDir(b.dir)
+	if os.IsNotExist(err) {
+		return []BackupInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []BackupInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || !backupNamePattern.MatchString(entry.Name()) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(entry.Name(), "memories-"), ".json")
+		createdAt, err := time.Parse(backupTimeLayout, stamp)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{Name: entry.Name(), CreatedAt: createdAt, Size: info.Size()})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// Create writes a snapshot of store and drops backups beyond the retention
+// count.
+func (b *Backups) Create(store *MemoryStore, now time.Time) (BackupInfo, error) {
+	store.mu.RLock()
+	data, err := json.MarshalIndent(store.memories, "", "  ")
+	store.mu.RUnlock()
+	if err == nil {
+		data, err = b.sealer.seal(data)
+	}
+	if err != nil {
+		return BackupInfo{}, err
+	}
+
+	if err := os.MkdirAll(b.dir, 0o755); err != nil {
+		return BackupInfo{}, err
+	}
+	now = now.UTC()
+	name := "memories-" + now.Format(backupTimeLayout) + ".json"
+	if err := fsutil.WriteFileAtomic(filepath.Join(b.dir, name), data, 0o644); err != nil {
+		return BackupInfo{}, err
+	}
+	return BackupInfo{Name: name, CreatedAt: now.Truncate(time.Millisecond), Size: int64(len(data))}, b.prune()
+}
+
+func (b *Backups) prune() error {
+	if b.retention <= 0 {
+		return nil
+	}
+	backups, err := b.List()
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups[min(b.retention, len(backups)):] {
+		if err := os.Remove(filepath.Join(b.dir, backup.Name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read loads the memories of a backup.
+func (b *Backups) Read(name string) (map[string]*Memory, error) {
+	if !backupNamePattern.MatchString(name) {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(filepath.Join(b.dir, name))
+	if err == nil {
+		data, err = b.sealer.open(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	memories := map[string]*Memory{}
+	if err := json.Unmarshal(data, &memories); err != nil {
+		return nil, err
+	}
+	return memories, nil
+}
+
+func (s *Service) startBackups() {
+	go func() {
+		ticker := time.NewTicker(s.cfg.BackupInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := s.backups.Create(s.store, time.Now()); err != nil {
+				s.logger.Printf("[ERROR] Memory-Backup fehlgeschlagen: %v", err)
+			}
+		}
+	}()
+}
+
+func (s *Service) listBackupsHandler(w http.ResponseWriter, _ *http.Request) {
+	backups, err := s.backups.List()
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Failed to list backups: %s"}`, err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"backups":   backups,
+		"retention": s.cfg.BackupRetention,
+	})
+}
+
+func (s *Service) createBackupHandler(w http.ResponseWriter, _ *http.Request) {
+	backup, err := s.backups.Create(s.store, time.Now())
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Failed to create backup: %s"}`, err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"backup":  backup,
+	})
+}
+
+// restoreBackupHandler replaces all memories with a backup. The current state
+// is backed up first so the restore can be undone.
+func (s *Service) restoreBackupHandler(w http.ResponseWriter, r *http.Request) {
+	memories, err := s.backups.Read(mux.Vars(r)["name"])
+	if os.IsNotExist(err) {
+		http.Error(w, `{"error":"Backup not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Failed to read backup: %s"}`, err), http.StatusInternalServerError)
+		return
+	}
+
+	previous, err := s.backups.Create(s.store, time.Now())
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Failed to back up current memories: %s"}`, err), http.StatusInternalServerError)
+		return
+	}
+	s.store.Replace(memories)
+	s.flushHistory()
+	s.enforceCapacity("")
+	if s.backend == nil {
+		if err := s.save(); err != nil {
+			s.logger.Printf("[ERROR] Memories konnten nach Wiederherstellung nicht gespeichert werden: %v", err)
+		}
+	}
+	s.logger.Printf("[INFO] Restored %d memories from backup %s", len(memories), mux.Vars(r)["name"])
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"count":    len(memories),
+		"previous": previous.Name,
+		"message":  "Memories restored from backup",
+	})
+}
@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackupsRetention(t *testing.T) {
+	dir := t.TempDir()
+	store := NewMemoryStore(dir)
+	store.Add(&Memory{Content: "Tee"})
+	backups := NewBackups(dir, 3, nil)
+
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var created []string
+	for i := 0; i < 5; i++ {
+		backup, err := backups.Create(store, start.Add(time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		created = append(created, backup.Name)
+	}
+	os.WriteFile(filepath.Join(dir, backupDir, "notes.txt"), []byte("x"), 0o644)
+
+	list, err := backups.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	want := []string{created[4], created[3], created[2]}
+	if len(list) != len(want) {
+		t.Fatalf("List = %+v, want %v", list, want)
+	}
+	for i, backup := range list {
+		if backup.Name != want[i] || backup.Size == 0 {
+			t.Errorf("backup %d = %+v, want %s", i, backup, want[i])
+		}
+	}
+	if !list[0].CreatedAt.Equal(start.Add(4 * time.Minute)) {
+		t.Errorf("CreatedAt = %v", list[0].CreatedAt)
+	}
+}
+
+func TestBackupsRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		readKey string
+		wantErr bool
+	}{
+		{"plain", "", "", false},
+		{"sealed", "geheim", "geheim", false},
+		{"sealed without key", "geheim", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := NewMemoryStore(dir)
+			id := store.Add(&Memory{Content: "Tee"})
+			backup, err := NewBackups(dir, 0, NewSealer(tt.key)).Create(store, time.Now())
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			memories, err := NewBackups(dir, 0, NewSealer(tt.readKey)).Read(backup.Name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (len(memories) != 1 || memories[id].Content != "Tee") {
+				t.Errorf("memories = %v", memories)
+			}
+		})
+	}
+
+	backups := NewBackups(t.TempDir(), 0, nil)
+	for _, name := range []string{"../memories.json", "memories.json", "memories-20260301T120000.000Z.json"} {
+		if _, err := backups.Read(name); !os.IsNotExist(err) {
+			t.Errorf("Read(%q) error = %v, want not exist", name, err)
+		}
+	}
+}
+
+func TestBackupHandlers(t *testing.T) {
+	svc := newTestService(t, Config{BackupRetention: 5})
+	keep := addMemory(t, svc, map[string]interface{}{"content": "Tee"})
+
+	var created struct {
+		Backup BackupInfo `json:"backup"`
+	}
+	decode(t, serve(svc, http.MethodPost, "/api/v1/memory/backups", nil), &created)
+
+	serve(svc, http.MethodDelete, "/api/v1/memory/memories/"+keep, nil)
+	added := addMemory(t, svc, map[string]interface{}{"content": "Kaffee"})
+
+	var restored struct {
+		Count    int    `json:"count"`
+		Previous string `json:"previous"`
+	}
+	decode(t, serve(svc, http.MethodPost, "/api/v1/memory/backups/"+created.Backup.Name+"/restore", nil), &restored)
+	if restored.Count != 1 || restored.Previous == "" {
+		t.Errorf("restore response = %+v", restored)
+	}
+	if _, ok := svc.store.Get(keep); !ok {
+		t.Error("memory from backup not restored")
+	}
+	if _, ok := svc.store.Get(added); ok {
+		t.Error("memory added after backup still present")
+	}
+
+	var listed struct {
+		Backups   []BackupInfo `json:"backups"`
+		Retention int          `json:"retention"`
+	}
+	decode(t, serve(svc, http.MethodGet, "/api/v1/memory/backups", nil), &listed)
+	if len(listed.Backups) != 2 || listed.Backups[0].Name != restored.Previous || listed.Retention != 5 {
+		t.Errorf("backups = %+v", listed)
+	}
+
+	for _, name := range []string{"memories-20200101T000000.000Z.json", "memories.json", "unknown"} {
+		if rec := serve(svc, http.MethodPost, "/api/v1/memory/backups/"+name+"/restore", nil); rec.Code != http.StatusNotFound {
+			t.Errorf("restore %s: status %d, want 404", name, rec.Code)
+		}
+	}
+}
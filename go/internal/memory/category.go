@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Memory types are hierarchical category paths such as
+// "personal/preferences/music". Filtering by a type matches the category and
+// everything below it, so plain types like "note" keep working unchanged.
+
+const categorySeparator = "/"
+
+// normalizeCategory trims every segment and drops empty ones.
+func normalizeCategory(category string) string {
+	segments := strings.Split(category, categorySeparator)
+	kept := segments[:0]
+	for _, segment := range segments {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			kept = append(kept, segment)
+		}
+	}
+	return strings.Join(kept, categorySeparator)
+}
+
+// inCategory reports whether memoryType is category or one of its
+// subcategories.
+func inCategory(memoryType, category string) bool {
+	category = normalizeCategory(category)
+	return memoryType == category || strings.HasPrefix(memoryType, category+categorySeparator)
+}
+
+// CategoryNode is one level of the category tree. Count is the number of
+// memories filed directly under Path, Total includes all subcategories.
+type CategoryNode struct {
+	Name     string          `json:"name"`
+	Path     string          `json:"path"`
+	Count    int             `json:"count"`
+	Total    int             `json:"total"`
+	Children []*CategoryNode `json:"children,omitempty"`
+}
+
+// Categories builds the category tree of all listed memories.
+func (s *MemoryStore) Categories() []*CategoryNode {
+	s.mu.RLock()
+	counts := map[string]int{}
+	now := time.Now()
+	for _, memory := range s.memories {
+		if memory.expired(now) || memory.Archived {
+			continue
+		}
+		counts[normalizeCategory(memory.Type)]++
+	}
+	s.mu.RUnlock()
+
+	root := &CategoryNode{}
+	nodes := map[string]*CategoryNode{"": root}
+	for category, count := range counts {
+		parent := root
+		path := ""
+		for _, segment := range strings.Split(category, categorySeparator) {
+			if path == "" {
+				path = segment
+			} else {
+				path += categorySeparator + segment
+			}
+			node, exists := nodes[path]
+			if !exists {
+				node = &CategoryNode{Name: segment, Path: path}
+				nodes[path] = node
+				parent.Children = append(parent.Children, node)
+			}
+			node.Total += count
+			parent = node
+		}
+		parent.Count += count
+	}
+	sortCategories(root.Children)
+	return root.Children
+}
+
+func sortCategories(nodes []*CategoryNode) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	for _, node := range nodes {
+		sortCategories(node.Children)
+	}
+}
+
+// categoriesHandler returns the category tree, or the subtree below ?path=.
+func (s *Service) categoriesHandler(w http.ResponseWriter, r *http.Request) {
+	tree := s.store.Categories()
+	if path := normalizeCategory(r.URL.Query().Get("path")); path != "" {
+		var found *CategoryNode
+		for _, segment := range strings.Split(path, categorySeparator) {
+			found = nil
+			for _, node := range tree {
+				if node.Name == segment {
+					found = node
+					break
+				}
+			}
+			if found == nil {
+				http.Error(w, `{"error":"Category not found"}`, http.StatusNotFound)
+				return
+			}
+			tree = found.Children
+		}
+		tree = []*CategoryNode{found}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"categories": tree,
+	})
+}
@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"net/http"
+	"sort"
+	"testing"
+)
+
+func TestNormalizeCategory(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"note", "note"},
+		{" personal / preferences /music ", "personal/preferences/music"},
+		{"personal//music/", "personal/music"},
+		{"/", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeCategory(tt.in); got != tt.want {
+			t.Errorf("normalizeCategory(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestInCategory(t *testing.T) {
+	tests := []struct {
+		memoryType, category string
+		want                 bool
+	}{
+		{"personal/preferences/music", "personal", true},
+		{"personal/preferences/music", "personal/preferences", true},
+		{"personal/preferences/music", "personal/preferences/music", true},
+		{"personal/preferences/music", " personal/ preferences/", true},
+		{"personal/preferences/music", "personal/pref", false},
+		{"personalities", "personal", false},
+		{"note", "personal", false},
+	}
+	for _, tt := range tests {
+		if got := inCategory(tt.memoryType, tt.category); got != tt.want {
+			t.Errorf("inCategory(%q, %q) = %v, want %v", tt.memoryType, tt.category, got, tt.want)
+		}
+	}
+}
+
+func categoryService(t *testing.T) *Service {
+	t.Helper()
+	svc := newTestService(t, Config{})
+	for _, memoryType := range []string{
+		"personal/preferences/music",
+		"personal/preferences/music",
+		"personal/preferences/food",
+		"personal",
+		"work/meetings",
+		"note",
+	} {
+		addMemory(t, svc, map[string]interface{}{"content": memoryType, "type": memoryType})
+	}
+	return svc
+}
+
+func TestCategoriesTree(t *testing.T) {
+	tree := categoryService(t).store.Categories()
+
+	type counts struct{ count, total int }
+	got := map[string]counts{}
+	var walk func([]*CategoryNode)
+	walk = func(nodes []*CategoryNode) {
+		for _, node := range nodes {
+			got[node.Path] = counts{node.Count, node.Total}
+			walk(node.Children)
+		}
+	}
+	walk(tree)
+
+	want := map[string]counts{
+		"note":                       {1, 1},
+		"personal":                   {1, 4},
+		"personal/preferences":       {0, 3},
+		"personal/preferences/food":  {1, 1},
+		"personal/preferences/music": {2, 2},
+		"work":                       {0, 1},
+		"work/meetings":              {1, 1},
+	}
+	for path, w := range want {
+		if got[path] != w {
+			t.Errorf("%s = %+v, want %+v", path, got[path], w)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("tree has %d nodes, want %d: %v", len(got), len(want), got)
+	}
+	if names := []string{tree[0].Name, tree[1].Name, tree[2].Name}; !sort.StringsAreSorted(names) {
+		t.Errorf("top level not sorted: %v", names)
+	}
+}
+
+func TestCategoriesHandler(t *testing.T) {
+	svc := categoryService(t)
+
+	tests := []struct {
+		query string
+		code  int
+		path  string
+		total int
+	}{
+		{"", http.StatusOK, "note", 1},
+		{"?path=personal/preferences", http.StatusOK, "personal/preferences", 3},
+		{"?path=/personal/preferences/music/", http.StatusOK, "personal/preferences/music", 2},
+		{"?path=personal/hobbies", http.StatusNotFound, "", 0},
+	}
+	for _, tt := range tests {
+		rec := serve(svc, http.MethodGet, "/api/v1/memory/categories"+tt.query, nil)
+		if rec.Code != tt.code {
+			t.Errorf("%q: status %d, want %d", tt.query, rec.Code, tt.code)
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		var body struct {
+			Categories []CategoryNode `json:"categories"`
+		}
+		decode(t, rec, &body)
+		if len(body.Categories) == 0 || body.Categories[0].Path != tt.path || body.Categories[0].Total != tt.total {
+			t.Errorf("%q: categories = %+v", tt.query, body.Categories)
+		}
+	}
+}
+
+func TestSearchBySubtree(t *testing.T) {
+	svc := categoryService(t)
+
+	tests := []struct {
+		category string
+		want     int
+	}{
+		{"personal", 4},
+		{"personal/preferences", 3},
+		{"personal/preferences/music", 2},
+		{"work", 1},
+		{"person", 0},
+	}
+	for _, tt := range tests {
+		if got := len(svc.store.Search(nil, Filter{Type: tt.category})); got != tt.want {
+			t.Errorf("type %q: %d results, want %d", tt.category, got, tt.want)
+		}
+	}
+}
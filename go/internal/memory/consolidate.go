@@ -0,0 +1,210 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultConsolidateInterval      = 24 * time.Hour
+	defaultConsolidateMaxImportance = 3
+	consolidateMinAge               = 7 * 24 * time.Hour
+	consolidateMinGroup             = 3
+	consolidateMaxGroup             = 20
+)
+
+// Summarizer condenses several memory texts into one.
+type Summarizer interface {
+	Summarize(ctx context.Context, memoryType string, texts []string) (string, error)
+}
+
+// HTTPSummarizer posts {"type": ..., "texts": [...]} and expects {"summary": "..."}.
+type HTTPSummarizer struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+func (h *HTTPSummarizer) Summarize(ctx context.Context, memoryType string, texts []string) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{"type": memoryType, "texts": texts})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.APIKey)
+	}
+	client := h.Client
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Minute}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("summarizer antwortete mit %d", resp.StatusCode)
+	}
+	var payload struct {
+		Summary string `json:"summary"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(payload.Summary) == "" {
+		return "", fmt.Errorf("summarizer lieferte keine Zusammenfassung")
+	}
+	return payload.Summary, nil
+}
+
+// ConsolidationResult summarizes one consolidation pass.
+type ConsolidationResult struct {
+	Groups   int      `json:"groups"`
+	Archived int      `json:"archived"`
+	Created  []string `json:"created"`
+	Failed   int      `json:"failed"`
+}
+
+// Archive flags the given memories as archived.
+func (s *MemoryStore) Archive(ids []string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	archived := 0
+	now := time.Now()
+	for _, id := range ids {
+		memory, exists := s.memories[id]
+		if !exists || memory.Archived {
+			continue
+		}
+		memory.Archived = true
+		memory.UpdatedAt = now
+		s.notifyLocked(memory, false)
+		archived++
+	}
+	return archived
+}
+
+// consolidationGroups collects old, low-importance memories that share a
+// type and primary tag, in chunks of at most consolidateMaxGroup.
+func (s *MemoryStore) consolidationGroups(maxImportance int, now time.Time) [][]Memory {
+	s.mu.RLock()
+	buckets := map[string][]Memory{}
+	for _, memory := range s.memories {
+		if memory.Archived || memory.Pinned || memory.Key != "" || memory.Importance > maxImportance ||
+			memory.expired(now) || now.Sub(memory.UpdatedAt) < consolidateMinAge {
+			continue
+		}
+		primaryTag := ""
+		if len(memory.Tags) > 0 {
+			tags := append([]string(nil), memory.Tags...)
+			sort.Strings(tags)
+			primaryTag = tags[0]
+		}
+		bucket := namespaceOf(memory) + "\x00" + memory.Type + "\x00" + primaryTag
+		buckets[bucket] = append(buckets[bucket], *memory)
+	}
+	s.mu.RUnlock()
+
+	groups := [][]Memory{}
+	for _, bucket := range buckets {
+		sort.Slice(bucket, func(i, j int) bool {
+			return bucket[i].CreatedAt.Before(bucket[j].CreatedAt)
+		})
+		for len(bucket) >= consolidateMinGroup {
+			size := min(len(bucket), consolidateMaxGroup)
+			groups = append(groups, bucket[:size])
+			bucket = bucket[size:]
+		}
+	}
+	return groups
+}
+
+// Consolidate summarizes each group into a new memory and archives the
+// originals.
+func (s *Service) Consolidate(ctx context.Context) ConsolidationResult {
+	result := ConsolidationResult{Created: []string{}}
+	for _, group := range s.store.consolidationGroups(s.cfg.ConsolidateMaxImportance, time.Now()) {
+		result.Groups++
+
+		texts := make([]string, 0, len(group))
+		ids := make([]string, 0, len(group))
+		tagSet := map[string]struct{}{"consolidated": {}}
+		importance := 0
+		for _, memory := range group {
+			texts = append(texts, memory.Content)
+			ids = append(ids, memory.ID)
+			importance = max(importance, memory.Importance)
+			for _, tag := range memory.Tags {
+				tagSet[tag] = struct{}{}
+			}
+		}
+
+		summary, err := s.summarizer.Summarize(ctx, group[0].Type, texts)
+		if err != nil {
+			s.logger.Printf("[WARN] Konsolidierung fehlgeschlagen: %v", err)
+			result.Failed++
+			continue
+		}
+
+		tags := make([]string, 0, len(tagSet))
+		for tag := range tagSet {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+
+		id := s.store.Add(&Memory{
+			Content:    summary,
+			Type:       group[0].Type,
+			Namespace:  group[0].Namespace,
+			Tags:       tags,
+			Importance: importance,
+			References: ids,
+			Metadata: map[string]interface{}{
+				"consolidated_from": len(ids),
+			},
+		})
+		result.Created = append(result.Created, id)
+		result.Archived += s.store.Archive(ids)
+	}
+	return result
+}
+
+func (s *Service) startConsolidation() {
+	if s.summarizer == nil || s.cfg.ConsolidateInterval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(s.cfg.ConsolidateInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			result := s.Consolidate(context.Background())
+			if result.Groups > 0 {
+				s.logger.Printf("[INFO] Consolidated %d groups, archived %d memories", len(result.Created), result.Archived)
+			}
+		}
+	}()
+}
+
+func (s *Service) consolidateHandler(w http.ResponseWriter, r *http.Request) {
+	if s.summarizer == nil {
+		http.Error(w, `{"error":"Consolidation is not configured"}`, http.StatusServiceUnavailable)
+		return
+	}
+	result := s.Consolidate(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
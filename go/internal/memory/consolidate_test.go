@@ -0,0 +1,144 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// joinSummarizer joins the texts, or fails for the type "broken".
+type joinSummarizer struct{}
+
+func (joinSummarizer) Summarize(_ context.Context, memoryType string, texts []string) (string, error) {
+	if memoryType == "broken" {
+		return "", errors.New("summarizer down")
+	}
+	return strings.Join(texts, " | "), nil
+}
+
+func TestConsolidationGroups(t *testing.T) {
+	store := NewMemoryStore(t.TempDir())
+	now := time.Now()
+	old := now.Add(-2 * consolidateMinAge)
+	add := func(content, memoryType string, importance int, updated time.Time, tags ...string) {
+		store.restore(&Memory{ID: content, Content: content, Type: memoryType, Importance: importance, Tags: tags, CreatedAt: updated, UpdatedAt: updated})
+	}
+	for i := 0; i < 3; i++ {
+		add(fmt.Sprintf("wetter-%d", i), "note", 2, old.Add(time.Duration(i)*time.Minute), "wetter", "zz")
+	}
+	for i := 0; i < 2; i++ {
+		add(fmt.Sprintf("auto-%d", i), "note", 2, old, "auto")
+	}
+	add("recent", "note", 1, now, "wetter")
+	add("important", "note", 9, old, "wetter")
+	add("fact", "fact", 1, old, "wetter")
+	store.restore(&Memory{ID: "pinned", Content: "pinned", Type: "note", Importance: 1, Tags: []string{"wetter"}, Pinned: true, UpdatedAt: old})
+	store.restore(&Memory{ID: "kv", Content: "kv", Type: "note", Importance: 1, Tags: []string{"wetter"}, Key: "k", UpdatedAt: old})
+
+	groups := store.consolidationGroups(3, now)
+	if len(groups) != 1 {
+		t.Fatalf("%d groups, want 1: %v", len(groups), groups)
+	}
+	var ids []string
+	for _, memory := range groups[0] {
+		ids = append(ids, memory.ID)
+	}
+	if want := []string{"wetter-0", "wetter-1", "wetter-2"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("group = %v, want %v", ids, want)
+	}
+
+	for i := 0; i < consolidateMaxGroup+consolidateMinGroup-1; i++ {
+		add(fmt.Sprintf("bulk-%02d", i), "note", 1, old, "bulk")
+	}
+	sizes := map[int]int{}
+	for _, group := range store.consolidationGroups(3, now) {
+		sizes[len(group)]++
+	}
+	if sizes[consolidateMaxGroup] != 1 || sizes[3] != 1 || len(sizes) != 2 {
+		t.Errorf("group sizes %v, want one full group, the wetter group and no remainder", sizes)
+	}
+}
+
+func TestConsolidate(t *testing.T) {
+	svc := newTestService(t, Config{ConsolidateMaxImportance: 3})
+	svc.summarizer = joinSummarizer{}
+	old := time.Now().Add(-2 * consolidateMinAge)
+	for i, memoryType := range []string{"note", "note", "note", "broken", "broken", "broken"} {
+		svc.store.restore(&Memory{ID: fmt.Sprintf("%s-%d", memoryType, i), Content: fmt.Sprintf("text %d", i), Type: memoryType,
+			Importance: i % 3, Tags: []string{"t"}, CreatedAt: old.Add(time.Duration(i) * time.Minute), UpdatedAt: old})
+	}
+
+	result := svc.Consolidate(context.Background())
+	if result.Groups != 2 || result.Failed != 1 || result.Archived != 3 || len(result.Created) != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+	summary, _ := svc.store.Get(result.Created[0])
+	if summary.Content != "text 0 | text 1 | text 2" || summary.Importance != 2 || len(summary.References) != 3 {
+		t.Errorf("summary = %+v", summary)
+	}
+	if !reflect.DeepEqual(summary.Tags, []string{"consolidated", "t"}) {
+		t.Errorf("tags = %v", summary.Tags)
+	}
+	if original, _ := svc.store.Get("note-0"); !original.Archived {
+		t.Error("original not archived")
+	}
+	if original, _ := svc.store.Get("broken-3"); original.Archived {
+		t.Error("memory archived although its group failed")
+	}
+
+	if again := svc.Consolidate(context.Background()); again.Archived != 0 || again.Groups != 1 {
+		t.Errorf("second pass = %+v, want only the failed group retried", again)
+	}
+}
+
+func TestConsolidateHandler(t *testing.T) {
+	svc := newTestService(t, Config{})
+	if rec := serve(svc, http.MethodPost, "/api/v1/memory/consolidate", nil); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("without summarizer: status %d, want 503", rec.Code)
+	}
+	svc.summarizer = joinSummarizer{}
+	if rec := serve(svc, http.MethodPost, "/api/v1/memory/consolidate", nil); rec.Code != http.StatusOK {
+		t.Errorf("status %d, want 200", rec.Code)
+	}
+}
+
+func TestHTTPSummarizer(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{name: "summary", body: `{"summary":"kurz"}`, want: "kurz"},
+		{name: "empty summary", body: `{"summary":" "}`, wantErr: true},
+		{name: "server error", status: http.StatusBadGateway, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer key" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			summary, err := (&HTTPSummarizer{URL: server.URL, APIKey: "key"}).Summarize(context.Background(), "note", []string{"a", "b"})
+			if (err != nil) != tt.wantErr || summary != tt.want {
+				t.Errorf("Summarize = %q, %v", summary, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"jarviscore/go/internal/secrets"
+)
+
+const (
+	keychainService = "jarvis"
+	keychainAccount = "memory"
+)
+
+var errEncrypted = errors.New("memory data is encrypted but no JARVIS_MEMORY_KEY is configured")
+
+// Sealer encrypts persisted memories with AES-256-GCM in the format of
+// secrets.Encrypt. A nil Sealer writes plaintext. Plain JSON is always
+// accepted on read, so existing stores are encrypted on their next write.
+type Sealer struct {
+	key string
+}
+
+func NewSealer(key string) *Sealer {
+	if key == "" {
+		return nil
+	}
+	return &Sealer{key: key}
+}
+
+func (c *Sealer) seal(data []byte) ([]byte, error) {
+	if c == nil {
+		return data, nil
+	}
+	return secrets.Encrypt(c.key, data)
+}
+
+func (c *Sealer) open(data []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return data, nil
+	}
+	if c == nil {
+		return nil, errEncrypted
+	}
+	return secrets.Decrypt(c.key, trimmed)
+}
+
+// keychainKey reads the memory key from the OS keychain: the login keychain
+// on macOS, the Secret Service (secret-tool) on Linux and the Credential
+// Manager (via the CredentialManager PowerShell module) on Windows.
+func keychainKey() (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keychainService, "-a", keychainAccount, "-w")
+	case "linux", "freebsd":
+		cmd = exec.Command("secret-tool", "lookup", "service", keychainService, "account", keychainAccount)
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-Command",
+			"(Get-StoredCredential -Target '"+keychainService+"-"+keychainAccount+"').GetNetworkCredential().Password")
+	default:
+		return "", fmt.Errorf("kein Schlüsselbund für %s unterstützt", runtime.GOOS)
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("Schlüssel konnte nicht aus dem Schlüsselbund gelesen werden: %w", err)
+	}
+	key := strings.TrimSpace(string(output))
+	if key == "" {
+		return "", fmt.Errorf("Schlüsselbund enthält keinen Memory-Schlüssel")
+	}
+	return key, nil
+}
@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSealer(t *testing.T) {
+	plain := []byte(`{"m1":{"content":"Anna mag Tee"}}`)
+	tests := []struct {
+		name    string
+		sealKey string
+		openKey string
+		wantErr error
+	}{
+		{"no key", "", "", nil},
+		{"round trip", "geheim", "geheim", nil},
+		{"plaintext read with key", "", "geheim", nil},
+		{"ciphertext without key", "geheim", "", errEncrypted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := NewSealer(tt.sealKey).seal(plain)
+			if err != nil {
+				t.Fatalf("seal: %v", err)
+			}
+			if tt.sealKey != "" && bytes.Contains(sealed, []byte("Anna")) {
+				t.Errorf("sealed data contains plaintext: %s", sealed)
+			}
+			opened, err := NewSealer(tt.openKey).open(sealed)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("open error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !bytes.Equal(opened, plain) {
+				t.Errorf("open = %s, want %s", opened, plain)
+			}
+		})
+	}
+
+	sealed, _ := NewSealer("geheim").seal(plain)
+	if _, err := NewSealer("falsch").open(sealed); err == nil {
+		t.Error("wrong key opened the data")
+	}
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	dir := t.TempDir()
+	svc := newTestService(t, Config{StorageDir: dir, EncryptionKey: "geheim"})
+	id := addMemory(t, svc, map[string]interface{}{"content": "Anna mag Tee"})
+	serve(svc, http.MethodPut, "/api/v1/memory/memories/"+id, map[string]interface{}{"content": "Anna mag Kaffee"})
+	serve(svc, http.MethodPost, "/api/v1/memory/backups", nil)
+	svc.Close()
+
+	written := 0
+	filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		data, _ := os.ReadFile(path)
+		if len(data) > 0 {
+			written++
+		}
+		if bytes.Contains(data, []byte("Anna")) {
+			t.Errorf("%s contains plaintext", path)
+		}
+		return nil
+	})
+	if written < 3 {
+		t.Errorf("only %d files written", written)
+	}
+
+	reopened := newTestService(t, Config{StorageDir: dir, EncryptionKey: "geheim"})
+	if memory := mustGet(t, reopened.store, id); memory.Content != "Anna mag Kaffee" {
+		t.Errorf("content after restart = %q", memory.Content)
+	}
+	reopened.Close()
+
+	if _, err := NewService(Config{StorageDir: dir}, log.New(io.Discard, "", 0)); err == nil {
+		t.Error("encrypted store opened without key")
+	}
+}
+
+func TestPlainStoreEncryptedOnWrite(t *testing.T) {
+	dir := t.TempDir()
+	svc := newTestService(t, Config{StorageDir: dir})
+	id := addMemory(t, svc, map[string]interface{}{"content": "Anna mag Tee"})
+	svc.Close()
+
+	encrypted := newTestService(t, Config{StorageDir: dir, EncryptionKey: "geheim"})
+	if memory := mustGet(t, encrypted.store, id); memory.Content != "Anna mag Tee" {
+		t.Fatalf("plain memory not loaded: %q", memory.Content)
+	}
+	addMemory(t, encrypted, map[string]interface{}{"content": "Anna mag Kaffee"})
+	encrypted.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, "memories.json"))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if bytes.Contains(data, []byte("Anna")) {
+		t.Error("memories.json still plaintext after write with key")
+	}
+}
+
+func TestKeychainKey(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("fake secret-tool only on linux")
+	}
+	tests := []struct {
+		name    string
+		script  string
+		want    string
+		wantErr bool
+	}{
+		{"key", "#!/bin/sh\n[ \"$*\" = \"lookup service jarvis account memory\" ] && echo ' schluessel '\n", "schluessel", false},
+		{"empty", "#!/bin/sh\necho\n", "", true},
+		{"missing entry", "#!/bin/sh\nexit 1\n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bin := t.TempDir()
+			if err := os.WriteFile(filepath.Join(bin, "secret-tool"), []byte(tt.script), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("PATH", bin)
+
+			key, err := keychainKey()
+			if (err != nil) != tt.wantErr || key != tt.want {
+				t.Errorf("keychainKey = %q, %v; want %q, error %v", key, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
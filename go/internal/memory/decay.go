@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"time"
+)
+
+const (
+	defaultDecayFloor   = 1
+	defaultAccessBoost  = 1
+	maxImportance       = 10
+	decayCheckInterval  = time.Hour
+	accessBoostCooldown = time.Hour
+)
+
+// lastTouched is the latest of update, access and decay.
+func (m *Memory) lastTouched() time.Time {
+	touched := m.UpdatedAt
+	if m.LastAccessed != nil && m.LastAccessed.After(touched) {
+		touched = *m.LastAccessed
+	}
+	if m.DecayedAt != nil && m.DecayedAt.After(touched) {
+		touched = *m.DecayedAt
+	}
+	return touched
+}
+
+// Touch records a read of the memory and raises its importance by boost,
+// at most once per accessBoostCooldown so repeated reads don't pin it at the
+// maximum. It returns a copy of the updated memory, safe to use after the
+// store lock is released.
+func (s *MemoryStore) Touch(id string, boost int, now time.Time) (*Memory, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	memory, exists := s.memories[id]
+	if !exists {
+		return nil, false
+	}
+	if boost > 0 && (memory.LastAccessed == nil || now.Sub(*memory.LastAccessed) >= accessBoostCooldown) {
+		memory.Importance = min(memory.Importance+boost, max(memory.Importance, maxImportance))
+	}
+	accessed := now
+	memory.LastAccessed = &accessed
+	memory.Hits++
+	s.notifyMetaLocked(memory)
+	copied := *memory
+	return &copied, true
+}
+
+// Decay lowers the importance of every memory untouched for at least period
+// by one step per elapsed period, never below floor.
+func (s *MemoryStore) Decay(period time.Duration, floor int, now time.Time) int {
+	if period <= 0 {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	decayed := 0
+	for _, memory := range s.memories {
+		if memory.Archived || memory.Pinned || memory.Importance <= floor {
+			continue
+		}
+		steps := int(now.Sub(memory.lastTouched()) / period)
+		if steps <= 0 {
+			continue
+		}
+		memory.Importance = max(memory.Importance-steps, floor)
+		at := now
+		memory.DecayedAt = &at
+		s.notifyMetaLocked(memory)
+		decayed++
+	}
+	return decayed
+}
+
+func (s *Service) startDecay() {
+	go func() {
+		ticker := time.NewTicker(decayCheckInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if decayed := s.store.Decay(s.cfg.DecayPeriod, s.cfg.DecayFloor, time.Now()); decayed > 0 {
+				s.logger.Printf("[INFO] Lowered importance of %d untouched memories", decayed)
+			}
+		}
+	}()
+}
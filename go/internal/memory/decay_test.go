@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDecay(t *testing.T) {
+	const period = 24 * time.Hour
+	tests := []struct {
+		name    string
+		memory  Memory
+		elapsed time.Duration
+		want    int
+	}{
+		{"within period", Memory{Importance: 8}, 23 * time.Hour, 8},
+		{"one period", Memory{Importance: 8}, period, 7},
+		{"several periods", Memory{Importance: 8}, 3*period + time.Hour, 5},
+		{"stops at floor", Memory{Importance: 4}, 10 * period, 2},
+		{"below floor untouched", Memory{Importance: 1}, 10 * period, 1},
+		{"pinned", Memory{Importance: 8, Pinned: true}, 3 * period, 8},
+		{"archived", Memory{Importance: 8, Archived: true}, 3 * period, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore(t.TempDir())
+			memory := tt.memory
+			id := store.Add(&memory)
+			start := mustGet(t, store, id).UpdatedAt
+
+			store.Decay(period, 2, start.Add(tt.elapsed))
+			if got := mustGet(t, store, id).Importance; got != tt.want {
+				t.Errorf("importance = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecayCountsFromLastTouch(t *testing.T) {
+	const period = 24 * time.Hour
+	store := NewMemoryStore(t.TempDir())
+	id := store.Add(&Memory{Importance: 8})
+	start := mustGet(t, store, id).UpdatedAt
+
+	steps := []struct {
+		name string
+		at   time.Duration
+		want int
+	}{
+		{"first decay", period, 7},
+		{"same run again", period, 7},
+		{"half a period after decay", period + 12*time.Hour, 7},
+		{"a period after decay", 2 * period, 6},
+	}
+	for _, step := range steps {
+		store.Decay(period, 1, start.Add(step.at))
+		if got := mustGet(t, store, id).Importance; got != step.want {
+			t.Errorf("%s: importance = %d, want %d", step.name, got, step.want)
+		}
+	}
+
+	store.Touch(id, 0, start.Add(3*period))
+	if store.Decay(period, 1, start.Add(3*period+time.Hour)) != 0 {
+		t.Error("memory decayed right after being read")
+	}
+	if store.Decay(0, 1, start.Add(100*period)) != 0 {
+		t.Error("zero period decayed memories")
+	}
+}
+
+func TestTouch(t *testing.T) {
+	store := NewMemoryStore(t.TempDir())
+	id := store.Add(&Memory{Importance: 5})
+	now := time.Now()
+
+	steps := []struct {
+		name  string
+		at    time.Duration
+		boost int
+		want  int
+	}{
+		{"first read boosts", 0, 2, 7},
+		{"read within cooldown", 10 * time.Minute, 2, 7},
+		{"read a cooldown after the last read", 10*time.Minute + accessBoostCooldown, 2, 9},
+		{"capped at maximum", 10*time.Minute + 2*accessBoostCooldown, 2, maxImportance},
+		{"no boost configured", 10*time.Minute + 3*accessBoostCooldown, 0, maxImportance},
+	}
+	for i, step := range steps {
+		touched, ok := store.Touch(id, step.boost, now.Add(step.at))
+		if !ok {
+			t.Fatalf("%s: Touch failed", step.name)
+		}
+		if touched.Importance != step.want || touched.Hits != i+1 {
+			t.Errorf("%s: importance %d hits %d, want %d %d", step.name, touched.Importance, touched.Hits, step.want, i+1)
+		}
+		if !touched.LastAccessed.Equal(now.Add(step.at)) {
+			t.Errorf("%s: last accessed %v", step.name, touched.LastAccessed)
+		}
+	}
+
+	above := store.Add(&Memory{Importance: 12})
+	if touched, _ := store.Touch(above, 1, now); touched.Importance != 12 {
+		t.Errorf("importance above maximum changed to %d", touched.Importance)
+	}
+	if _, ok := store.Touch("missing", 1, now); ok {
+		t.Error("Touch of unknown id succeeded")
+	}
+}
+
+func TestGetBoostsImportance(t *testing.T) {
+	svc := newTestService(t, Config{AccessBoost: 3})
+	id := addMemory(t, svc, map[string]interface{}{"content": "Zahnarzt", "importance": 4})
+
+	rec := serve(svc, http.MethodGet, "/api/v1/memory/memories/"+id, nil)
+	var memory Memory
+	decode(t, rec, &memory)
+	if memory.Importance != 7 || memory.LastAccessed == nil {
+		t.Errorf("memory after read = importance %d, last accessed %v", memory.Importance, memory.LastAccessed)
+	}
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const eventQueueSize = 64
+
+type gatewayEvent struct {
+	Type      string                 `json:"type"`
+	Timestamp float64                `json:"timestamp"`
+	Payload   map[string]interface{} `json:"payload"`
+}
+
+// eventPublisher forwards events to gatewayd (POST /api/events), which
+// broadcasts them to connected clients. Events are sent in the background
+// and dropped while the queue is full.
+type eventPublisher struct {
+	url    string
+	token  string
+	client *http.Client
+	logger *log.Logger
+	queue  chan gatewayEvent
+}
+
+func newEventPublisher(cfg Config, logger *log.Logger) *eventPublisher {
+	return &eventPublisher{
+		url:    strings.TrimRight(cfg.GatewayURL, "/") + "/api/events",
+		token:  cfg.GatewayToken,
+		client: &http.Client{Timeout: 5 * time.Second},
+		logger: logger,
+		queue:  make(chan gatewayEvent, eventQueueSize),
+	}
+}
+
+// publish queues an event. A nil publisher discards it.
+func (p *eventPublisher) publish(eventType string, now time.Time, payload map[string]interface{}) {
+	if p == nil {
+		return
+	}
+	select {
+	case p.queue <- gatewayEvent{Type: eventType, Timestamp: float64(now.UnixNano()) / 1e9, Payload: payload}:
+	default:
+		p.logger.Printf("[WARN] gatewayd Event %s verworfen: Warteschlange voll", eventType)
+	}
+}
+
+func (p *eventPublisher) run() {
+	for event := range p.queue {
+		p.send(event)
+	}
+}
+
+func (p *eventPublisher) send(event gatewayEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("X-API-Key", p.token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		p.logger.Printf("[WARN] gatewayd nicht erreichbar: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		p.logger.Printf("[WARN] gatewayd Event fehlgeschlagen: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Eviction policies for the capacity limits.
+const (
+	EvictLowestImportance = "importance"
+	EvictLeastRecent      = "lru"
+)
+
+func evictionPolicy(value string) string {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "lru", "oldest", "untouched":
+		return EvictLeastRecent
+	default:
+		return EvictLowestImportance
+	}
+}
+
+// Capacity bounds the store by entry count and approximate size in bytes
+// (see estimateSize). Zero disables a limit.
+type Capacity struct {
+	MaxEntries int
+	MaxBytes   int
+	Policy     string
+}
+
+func (c Capacity) enabled() bool {
+	return c.MaxEntries > 0 || c.MaxBytes > 0
+}
+
+// Evict deletes memories until the store fits capacity and returns them.
+// Archived memories go first, pinned ones and keep are never evicted. The
+// importance policy drops the lowest importance first, lru the memory
+// untouched the longest; the other criterion breaks ties.
+func (s *MemoryStore) Evict(capacity Capacity, keep string) []*Memory {
+	if !capacity.enabled() {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, bytes := s.stats.totals()
+	if !capacity.exceeded(entries, bytes) {
+		return nil
+	}
+
+	candidates := make([]*Memory, 0, len(s.memories))
+	for _, memory := range s.memories {
+		if !memory.Pinned && memory.ID != keep {
+			candidates = append(candidates, memory)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Archived != b.Archived {
+			return a.Archived
+		}
+		byImportance := a.Importance - b.Importance
+		byAge := a.lastTouched().Compare(b.lastTouched())
+		if capacity.Policy == EvictLeastRecent {
+			byImportance, byAge = byAge, byImportance
+		}
+		if byImportance != 0 {
+			return byImportance < 0
+		}
+		return byAge < 0
+	})
+
+	var evicted []*Memory
+	for _, memory := range candidates {
+		if !capacity.exceeded(entries, bytes) {
+			break
+		}
+		entries--
+		bytes -= estimateSize(memory)
+		delete(s.memories, memory.ID)
+		if memory.Key != "" && s.keys[memory.Key] == memory.ID {
+			delete(s.keys, memory.Key)
+		}
+		s.notifyLocked(memory, true)
+		evicted = append(evicted, memory)
+	}
+	return evicted
+}
+
+func (c Capacity) exceeded(entries, bytes int) bool {
+	return (c.MaxEntries > 0 && entries > c.MaxEntries) || (c.MaxBytes > 0 && bytes > c.MaxBytes)
+}
+
+// enforceCapacity evicts memories over the configured limits, sparing keep
+// (usually the memory that was just written), and reports every eviction.
+func (s *Service) enforceCapacity(keep string) {
+	evicted := s.store.Evict(s.cfg.Capacity, keep)
+	now := time.Now()
+	for _, memory := range evicted {
+		s.logger.Printf("[INFO] Memory %s verdrängt (Policy %s, Wichtigkeit %d)", memory.ID, s.cfg.Capacity.Policy, memory.Importance)
+		s.events.publish("memory.evicted", now, map[string]interface{}{
+			"id":         memory.ID,
+			"type":       memory.Type,
+			"namespace":  namespaceOf(memory),
+			"importance": memory.Importance,
+			"policy":     s.cfg.Capacity.Policy,
+		})
+	}
+}
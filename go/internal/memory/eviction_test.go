@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestEvictionPolicy(t *testing.T) {
+	tests := map[string]string{
+		"":           EvictLowestImportance,
+		"importance": EvictLowestImportance,
+		"unknown":    EvictLowestImportance,
+		" LRU ":      EvictLeastRecent,
+		"oldest":     EvictLeastRecent,
+		"untouched":  EvictLeastRecent,
+	}
+	for value, want := range tests {
+		if got := evictionPolicy(value); got != want {
+			t.Errorf("evictionPolicy(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+// evictionStore holds memories "a" to "e" with distinct importance and last
+// access: a is the least important, e the least recently used.
+func evictionStore(t *testing.T) *MemoryStore {
+	t.Helper()
+	store := NewMemoryStore(t.TempDir())
+	start := time.Now()
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		store.Add(&Memory{ID: id, Content: "0123456789", Importance: i + 1})
+		accessed := start.Add(time.Duration(-i) * time.Hour)
+		store.memories[id].LastAccessed = &accessed
+		store.memories[id].UpdatedAt = accessed.Add(-time.Hour)
+	}
+	return store
+}
+
+func TestEvict(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity Capacity
+		keep     string
+		setup    func(store *MemoryStore)
+		want     []string
+	}{
+		{"disabled", Capacity{}, "", nil, nil},
+		{"within limit", Capacity{MaxEntries: 5}, "", nil, nil},
+		{"lowest importance", Capacity{MaxEntries: 3}, "", nil, []string{"a", "b"}},
+		{"least recent", Capacity{MaxEntries: 3, Policy: EvictLeastRecent}, "", nil, []string{"d", "e"}},
+		{"keep is spared", Capacity{MaxEntries: 4}, "a", nil, []string{"b"}},
+		{"pinned is spared", Capacity{MaxEntries: 4}, "", func(store *MemoryStore) {
+			store.SetFlag("a", flagPinned, true)
+		}, []string{"b"}},
+		{"archived first", Capacity{MaxEntries: 4}, "", func(store *MemoryStore) {
+			store.SetFlag("e", flagArchived, true)
+		}, []string{"e"}},
+		{"importance tie broken by age", Capacity{MaxEntries: 4}, "", func(store *MemoryStore) {
+			store.memories["b"].Importance = 1
+		}, []string{"b"}},
+		{"byte budget", Capacity{MaxBytes: 3 * estimateSize(&Memory{ID: "a", Content: "0123456789"})}, "", nil, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := evictionStore(t)
+			if tt.setup != nil {
+				tt.setup(store)
+			}
+			var got []string
+			for _, memory := range store.Evict(tt.capacity, tt.keep) {
+				got = append(got, memory.ID)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("evicted %v, want %v", got, tt.want)
+			}
+			for _, id := range got {
+				if _, ok := store.Get(id); ok {
+					t.Errorf("%s still stored", id)
+				}
+			}
+			if entries, _ := store.stats.totals(); entries != 5-len(got) {
+				t.Errorf("stats count %d entries, want %d", entries, 5-len(got))
+			}
+		})
+	}
+}
+
+func TestEvictReleasesKey(t *testing.T) {
+	store := NewMemoryStore(t.TempDir())
+	store.Put("wetter", &Memory{Content: "sonnig", Importance: 1})
+	store.Add(&Memory{Content: "Tee", Importance: 9})
+
+	store.Evict(Capacity{MaxEntries: 1}, "")
+	if _, ok := store.GetByKey("wetter"); ok {
+		t.Error("evicted key still resolvable")
+	}
+}
+
+func TestEvictionPublishesEvent(t *testing.T) {
+	events := make(chan gatewayEvent, 4)
+	var token string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event gatewayEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		token = r.Header.Get("X-API-Key")
+		events <- event
+	}))
+	defer gateway.Close()
+
+	svc := newTestService(t, Config{
+		Capacity:     Capacity{MaxEntries: 2, Policy: EvictLowestImportance},
+		GatewayURL:   gateway.URL,
+		GatewayToken: "gw-token",
+	})
+	first := addMemory(t, svc, map[string]interface{}{"content": "eins", "importance": 5})
+	addMemory(t, svc, map[string]interface{}{"content": "zwei", "importance": 6})
+	third := addMemory(t, svc, map[string]interface{}{"content": "drei", "importance": 1})
+
+	if _, ok := svc.store.Get(first); ok {
+		t.Error("lowest importance memory other than the new one not evicted")
+	}
+	if _, ok := svc.store.Get(third); !ok {
+		t.Error("newly added memory evicted")
+	}
+
+	select {
+	case event := <-events:
+		if event.Type != "memory.evicted" || event.Payload["id"] != first || event.Payload["policy"] != EvictLowestImportance || token != "gw-token" {
+			t.Errorf("event = %+v, token %q", event, token)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no eviction event published")
+	}
+}
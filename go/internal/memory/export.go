@@ -0,0 +1,370 @@
+package memory
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Export formats.
+const (
+	FormatJSON     = "json"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+	FormatNDJSON   = "ndjson"
+)
+
+// Import merge strategies for memories whose ID already exists.
+const (
+	MergeSkip      = "skip"
+	MergeOverwrite = "overwrite"
+	MergeDuplicate = "duplicate"
+)
+
+const (
+	maxImportSize = 64 << 20
+	// ndjsonFlushEvery is how many lines a stream buffers before flushing.
+	ndjsonFlushEvery = 100
+)
+
+var csvHeader = []string{"id", "type", "importance", "tags", "created_at", "updated_at", "content", "metadata"}
+
+// ImportResult summarizes an import.
+type ImportResult struct {
+	Added       int `json:"added"`
+	Overwritten int `json:"overwritten"`
+	Skipped     int `json:"skipped"`
+	// OverQuota counts memories left out because their namespace was full.
+	OverQuota int `json:"over_quota,omitempty"`
+}
+
+// Import merges memories into the store using strategy for existing IDs.
+// quota returns the namespace limits (0 for none, nil disables them); a
+// memory that would exceed one is left out. Checks and writes happen under
+// one lock, so concurrent writes can't slip in between.
+func (s *MemoryStore) Import(memories []*Memory, strategy string, quota func(namespace string) int) ImportResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := ImportResult{}
+	now := time.Now()
+	for _, memory := range memories {
+		namespace, err := normalizeNamespace(memory.Namespace)
+		if memory.Content == "" || err != nil {
+			result.Skipped++
+			continue
+		}
+		memory.Namespace = namespace
+		memory.Type = normalizeCategory(memory.Type)
+		if memory.Type == "" {
+			memory.Type = "note"
+		}
+		if memory.CreatedAt.IsZero() {
+			memory.CreatedAt = now
+		}
+		if memory.UpdatedAt.IsZero() {
+			memory.UpdatedAt = memory.CreatedAt
+		}
+
+		previous, exists := s.memories[memory.ID]
+		overwrite := false
+		switch {
+		case memory.ID == "":
+			memory.ID = uuid.New().String()
+		case !exists:
+		case strategy == MergeOverwrite:
+			overwrite = true
+		case strategy == MergeDuplicate:
+			memory.ID = uuid.New().String()
+			memory.Key = ""
+		default:
+			result.Skipped++
+			continue
+		}
+		if quota != nil && (!overwrite || namespaceOf(previous) != namespace) {
+			if limit := quota(namespace); limit > 0 && s.stats.namespaceCount(namespace) >= limit {
+				result.OverQuota++
+				continue
+			}
+		}
+
+		if overwrite {
+			s.record(previous, now)
+			result.Overwritten++
+		} else {
+			result.Added++
+		}
+		s.restoreLocked(memory)
+	}
+	return result
+}
+
+// Dump returns every unexpired memory including archived ones, oldest first.
+func (s *MemoryStore) Dump() []*Memory {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	results := make([]*Memory, 0, len(s.memories))
+	for _, memory := range s.memories {
+		if !memory.expired(now) {
+			copied := *memory
+			results = append(results, &copied)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].CreatedAt.Before(results[j].CreatedAt)
+	})
+	return results
+}
+
+// writeNDJSON writes one memory per line and flushes regularly, so large
+// stores are sent incrementally instead of as one encoded array.
+func writeNDJSON(w io.Writer, memories []*Memory) error {
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	for i, memory := range memories {
+		if err := encoder.Encode(memory); err != nil {
+			return err
+		}
+		if flusher != nil && (i+1)%ndjsonFlushEvery == 0 {
+			flusher.Flush()
+		}
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+	return nil
+}
+
+func writeCSV(w io.Writer, memories []*Memory) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, memory := range memories {
+		metadata := ""
+		if len(memory.Metadata) > 0 {
+			raw, err := json.Marshal(memory.Metadata)
+			if err != nil {
+				return err
+			}
+			metadata = string(raw)
+		}
+		record := []string{
+			memory.ID,
+			memory.Type,
+			strconv.Itoa(memory.Importance),
+			strings.Join(memory.Tags, ";"),
+			memory.CreatedAt.UTC().Format(time.RFC3339),
+			memory.UpdatedAt.UTC().Format(time.RFC3339),
+			memory.Content,
+			metadata,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func readCSV(r io.Reader) ([]*Memory, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+	if _, ok := columns["content"]; !ok {
+		return nil, fmt.Errorf("CSV braucht eine content-Spalte")
+	}
+
+	memories := []*Memory{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		memory := &Memory{
+			ID:      field("id"),
+			Type:    field("type"),
+			Content: field("content"),
+		}
+		memory.Importance, _ = strconv.Atoi(field("importance"))
+		if tags := field("tags"); tags != "" {
+			memory.Tags = strings.Split(tags, ";")
+		}
+		memory.CreatedAt, _ = time.Parse(time.RFC3339, field("created_at"))
+		memory.UpdatedAt, _ = time.Parse(time.RFC3339, field("updated_at"))
+		if metadata := field("metadata"); metadata != "" {
+			if err := json.Unmarshal([]byte(metadata), &memory.Metadata); err != nil {
+				return nil, fmt.Errorf("ungültige Metadaten für %q: %w", memory.ID, err)
+			}
+		}
+		memories = append(memories, memory)
+	}
+	return memories, nil
+}
+
+// writeMarkdown renders memories grouped by type for human review.
+func writeMarkdown(w io.Writer, memories []*Memory) error {
+	byType := map[string][]*Memory{}
+	for _, memory := range memories {
+		byType[memory.Type] = append(byType[memory.Type], memory)
+	}
+	types := make([]string, 0, len(byType))
+	for memoryType := range byType {
+		types = append(types, memoryType)
+	}
+	sort.Strings(types)
+
+	if _, err := fmt.Fprintf(w, "# Jarvis Memories\n\nExportiert am %s, %d Einträge.\n", time.Now().UTC().Format(time.RFC3339), len(memories)); err != nil {
+		return err
+	}
+	for _, memoryType := range types {
+		if _, err := fmt.Fprintf(w, "\n## %s\n", memoryType); err != nil {
+			return err
+		}
+		for _, memory := range byType[memoryType] {
+			fmt.Fprintf(w, "\n- **%s** (Wichtigkeit %d, %s)\n", memory.ID, memory.Importance, memory.UpdatedAt.UTC().Format("2006-01-02"))
+			if len(memory.Tags) > 0 {
+				fmt.Fprintf(w, "  Tags: %s\n", strings.Join(memory.Tags, ", "))
+			}
+			for _, line := range strings.Split(memory.Content, "\n") {
+				if _, err := fmt.Fprintf(w, "  > %s\n", line); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// HTTP Handlers
+
+func (s *Service) exportHandler(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = FormatJSON
+	}
+	memories := s.store.Dump()
+	filename := "jarvis-memories-" + time.Now().UTC().Format("20060102-150405")
+
+	var err error
+	switch format {
+	case FormatJSON:
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(memories)
+	case FormatCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		err = writeCSV(w, memories)
+	case FormatMarkdown, "md":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.md"`)
+		err = writeMarkdown(w, memories)
+	case FormatNDJSON, "jsonl":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.ndjson"`)
+		err = writeNDJSON(w, memories)
+	default:
+		http.Error(w, `{"error":"Unsupported format"}`, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.logger.Printf("[ERROR] Export failed: %v", err)
+	}
+}
+
+// streamMemoriesHandler streams the memories matching the list filters as
+// NDJSON, oldest first. Unlike /all it never builds the whole response in
+// memory.
+func (s *Service) streamMemoriesHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	memories := s.store.Search(nil, filter)
+	sort.Slice(memories, func(i, j int) bool {
+		return memories[i].CreatedAt.Before(memories[j].CreatedAt)
+	})
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(memories)))
+	if err := writeNDJSON(w, memories); err != nil {
+		s.logger.Printf("[ERROR] NDJSON stream failed: %v", err)
+	}
+}
+
+func (s *Service) importHandler(w http.ResponseWriter, r *http.Request) {
+	strategy := strings.ToLower(r.URL.Query().Get("strategy"))
+	switch strategy {
+	case "":
+		strategy = MergeSkip
+	case MergeSkip, MergeOverwrite, MergeDuplicate:
+	default:
+		http.Error(w, `{"error":"Invalid strategy"}`, http.StatusBadRequest)
+		return
+	}
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" && strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		format = FormatCSV
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	var memories []*Memory
+	var err error
+	switch format {
+	case "", FormatJSON:
+		err = json.NewDecoder(r.Body).Decode(&memories)
+	case FormatCSV:
+		memories, err = readCSV(r.Body)
+	default:
+		http.Error(w, `{"error":"Unsupported format"}`, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, "Invalid import data: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	result := s.store.Import(memories, strategy, s.cfg.namespaceQuota)
+	s.flushHistory()
+	s.enforceCapacity("")
+	s.logger.Printf("[INFO] Imported memories: %d added, %d overwritten, %d skipped, %d over quota", result.Added, result.Overwritten, result.Skipped, result.OverQuota)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"strategy": strategy,
+		"result":   result,
+	})
+}
@@ -0,0 +1,277 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	created := time.Date(2026, 2, 3, 4, 5, 6, 0, time.UTC)
+	memories := []*Memory{
+		{ID: "a", Type: "fact", Importance: 7, Tags: []string{"anna", "familie"}, CreatedAt: created, UpdatedAt: created.Add(time.Hour),
+			Content: "Anna sagt: \"Hallo, Welt\"\nzweite Zeile", Metadata: map[string]interface{}{"source": "chat"}},
+		{ID: "b", Type: "note", Importance: 1, CreatedAt: created, UpdatedAt: created, Content: "ohne Tags"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeCSV(&buf, memories); err != nil {
+		t.Fatalf("writeCSV: %v", err)
+	}
+	parsed, err := readCSV(&buf)
+	if err != nil {
+		t.Fatalf("readCSV: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, memories) {
+		t.Errorf("round trip\n got %+v\nwant %+v", parsed[0], memories[0])
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{"content only", "Content\nerste\nzweite\n", 2, false},
+		{"reordered columns", "tags,content,importance\na;b,text,3\n", 1, false},
+		{"missing content column", "id,type\n1,note\n", 0, true},
+		{"invalid metadata", "content,metadata\ntext,{broken\n", 0, true},
+		{"empty", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memories, err := readCSV(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(memories) != tt.want {
+				t.Errorf("%d memories, want %d", len(memories), tt.want)
+			}
+		})
+	}
+}
+
+func TestImportStrategies(t *testing.T) {
+	tests := []struct {
+		strategy string
+		result   ImportResult
+		content  string
+		total    int
+	}{
+		{MergeSkip, ImportResult{Added: 2, Skipped: 2}, "alt", 3},
+		{MergeOverwrite, ImportResult{Added: 2, Overwritten: 1, Skipped: 1}, "neu", 3},
+		{MergeDuplicate, ImportResult{Added: 3, Skipped: 1}, "alt", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			store := NewMemoryStore(t.TempDir())
+			store.restore(&Memory{ID: "existing", Content: "alt", Type: "note"})
+
+			result := store.Import([]*Memory{
+				{ID: "existing", Content: "neu"},
+				{ID: "fresh", Content: "frisch"},
+				{Content: "ohne ID"},
+				{ID: "empty"},
+			}, tt.strategy, nil)
+			if result != tt.result {
+				t.Errorf("result = %+v, want %+v", result, tt.result)
+			}
+			if memory, _ := store.Get("existing"); memory.Content != tt.content {
+				t.Errorf("existing = %q, want %q", memory.Content, tt.content)
+			}
+			if len(store.memories) != tt.total {
+				t.Errorf("%d memories, want %d", len(store.memories), tt.total)
+			}
+		})
+	}
+}
+
+func TestImportNamespaceQuota(t *testing.T) {
+	store := NewMemoryStore(t.TempDir())
+	store.restore(&Memory{ID: "voll", Content: "alt", Type: "note", Namespace: "chat"})
+	quota := func(namespace string) int {
+		if namespace == "chat" {
+			return 2
+		}
+		return 0
+	}
+
+	result := store.Import([]*Memory{
+		{Content: "eins", Namespace: "Chat"},
+		{Content: "zwei", Namespace: "chat"},
+		{ID: "voll", Content: "neu", Namespace: "chat"},
+		{Content: "frei"},
+		{Content: "ungültig", Namespace: "a b"},
+	}, MergeOverwrite, quota)
+	want := ImportResult{Added: 2, Overwritten: 1, Skipped: 1, OverQuota: 1}
+	if result != want {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+	if got := store.stats.namespaceCount("chat"); got != 2 {
+		t.Errorf("chat holds %d memories, want 2", got)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	writeMarkdown(&buf, []*Memory{
+		{ID: "n1", Type: "note", Content: "eine Notiz\nmit zwei Zeilen"},
+		{ID: "f1", Type: "fact", Content: "ein Fakt", Tags: []string{"x", "y"}},
+	})
+	out := buf.String()
+	for _, want := range []string{"2 Einträge", "## fact", "## note", "  > mit zwei Zeilen", "Tags: x, y"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown misses %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "## fact") > strings.Index(out, "## note") {
+		t.Error("types are not sorted")
+	}
+}
+
+func TestExportImportHandlers(t *testing.T) {
+	source := newTestService(t, Config{})
+	addMemory(t, source, map[string]interface{}{"content": "exportiert", "tags": []string{"a"}})
+	archived := addMemory(t, source, map[string]interface{}{"content": "archiviert"})
+	serve(source, http.MethodPost, "/api/v1/memory/memories/"+archived+"/archive", nil)
+
+	tests := []struct {
+		format      string
+		status      int
+		contentType string
+	}{
+		{"", http.StatusOK, "application/json"},
+		{"csv", http.StatusOK, "text/csv; charset=utf-8"},
+		{"md", http.StatusOK, "text/markdown; charset=utf-8"},
+		{"jsonl", http.StatusOK, "application/x-ndjson"},
+		{"xml", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		rec := serve(source, http.MethodGet, "/api/v1/memory/export?format="+tt.format, nil)
+		if rec.Code != tt.status {
+			t.Errorf("format %q: status %d, want %d", tt.format, rec.Code, tt.status)
+			continue
+		}
+		if tt.contentType != "" && rec.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("format %q: Content-Type %q", tt.format, rec.Header().Get("Content-Type"))
+		}
+	}
+
+	exported := serve(source, http.MethodGet, "/api/v1/memory/export?format=csv", nil)
+	target := newTestService(t, Config{})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/memory/import", exported.Body)
+	req.Header.Set("Content-Type", "text/csv")
+	rec := serveRequest(target, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import: status %d (%s)", rec.Code, rec.Body)
+	}
+	if len(target.store.memories) != 2 {
+		t.Errorf("imported %d memories, want both including the archived one", len(target.store.memories))
+	}
+
+	if rec := serve(target, http.MethodPost, "/api/v1/memory/import?strategy=merge", []Memory{}); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid strategy: status %d, want 400", rec.Code)
+	}
+}
+
+type countingFlusher struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (c *countingFlusher) Flush() { c.flushes++ }
+
+func TestWriteNDJSON(t *testing.T) {
+	tests := []struct {
+		count   int
+		flushes int
+	}{
+		{0, 1},
+		{1, 1},
+		{ndjsonFlushEvery, 2},
+		{2*ndjsonFlushEvery + 50, 3},
+	}
+	for _, tt := range tests {
+		memories := make([]*Memory, tt.count)
+		for i := range memories {
+			memories[i] = &Memory{ID: strconv.Itoa(i), Content: "Zeile\nmit Umbruch"}
+		}
+		var out countingFlusher
+		if err := writeNDJSON(&out, memories); err != nil {
+			t.Fatalf("writeNDJSON: %v", err)
+		}
+		if out.flushes != tt.flushes {
+			t.Errorf("%d memories: %d flushes, want %d", tt.count, out.flushes, tt.flushes)
+		}
+
+		lines := 0
+		scanner := bufio.NewScanner(&out.Buffer)
+		for scanner.Scan() {
+			var memory Memory
+			if err := json.Unmarshal(scanner.Bytes(), &memory); err != nil || memory.ID != strconv.Itoa(lines) {
+				t.Fatalf("line %d = %s (%v)", lines, scanner.Bytes(), err)
+			}
+			lines++
+		}
+		if lines != tt.count {
+			t.Errorf("%d lines, want %d", lines, tt.count)
+		}
+	}
+}
+
+func TestStreamMemoriesHandler(t *testing.T) {
+	svc := newTestService(t, Config{})
+	for _, memory := range []map[string]interface{}{
+		{"content": "erste", "type": "note"},
+		{"content": "zweite", "type": "task"},
+		{"content": "dritte", "type": "note"},
+	} {
+		addMemory(t, svc, memory)
+	}
+	archived := addMemory(t, svc, map[string]interface{}{"content": "archiviert", "type": "note"})
+	svc.store.SetFlag(archived, flagArchived, true)
+
+	tests := []struct {
+		query string
+		code  int
+		want  []string
+	}{
+		{"", http.StatusOK, []string{"erste", "zweite", "dritte"}},
+		{"?type=note", http.StatusOK, []string{"erste", "dritte"}},
+		{"?archived=only", http.StatusOK, []string{"archiviert"}},
+		{"?created_after=morgen", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		rec := serve(svc, http.MethodGet, "/api/v1/memory/export/stream"+tt.query, nil)
+		if rec.Code != tt.code {
+			t.Errorf("%q: status %d, want %d", tt.query, rec.Code, tt.code)
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		if rec.Header().Get("Content-Type") != "application/x-ndjson" || rec.Header().Get("X-Total-Count") != strconv.Itoa(len(tt.want)) {
+			t.Errorf("%q: headers %v", tt.query, rec.Header())
+		}
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n") {
+			var memory Memory
+			if err := json.Unmarshal([]byte(line), &memory); err != nil {
+				t.Fatalf("%q: line %q: %v", tt.query, line, err)
+			}
+			got = append(got, memory.Content)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: streamed %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package memory
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// metadataParamPrefix marks metadata filters in the query string:
+// ?meta.source=chat&meta.room=kitchen.
+const metadataParamPrefix = "meta."
+
+// Filter restricts search results. Zero fields are ignored.
+type Filter struct {
+	Type      string
+	Namespace string
+	// Tags matches memories with at least one of the tags.
+	Tags []string
+
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+
+	// Metadata matches memories whose metadata has, for every key, one of
+	// the listed values.
+	Metadata map[string][]string
+
+	// Archived is ArchivedExclude (default), ArchivedInclude or ArchivedOnly.
+	Archived string
+}
+
+// Values of the archived search parameter.
+const (
+	ArchivedExclude = "exclude"
+	ArchivedInclude = "include"
+	ArchivedOnly    = "only"
+)
+
+func parseFilter(query url.Values) (Filter, error) {
+	filter := Filter{Type: query.Get("type")}
+	if namespace := query.Get("namespace"); namespace != "" {
+		normalized, err := normalizeNamespace(namespace)
+		if err != nil {
+			return filter, err
+		}
+		filter.Namespace = normalized
+	}
+	if tags := query.Get("tags"); tags != "" {
+		filter.Tags = strings.Split(tags, ",")
+	}
+	switch archived := strings.ToLower(query.Get("archived")); archived {
+	case "", "false", ArchivedExclude:
+	case "true", ArchivedInclude:
+		filter.Archived = ArchivedInclude
+	case ArchivedOnly:
+		filter.Archived = ArchivedOnly
+	default:
+		return filter, fmt.Errorf("invalid archived")
+	}
+
+	bounds := []struct {
+		param  string
+		target *time.Time
+	}{
+		{"created_after", &filter.CreatedAfter},
+		{"created_before", &filter.CreatedBefore},
+		{"updated_after", &filter.UpdatedAfter},
+		{"updated_before", &filter.UpdatedBefore},
+	}
+	for _, bound := range bounds {
+		value := strings.TrimSpace(query.Get(bound.param))
+		if value == "" {
+			continue
+		}
+		parsed, err := parseFilterTime(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s", bound.param)
+		}
+		*bound.target = parsed
+	}
+
+	for param, values := range query {
+		key, ok := strings.CutPrefix(param, metadataParamPrefix)
+		if !ok || key == "" {
+			continue
+		}
+		if filter.Metadata == nil {
+			filter.Metadata = map[string][]string{}
+		}
+		filter.Metadata[key] = values
+	}
+	return filter, nil
+}
+
+// parseFilterTime accepts RFC 3339 timestamps and plain dates (UTC midnight).
+func parseFilterTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// Matches reports whether memory passes every filter that is set.
+func (f Filter) Matches(memory *Memory) bool {
+	switch f.Archived {
+	case ArchivedInclude:
+	case ArchivedOnly:
+		if !memory.Archived {
+			return false
+		}
+	default:
+		if memory.Archived {
+			return false
+		}
+	}
+	if f.Type != "" && !inCategory(memory.Type, f.Type) {
+		return false
+	}
+	if f.Namespace != "" && namespaceOf(memory) != f.Namespace {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && memory.CreatedAt.Before(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !memory.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	if !f.UpdatedAfter.IsZero() && memory.UpdatedAt.Before(f.UpdatedAfter) {
+		return false
+	}
+	if !f.UpdatedBefore.IsZero() && !memory.UpdatedAt.Before(f.UpdatedBefore) {
+		return false
+	}
+	for key, values := range f.Metadata {
+		if !metadataMatches(memory.Metadata, key, values) {
+			return false
+		}
+	}
+	if len(f.Tags) == 0 {
+		return true
+	}
+	for _, tag := range f.Tags {
+		for _, memTag := range memory.Tags {
+			if tag == memTag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// metadataMatches compares the metadata value as text, so
+// meta.count=3 and meta.done=true match numbers and booleans.
+func metadataMatches(metadata map[string]interface{}, key string, values []string) bool {
+	value, exists := metadata[key]
+	if !exists || value == nil {
+		return false
+	}
+	text := fmt.Sprint(value)
+	for _, want := range values {
+		if text == want {
+			return true
+		}
+	}
+	return false
+}
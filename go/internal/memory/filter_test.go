@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    Filter
+		wantErr bool
+	}{
+		{"", Filter{}, false},
+		{"type=note&tags=a,b", Filter{Type: "note", Tags: []string{"a", "b"}}, false},
+		{"archived=true", Filter{Archived: ArchivedInclude}, false},
+		{"archived=ONLY", Filter{Archived: ArchivedOnly}, false},
+		{"archived=exclude", Filter{}, false},
+		{"archived=maybe", Filter{}, true},
+		{"created_after=2026-03-01", Filter{CreatedAfter: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)}, false},
+		{"updated_before=2026-03-01T10:00:00%2B02:00", Filter{UpdatedBefore: time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)}, false},
+		{"created_before=gestern", Filter{}, true},
+		{"meta.source=chat&meta.room=kitchen&meta.room=bath&meta.=x", Filter{Metadata: map[string][]string{"source": {"chat"}, "room": {"kitchen", "bath"}}}, false},
+	}
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		got, err := parseFilter(values)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: error = %v, want error %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if !got.CreatedAfter.Equal(tt.want.CreatedAfter) || !got.UpdatedBefore.Equal(tt.want.UpdatedBefore) {
+			t.Errorf("%q: bounds = %v %v", tt.query, got.CreatedAfter, got.UpdatedBefore)
+		}
+		got.CreatedAfter, got.UpdatedBefore = tt.want.CreatedAfter, tt.want.UpdatedBefore
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: filter = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	memory := &Memory{
+		Type:      "personal/music",
+		Namespace: "anna",
+		Tags:      []string{"jazz", "abend"},
+		CreatedAt: day(5),
+		UpdatedAt: day(10),
+		Metadata:  map[string]interface{}{"source": "chat", "count": float64(3), "done": true, "empty": nil},
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty filter", Filter{}, true},
+		{"category", Filter{Type: "personal"}, true},
+		{"other category", Filter{Type: "work"}, false},
+		{"namespace", Filter{Namespace: "anna"}, true},
+		{"other namespace", Filter{Namespace: "default"}, false},
+		{"one of the tags", Filter{Tags: []string{"rock", "jazz"}}, true},
+		{"no tag", Filter{Tags: []string{"rock"}}, false},
+		{"created after, inclusive", Filter{CreatedAfter: day(5)}, true},
+		{"created after, too late", Filter{CreatedAfter: day(6)}, false},
+		{"created before, exclusive", Filter{CreatedBefore: day(5)}, false},
+		{"created before", Filter{CreatedBefore: day(6)}, true},
+		{"updated range", Filter{UpdatedAfter: day(9), UpdatedBefore: day(11)}, true},
+		{"updated after", Filter{UpdatedAfter: day(11)}, false},
+		{"updated before, exclusive", Filter{UpdatedBefore: day(10)}, false},
+		{"metadata string", Filter{Metadata: map[string][]string{"source": {"mail", "chat"}}}, true},
+		{"metadata number", Filter{Metadata: map[string][]string{"count": {"3"}}}, true},
+		{"metadata bool", Filter{Metadata: map[string][]string{"done": {"true"}}}, true},
+		{"metadata mismatch", Filter{Metadata: map[string][]string{"source": {"mail"}}}, false},
+		{"metadata null", Filter{Metadata: map[string][]string{"empty": {"<nil>"}}}, false},
+		{"metadata missing key", Filter{Metadata: map[string][]string{"room": {"kitchen"}}}, false},
+		{"all metadata keys", Filter{Metadata: map[string][]string{"source": {"chat"}, "count": {"4"}}}, false},
+		{"archived only", Filter{Archived: ArchivedOnly}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Matches(memory); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	archived := &Memory{Archived: true}
+	for archivedMode, want := range map[string]bool{"": false, ArchivedInclude: true, ArchivedOnly: true} {
+		if got := (Filter{Archived: archivedMode}).Matches(archived); got != want {
+			t.Errorf("archived memory with %q: Matches = %v, want %v", archivedMode, got, want)
+		}
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	svc := newTestService(t, Config{})
+	addMemory(t, svc, map[string]interface{}{"content": "Tee aus dem Chat", "metadata": map[string]interface{}{"source": "chat"}})
+	addMemory(t, svc, map[string]interface{}{"content": "Tee aus der Mail", "metadata": map[string]interface{}{"source": "mail"}})
+
+	tests := []struct {
+		query string
+		code  int
+		want  int
+	}{
+		{"query=tee", http.StatusOK, 2},
+		{"query=tee&meta.source=chat", http.StatusOK, 1},
+		{"query=tee&created_after=2000-01-01", http.StatusOK, 2},
+		{"query=tee&created_before=2000-01-01", http.StatusOK, 0},
+		{"query=tee&created_after=bald", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		rec := serve(svc, http.MethodGet, "/api/v1/memory/search?"+tt.query, nil)
+		if rec.Code != tt.code {
+			t.Errorf("%q: status %d, want %d", tt.query, rec.Code, tt.code)
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		var results []Memory
+		decode(t, rec, &results)
+		if len(results) != tt.want {
+			t.Errorf("%q: %d results, want %d", tt.query, len(results), tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Pinned memories never decay and rank above all others; archived memories
+// are left out of lists and searches unless asked for with ?archived=.
+const (
+	flagPinned   = "pinned"
+	flagArchived = "archived"
+)
+
+// SetFlag sets the pinned or archived flag of memory id. It returns a copy
+// of the updated memory, safe to use after the store lock is released.
+func (s *MemoryStore) SetFlag(id, flag string, value bool) (*Memory, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	memory, exists := s.memories[id]
+	if !exists {
+		return nil, false
+	}
+	switch flag {
+	case flagPinned:
+		memory.Pinned = value
+	case flagArchived:
+		memory.Archived = value
+	}
+	memory.UpdatedAt = time.Now()
+	s.notifyLocked(memory, false)
+	copied := *memory
+	return &copied, true
+}
+
+func (s *Service) flagHandler(flag string, value bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		memory, exists := s.store.SetFlag(mux.Vars(r)["id"], flag, value)
+		if !exists {
+			http.Error(w, `{"error":"Memory not found"}`, http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"memory":  memory,
+		})
+	}
+}
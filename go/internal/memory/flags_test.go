@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFlagHandlers(t *testing.T) {
+	svc := newTestService(t, Config{})
+	id := addMemory(t, svc, map[string]interface{}{"content": "Tee"})
+	base := "/api/v1/memory/memories/" + id
+
+	steps := []struct {
+		method   string
+		path     string
+		code     int
+		pinned   bool
+		archived bool
+	}{
+		{http.MethodPost, base + "/pin", http.StatusOK, true, false},
+		{http.MethodPost, base + "/archive", http.StatusOK, true, true},
+		{http.MethodDelete, base + "/pin", http.StatusOK, false, true},
+		{http.MethodDelete, base + "/archive", http.StatusOK, false, false},
+		{http.MethodPost, "/api/v1/memory/memories/missing/pin", http.StatusNotFound, false, false},
+		{http.MethodDelete, "/api/v1/memory/memories/missing/archive", http.StatusNotFound, false, false},
+	}
+	for _, step := range steps {
+		rec := serve(svc, step.method, step.path, nil)
+		if rec.Code != step.code {
+			t.Errorf("%s %s: status %d, want %d", step.method, step.path, rec.Code, step.code)
+			continue
+		}
+		if step.code != http.StatusOK {
+			continue
+		}
+		var body struct {
+			Memory Memory `json:"memory"`
+		}
+		decode(t, rec, &body)
+		stored := mustGet(t, svc.store, id)
+		if body.Memory.Pinned != step.pinned || body.Memory.Archived != step.archived ||
+			stored.Pinned != step.pinned || stored.Archived != step.archived {
+			t.Errorf("%s %s: response %v/%v, stored %v/%v, want %v/%v", step.method, step.path,
+				body.Memory.Pinned, body.Memory.Archived, stored.Pinned, stored.Archived, step.pinned, step.archived)
+		}
+	}
+}
+
+func TestPinnedRanksFirst(t *testing.T) {
+	store := NewMemoryStore(t.TempDir())
+	store.Add(&Memory{Content: "Tee wichtig", Importance: 9})
+	pinned := store.Add(&Memory{Content: "Tee unwichtig", Importance: 1})
+	store.Add(&Memory{Content: "Tee mittel", Importance: 5})
+	store.SetFlag(pinned, flagPinned, true)
+
+	query, _ := ParseQuery("tee")
+	orders := []PageOptions{
+		{Sort: SortImportance, Desc: true},
+		{Sort: SortImportance},
+		{Sort: SortCreatedAt},
+		{Sort: SortRelevance, Desc: true},
+	}
+	for _, opts := range orders {
+		results := store.Search(query, Filter{})
+		sortMemories(results, opts, query)
+		if results[0].ID != pinned {
+			t.Errorf("sort %s desc %v: first is %q", opts.Sort, opts.Desc, results[0].Content)
+		}
+	}
+	if results := store.Search(query, Filter{}); results[0].ID != pinned || results[1].Importance != 9 {
+		t.Errorf("default order starts with %q, %q", results[0].Content, results[1].Content)
+	}
+}
+
+func TestArchivedExcluded(t *testing.T) {
+	svc := newTestService(t, Config{})
+	addMemory(t, svc, map[string]interface{}{"content": "Tee aktiv", "type": "note"})
+	archived := addMemory(t, svc, map[string]interface{}{"content": "Tee alt", "type": "old"})
+	serve(svc, http.MethodPost, "/api/v1/memory/memories/"+archived+"/archive", nil)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"query=tee", []string{"Tee aktiv"}},
+		{"query=tee&archived=include", []string{"Tee aktiv", "Tee alt"}},
+		{"query=tee&archived=only", []string{"Tee alt"}},
+	}
+	for _, tt := range tests {
+		var results []Memory
+		decode(t, serve(svc, http.MethodGet, "/api/v1/memory/search?"+tt.query+"&sort=created_at&order=asc", nil), &results)
+		var contents []string
+		for _, memory := range results {
+			contents = append(contents, memory.Content)
+		}
+		if len(contents) != len(tt.want) || (len(contents) > 0 && contents[0] != tt.want[0]) {
+			t.Errorf("%q: %v, want %v", tt.query, contents, tt.want)
+		}
+	}
+
+	var listed []Memory
+	decode(t, serve(svc, http.MethodGet, "/api/v1/memory/memories", nil), &listed)
+	if len(listed) != 1 {
+		t.Errorf("list returned %d memories, want 1", len(listed))
+	}
+	for _, node := range svc.store.Categories() {
+		if node.Path == "old" {
+			t.Error("archived memory counted in categories")
+		}
+	}
+	if svc.store.Decay(time.Nanosecond, 1, time.Now().Add(time.Hour)) != 1 {
+		t.Error("archived memory decayed")
+	}
+}
@@ -0,0 +1,269 @@
+package memory
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"jarviscore/go/internal/grpc/memorypb"
+)
+
+// grpcServer exposes the store through memorypb.MemoryService. It applies
+// the same defaults and limits as the HTTP handlers.
+type grpcServer struct {
+	memorypb.UnimplementedMemoryServiceServer
+	svc *Service
+}
+
+// ServeGRPC serves the gRPC API on lis until the server is stopped.
+func (s *Service) ServeGRPC(lis net.Listener) *grpc.Server {
+	server := grpc.NewServer()
+	memorypb.RegisterMemoryServiceServer(server, &grpcServer{svc: s})
+	go func() {
+		if err := server.Serve(lis); err != nil {
+			s.logger.Printf("[ERROR] gRPC-Server-Fehler: %v", err)
+		}
+	}()
+	return server
+}
+
+func (g *grpcServer) AddMemory(_ context.Context, req *memorypb.AddMemoryRequest) (*memorypb.Memory, error) {
+	if req.GetMemory() == nil || req.GetMemory().GetContent() == "" {
+		return nil, status.Error(codes.InvalidArgument, "content is required")
+	}
+	memory := fromProto(req.GetMemory())
+	memory.ID = ""
+	if normalizeCategory(memory.Type) == "" {
+		memory.Type = "note"
+	}
+	if memory.Importance == 0 {
+		memory.Importance = 5
+	}
+	namespace, err := normalizeNamespace(memory.Namespace)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	memory.Namespace = namespace
+	if err := g.svc.checkNamespaceQuota(namespace); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	g.svc.store.Add(memory)
+	g.svc.enforceCapacity(memory.ID)
+	return toProto(memory), nil
+}
+
+func (g *grpcServer) GetMemory(_ context.Context, req *memorypb.GetMemoryRequest) (*memorypb.Memory, error) {
+	memory, exists := g.svc.store.Get(req.GetId())
+	if !exists {
+		return nil, status.Error(codes.NotFound, "memory not found")
+	}
+	touched, exists := g.svc.store.Touch(memory.ID, g.svc.cfg.AccessBoost, time.Now())
+	if !exists {
+		return nil, status.Error(codes.NotFound, "memory not found")
+	}
+	return toProto(touched), nil
+}
+
+func (g *grpcServer) UpdateMemory(_ context.Context, req *memorypb.UpdateMemoryRequest) (*memorypb.Memory, error) {
+	if req.GetMemory() == nil {
+		return nil, status.Error(codes.InvalidArgument, "memory is required")
+	}
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		paths = []string{"content", "type", "tags", "importance"}
+	}
+
+	updates := map[string]interface{}{}
+	for _, path := range paths {
+		switch path {
+		case "content":
+			updates["content"] = req.GetMemory().GetContent()
+		case "type":
+			updates["type"] = req.GetMemory().GetType()
+		case "tags":
+			updates["tags"] = req.GetMemory().GetTags()
+		case "importance":
+			updates["importance"] = float64(req.GetMemory().GetImportance())
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "field %q cannot be updated", path)
+		}
+	}
+
+	if !g.svc.store.Update(req.GetId(), updates) {
+		return nil, status.Error(codes.NotFound, "memory not found")
+	}
+	g.svc.flushHistory()
+	g.svc.enforceCapacity(req.GetId())
+	memory, exists := g.svc.store.Get(req.GetId())
+	if !exists {
+		return nil, status.Error(codes.NotFound, "memory not found")
+	}
+	return toProto(memory), nil
+}
+
+func (g *grpcServer) DeleteMemory(_ context.Context, req *memorypb.DeleteMemoryRequest) (*memorypb.DeleteMemoryResponse, error) {
+	if !g.svc.store.Delete(req.GetId()) {
+		return nil, status.Error(codes.NotFound, "memory not found")
+	}
+	return &memorypb.DeleteMemoryResponse{Deleted: true}, nil
+}
+
+func (g *grpcServer) SearchMemories(ctx context.Context, req *memorypb.SearchMemoriesRequest) (*memorypb.SearchMemoriesResponse, error) {
+	filter := Filter{Type: req.GetType(), Tags: req.GetTags()}
+	if req.GetNamespace() != "" {
+		namespace, err := normalizeNamespace(req.GetNamespace())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		filter.Namespace = namespace
+	}
+	switch archived := req.GetArchived(); archived {
+	case "", ArchivedExclude:
+	case ArchivedInclude, ArchivedOnly:
+		filter.Archived = archived
+	default:
+		return nil, status.Error(codes.InvalidArgument, "invalid archived")
+	}
+	if req.CreatedAfter != nil {
+		filter.CreatedAfter = req.GetCreatedAfter().AsTime()
+	}
+	if req.CreatedBefore != nil {
+		filter.CreatedBefore = req.GetCreatedBefore().AsTime()
+	}
+	if req.UpdatedAfter != nil {
+		filter.UpdatedAfter = req.GetUpdatedAfter().AsTime()
+	}
+	if req.UpdatedBefore != nil {
+		filter.UpdatedBefore = req.GetUpdatedBefore().AsTime()
+	}
+	for key, value := range req.GetMetadata() {
+		if filter.Metadata == nil {
+			filter.Metadata = map[string][]string{}
+		}
+		filter.Metadata[key] = []string{value}
+	}
+
+	limit := int(req.GetLimit())
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	limit = min(limit, g.svc.cfg.MaxLimit)
+
+	if req.GetSemantic() {
+		return g.semanticSearch(ctx, req.GetQuery(), filter, limit)
+	}
+
+	opts := PageOptions{Limit: limit, Offset: max(int(req.GetOffset()), 0), Sort: req.GetSort(), Desc: !req.GetAscending()}
+	switch opts.Sort {
+	case "":
+		opts.Sort = SortImportance
+		if req.GetQuery() != "" {
+			opts.Sort = SortRelevance
+		}
+	case SortImportance, SortCreatedAt, SortUpdatedAt, SortRelevance:
+	default:
+		return nil, status.Error(codes.InvalidArgument, "invalid sort")
+	}
+
+	query, err := ParseQuery(req.GetQuery())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	results := g.svc.store.Search(query, filter)
+	sortMemories(results, opts, query)
+
+	response := &memorypb.SearchMemoriesResponse{Total: int32(len(results))}
+	page := results[min(opts.Offset, len(results)):min(opts.Offset+opts.Limit, len(results))]
+	g.svc.store.RecordHits(page, time.Now())
+	for _, memory := range page {
+		response.Memories = append(response.Memories, toProto(memory))
+	}
+	return response, nil
+}
+
+func (g *grpcServer) semanticSearch(ctx context.Context, query string, filter Filter, limit int) (*memorypb.SearchMemoriesResponse, error) {
+	if g.svc.semantic == nil {
+		return nil, status.Error(codes.Unavailable, "semantic search is not configured")
+	}
+	if strings.TrimSpace(query) == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	results, err := g.svc.semanticSearch(ctx, query, filter, limit)
+	if err != nil {
+		g.svc.logger.Printf("[WARN] Semantic search failed: %v", err)
+		return nil, status.Error(codes.Unavailable, "embedding failed")
+	}
+
+	hits := make([]*Memory, 0, len(results))
+	for _, result := range results {
+		hits = append(hits, result.Memory)
+	}
+	g.svc.store.RecordHits(hits, time.Now())
+
+	response := &memorypb.SearchMemoriesResponse{Total: int32(len(results))}
+	for i, result := range results {
+		response.Memories = append(response.Memories, toProto(hits[i]))
+		response.Scores = append(response.Scores, result.Score)
+	}
+	return response, nil
+}
+
+func toProto(memory *Memory) *memorypb.Memory {
+	pb := &memorypb.Memory{
+		Id:         memory.ID,
+		Content:    memory.Content,
+		Type:       memory.Type,
+		Tags:       memory.Tags,
+		Importance: int32(memory.Importance),
+		CreatedAt:  timestamppb.New(memory.CreatedAt),
+		UpdatedAt:  timestamppb.New(memory.UpdatedAt),
+		References: memory.References,
+		Key:        memory.Key,
+		Archived:   memory.Archived,
+		Namespace:  namespaceOf(memory),
+		Pinned:     memory.Pinned,
+		Hits:       int32(memory.Hits),
+	}
+	if memory.Metadata != nil {
+		if metadata, err := structpb.NewStruct(memory.Metadata); err == nil {
+			pb.Metadata = metadata
+		}
+	}
+	if memory.ExpiresAt != nil {
+		pb.ExpiresAt = timestamppb.New(*memory.ExpiresAt)
+	}
+	if memory.LastAccessed != nil {
+		pb.LastAccessed = timestamppb.New(*memory.LastAccessed)
+	}
+	return pb
+}
+
+func fromProto(pb *memorypb.Memory) *Memory {
+	memory := &Memory{
+		ID:         pb.GetId(),
+		Content:    pb.GetContent(),
+		Type:       pb.GetType(),
+		Tags:       pb.GetTags(),
+		Importance: int(pb.GetImportance()),
+		References: pb.GetReferences(),
+		Metadata:   pb.GetMetadata().AsMap(),
+		Namespace:  pb.GetNamespace(),
+	}
+	if pb.GetMetadata() == nil {
+		memory.Metadata = nil
+	}
+	if pb.ExpiresAt != nil {
+		expiresAt := pb.GetExpiresAt().AsTime()
+		memory.ExpiresAt = &expiresAt
+	}
+	return memory
+}
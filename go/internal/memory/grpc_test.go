@@ -0,0 +1,189 @@
+package memory
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"jarviscore/go/internal/grpc/memorypb"
+)
+
+// grpcClient serves svc on a loopback listener and returns a client for it.
+func grpcClient(t *testing.T, svc *Service) memorypb.MemoryServiceClient {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := svc.ServeGRPC(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return memorypb.NewMemoryServiceClient(conn)
+}
+
+func TestGRPCCrud(t *testing.T) {
+	svc := newTestService(t, Config{})
+	client := grpcClient(t, svc)
+	ctx := context.Background()
+
+	metadata, _ := structpb.NewStruct(map[string]interface{}{"source": "chat"})
+	added, err := client.AddMemory(ctx, &memorypb.AddMemoryRequest{Memory: &memorypb.Memory{
+		Id: "ignored", Content: "Anna mag Tee", Tags: []string{"anna"}, Metadata: metadata,
+	}})
+	if err != nil {
+		t.Fatalf("AddMemory: %v", err)
+	}
+	if added.GetId() == "ignored" || added.GetType() != "note" || added.GetImportance() != 5 || added.GetNamespace() != defaultNamespace {
+		t.Errorf("added = %+v", added)
+	}
+	if added.GetMetadata().AsMap()["source"] != "chat" {
+		t.Errorf("metadata = %v", added.GetMetadata())
+	}
+
+	got, err := client.GetMemory(ctx, &memorypb.GetMemoryRequest{Id: added.GetId()})
+	if err != nil || got.GetContent() != "Anna mag Tee" || got.GetHits() != 1 || got.GetLastAccessed() == nil {
+		t.Errorf("GetMemory = %+v, %v", got, err)
+	}
+
+	updated, err := client.UpdateMemory(ctx, &memorypb.UpdateMemoryRequest{
+		Id:         added.GetId(),
+		Memory:     &memorypb.Memory{Content: "Anna mag Kaffee", Importance: 9},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"content"}},
+	})
+	if err != nil || updated.GetContent() != "Anna mag Kaffee" || updated.GetImportance() != 5 {
+		t.Errorf("UpdateMemory = %+v, %v", updated, err)
+	}
+
+	deleted, err := client.DeleteMemory(ctx, &memorypb.DeleteMemoryRequest{Id: added.GetId()})
+	if err != nil || !deleted.GetDeleted() {
+		t.Errorf("DeleteMemory = %+v, %v", deleted, err)
+	}
+	if _, ok := svc.store.Get(added.GetId()); ok {
+		t.Error("memory still stored after DeleteMemory")
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	svc := newTestService(t, Config{NamespaceQuotas: map[string]int{"chat": 1}})
+	client := grpcClient(t, svc)
+	ctx := context.Background()
+
+	existing, err := client.AddMemory(ctx, &memorypb.AddMemoryRequest{Memory: &memorypb.Memory{Content: "Tee", Namespace: "chat"}})
+	if err != nil {
+		t.Fatalf("AddMemory: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{"add without content", func() error {
+			_, err := client.AddMemory(ctx, &memorypb.AddMemoryRequest{Memory: &memorypb.Memory{}})
+			return err
+		}, codes.InvalidArgument},
+		{"add invalid namespace", func() error {
+			_, err := client.AddMemory(ctx, &memorypb.AddMemoryRequest{Memory: &memorypb.Memory{Content: "x", Namespace: "anna"}})
+			return err
+		}, codes.InvalidArgument},
+		{"add over quota", func() error {
+			_, err := client.AddMemory(ctx, &memorypb.AddMemoryRequest{Memory: &memorypb.Memory{Content: "x", Namespace: "chat"}})
+			return err
+		}, codes.ResourceExhausted},
+		{"get unknown", func() error {
+			_, err := client.GetMemory(ctx, &memorypb.GetMemoryRequest{Id: "missing"})
+			return err
+		}, codes.NotFound},
+		{"update without memory", func() error {
+			_, err := client.UpdateMemory(ctx, &memorypb.UpdateMemoryRequest{Id: existing.GetId()})
+			return err
+		}, codes.InvalidArgument},
+		{"update read-only field", func() error {
+			_, err := client.UpdateMemory(ctx, &memorypb.UpdateMemoryRequest{
+				Id: existing.GetId(), Memory: &memorypb.Memory{}, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"namespace"}},
+			})
+			return err
+		}, codes.InvalidArgument},
+		{"update unknown", func() error {
+			_, err := client.UpdateMemory(ctx, &memorypb.UpdateMemoryRequest{Id: "missing", Memory: &memorypb.Memory{Content: "x"}})
+			return err
+		}, codes.NotFound},
+		{"delete unknown", func() error {
+			_, err := client.DeleteMemory(ctx, &memorypb.DeleteMemoryRequest{Id: "missing"})
+			return err
+		}, codes.NotFound},
+		{"search invalid sort", func() error {
+			_, err := client.SearchMemories(ctx, &memorypb.SearchMemoriesRequest{Sort: "random"})
+			return err
+		}, codes.InvalidArgument},
+		{"search invalid archived", func() error {
+			_, err := client.SearchMemories(ctx, &memorypb.SearchMemoriesRequest{Archived: "maybe"})
+			return err
+		}, codes.InvalidArgument},
+		{"semantic without embedder", func() error {
+			_, err := client.SearchMemories(ctx, &memorypb.SearchMemoriesRequest{Query: "tee", Semantic: true})
+			return err
+		}, codes.Unavailable},
+	}
+	for _, tt := range tests {
+		if code := status.Code(tt.call()); code != tt.code {
+			t.Errorf("%s: code %v, want %v", tt.name, code, tt.code)
+		}
+	}
+}
+
+func TestGRPCSearch(t *testing.T) {
+	svc := newTestService(t, Config{MaxLimit: 2})
+	client := grpcClient(t, svc)
+	ctx := context.Background()
+	for i, content := range []string{"Tee am Morgen", "Tee am Abend", "Grüner Tee", "Kaffee"} {
+		if _, err := client.AddMemory(ctx, &memorypb.AddMemoryRequest{Memory: &memorypb.Memory{Content: content, Importance: int32(i + 1)}}); err != nil {
+			t.Fatalf("AddMemory: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		req   *memorypb.SearchMemoriesRequest
+		total int32
+		first []string
+	}{
+		{"query capped by max limit", &memorypb.SearchMemoriesRequest{Query: "tee", Sort: SortImportance, Limit: 10}, 3, []string{"Grüner Tee", "Tee am Abend"}},
+		{"ascending with offset", &memorypb.SearchMemoriesRequest{Query: "tee", Sort: SortImportance, Ascending: true, Offset: 1}, 3, []string{"Tee am Abend", "Grüner Tee"}},
+		{"all by importance", &memorypb.SearchMemoriesRequest{}, 4, []string{"Kaffee", "Grüner Tee"}},
+		{"offset past the end", &memorypb.SearchMemoriesRequest{Offset: 10}, 4, nil},
+	}
+	for _, tt := range tests {
+		response, err := client.SearchMemories(ctx, tt.req)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var contents []string
+		for _, memory := range response.GetMemories() {
+			contents = append(contents, memory.GetContent())
+		}
+		if response.GetTotal() != tt.total || len(contents) != len(tt.first) {
+			t.Errorf("%s: total %d, memories %v, want %d %v", tt.name, response.GetTotal(), contents, tt.total, tt.first)
+			continue
+		}
+		for i := range contents {
+			if contents[i] != tt.first[i] {
+				t.Errorf("%s: memories %v, want %v", tt.name, contents, tt.first)
+				break
+			}
+		}
+	}
+}
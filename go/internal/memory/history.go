@@ -0,0 +1,247 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"jarviscore/go/internal/fsutil"
+)
+
+const (
+	historyFile         = "history.json"
+	defaultMaxRevisions = 20
+)
+
+var (
+	errMemoryNotFound   = errors.New("memory not found")
+	errRevisionNotFound = errors.New("revision not found")
+)
+
+// Revision is the state of a memory before it was overwritten.
+type Revision struct {
+	Revision   int                    `json:"revision"`
+	Content    string                 `json:"content"`
+	Type       string                 `json:"type"`
+	Tags       []string               `json:"tags"`
+	Importance int                    `json:"importance"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+	ReplacedAt time.Time              `json:"replaced_at"`
+}
+
+// History keeps the last revisions of every updated memory in history.json,
+// independent of the storage backend.
+type History struct {
+	path      string
+	sealer    *Sealer
+	limit     int
+	revisions map[string][]Revision
+	dirty     bool
+	mu        sync.Mutex
+}
+
+func OpenHistory(path string, limit int, sealer *Sealer) (*History, error) {
+	if limit <= 0 {
+		limit = defaultMaxRevisions
+	}
+	h := &History{path: path, sealer: sealer, limit: limit, revisions: make(map[string][]Revision)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err == nil {
+		data, err = sealer.open(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &h.revisions); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Record stores the current state of memory as its newest revision.
+func (h *History) Record(memory *Memory, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	revisions := h.revisions[memory.ID]
+	number := 1
+	if len(revisions) > 0 {
+		number = revisions[len(revisions)-1].Revision + 1
+	}
+	revisions = append(revisions, Revision{
+		Revision:   number,
+		Content:    memory.Content,
+		Type:       memory.Type,
+		Tags:       append([]string(nil), memory.Tags...),
+		Importance: memory.Importance,
+		Metadata:   memory.Metadata,
+		UpdatedAt:  memory.UpdatedAt,
+		ReplacedAt: now,
+	})
+	if len(revisions) > h.limit {
+		revisions = append([]Revision(nil), revisions[len(revisions)-h.limit:]...)
+	}
+	h.revisions[memory.ID] = revisions
+	h.dirty = true
+}
+
+// List returns the revisions of id, newest first.
+func (h *History) List(id string) []Revision {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	revisions := h.revisions[id]
+	result := make([]Revision, 0, len(revisions))
+	for i := len(revisions) - 1; i >= 0; i-- {
+		result = append(result, revisions[i])
+	}
+	return result
+}
+
+func (h *History) get(id string, number int) (Revision, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, revision := range h.revisions[id] {
+		if revision.Revision == number {
+			return revision, true
+		}
+	}
+	return Revision{}, false
+}
+
+func (h *History) observe(change Change) {
+	if !change.Deleted {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, exists := h.revisions[change.ID]; exists {
+		delete(h.revisions, change.ID)
+		h.dirty = true
+	}
+}
+
+// Flush writes history.json if it changed.
+func (h *History) Flush() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.dirty {
+		return nil
+	}
+	data, err := json.Marshal(h.revisions)
+	if err == nil {
+		data, err = h.sealer.seal(data)
+	}
+	if err != nil {
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(h.path, data, 0o644); err != nil {
+		return err
+	}
+	h.dirty = false
+	return nil
+}
+
+// record adds a revision of memory if the store keeps a history. Caller
+// holds s.mu.
+func (s *MemoryStore) record(memory *Memory, now time.Time) {
+	if s.history != nil {
+		s.history.Record(memory, now)
+	}
+}
+
+// Revert restores revision number of memory id and returns a copy of the
+// result. The replaced state becomes a revision itself, so a revert can be
+// undone.
+func (s *MemoryStore) Revert(id string, number int) (*Memory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	memory, exists := s.memories[id]
+	if !exists || s.history == nil {
+		return nil, errMemoryNotFound
+	}
+	revision, found := s.history.get(id, number)
+	if !found {
+		return nil, errRevisionNotFound
+	}
+
+	now := time.Now()
+	s.record(memory, now)
+	memory.Content = revision.Content
+	memory.Type = revision.Type
+	memory.Tags = append([]string(nil), revision.Tags...)
+	memory.Importance = revision.Importance
+	memory.Metadata = revision.Metadata
+	memory.UpdatedAt = now
+	s.notifyLocked(memory, false)
+	copied := *memory
+	return &copied, nil
+}
+
+func (s *Service) flushHistory() {
+	if s.history == nil {
+		return
+	}
+	if err := s.history.Flush(); err != nil {
+		s.logger.Printf("[ERROR] Memory-Historie konnte nicht gespeichert werden: %v", err)
+	}
+}
+
+func (s *Service) listRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, exists := s.store.Get(id); !exists {
+		http.Error(w, `{"error":"Memory not found"}`, http.StatusNotFound)
+		return
+	}
+
+	revisions := []Revision{}
+	if s.history != nil {
+		revisions = s.history.List(id)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":        id,
+		"revisions": revisions,
+	})
+}
+
+func (s *Service) restoreRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	number, err := strconv.Atoi(vars["revision"])
+	if err != nil || number <= 0 {
+		http.Error(w, `{"error":"Invalid revision"}`, http.StatusBadRequest)
+		return
+	}
+
+	memory, err := s.store.Revert(vars["id"], number)
+	switch {
+	case errors.Is(err, errMemoryNotFound):
+		http.Error(w, `{"error":"Memory not found"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, errRevisionNotFound):
+		http.Error(w, `{"error":"Revision not found"}`, http.StatusNotFound)
+		return
+	}
+	s.flushHistory()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"memory":  memory,
+		"message": "Memory restored to revision " + strconv.Itoa(number),
+	})
+}
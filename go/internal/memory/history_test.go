@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistoryRecordKeepsLimit(t *testing.T) {
+	history, err := OpenHistory(filepath.Join(t.TempDir(), historyFile), 3, nil)
+	if err != nil {
+		t.Fatalf("OpenHistory: %v", err)
+	}
+	memory := &Memory{ID: "m1"}
+	now := time.Now()
+	for i, content := range []string{"a", "b", "c", "d", "e"} {
+		memory.Content = content
+		history.Record(memory, now.Add(time.Duration(i)*time.Second))
+	}
+
+	var numbers []int
+	var contents []string
+	for _, revision := range history.List("m1") {
+		numbers = append(numbers, revision.Revision)
+		contents = append(contents, revision.Content)
+	}
+	if !reflect.DeepEqual(numbers, []int{5, 4, 3}) || !reflect.DeepEqual(contents, []string{"e", "d", "c"}) {
+		t.Errorf("revisions = %v %v, want [5 4 3] [e d c]", numbers, contents)
+	}
+	if _, ok := history.get("m1", 2); ok {
+		t.Error("trimmed revision still available")
+	}
+	if got := history.List("other"); len(got) != 0 {
+		t.Errorf("unknown memory has revisions %v", got)
+	}
+
+	history.observe(Change{ID: "m1", Deleted: true})
+	if got := history.List("m1"); len(got) != 0 {
+		t.Errorf("revisions after delete = %v", got)
+	}
+}
+
+func TestHistoryPersistence(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		reopenKey string
+		wantErr   bool
+	}{
+		{"plain", "", "", false},
+		{"plain read with key", "", "geheim", false},
+		{"sealed", "geheim", "geheim", false},
+		{"sealed without key", "geheim", "", true},
+		{"sealed wrong key", "geheim", "falsch", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), historyFile)
+			history, err := OpenHistory(path, 0, NewSealer(tt.key))
+			if err != nil {
+				t.Fatalf("OpenHistory: %v", err)
+			}
+			history.Record(&Memory{ID: "m1", Content: "alt", Tags: []string{"x"}}, time.Now())
+			if err := history.Flush(); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+
+			reopened, err := OpenHistory(path, 0, NewSealer(tt.reopenKey))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("reopen error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			revisions := reopened.List("m1")
+			if len(revisions) != 1 || revisions[0].Content != "alt" || !reflect.DeepEqual(revisions[0].Tags, []string{"x"}) {
+				t.Errorf("revisions after reopen = %+v", revisions)
+			}
+		})
+	}
+}
+
+func TestRevisionHandlers(t *testing.T) {
+	svc := newTestService(t, Config{})
+	id := addMemory(t, svc, map[string]interface{}{"content": "Version 1", "importance": 3})
+	for _, content := range []string{"Version 2", "Version 3"} {
+		if rec := serve(svc, http.MethodPut, "/api/v1/memory/memories/"+id, map[string]interface{}{"content": content}); rec.Code != http.StatusOK {
+			t.Fatalf("update: status %d", rec.Code)
+		}
+	}
+
+	var listed struct {
+		Revisions []Revision `json:"revisions"`
+	}
+	decode(t, serve(svc, http.MethodGet, "/api/v1/memory/memories/"+id+"/revisions", nil), &listed)
+	if len(listed.Revisions) != 2 || listed.Revisions[0].Content != "Version 2" || listed.Revisions[1].Content != "Version 1" {
+		t.Fatalf("revisions = %+v", listed.Revisions)
+	}
+
+	var restored struct {
+		Memory Memory `json:"memory"`
+	}
+	decode(t, serve(svc, http.MethodPost, "/api/v1/memory/memories/"+id+"/revisions/1/restore", nil), &restored)
+	if restored.Memory.Content != "Version 1" || restored.Memory.Importance != 3 {
+		t.Errorf("restored memory = %+v", restored.Memory)
+	}
+
+	// The replaced state is kept, so the restore can be undone.
+	decode(t, serve(svc, http.MethodGet, "/api/v1/memory/memories/"+id+"/revisions", nil), &listed)
+	if len(listed.Revisions) != 3 || listed.Revisions[0].Revision != 3 || listed.Revisions[0].Content != "Version 3" {
+		t.Errorf("revisions after restore = %+v", listed.Revisions)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+	}{
+		{"list unknown memory", http.MethodGet, "/api/v1/memory/memories/missing/revisions", http.StatusNotFound},
+		{"restore unknown memory", http.MethodPost, "/api/v1/memory/memories/missing/revisions/1/restore", http.StatusNotFound},
+		{"restore unknown revision", http.MethodPost, "/api/v1/memory/memories/" + id + "/revisions/9/restore", http.StatusNotFound},
+		{"restore revision zero", http.MethodPost, "/api/v1/memory/memories/" + id + "/revisions/0/restore", http.StatusBadRequest},
+		{"restore invalid revision", http.MethodPost, "/api/v1/memory/memories/" + id + "/revisions/abc/restore", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := serve(svc, tt.method, tt.path, nil); rec.Code != tt.code {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.code)
+		}
+	}
+}
+
+func TestHistorySurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	svc := newTestService(t, Config{StorageDir: dir})
+	id := addMemory(t, svc, map[string]interface{}{"content": "vorher"})
+	serve(svc, http.MethodPut, "/api/v1/memory/memories/"+id, map[string]interface{}{"content": "nachher"})
+	svc.Close()
+
+	restarted := newTestService(t, Config{StorageDir: dir})
+	if rec := serve(restarted, http.MethodPost, "/api/v1/memory/memories/"+id+"/revisions/1/restore", nil); rec.Code != http.StatusOK {
+		t.Fatalf("restore after restart: status %d (%s)", rec.Code, rec.Body)
+	}
+	if memory := mustGet(t, restarted.store, id); memory.Content != "vorher" {
+		t.Errorf("content after restore = %q", memory.Content)
+	}
+}
+
+func TestRevertReturnsCopy(t *testing.T) {
+	svc := newTestService(t, Config{})
+	id := addMemory(t, svc, map[string]interface{}{"content": "erste"})
+	serve(svc, http.MethodPut, "/api/v1/memory/memories/"+id, map[string]interface{}{"content": "zweite"})
+
+	reverted, err := svc.store.Revert(id, 1)
+	if err != nil {
+		t.Fatalf("Revert: %v", err)
+	}
+	reverted.Content = "verändert"
+	if memory := mustGet(t, svc.store, id); memory.Content != "erste" {
+		t.Errorf("content = %q, Revert returned the stored memory", memory.Content)
+	}
+}
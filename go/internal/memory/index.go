@@ -0,0 +1,212 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// TextIndex is an inverted index from lowercase word tokens to memory IDs.
+// Search looks up the query tokens in the vocabulary and only verifies the
+// full query against the resulting candidates, so a query no longer scans
+// every memory.
+type TextIndex struct {
+	postings map[string]map[string]struct{}
+	tokens   map[string][]string // memory ID -> tokens
+	lower    map[string]string   // memory ID -> lowercase content
+	vocab    []string
+	dirty    bool
+	mu       sync.RWMutex
+}
+
+func NewTextIndex() *TextIndex {
+	return &TextIndex{
+		postings: make(map[string]map[string]struct{}),
+		tokens:   make(map[string][]string),
+		lower:    make(map[string]string),
+	}
+}
+
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]struct{}, len(fields))
+	tokens := fields[:0]
+	for _, field := range fields {
+		if _, dup := seen[field]; dup {
+			continue
+		}
+		seen[field] = struct{}{}
+		tokens = append(tokens, field)
+	}
+	return tokens
+}
+
+// observe keeps the index in sync with the store.
+func (x *TextIndex) observe(change Change) {
+	if change.MetaOnly {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.removeLocked(change.ID)
+	if change.Deleted {
+		return
+	}
+	tokens := tokenize(change.Content)
+	x.tokens[change.ID] = tokens
+	x.lower[change.ID] = strings.ToLower(change.Content)
+	for _, token := range tokens {
+		ids, exists := x.postings[token]
+		if !exists {
+			ids = make(map[string]struct{})
+			x.postings[token] = ids
+			x.dirty = true
+		}
+		ids[change.ID] = struct{}{}
+	}
+}
+
+func (x *TextIndex) removeLocked(id string) {
+	for _, token := range x.tokens[id] {
+		ids := x.postings[token]
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(x.postings, token)
+			x.dirty = true
+		}
+	}
+	delete(x.tokens, id)
+	delete(x.lower, id)
+}
+
+// Reset empties the index.
+func (x *TextIndex) Reset() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.postings = make(map[string]map[string]struct{})
+	x.tokens = make(map[string][]string)
+	x.lower = make(map[string]string)
+	x.vocab = nil
+	x.dirty = false
+}
+
+// tokenMatch says how a query token may appear in the tokens of a memory
+// that contains the query as a substring. A token cut off by the start of
+// the query may be the end of a longer word, one cut off by its end the
+// beginning; tokens between separators are whole words.
+type tokenMatch int
+
+const (
+	matchWord tokenMatch = iota
+	matchPrefix
+	matchSuffix
+	matchInfix
+)
+
+// queryTokens returns the tokens of query and how each may match.
+func queryTokens(query string) ([]string, []tokenMatch) {
+	lower := strings.ToLower(query)
+	fields := strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	modes := make([]tokenMatch, len(fields))
+	for i, field := range fields {
+		openStart := i == 0 && strings.HasPrefix(lower, field)
+		openEnd := i == len(fields)-1 && strings.HasSuffix(lower, field)
+		switch {
+		case openStart && openEnd:
+			modes[i] = matchInfix
+		case openStart:
+			modes[i] = matchSuffix
+		case openEnd:
+			modes[i] = matchPrefix
+		}
+	}
+	return fields, modes
+}
+
+// matchLocked returns the IDs of all memories with a token that matches
+// token in mode. Caller holds x.mu for writing (the vocabulary may be
+// rebuilt).
+func (x *TextIndex) matchLocked(token string, mode tokenMatch) map[string]struct{} {
+	if x.dirty {
+		x.vocab = x.vocab[:0]
+		for token := range x.postings {
+			x.vocab = append(x.vocab, token)
+		}
+		sort.Strings(x.vocab)
+		x.dirty = false
+	}
+
+	if mode == matchWord {
+		return x.postings[token]
+	}
+	if ids, exact := x.postings[token]; exact && mode == matchPrefix && !x.hasLongerLocked(token) {
+		return ids
+	}
+	matches := make(map[string]struct{})
+	add := func(word string) {
+		for id := range x.postings[word] {
+			matches[id] = struct{}{}
+		}
+	}
+	if mode == matchPrefix {
+		for i := sort.SearchStrings(x.vocab, token); i < len(x.vocab) && strings.HasPrefix(x.vocab[i], token); i++ {
+			add(x.vocab[i])
+		}
+		return matches
+	}
+	for _, word := range x.vocab {
+		if (mode == matchSuffix && strings.HasSuffix(word, token)) || (mode == matchInfix && strings.Contains(word, token)) {
+			add(word)
+		}
+	}
+	return matches
+}
+
+func (x *TextIndex) hasLongerLocked(token string) bool {
+	i := sort.SearchStrings(x.vocab, token)
+	return i+1 < len(x.vocab) && strings.HasPrefix(x.vocab[i+1], token)
+}
+
+// Candidates returns the IDs whose content contains query. ok is false if
+// the query has no word tokens and the index cannot answer it.
+func (x *TextIndex) Candidates(query string) (ids []string, ok bool) {
+	tokens, modes := queryTokens(query)
+	if len(tokens) == 0 {
+		return nil, false
+	}
+	queryLower := strings.ToLower(query)
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	sets := make([]map[string]struct{}, 0, len(tokens))
+	for i, token := range tokens {
+		set := x.matchLocked(token, modes[i])
+		if len(set) == 0 {
+			return []string{}, true
+		}
+		sets = append(sets, set)
+	}
+	sort.Slice(sets, func(i, j int) bool { return len(sets[i]) < len(sets[j]) })
+
+	ids = []string{}
+	for id := range sets[0] {
+		inAll := true
+		for _, set := range sets[1:] {
+			if _, found := set[id]; !found {
+				inAll = false
+				break
+			}
+		}
+		if inAll && strings.Contains(x.lower[id], queryLower) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, true
+}
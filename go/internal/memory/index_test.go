@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("Anna's Tee-Kanne, anna 2x!")
+	if want := []string{"anna", "s", "tee", "kanne", "2x"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenize = %v, want %v", got, want)
+	}
+}
+
+func TestQueryTokens(t *testing.T) {
+	tests := []struct {
+		query  string
+		tokens []string
+		modes  []tokenMatch
+	}{
+		{"tee", []string{"tee"}, []tokenMatch{matchInfix}},
+		{"anna mag", []string{"anna", "mag"}, []tokenMatch{matchSuffix, matchPrefix}},
+		{" anna mag ", []string{"anna", "mag"}, []tokenMatch{matchWord, matchWord}},
+		{"a b c", []string{"a", "b", "c"}, []tokenMatch{matchSuffix, matchWord, matchPrefix}},
+		{"--", []string{}, []tokenMatch{}},
+	}
+	for _, tt := range tests {
+		tokens, modes := queryTokens(tt.query)
+		if !reflect.DeepEqual(tokens, tt.tokens) || !reflect.DeepEqual(modes, tt.modes) {
+			t.Errorf("queryTokens(%q) = %v %v, want %v %v", tt.query, tokens, modes, tt.tokens, tt.modes)
+		}
+	}
+}
+
+var indexContents = map[string]string{
+	"1": "Anna mag grünen Tee",
+	"2": "Kaffeetee ist eine seltsame Mischung",
+	"3": "Teekanne im Schrank",
+	"4": "Annabelle mag keinen Tee, Anna schon",
+	"5": "Termin: 10:30 beim Zahnarzt",
+	"6": "tee",
+}
+
+// TestCandidatesMatchSubstringScan checks that the index returns exactly the
+// memories a case-insensitive substring scan finds.
+func TestCandidatesMatchSubstringScan(t *testing.T) {
+	index := NewTextIndex()
+	for id, content := range indexContents {
+		index.observe(Change{ID: id, Content: content})
+	}
+
+	queries := []string{"tee", "Tee", "anna", "anna mag", "nna ma", "mag grü", "ee", "kanne im", "10:30", "0:3", "schrank ", " im ", "nichts", "tee, anna"}
+	for _, query := range queries {
+		ids, ok := index.Candidates(query)
+		if !ok {
+			t.Errorf("Candidates(%q) not answerable", query)
+			continue
+		}
+		sort.Strings(ids)
+		want := []string{}
+		for id, content := range indexContents {
+			if strings.Contains(strings.ToLower(content), strings.ToLower(query)) {
+				want = append(want, id)
+			}
+		}
+		sort.Strings(want)
+		if !reflect.DeepEqual(ids, want) {
+			t.Errorf("Candidates(%q) = %v, want %v", query, ids, want)
+		}
+	}
+
+	if _, ok := index.Candidates(" : "); ok {
+		t.Error("query without word tokens answered by the index")
+	}
+}
+
+func TestIndexFollowsChanges(t *testing.T) {
+	store := NewMemoryStore(t.TempDir())
+	id := store.Add(&Memory{Content: "alter Inhalt"})
+	store.Update(id, map[string]interface{}{"content": "neuer Inhalt"})
+	other := store.Add(&Memory{Content: "alter Schrank"})
+	store.Delete(other)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"alter", 0},
+		{"neuer", 1},
+		{"inhalt", 1},
+		{"schrank", 0},
+	}
+	for _, tt := range tests {
+		query, _ := ParseQuery(tt.query)
+		if got := len(store.Search(query, Filter{})); got != tt.want {
+			t.Errorf("Search(%q) = %d results, want %d", tt.query, got, tt.want)
+		}
+	}
+	if len(store.index.postings["alter"]) != 0 {
+		t.Error("postings of removed content left behind")
+	}
+}
+
+func BenchmarkSearch(b *testing.B) {
+	store := NewMemoryStore(b.TempDir())
+	words := []string{"tee", "kaffee", "anna", "termin", "zahnarzt", "schrank", "auto", "werkstatt", "wetter", "berlin"}
+	for i := 0; i < 20000; i++ {
+		store.Add(&Memory{Content: fmt.Sprintf("%s %s notiz %d", words[i%len(words)], words[(i/7)%len(words)], i)})
+	}
+	query, _ := ParseQuery("notiz 1234")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		store.Search(query, Filter{})
+	}
+}
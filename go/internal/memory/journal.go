@@ -0,0 +1,176 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	journalFile         = "memories.journal"
+	defaultCompactAfter = 10000
+	journalOpPut        = "put"
+	journalOpDelete     = "delete"
+)
+
+type journalEntry struct {
+	Op     string    `json:"op"`
+	ID     string    `json:"id"`
+	Time   time.Time `json:"time"`
+	Memory *Memory   `json:"memory,omitempty"`
+}
+
+// Journal is an append-only log of changes made since the last snapshot of
+// memories.json. On startup it is replayed on top of the snapshot; compaction
+// writes a new snapshot and truncates it.
+type Journal struct {
+	path    string
+	sealer  *Sealer
+	file    *os.File
+	entries int
+	compact chan struct{}
+	mu      sync.Mutex
+}
+
+func OpenJournal(storageDir string, sealer *Sealer) (*Journal, error) {
+	if err := os.MkdirAll(storageDir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(storageDir, journalFile)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{path: path, sealer: sealer, file: file, compact: make(chan struct{}, 1)}, nil
+}
+
+// Replay applies all journal entries to store and returns their number.
+func (j *Journal) Replay(store *MemoryStore) (int, error) {
+	file, err := os.Open(j.path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	applied := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry journalEntry
+		line, err := j.sealer.open(scanner.Bytes())
+		if errors.Is(err, errEncrypted) {
+			return applied, err
+		}
+		if err != nil || json.Unmarshal(line, &entry) != nil {
+			// A torn last line after a crash is expected; skip it.
+			continue
+		}
+		switch entry.Op {
+		case journalOpPut:
+			if entry.Memory != nil {
+				store.restore(entry.Memory)
+			}
+		case journalOpDelete:
+			store.Delete(entry.ID)
+		}
+		applied++
+	}
+	j.mu.Lock()
+	j.entries = applied
+	j.mu.Unlock()
+	return applied, scanner.Err()
+}
+
+// observe is registered with the store and appends every change. Writes are
+// not fsynced; they survive a process crash but not a power loss. MetaOnly
+// changes, made on every read, are not logged; the next snapshot saves them.
+func (j *Journal) observe(change Change) error {
+	if change.MetaOnly {
+		return nil
+	}
+	entry := journalEntry{ID: change.ID, Time: time.Now().UTC()}
+	if change.Deleted {
+		entry.Op = journalOpDelete
+	} else {
+		entry.Op = journalOpPut
+		entry.Memory = change.Memory
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if line, err = j.sealer.seal(line); err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	j.entries++
+	if j.entries >= defaultCompactAfter {
+		select {
+		case j.compact <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Truncate empties the journal after a snapshot has been written.
+func (j *Journal) Truncate() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.file.Truncate(0); err != nil {
+		return err
+	}
+	j.entries = 0
+	return nil
+}
+
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+// restore inserts memory as stored, keeping its timestamps.
+func (s *MemoryStore) restore(memory *Memory) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.restoreLocked(memory)
+}
+
+func (s *MemoryStore) restoreLocked(memory *Memory) {
+	if memory.Key != "" {
+		if previous, exists := s.keys[memory.Key]; exists && previous != memory.ID {
+			if old, ok := s.memories[previous]; ok {
+				delete(s.memories, previous)
+				s.notifyLocked(old, true)
+			}
+		}
+		s.keys[memory.Key] = memory.ID
+	}
+	s.memories[memory.ID] = memory
+	s.notifyLocked(memory, false)
+}
+
+// snapshot writes memories.json and truncates the journal while holding the
+// read lock, so no change can slip in between the two steps.
+func (s *MemoryStore) snapshot(filename string, journal *Journal) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.saveLocked(filename); err != nil {
+		return err
+	}
+	if journal == nil {
+		return nil
+	}
+	return journal.Truncate()
+}
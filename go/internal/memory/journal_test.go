@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// crash stops svc without writing a snapshot, leaving only the journal.
+func crash(t *testing.T, svc *Service) {
+	t.Helper()
+	if err := svc.journal.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJournalReplayAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	first, err := NewService(Config{StorageDir: dir, Journal: true}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := first.store.Add(&Memory{Content: "bleibt", Importance: 5})
+	deleted := first.store.Add(&Memory{Content: "wird gelöscht", Importance: 5})
+	first.store.Update(kept, map[string]interface{}{"content": "bleibt, geändert"})
+	first.store.Delete(deleted)
+	first.store.Put("user.name", &Memory{Content: "Anna"})
+	first.store.Put("user.name", &Memory{Content: "Anne"})
+	crash(t, first)
+
+	if _, err := os.Stat(filepath.Join(dir, "memories.json")); err == nil {
+		t.Fatal("snapshot written before the crash")
+	}
+
+	second := newTestService(t, Config{StorageDir: dir, Journal: true})
+	if memory, ok := second.store.Get(kept); !ok || memory.Content != "bleibt, geändert" {
+		t.Errorf("kept = %+v, %v", memory, ok)
+	}
+	if _, ok := second.store.Get(deleted); ok {
+		t.Error("deleted memory came back")
+	}
+	if memory, ok := second.store.GetByKey("user.name"); !ok || memory.Content != "Anne" {
+		t.Errorf("user.name = %+v, %v", memory, ok)
+	}
+	if len(second.store.memories) != 2 {
+		t.Errorf("%d memories, want 2", len(second.store.memories))
+	}
+}
+
+func TestJournalSkipsTornLine(t *testing.T) {
+	dir := t.TempDir()
+	first, _ := NewService(Config{StorageDir: dir, Journal: true}, log.New(io.Discard, "", 0))
+	id := first.store.Add(&Memory{Content: "vollständig", Importance: 5})
+	crash(t, first)
+
+	file, _ := os.OpenFile(filepath.Join(dir, journalFile), os.O_APPEND|os.O_WRONLY, 0o644)
+	file.WriteString(`{"op":"put","id":"torn","memory":{"id":"torn","con`)
+	file.Close()
+
+	journal, err := OpenJournal(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+	store := NewMemoryStore(dir)
+	applied, err := journal.Replay(store)
+	if err != nil || applied != 1 {
+		t.Fatalf("Replay = %d, %v; want 1 entry", applied, err)
+	}
+	if _, ok := store.Get(id); !ok {
+		t.Error("complete entry not replayed")
+	}
+}
+
+func TestSnapshotTruncatesJournal(t *testing.T) {
+	dir := t.TempDir()
+	svc := newTestService(t, Config{StorageDir: dir, Journal: true})
+	svc.store.Add(&Memory{Content: "eins", Importance: 5})
+
+	info, _ := os.Stat(filepath.Join(dir, journalFile))
+	if info.Size() == 0 {
+		t.Fatal("changes were not journaled")
+	}
+	if err := svc.save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	info, _ = os.Stat(filepath.Join(dir, journalFile))
+	if info.Size() != 0 {
+		t.Errorf("journal holds %d bytes after the snapshot", info.Size())
+	}
+}
+
+func TestEncryptedJournalNeedsKey(t *testing.T) {
+	dir := t.TempDir()
+	first, _ := NewService(Config{StorageDir: dir, Journal: true, EncryptionKey: "passphrase"}, log.New(io.Discard, "", 0))
+	first.store.Add(&Memory{Content: "geheim", Importance: 5})
+	crash(t, first)
+
+	raw, _ := os.ReadFile(filepath.Join(dir, journalFile))
+	if len(raw) == 0 || raw[0] == '{' {
+		t.Fatalf("journal not encrypted: %q", raw)
+	}
+	if _, err := NewService(Config{StorageDir: dir, Journal: true}, log.New(io.Discard, "", 0)); err == nil {
+		t.Error("started without the key over an encrypted journal")
+	}
+	svc := newTestService(t, Config{StorageDir: dir, Journal: true, EncryptionKey: "passphrase"})
+	if len(svc.store.memories) != 1 {
+		t.Errorf("%d memories replayed, want 1", len(svc.store.memories))
+	}
+}
@@ -0,0 +1,229 @@
+package memory
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const expiryCheckInterval = time.Minute
+
+// Key-value access on top of the memory store. Entries written through this
+// API are ordinary memories with a unique Key and an optional expiry, so they
+// show up in search and stats like any other memory.
+
+func (m *Memory) expired(now time.Time) bool {
+	return m.ExpiresAt != nil && !now.Before(*m.ExpiresAt)
+}
+
+// Put creates or replaces the memory stored under key.
+func (s *MemoryStore) Put(key string, memory *Memory) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	memory.Key = key
+	if id, exists := s.keys[key]; exists {
+		if previous, ok := s.memories[id]; ok {
+			memory.ID = previous.ID
+			memory.CreatedAt = previous.CreatedAt
+		}
+	}
+	if memory.ID == "" {
+		memory.ID = uuid.New().String()
+	}
+	if memory.CreatedAt.IsZero() {
+		memory.CreatedAt = now
+	}
+	memory.UpdatedAt = now
+	memory.Type = normalizeCategory(memory.Type)
+
+	s.memories[memory.ID] = memory
+	s.keys[key] = memory.ID
+	s.notifyLocked(memory, false)
+	return memory.ID
+}
+
+// GetByKey returns the memory stored under key unless it has expired.
+func (s *MemoryStore) GetByKey(key string) (*Memory, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, exists := s.keys[key]
+	if !exists {
+		return nil, false
+	}
+	memory, exists := s.memories[id]
+	if !exists || memory.expired(time.Now()) {
+		return nil, false
+	}
+	copied := *memory
+	return &copied, true
+}
+
+// DeleteByKey removes the memory stored under key.
+func (s *MemoryStore) DeleteByKey(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, exists := s.keys[key]
+	if !exists {
+		return false
+	}
+	delete(s.keys, key)
+	if memory, ok := s.memories[id]; ok {
+		delete(s.memories, id)
+		s.notifyLocked(memory, true)
+	}
+	return true
+}
+
+// PurgeExpired deletes all expired memories and returns how many were removed.
+func (s *MemoryStore) PurgeExpired(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for id, memory := range s.memories {
+		if !memory.expired(now) {
+			continue
+		}
+		if memory.Key != "" {
+			delete(s.keys, memory.Key)
+		}
+		delete(s.memories, id)
+		s.notifyLocked(memory, true)
+		removed++
+	}
+	return removed
+}
+
+// rebuildKeys recreates the key index after loading. Caller holds s.mu.
+func (s *MemoryStore) rebuildKeys() {
+	s.keys = make(map[string]string)
+	for id, memory := range s.memories {
+		if memory.Key != "" {
+			s.keys[memory.Key] = id
+		}
+	}
+}
+
+func (s *Service) startExpiryJanitor() {
+	go func() {
+		ticker := time.NewTicker(expiryCheckInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if removed := s.store.PurgeExpired(time.Now()); removed > 0 {
+				s.logger.Printf("[INFO] Removed %d expired memories", removed)
+			}
+		}
+	}()
+}
+
+// HTTP Handlers
+
+type kvRequest struct {
+	Value      string                 `json:"value"`
+	TTL        string                 `json:"ttl"`
+	Type       string                 `json:"type"`
+	Tags       []string               `json:"tags"`
+	Importance int                    `json:"importance"`
+	Metadata   map[string]interface{} `json:"metadata"`
+	Namespace  string                 `json:"namespace"`
+}
+
+func kvResponse(memory *Memory) map[string]interface{} {
+	response := map[string]interface{}{
+		"key":        memory.Key,
+		"value":      memory.Content,
+		"id":         memory.ID,
+		"updated_at": memory.UpdatedAt,
+	}
+	if memory.ExpiresAt != nil {
+		response["expires_at"] = memory.ExpiresAt
+	}
+	return response
+}
+
+func (s *Service) putKeyHandler(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimSpace(mux.Vars(r)["key"])
+	if key == "" {
+		http.Error(w, `{"error":"Key is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req kvRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	memory := &Memory{
+		Content:    req.Value,
+		Type:       req.Type,
+		Tags:       req.Tags,
+		Importance: req.Importance,
+		Metadata:   req.Metadata,
+		Namespace:  req.Namespace,
+	}
+	if normalizeCategory(memory.Type) == "" {
+		memory.Type = "kv"
+	}
+	if memory.Importance == 0 {
+		memory.Importance = 5
+	}
+	if req.TTL != "" {
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			http.Error(w, `{"error":"Invalid ttl"}`, http.StatusBadRequest)
+			return
+		}
+		expiresAt := time.Now().Add(ttl)
+		memory.ExpiresAt = &expiresAt
+	}
+
+	previous, _ := s.store.GetByKey(key)
+	if !s.admitNamespace(w, memory, previous) {
+		return
+	}
+
+	s.store.Put(key, memory)
+	s.enforceCapacity(memory.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(kvResponse(memory))
+}
+
+func (s *Service) getKeyHandler(w http.ResponseWriter, r *http.Request) {
+	memory, exists := s.store.GetByKey(mux.Vars(r)["key"])
+	if !exists {
+		http.Error(w, `{"error":"Key not found"}`, http.StatusNotFound)
+		return
+	}
+	touched, exists := s.store.Touch(memory.ID, s.cfg.AccessBoost, time.Now())
+	if !exists {
+		http.Error(w, `{"error":"Key not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(kvResponse(touched))
+}
+
+func (s *Service) deleteKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.store.DeleteByKey(mux.Vars(r)["key"]) {
+		http.Error(w, `{"error":"Key not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Key deleted successfully",
+	})
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStorePutReplacesKey(t *testing.T) {
+	store := NewMemoryStore(t.TempDir())
+	firstID := store.Put("user.name", &Memory{Content: "Anna"})
+	created := store.memories[firstID].CreatedAt
+	secondID := store.Put("user.name", &Memory{Content: "Anne"})
+
+	if secondID != firstID {
+		t.Errorf("Put changed the id from %s to %s", firstID, secondID)
+	}
+	memory, ok := store.GetByKey("user.name")
+	if !ok || memory.Content != "Anne" || !memory.CreatedAt.Equal(created) {
+		t.Errorf("GetByKey = %+v, %v", memory, ok)
+	}
+	if len(store.memories) != 1 {
+		t.Errorf("%d memories, want 1", len(store.memories))
+	}
+
+	if !store.DeleteByKey("user.name") || store.DeleteByKey("user.name") {
+		t.Error("DeleteByKey did not delete exactly once")
+	}
+	if _, ok := store.GetByKey("user.name"); ok {
+		t.Error("deleted key still readable")
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	store := NewMemoryStore(t.TempDir())
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	store.Put("expired", &Memory{Content: "a", ExpiresAt: &past})
+	store.Put("valid", &Memory{Content: "b", ExpiresAt: &future})
+	store.Add(&Memory{Content: "c"})
+
+	if _, ok := store.GetByKey("expired"); ok {
+		t.Error("expired key readable before purge")
+	}
+	if len(store.Search(nil, Filter{})) != 2 {
+		t.Error("search returns expired memories")
+	}
+	if removed := store.PurgeExpired(now); removed != 1 {
+		t.Errorf("PurgeExpired removed %d, want 1", removed)
+	}
+	if _, exists := store.keys["expired"]; exists {
+		t.Error("key index still holds the purged key")
+	}
+	if removed := store.PurgeExpired(future); removed != 1 {
+		t.Errorf("PurgeExpired at expiry removed %d, want 1", removed)
+	}
+}
+
+func TestKeyValueHandlers(t *testing.T) {
+	svc := newTestService(t, Config{})
+	tests := []struct {
+		name   string
+		method string
+		key    string
+		body   interface{}
+		status int
+		value  string
+	}{
+		{"put", http.MethodPut, "user.name", map[string]interface{}{"value": "Anna"}, http.StatusOK, "Anna"},
+		{"get", http.MethodGet, "user.name", nil, http.StatusOK, "Anna"},
+		{"overwrite with ttl", http.MethodPut, "user.name", map[string]interface{}{"value": "Anne", "ttl": "1h"}, http.StatusOK, "Anne"},
+		{"invalid ttl", http.MethodPut, "user.name", map[string]interface{}{"value": "x", "ttl": "-1h"}, http.StatusBadRequest, ""},
+		{"unknown key", http.MethodGet, "missing", nil, http.StatusNotFound, ""},
+		{"delete", http.MethodDelete, "user.name", nil, http.StatusOK, ""},
+		{"get deleted", http.MethodGet, "user.name", nil, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(svc, tt.method, "/api/v1/memory/kv/"+tt.key, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.status, rec.Body)
+			}
+			if tt.value == "" {
+				return
+			}
+			var body map[string]interface{}
+			decode(t, rec, &body)
+			if body["key"] != tt.key || body["value"] != tt.value {
+				t.Errorf("body = %v", body)
+			}
+		})
+	}
+
+	memory, _ := svc.store.Get(addMemory(t, svc, map[string]interface{}{"content": "x"}))
+	if memory.Key != "" {
+		t.Errorf("plain memory got key %q", memory.Key)
+	}
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Namespaces record which producer wrote a memory: desktop, speech, chat or
+// plugin:<name>. Memories without one belong to the default namespace.
+const defaultNamespace = "default"
+
+var namespacePattern = regexp.MustCompile(`^(default|desktop|speech|chat|plugin:[a-z0-9][a-z0-9._-]*)$`)
+
+// namespaceOf returns the namespace of memory, defaulting legacy entries.
+func namespaceOf(memory *Memory) string {
+	if memory.Namespace == "" {
+		return defaultNamespace
+	}
+	return memory.Namespace
+}
+
+// normalizeNamespace lowercases namespace and validates it.
+func normalizeNamespace(namespace string) (string, error) {
+	namespace = strings.ToLower(strings.TrimSpace(namespace))
+	if namespace == "" {
+		return defaultNamespace, nil
+	}
+	if !namespacePattern.MatchString(namespace) {
+		return "", fmt.Errorf("invalid namespace %q", namespace)
+	}
+	return namespace, nil
+}
+
+// parseNamespaceQuotas reads "chat=5000,plugin:*=1000". plugin:* applies to
+// every plugin namespace without its own entry.
+func parseNamespaceQuotas(raw string) map[string]int {
+	quotas := map[string]int{}
+	for _, entry := range strings.Split(raw, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit <= 0 {
+			continue
+		}
+		quotas[strings.ToLower(strings.TrimSpace(name))] = limit
+	}
+	return quotas
+}
+
+// namespaceQuota returns the maximum number of memories in namespace, or 0.
+func (c Config) namespaceQuota(namespace string) int {
+	if limit, ok := c.NamespaceQuotas[namespace]; ok {
+		return limit
+	}
+	if strings.HasPrefix(namespace, "plugin:") {
+		return c.NamespaceQuotas["plugin:*"]
+	}
+	return 0
+}
+
+// admitNamespace validates the namespace of a new memory and enforces its
+// quota; replacing an entry of the same namespace (previous) is always
+// allowed. It writes the error response and returns false when rejected.
+func (s *Service) admitNamespace(w http.ResponseWriter, memory *Memory, previous *Memory) bool {
+	namespace, err := normalizeNamespace(memory.Namespace)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return false
+	}
+	memory.Namespace = namespace
+	if previous != nil && namespaceOf(previous) == namespace {
+		return true
+	}
+
+	if err := s.checkNamespaceQuota(namespace); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+func (s *Service) checkNamespaceQuota(namespace string) error {
+	limit := s.cfg.namespaceQuota(namespace)
+	if limit > 0 && s.store.stats.namespaceCount(namespace) >= limit {
+		return fmt.Errorf("namespace %s has reached its quota of %d memories", namespace, limit)
+	}
+	return nil
+}
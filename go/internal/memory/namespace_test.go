@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeNamespace(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", defaultNamespace, false},
+		{" Chat ", "chat", false},
+		{"speech", "speech", false},
+		{"plugin:wetter-2.0", "plugin:wetter-2.0", false},
+		{"plugin:", "", true},
+		{"plugin:-x", "", true},
+		{"anna", "", true},
+		{"chat/anna", "", true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeNamespace(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("normalizeNamespace(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNamespaceQuotas(t *testing.T) {
+	quotas := parseNamespaceQuotas(" chat=2, plugin:*=1 ,plugin:wetter=3,speech=0,desktop=x,broken")
+	if want := map[string]int{"chat": 2, "plugin:*": 1, "plugin:wetter": 3}; !reflect.DeepEqual(quotas, want) {
+		t.Fatalf("parseNamespaceQuotas = %v, want %v", quotas, want)
+	}
+
+	cfg := Config{NamespaceQuotas: quotas}
+	tests := []struct {
+		namespace string
+		want      int
+	}{
+		{"chat", 2},
+		{"plugin:wetter", 3},
+		{"plugin:kalender", 1},
+		{"desktop", 0},
+	}
+	for _, tt := range tests {
+		if got := cfg.namespaceQuota(tt.namespace); got != tt.want {
+			t.Errorf("namespaceQuota(%q) = %d, want %d", tt.namespace, got, tt.want)
+		}
+	}
+}
+
+func TestNamespaceAdmission(t *testing.T) {
+	svc := newTestService(t, Config{NamespaceQuotas: map[string]int{"chat": 2, "plugin:*": 1}})
+
+	tests := []struct {
+		name      string
+		namespace string
+		code      int
+	}{
+		{"first chat", "chat", http.StatusOK},
+		{"second chat", "CHAT", http.StatusOK},
+		{"chat over quota", "chat", http.StatusTooManyRequests},
+		{"plugin", "plugin:wetter", http.StatusOK},
+		{"plugin over wildcard quota", "plugin:wetter", http.StatusTooManyRequests},
+		{"other plugin has its own count", "plugin:kalender", http.StatusOK},
+		{"no quota", "desktop", http.StatusOK},
+		{"default", "", http.StatusOK},
+		{"invalid", "anna", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := serve(svc, http.MethodPost, "/api/v1/memory/memories", map[string]interface{}{"content": tt.name, "namespace": tt.namespace})
+		if rec.Code != tt.code {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.code)
+		}
+	}
+
+	if rec := serve(svc, http.MethodPut, "/api/v1/memory/kv/wetter", map[string]interface{}{"value": "sonnig", "namespace": "plugin:kalender"}); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("kv put in full namespace: status %d, want 429", rec.Code)
+	}
+	// Replacing a key in the same namespace does not count against the quota.
+	if rec := serve(svc, http.MethodPut, "/api/v1/memory/kv/stimmung", map[string]interface{}{"value": "gut", "namespace": "desktop"}); rec.Code != http.StatusOK {
+		t.Fatalf("kv put: status %d", rec.Code)
+	}
+	svc.cfg.NamespaceQuotas["desktop"] = 2
+	if rec := serve(svc, http.MethodPut, "/api/v1/memory/kv/stimmung", map[string]interface{}{"value": "besser", "namespace": "desktop"}); rec.Code != http.StatusOK {
+		t.Errorf("kv replace: status %d", rec.Code)
+	}
+
+	var stats struct {
+		ByNamespace map[string]int `json:"by_namespace"`
+	}
+	decode(t, serve(svc, http.MethodGet, "/api/v1/memory/stats", nil), &stats)
+	want := map[string]int{"chat": 2, "plugin:wetter": 1, "plugin:kalender": 1, "desktop": 2, defaultNamespace: 1}
+	if !reflect.DeepEqual(stats.ByNamespace, want) {
+		t.Errorf("by_namespace = %v, want %v", stats.ByNamespace, want)
+	}
+}
+
+func TestSearchByNamespace(t *testing.T) {
+	svc := newTestService(t, Config{})
+	addMemory(t, svc, map[string]interface{}{"content": "Tee", "namespace": "chat"})
+	addMemory(t, svc, map[string]interface{}{"content": "Tee", "namespace": "speech"})
+	addMemory(t, svc, map[string]interface{}{"content": "Tee"})
+
+	tests := []struct {
+		query string
+		code  int
+		want  int
+	}{
+		{"query=tee", http.StatusOK, 3},
+		{"query=tee&namespace=chat", http.StatusOK, 1},
+		{"query=tee&namespace=default", http.StatusOK, 1},
+		{"query=tee&namespace=anna", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		rec := serve(svc, http.MethodGet, "/api/v1/memory/search?"+tt.query, nil)
+		if rec.Code != tt.code {
+			t.Errorf("%q: status %d, want %d", tt.query, rec.Code, tt.code)
+			continue
+		}
+		if tt.code == http.StatusOK {
+			var results []Memory
+			decode(t, rec, &results)
+			if len(results) != tt.want {
+				t.Errorf("%q: %d results, want %d", tt.query, len(results), tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package memory
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageSize = 100
+	defaultMaxLimit = 1000
+)
+
+// Sort keys accepted by the list and search endpoints.
+const (
+	SortImportance = "importance"
+	SortCreatedAt  = "created_at"
+	SortUpdatedAt  = "updated_at"
+	SortRelevance  = "relevance"
+)
+
+// PageOptions controls sorting and pagination of memory lists.
+type PageOptions struct {
+	Limit  int
+	Offset int
+	Sort   string
+	Desc   bool
+}
+
+// parsePageOptions reads limit, offset, sort and order. Limits above
+// maxLimit are capped.
+func parsePageOptions(r *http.Request, defaultSort string, maxLimit int) (PageOptions, error) {
+	query := r.URL.Query()
+	opts := PageOptions{Limit: min(defaultPageSize, maxLimit), Sort: defaultSort, Desc: true}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return opts, fmt.Errorf("invalid limit")
+		}
+		opts.Limit = min(limit, maxLimit)
+	}
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return opts, fmt.Errorf("invalid offset")
+		}
+		opts.Offset = offset
+	}
+	if value := strings.ToLower(query.Get("sort")); value != "" {
+		switch value {
+		case SortImportance, SortCreatedAt, SortUpdatedAt, SortRelevance:
+			opts.Sort = value
+		default:
+			return opts, fmt.Errorf("invalid sort key")
+		}
+	}
+	switch strings.ToLower(query.Get("order")) {
+	case "", "desc":
+	case "asc":
+		opts.Desc = false
+	default:
+		return opts, fmt.Errorf("invalid order")
+	}
+	return opts, nil
+}
+
+// relevance scores how well memory matches the text terms of a query,
+// blended with how often and how recently it was used.
+func relevance(memory *Memory, terms []string, now time.Time) int {
+	score := usageScore(memory, now)
+	if len(terms) == 0 {
+		return score
+	}
+	content := strings.ToLower(memory.Content)
+	for _, term := range terms {
+		score += strings.Count(content, term) * 10
+		if strings.HasPrefix(content, term) {
+			score += 5
+		}
+		for _, tag := range memory.Tags {
+			if strings.EqualFold(tag, term) {
+				score += 20
+			}
+		}
+	}
+	return score
+}
+
+// sortMemories orders memories by opts.Sort with pinned memories first in
+// either order; ties fall back to updated_at.
+func sortMemories(memories []*Memory, opts PageOptions, query *Query) {
+	scores := map[string]int{}
+	if opts.Sort == SortRelevance {
+		now := time.Now()
+		for _, memory := range memories {
+			scores[memory.ID] = relevance(memory, query.Terms(), now)
+		}
+	}
+
+	sort.SliceStable(memories, func(i, j int) bool {
+		a, b := memories[i], memories[j]
+		if a.Pinned != b.Pinned {
+			return a.Pinned
+		}
+		var cmp int
+		switch opts.Sort {
+		case SortImportance:
+			cmp = a.Importance - b.Importance
+		case SortCreatedAt:
+			cmp = a.CreatedAt.Compare(b.CreatedAt)
+		case SortRelevance:
+			cmp = scores[a.ID] - scores[b.ID]
+		}
+		if cmp == 0 {
+			cmp = a.UpdatedAt.Compare(b.UpdatedAt)
+		}
+		if opts.Desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+}
+
+// paginate returns the requested page and sets X-Total-Count.
+func paginate(w http.ResponseWriter, memories []*Memory, opts PageOptions) []*Memory {
+	total := len(memories)
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if opts.Offset >= total {
+		return []*Memory{}
+	}
+	end := min(opts.Offset+opts.Limit, total)
+	return memories[opts.Offset:end]
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePageOptions(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    PageOptions
+		wantErr bool
+	}{
+		{"", PageOptions{Limit: 100, Sort: SortUpdatedAt, Desc: true}, false},
+		{"limit=5&offset=10&sort=IMPORTANCE&order=asc", PageOptions{Limit: 5, Offset: 10, Sort: SortImportance}, false},
+		{"limit=5000", PageOptions{Limit: 500, Sort: SortUpdatedAt, Desc: true}, false},
+		{"limit=0", PageOptions{}, true},
+		{"limit=ten", PageOptions{}, true},
+		{"offset=-1", PageOptions{}, true},
+		{"sort=content", PageOptions{}, true},
+		{"order=random", PageOptions{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			got, err := parsePageOptions(req, SortUpdatedAt, 500)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("options = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortMemories(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	memories := func() []*Memory {
+		return []*Memory{
+			{ID: "a", Content: "tee und kaffee", Importance: 3, CreatedAt: base.Add(3 * time.Hour), UpdatedAt: base.Add(1 * time.Hour)},
+			{ID: "b", Content: "tee tee tee", Importance: 7, CreatedAt: base.Add(1 * time.Hour), UpdatedAt: base.Add(2 * time.Hour)},
+			{ID: "c", Content: "kaffee", Importance: 7, CreatedAt: base.Add(2 * time.Hour), UpdatedAt: base.Add(3 * time.Hour)},
+			{ID: "pinned", Content: "wasser", Importance: 1, Pinned: true, CreatedAt: base, UpdatedAt: base},
+		}
+	}
+	query, _ := ParseQuery("tee")
+
+	tests := []struct {
+		opts PageOptions
+		want []string
+	}{
+		{PageOptions{Sort: SortImportance, Desc: true}, []string{"pinned", "c", "b", "a"}},
+		{PageOptions{Sort: SortImportance}, []string{"pinned", "a", "b", "c"}},
+		{PageOptions{Sort: SortCreatedAt, Desc: true}, []string{"pinned", "a", "c", "b"}},
+		{PageOptions{Sort: SortUpdatedAt, Desc: true}, []string{"pinned", "c", "b", "a"}},
+		{PageOptions{Sort: SortRelevance, Desc: true}, []string{"pinned", "b", "a", "c"}},
+	}
+	for _, tt := range tests {
+		list := memories()
+		sortMemories(list, tt.opts, query)
+		var ids []string
+		for _, memory := range list {
+			ids = append(ids, memory.ID)
+		}
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("%+v: order = %v, want %v", tt.opts, ids, tt.want)
+		}
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	svc := newTestService(t, Config{MaxLimit: 3})
+	for i := 1; i <= 5; i++ {
+		addMemory(t, svc, map[string]interface{}{"content": "Eintrag", "importance": i})
+	}
+
+	tests := []struct {
+		query      string
+		status     int
+		importance []int
+	}{
+		{"sort=importance&limit=2", http.StatusOK, []int{5, 4}},
+		{"sort=importance&limit=2&offset=2", http.StatusOK, []int{3, 2}},
+		{"sort=importance&order=asc&limit=10", http.StatusOK, []int{1, 2, 3}},
+		{"sort=importance&offset=10", http.StatusOK, nil},
+		{"limit=-1", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		for _, path := range []string{"/api/v1/memory/memories?", "/api/v1/memory/search?query=eintrag&"} {
+			rec := serve(svc, http.MethodGet, path+tt.query, nil)
+			if rec.Code != tt.status {
+				t.Fatalf("%s%s: status %d, want %d", path, tt.query, rec.Code, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				continue
+			}
+			if total := rec.Header().Get("X-Total-Count"); total != "5" {
+				t.Errorf("%s%s: X-Total-Count = %s, want 5", path, tt.query, total)
+			}
+			var page []Memory
+			decode(t, rec, &page)
+			var importance []int
+			for _, memory := range page {
+				importance = append(importance, memory.Importance)
+			}
+			if !reflect.DeepEqual(importance, tt.importance) {
+				t.Errorf("%s%s: importance = %v, want %v", path, tt.query, importance, tt.importance)
+			}
+		}
+	}
+}
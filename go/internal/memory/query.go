@@ -0,0 +1,333 @@
+package memory
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed search expression such as
+//
+//	tag:work AND (type:note OR "project plan") AND NOT importance<3
+//
+// Bare words and quoted phrases match memory content case-insensitively.
+// tag:, type:, namespace: and key: match fields (type: includes
+// subcategories) and importance accepts =, <, <=, > and >=. Terms next to
+// each other are joined with AND; AND binds tighter than OR.
+type Query struct {
+	root  queryNode
+	terms []string
+}
+
+type queryNode interface {
+	match(memory *Memory, content string) bool
+}
+
+type (
+	andNode   []queryNode
+	orNode    []queryNode
+	notNode   struct{ node queryNode }
+	textNode  string
+	fieldNode struct {
+		field string
+		value string
+	}
+	importanceNode struct {
+		op    string
+		value int
+	}
+)
+
+func (n andNode) match(memory *Memory, content string) bool {
+	for _, node := range n {
+		if !node.match(memory, content) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n orNode) match(memory *Memory, content string) bool {
+	for _, node := range n {
+		if node.match(memory, content) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n notNode) match(memory *Memory, content string) bool {
+	return !n.node.match(memory, content)
+}
+
+func (n textNode) match(_ *Memory, content string) bool {
+	return strings.Contains(content, string(n))
+}
+
+func (n fieldNode) match(memory *Memory, _ string) bool {
+	switch n.field {
+	case "tag":
+		for _, tag := range memory.Tags {
+			if strings.EqualFold(tag, n.value) {
+				return true
+			}
+		}
+		return false
+	case "type":
+		return inCategory(memory.Type, n.value)
+	case "namespace":
+		return namespaceOf(memory) == strings.ToLower(n.value)
+	default:
+		return memory.Key == n.value
+	}
+}
+
+func (n importanceNode) match(memory *Memory, _ string) bool {
+	switch n.op {
+	case "<":
+		return memory.Importance < n.value
+	case "<=":
+		return memory.Importance <= n.value
+	case ">":
+		return memory.Importance > n.value
+	case ">=":
+		return memory.Importance >= n.value
+	default:
+		return memory.Importance == n.value
+	}
+}
+
+var (
+	queryFields        = map[string]bool{"tag": true, "type": true, "namespace": true, "key": true}
+	importancePattern  = regexp.MustCompile(`^(?i:importance)(>=|<=|>|<|=|:)(-?\d+)$`)
+	queryFieldSplitter = regexp.MustCompile(`^([A-Za-z]+):(.+)$`)
+)
+
+type queryToken struct {
+	text   string
+	raw    string
+	quoted bool
+}
+
+// lexQuery splits raw into words, quoted phrases and parentheses. A quote
+// inside a word (tag:"two words") continues the word.
+func lexQuery(raw string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(raw)
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, queryToken{text: string(r), raw: string(r)})
+			i++
+		default:
+			var word strings.Builder
+			start, quoted := i, false
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+				if runes[i] != '"' {
+					word.WriteRune(runes[i])
+					i++
+					continue
+				}
+				end := i + 1
+				for end < len(runes) && runes[end] != '"' {
+					end++
+				}
+				if end == len(runes) {
+					return nil, fmt.Errorf("invalid query: unterminated quote")
+				}
+				word.WriteString(string(runes[i+1 : end]))
+				quoted = true
+				i = end + 1
+			}
+			tokens = append(tokens, queryToken{text: word.String(), raw: string(runes[start:i]), quoted: quoted})
+		}
+	}
+	return tokens, nil
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+	terms  []string
+}
+
+// ParseQuery parses raw. An empty query matches every memory and is
+// returned as nil.
+func ParseQuery(raw string) (*Query, error) {
+	tokens, err := lexQuery(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	p := &queryParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("invalid query: unexpected %q", p.tokens[p.pos].text)
+	}
+	return &Query{root: root, terms: p.terms}, nil
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *queryParser) keyword(word string) bool {
+	token, ok := p.peek()
+	if ok && !token.quoted && token.text == word {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	nodes := orNode{node}
+	for p.keyword("OR") {
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	node, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	nodes := andNode{node}
+	for {
+		token, ok := p.peek()
+		if !ok || (!token.quoted && (token.text == ")" || token.text == "OR")) {
+			break
+		}
+		p.keyword("AND")
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *queryParser) parseNot() (queryNode, error) {
+	if p.keyword("NOT") {
+		// Terms under NOT don't contribute to relevance.
+		terms := p.terms
+		node, err := p.parseNot()
+		p.terms = terms
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	token, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("invalid query: unexpected end")
+	}
+	p.pos++
+
+	if !token.quoted {
+		switch token.text {
+		case "(":
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.keyword(")") {
+				return nil, fmt.Errorf("invalid query: missing )")
+			}
+			return node, nil
+		case ")", "AND", "OR":
+			return nil, fmt.Errorf("invalid query: unexpected %q", token.text)
+		}
+	}
+
+	// Fields and comparisons must be written outside quotes: "tag:x" is a
+	// phrase, tag:"x y" a field.
+	if match := importancePattern.FindStringSubmatch(token.raw); match != nil {
+		value, err := strconv.Atoi(match[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid query: %q", token.text)
+		}
+		return importanceNode{op: match[1], value: value}, nil
+	}
+	if match := queryFieldSplitter.FindStringSubmatch(token.raw); match != nil {
+		if field := strings.ToLower(match[1]); queryFields[field] {
+			return fieldNode{field: field, value: strings.TrimPrefix(token.text, match[1]+":")}, nil
+		}
+	}
+
+	text := strings.ToLower(token.text)
+	if text == "" {
+		return nil, fmt.Errorf("invalid query: empty phrase")
+	}
+	p.terms = append(p.terms, text)
+	return textNode(text), nil
+}
+
+// Matches reports whether memory satisfies the query. A nil query matches
+// everything.
+func (q *Query) Matches(memory *Memory) bool {
+	if q == nil {
+		return true
+	}
+	return q.root.match(memory, strings.ToLower(memory.Content))
+}
+
+// Terms returns the lowercase words and phrases the query searches for,
+// excluding negated ones.
+func (q *Query) Terms() []string {
+	if q == nil {
+		return nil
+	}
+	return q.terms
+}
+
+// required returns the text terms every match must contain, so the text
+// index can narrow the candidates.
+func (q *Query) required() []string {
+	if q == nil {
+		return nil
+	}
+	var terms []string
+	nodes := andNode{q.root}
+	if and, ok := q.root.(andNode); ok {
+		nodes = and
+	}
+	for _, node := range nodes {
+		if text, ok := node.(textNode); ok {
+			terms = append(terms, string(text))
+		}
+	}
+	return terms
+}
@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+var queryMemories = map[string]*Memory{
+	"plan":   {ID: "plan", Content: "Project plan for the kitchen", Type: "work/projects", Tags: []string{"Work"}, Importance: 8},
+	"tee":    {ID: "tee", Content: "Anna mag grünen Tee", Type: "personal/preferences", Tags: []string{"anna"}, Importance: 4, Namespace: "chat"},
+	"termin": {ID: "termin", Content: "Termin beim Zahnarzt", Type: "note", Tags: []string{"work", "health"}, Importance: 2, Key: "zahnarzt"},
+	"phrase": {ID: "phrase", Content: "the plan project is late", Type: "note", Importance: 7},
+}
+
+func TestQueryMatches(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"plan", []string{"phrase", "plan"}},
+		{"PROJECT plan", []string{"phrase", "plan"}},
+		{`"project plan"`, []string{"plan"}},
+		{"tag:work", []string{"plan", "termin"}},
+		{`tag:"work"`, []string{"plan", "termin"}},
+		{"type:work", []string{"plan"}},
+		{"type:personal/preferences", []string{"tee"}},
+		{"namespace:CHAT", []string{"tee"}},
+		{"namespace:default", []string{"phrase", "plan", "termin"}},
+		{"key:zahnarzt", []string{"termin"}},
+		{"importance>=7", []string{"phrase", "plan"}},
+		{"importance>7", []string{"plan"}},
+		{"importance<4", []string{"termin"}},
+		{"importance<=4", []string{"tee", "termin"}},
+		{"importance=4", []string{"tee"}},
+		{"importance:2", []string{"termin"}},
+		{"tag:work AND importance>=7", []string{"plan"}},
+		{"tag:work importance>=7", []string{"plan"}},
+		{"tee OR zahnarzt", []string{"tee", "termin"}},
+		{"tag:work AND NOT plan", []string{"termin"}},
+		{"NOT NOT tee", []string{"tee"}},
+		{"tag:work AND (type:note OR \"project plan\")", []string{"plan", "termin"}},
+		{"tag:anna OR tag:health AND importance>3", []string{"tee"}},
+		{"(tag:anna OR tag:health) AND importance<3", []string{"termin"}},
+		{`"tag:work"`, nil},
+		{"color:blue", nil},
+	}
+	for _, tt := range tests {
+		query, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): %v", tt.query, err)
+			continue
+		}
+		var got []string
+		for _, id := range []string{"phrase", "plan", "tee", "termin"} {
+			if query.Matches(queryMemories[id]) {
+				got = append(got, id)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q matches %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, raw := range []string{
+		`"open phrase`,
+		"(tee",
+		"tee)",
+		"AND tee",
+		"tee OR",
+		"NOT",
+		`""`,
+		"importance>=99999999999999999999",
+	} {
+		if _, err := ParseQuery(raw); err == nil {
+			t.Errorf("ParseQuery(%q) succeeded", raw)
+		}
+	}
+
+	query, err := ParseQuery("   ")
+	if err != nil || query != nil || !query.Matches(queryMemories["tee"]) {
+		t.Errorf("empty query = %v, %v", query, err)
+	}
+}
+
+func TestQueryTerms(t *testing.T) {
+	tests := []struct {
+		query    string
+		terms    []string
+		required []string
+	}{
+		{"Anna Tee", []string{"anna", "tee"}, []string{"anna", "tee"}},
+		{`"grünen Tee" tag:anna`, []string{"grünen tee"}, []string{"grünen tee"}},
+		{"tee OR kaffee", []string{"tee", "kaffee"}, nil},
+		{"tee NOT kaffee", []string{"tee"}, []string{"tee"}},
+		{"tee (kaffee OR wasser)", []string{"tee", "kaffee", "wasser"}, []string{"tee"}},
+		{"importance>3", nil, nil},
+	}
+	for _, tt := range tests {
+		query, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+		}
+		if !reflect.DeepEqual(query.Terms(), tt.terms) || !reflect.DeepEqual(query.required(), tt.required) {
+			t.Errorf("%q: terms %v required %v, want %v %v", tt.query, query.Terms(), query.required(), tt.terms, tt.required)
+		}
+	}
+}
+
+func TestSearchQueryLanguage(t *testing.T) {
+	svc := newTestService(t, Config{})
+	for _, memory := range queryMemories {
+		addMemory(t, svc, map[string]interface{}{"content": memory.Content, "type": memory.Type, "tags": memory.Tags, "importance": memory.Importance})
+	}
+
+	var results []Memory
+	decode(t, serve(svc, http.MethodGet, "/api/v1/memory/search?query="+url.QueryEscape(`tag:work AND NOT "project plan"`), nil), &results)
+	if len(results) != 1 || results[0].Content != "Termin beim Zahnarzt" {
+		t.Errorf("results = %+v", results)
+	}
+	if rec := serve(svc, http.MethodGet, "/api/v1/memory/search?query="+url.QueryEscape("(tee"), nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid query: status %d, want 400", rec.Code)
+	}
+}
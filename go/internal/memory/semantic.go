@@ -0,0 +1,266 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const embedBatchSize = 32
+
+// Embedder turns texts into vectors.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// HTTPEmbedder calls an embedding endpoint. It sends
+// {"model": ..., "input": [...]} and accepts both the OpenAI style
+// {"data": [{"embedding": [...]}]} and {"embeddings": [[...]]} responses.
+type HTTPEmbedder struct {
+	URL    string
+	Model  string
+	APIKey string
+	Client *http.Client
+}
+
+func (e *HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": e.Model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+
+	client := e.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("embedder antwortete mit %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+	vectors := payload.Embeddings
+	if len(vectors) == 0 {
+		for _, item := range payload.Data {
+			vectors = append(vectors, item.Embedding)
+		}
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("embedder lieferte %d statt %d Vektoren", len(vectors), len(texts))
+	}
+	return vectors, nil
+}
+
+// VectorIndex is an in-memory cosine similarity index over normalized vectors.
+type VectorIndex struct {
+	vectors map[string][]float32
+	mu      sync.RWMutex
+}
+
+type vectorMatch struct {
+	ID    string
+	Score float64
+}
+
+func NewVectorIndex() *VectorIndex {
+	return &VectorIndex{vectors: make(map[string][]float32)}
+}
+
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	norm := math.Sqrt(sum)
+	if norm == 0 {
+		return vector
+	}
+	normalized := make([]float32, len(vector))
+	for i, v := range vector {
+		normalized[i] = float32(float64(v) / norm)
+	}
+	return normalized
+}
+
+func (x *VectorIndex) Set(id string, vector []float32) {
+	x.mu.Lock()
+	x.vectors[id] = normalize(vector)
+	x.mu.Unlock()
+}
+
+func (x *VectorIndex) Remove(id string) {
+	x.mu.Lock()
+	delete(x.vectors, id)
+	x.mu.Unlock()
+}
+
+func (x *VectorIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.vectors)
+}
+
+// Nearest returns all indexed ids ordered by similarity to query.
+func (x *VectorIndex) Nearest(query []float32) []vectorMatch {
+	query = normalize(query)
+	x.mu.RLock()
+	matches := make([]vectorMatch, 0, len(x.vectors))
+	for id, vector := range x.vectors {
+		if len(vector) != len(query) {
+			continue
+		}
+		var dot float64
+		for i := range vector {
+			dot += float64(vector[i]) * float64(query[i])
+		}
+		matches = append(matches, vectorMatch{ID: id, Score: dot})
+	}
+	x.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// semanticIndexer embeds changed memories in the background and keeps the
+// vector index in sync with the store.
+type semanticIndexer struct {
+	embedder Embedder
+	index    *VectorIndex
+	logger   *log.Logger
+	pending  map[string]string
+	wake     chan struct{}
+	mu       sync.Mutex
+}
+
+func newSemanticIndexer(embedder Embedder, logger *log.Logger) *semanticIndexer {
+	return &semanticIndexer{
+		embedder: embedder,
+		index:    NewVectorIndex(),
+		logger:   logger,
+		pending:  make(map[string]string),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// observe is registered with the store; it only queues work.
+func (i *semanticIndexer) observe(change Change) {
+	if change.MetaOnly {
+		return
+	}
+	i.mu.Lock()
+	if change.Deleted {
+		delete(i.pending, change.ID)
+	} else {
+		i.pending[change.ID] = change.Content
+	}
+	i.mu.Unlock()
+
+	if change.Deleted {
+		i.index.Remove(change.ID)
+		return
+	}
+	select {
+	case i.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (i *semanticIndexer) run() {
+	for range i.wake {
+		for {
+			i.mu.Lock()
+			ids := make([]string, 0, embedBatchSize)
+			texts := make([]string, 0, embedBatchSize)
+			for id, content := range i.pending {
+				ids = append(ids, id)
+				texts = append(texts, content)
+				delete(i.pending, id)
+				if len(ids) == embedBatchSize {
+					break
+				}
+			}
+			i.mu.Unlock()
+			if len(ids) == 0 {
+				break
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			vectors, err := i.embedder.Embed(ctx, texts)
+			cancel()
+			if err != nil {
+				i.logger.Printf("[WARN] Embedding fehlgeschlagen: %v", err)
+				i.requeue(ids, texts)
+				time.Sleep(10 * time.Second)
+				continue
+			}
+			for n, id := range ids {
+				i.index.Set(id, vectors[n])
+			}
+		}
+	}
+}
+
+func (i *semanticIndexer) requeue(ids []string, texts []string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for n, id := range ids {
+		if _, newer := i.pending[id]; !newer {
+			i.pending[id] = texts[n]
+		}
+	}
+}
+
+// ScoredMemory is a search result with its similarity score.
+type ScoredMemory struct {
+	*Memory
+	Score float64 `json:"score"`
+}
+
+func (s *Service) semanticSearch(ctx context.Context, query string, filter Filter, limit int) ([]ScoredMemory, error) {
+	vectors, err := s.semantic.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+
+	results := []ScoredMemory{}
+	for _, match := range s.semantic.index.Nearest(vectors[0]) {
+		memory, exists := s.store.Get(match.ID)
+		if !exists || !filter.Matches(memory) {
+			continue
+		}
+		results = append(results, ScoredMemory{Memory: memory, Score: match.Score})
+		if len(results) == limit {
+			break
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVectorIndexNearest(t *testing.T) {
+	index := NewVectorIndex()
+	index.Set("x", []float32{10, 0})
+	index.Set("diagonal", []float32{1, 1})
+	index.Set("y", []float32{0, 3})
+	index.Set("other-dimension", []float32{1, 0, 0})
+
+	var ids []string
+	for _, match := range index.Nearest([]float32{3, 1}) {
+		ids = append(ids, match.ID)
+	}
+	if want := []string{"x", "diagonal", "y"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Nearest = %v, want %v", ids, want)
+	}
+
+	index.Remove("x")
+	if matches := index.Nearest([]float32{1, 0}); len(matches) != 2 || matches[0].ID != "diagonal" {
+		t.Errorf("after Remove %v", matches)
+	}
+	if score := index.Nearest([]float32{0, 5})[0].Score; score < 0.999 || score > 1.001 {
+		t.Errorf("cosine of identical directions = %v, want 1", score)
+	}
+}
+
+func TestHTTPEmbedder(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    [][]float32
+		wantErr bool
+	}{
+		{name: "openai format", body: `{"data":[{"embedding":[1,2]},{"embedding":[3,4]}]}`, want: [][]float32{{1, 2}, {3, 4}}},
+		{name: "embeddings format", body: `{"embeddings":[[1,2],[3,4]]}`, want: [][]float32{{1, 2}, {3, 4}}},
+		{name: "wrong count", body: `{"embeddings":[[1,2]]}`, wantErr: true},
+		{name: "server error", status: http.StatusInternalServerError, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req struct {
+					Model string   `json:"model"`
+					Input []string `json:"input"`
+				}
+				json.NewDecoder(r.Body).Decode(&req)
+				if r.Header.Get("Authorization") != "Bearer secret" || req.Model != "mini" || len(req.Input) != 2 {
+					http.Error(w, "bad request", http.StatusBadRequest)
+					return
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			embedder := &HTTPEmbedder{URL: server.URL, Model: "mini", APIKey: "secret"}
+			vectors, err := embedder.Embed(context.Background(), []string{"a", "b"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(vectors, tt.want) {
+				t.Errorf("vectors = %v, want %v", vectors, tt.want)
+			}
+		})
+	}
+}
+
+// topicEmbedder maps a text onto the axes tea, car and weather.
+type topicEmbedder struct{}
+
+func (topicEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		text = strings.ToLower(text)
+		vectors[i] = []float32{
+			float32(strings.Count(text, "tee")),
+			float32(strings.Count(text, "auto")),
+			float32(strings.Count(text, "wetter")) + 0.1,
+		}
+	}
+	return vectors, nil
+}
+
+func TestSemanticSearch(t *testing.T) {
+	svc := newTestService(t, Config{})
+	if rec := serve(svc, http.MethodGet, "/api/v1/memory/search?mode=semantic&query=tee", nil); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("without embedder: status %d, want 503", rec.Code)
+	}
+
+	svc.semantic = newSemanticIndexer(topicEmbedder{}, log.New(io.Discard, "", 0))
+	svc.store.Observe(svc.semantic.observe)
+	go svc.semantic.run()
+
+	tea := addMemory(t, svc, map[string]interface{}{"content": "Anna trinkt gern Tee, grünen Tee"})
+	car := addMemory(t, svc, map[string]interface{}{"content": "Das Auto steht in der Werkstatt"})
+	addMemory(t, svc, map[string]interface{}{"content": "Das Wetter wird schön", "type": "fact"})
+	deadline := time.Now().Add(5 * time.Second)
+	for svc.semantic.index.Len() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of 3 memories embedded", svc.semantic.index.Len())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"query=Tee&limit=1", []string{tea}},
+		{"query=Auto&type=note&limit=2", []string{car, tea}},
+	}
+	for _, tt := range tests {
+		rec := serve(svc, http.MethodGet, "/api/v1/memory/search?mode=semantic&"+tt.query, nil)
+		var results []ScoredMemory
+		decode(t, rec, &results)
+		var ids []string
+		for _, result := range results {
+			ids = append(ids, result.ID)
+		}
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("%s: ids = %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+
+	if rec := serve(svc, http.MethodGet, "/api/v1/memory/search?mode=semantic&query=+", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("blank query: status %d, want 400", rec.Code)
+	}
+	serve(svc, http.MethodDelete, "/api/v1/memory/memories/"+car, nil)
+	if svc.semantic.index.Len() != 2 {
+		t.Errorf("deleted memory still indexed")
+	}
+}
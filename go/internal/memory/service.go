@@ -1,19 +1,25 @@
 package memory
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+
+	"jarviscore/go/internal/cors"
+	"jarviscore/go/internal/fsutil"
 )
 
 const (
@@ -23,9 +29,77 @@ const (
 )
 
 type Config struct {
-	ListenAddr       string
+	ListenAddr string
+	// GRPCAddr serves the gRPC API next to HTTP (JARVIS_MEMORY_GRPC_ADDR, e.g.
+	// 127.0.0.1:9082). It has no authentication of its own and is off unless
+	// set.
+	GRPCAddr         string
 	StorageDir       string
 	AutoSaveInterval time.Duration
+	CORS             cors.Config
+
+	// Journal logs every change of the JSON backend between autosaves.
+	Journal bool
+
+	// SummarizerURL enables the background consolidation of old,
+	// low-importance memories into summaries.
+	SummarizerURL            string
+	SummarizerKey            string
+	ConsolidateInterval      time.Duration
+	ConsolidateMaxImportance int
+
+	// DecayPeriod lowers the importance of a memory by one for every period
+	// it goes untouched, down to DecayFloor; reads raise it by AccessBoost.
+	// Decay is off unless JARVIS_MEMORY_DECAY_PERIOD is set (e.g. 720h).
+	DecayPeriod time.Duration
+	DecayFloor  int
+	AccessBoost int
+
+	// BackupInterval is how often a timestamped snapshot is written to the
+	// backups directory; only the newest BackupRetention are kept.
+	BackupInterval  time.Duration
+	BackupRetention int
+
+	// NamespaceQuotas caps the number of memories per namespace
+	// (JARVIS_MEMORY_NAMESPACE_QUOTAS="chat=5000,plugin:*=1000").
+	NamespaceQuotas map[string]int
+
+	// Capacity caps the store (JARVIS_MEMORY_MAX_ENTRIES,
+	// JARVIS_MEMORY_MAX_BYTES); writes beyond it evict memories according to
+	// JARVIS_MEMORY_EVICTION (importance or lru).
+	Capacity Capacity
+
+	// GatewayURL receives memory events such as evictions (POST /api/events).
+	GatewayURL   string
+	GatewayToken string
+
+	// MaxRevisions is the number of previous versions kept per memory.
+	MaxRevisions int
+
+	// MaxLimit caps the page size of list and search responses.
+	MaxLimit int
+
+	// Backend selects the persistence: json (default), bbolt or sqlite.
+	Backend     string
+	BackendPath string
+
+	// EncryptionKey encrypts memories.json, the journal, backups, history and
+	// the bbolt/sqlite records with AES-256-GCM (JARVIS_MEMORY_KEY). With
+	// UseKeychain the key is read from the OS keychain instead.
+	EncryptionKey string
+	UseKeychain   bool
+
+	// SyncURL mirrors every write into the memories table of the database
+	// service (JARVIS_MEMORY_SYNC_URL, e.g. http://localhost:8083). Requests
+	// carry SyncAPIKey as X-API-Key (JARVIS_MEMORY_SYNC_KEY), which must be
+	// one of the database service's JARVIS_AUTH_KEYS.
+	SyncURL    string
+	SyncAPIKey string
+
+	// EmbedderURL enables semantic search (JARVIS_MEMORY_EMBEDDER_URL).
+	EmbedderURL   string
+	EmbedderModel string
+	EmbedderKey   string
 }
 
 func LoadConfig() Config {
@@ -33,14 +107,115 @@ func LoadConfig() Config {
 		ListenAddr:       defaultListenAddr,
 		StorageDir:       defaultStorageDir,
 		AutoSaveInterval: defaultAutoSaveInterval,
+		CORS:             cors.LoadConfig("JARVIS_MEMORY_CORS_ORIGINS"),
+		EmbedderURL:      strings.TrimSpace(os.Getenv("JARVIS_MEMORY_EMBEDDER_URL")),
+		EmbedderModel:    strings.TrimSpace(os.Getenv("JARVIS_MEMORY_EMBEDDER_MODEL")),
+		EmbedderKey:      strings.TrimSpace(os.Getenv("JARVIS_MEMORY_EMBEDDER_KEY")),
+		Backend:          BackendJSON,
+		MaxLimit:         defaultMaxLimit,
+		MaxRevisions:     defaultMaxRevisions,
+		BackupInterval:   defaultBackupInterval,
+		BackupRetention:  defaultBackupRetention,
+		Journal:          true,
+		DecayFloor:       defaultDecayFloor,
+		AccessBoost:      defaultAccessBoost,
+
+		SummarizerURL:            strings.TrimSpace(os.Getenv("JARVIS_MEMORY_SUMMARIZER_URL")),
+		SummarizerKey:            strings.TrimSpace(os.Getenv("JARVIS_MEMORY_SUMMARIZER_KEY")),
+		ConsolidateInterval:      defaultConsolidateInterval,
+		ConsolidateMaxImportance: defaultConsolidateMaxImportance,
+		BackendPath:              strings.TrimSpace(os.Getenv("JARVIS_MEMORY_DB_PATH")),
+		SyncURL:                  strings.TrimSpace(os.Getenv("JARVIS_MEMORY_SYNC_URL")),
+		SyncAPIKey:               strings.TrimSpace(os.Getenv("JARVIS_MEMORY_SYNC_KEY")),
+		EncryptionKey:            strings.TrimSpace(os.Getenv("JARVIS_MEMORY_KEY")),
+		GatewayURL:               strings.TrimSpace(os.Getenv("JARVIS_GATEWAYD_URL")),
+		GatewayToken:             strings.TrimSpace(os.Getenv("JARVIS_GATEWAYD_TOKEN")),
+		Capacity:                 Capacity{Policy: evictionPolicy(os.Getenv("JARVIS_MEMORY_EVICTION"))},
 	}
 
 	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_ADDR")); value != "" {
 		cfg.ListenAddr = value
 	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_GRPC_ADDR")); value != "" {
+		cfg.GRPCAddr = value
+		if strings.EqualFold(value, "off") {
+			cfg.GRPCAddr = ""
+		}
+	}
 	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_STORAGE_DIR")); value != "" {
 		cfg.StorageDir = value
 	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_CONSOLIDATE_INTERVAL")); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			cfg.ConsolidateInterval = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_CONSOLIDATE_MAX_IMPORTANCE")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			cfg.ConsolidateMaxImportance = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_DECAY_PERIOD")); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed >= 0 {
+			cfg.DecayPeriod = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_DECAY_FLOOR")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			cfg.DecayFloor = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_ACCESS_BOOST")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			cfg.AccessBoost = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_KEYCHAIN")); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.UseKeychain = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_JOURNAL")); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.Journal = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_BACKUP_INTERVAL")); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed >= 0 {
+			cfg.BackupInterval = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_BACKUP_RETENTION")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			cfg.BackupRetention = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_NAMESPACE_QUOTAS")); value != "" {
+		cfg.NamespaceQuotas = parseNamespaceQuotas(value)
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_MAX_ENTRIES")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			cfg.Capacity.MaxEntries = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_MAX_BYTES")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			cfg.Capacity.MaxBytes = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_MAX_REVISIONS")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			cfg.MaxRevisions = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_MAX_LIMIT")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			cfg.MaxLimit = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_BACKEND")); value != "" {
+		cfg.Backend = backendName(value)
+	}
 	if value := strings.TrimSpace(os.Getenv("JARVIS_MEMORY_AUTOSAVE_INTERVAL")); value != "" {
 		if parsed, err := time.ParseDuration(value); err == nil {
 			cfg.AutoSaveInterval = parsed
@@ -61,19 +236,84 @@ type Memory struct {
 	UpdatedAt  time.Time              `json:"updated_at"`
 	References []string               `json:"references"`
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+
+	// Namespace is the producer of the memory (desktop, speech, chat,
+	// plugin:<name>); empty means the default namespace.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key and ExpiresAt are set for entries written through the key-value API.
+	Key       string     `json:"key,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Archived memories are kept but excluded from listing and search unless
+	// asked for. Pinned memories never decay and rank above all others.
+	Archived bool `json:"archived,omitempty"`
+	Pinned   bool `json:"pinned,omitempty"`
+
+	// LastAccessed is set when the memory is read; DecayedAt when the decay
+	// job last lowered its importance.
+	LastAccessed *time.Time `json:"last_accessed,omitempty"`
+	DecayedAt    *time.Time `json:"decayed_at,omitempty"`
+
+	// Hits counts how often the memory was read or returned by a search.
+	Hits int `json:"hits,omitempty"`
+}
+
+// Change describes a write to the store. MetaOnly is set when only
+// bookkeeping fields such as importance or last_accessed changed.
+type Change struct {
+	ID       string
+	Content  string
+	Deleted  bool
+	MetaOnly bool
+	Memory   *Memory
 }
 
 // MemoryStore manages all memories.
 type MemoryStore struct {
 	memories   map[string]*Memory
+	keys       map[string]string // key -> memory ID
 	storageDir string
+	index      *TextIndex
+	stats      *storeStats
+	sealer     *Sealer
+	history    *History
+	observers  []func(Change)
 	mu         sync.RWMutex
 }
 
 func NewMemoryStore(storageDir string) *MemoryStore {
+	index := NewTextIndex()
+	stats := newStoreStats()
 	return &MemoryStore{
 		memories:   make(map[string]*Memory),
+		keys:       make(map[string]string),
 		storageDir: storageDir,
+		index:      index,
+		stats:      stats,
+		observers:  []func(Change){index.observe, stats.observe},
+	}
+}
+
+// Observe registers fn for every change. fn runs with the store lock held and
+// must not call back into the store.
+func (s *MemoryStore) Observe(fn func(Change)) {
+	s.mu.Lock()
+	s.observers = append(s.observers, fn)
+	s.mu.Unlock()
+}
+
+func (s *MemoryStore) notifyLocked(memory *Memory, deleted bool) {
+	change := Change{ID: memory.ID, Content: memory.Content, Deleted: deleted, Memory: memory}
+	for _, fn := range s.observers {
+		fn(change)
+	}
+}
+
+func (s *MemoryStore) notifyMetaLocked(memory *Memory) {
+	change := Change{ID: memory.ID, Content: memory.Content, MetaOnly: true, Memory: memory}
+	for _, fn := range s.observers {
+		fn(change)
 	}
 }
 
@@ -88,8 +328,19 @@ func (s *MemoryStore) Add(memory *Memory) string {
 		memory.CreatedAt = time.Now()
 	}
 	memory.UpdatedAt = time.Now()
+	memory.Type = normalizeCategory(memory.Type)
 
+	if memory.Key != "" {
+		if previous, exists := s.keys[memory.Key]; exists && previous != memory.ID {
+			if old, ok := s.memories[previous]; ok {
+				delete(s.memories, previous)
+				s.notifyLocked(old, true)
+			}
+		}
+		s.keys[memory.Key] = memory.ID
+	}
 	s.memories[memory.ID] = memory
+	s.notifyLocked(memory, false)
 	return memory.ID
 }
 
@@ -98,7 +349,11 @@ func (s *MemoryStore) Get(id string) (*Memory, bool) {
 	defer s.mu.RUnlock()
 
 	memory, exists := s.memories[id]
-	return memory, exists
+	if !exists || memory.expired(time.Now()) {
+		return nil, false
+	}
+	copied := *memory
+	return &copied, true
 }
 
 func (s *MemoryStore) Update(id string, updates map[string]interface{}) bool {
@@ -111,9 +366,15 @@ func (s *MemoryStore) Update(id string, updates map[string]interface{}) bool {
 	}
 
 	// Apply updates
+	now := time.Now()
+	s.record(memory, now)
+
 	if content, ok := updates["content"].(string); ok {
 		memory.Content = content
 	}
+	if memoryType, ok := updates["type"].(string); ok && normalizeCategory(memoryType) != "" {
+		memory.Type = normalizeCategory(memoryType)
+	}
 	if tags, ok := updates["tags"].([]string); ok {
 		memory.Tags = tags
 	}
@@ -121,7 +382,8 @@ func (s *MemoryStore) Update(id string, updates map[string]interface{}) bool {
 		memory.Importance = int(importance)
 	}
 
-	memory.UpdatedAt = time.Now()
+	memory.UpdatedAt = now
+	s.notifyLocked(memory, false)
 	return true
 }
 
@@ -129,53 +391,57 @@ func (s *MemoryStore) Delete(id string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.memories[id]; exists {
+	if memory, exists := s.memories[id]; exists {
+		if memory.Key != "" {
+			delete(s.keys, memory.Key)
+		}
 		delete(s.memories, id)
+		s.notifyLocked(memory, true)
 		return true
 	}
 	return false
 }
 
-func (s *MemoryStore) Search(query string, memoryType string, tags []string) []*Memory {
+// Search returns copies of the memories matching query and filter. A nil
+// query matches every memory.
+func (s *MemoryStore) Search(query *Query, filter Filter) []*Memory {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	results := []*Memory{}
-	queryLower := strings.ToLower(query)
-
-	for _, memory := range s.memories {
-		// Filter by type
-		if memoryType != "" && memory.Type != memoryType {
+	now := time.Now()
+
+	// Every match contains all required terms, so the smallest candidate set
+	// of any of them is enough to check.
+	candidates := s.memories
+	for _, term := range query.required() {
+		ids, ok := s.index.Candidates(term)
+		if !ok || len(ids) >= len(candidates) {
 			continue
 		}
-
-		// Filter by tags
-		if len(tags) > 0 {
-			hasTag := false
-			for _, tag := range tags {
-				for _, memTag := range memory.Tags {
-					if tag == memTag {
-						hasTag = true
-						break
-					}
-				}
-				if hasTag {
-					break
-				}
-			}
-			if !hasTag {
-				continue
+		candidates = make(map[string]*Memory, len(ids))
+		for _, id := range ids {
+			if memory, exists := s.memories[id]; exists {
+				candidates[id] = memory
 			}
 		}
+	}
 
-		// Search in content
-		if query == "" || strings.Contains(strings.ToLower(memory.Content), queryLower) {
-			results = append(results, memory)
+	for _, memory := range candidates {
+		if memory.expired(now) || !filter.Matches(memory) {
+			continue
+		}
+		if query.Matches(memory) {
+			copied := *memory
+			results = append(results, &copied)
 		}
 	}
 
-	// Sort by importance then updated_at
+	// Sort pinned first, then by importance and updated_at
 	sort.Slice(results, func(i, j int) bool {
+		if results[i].Pinned != results[j].Pinned {
+			return results[i].Pinned
+		}
 		if results[i].Importance != results[j].Importance {
 			return results[i].Importance > results[j].Importance
 		}
@@ -190,8 +456,13 @@ func (s *MemoryStore) GetAll() []*Memory {
 	defer s.mu.RUnlock()
 
 	results := make([]*Memory, 0, len(s.memories))
+	now := time.Now()
 	for _, memory := range s.memories {
-		results = append(results, memory)
+		if memory.expired(now) || memory.Archived {
+			continue
+		}
+		copied := *memory
+		results = append(results, &copied)
 	}
 
 	// Sort by updated_at descending
@@ -202,84 +473,81 @@ func (s *MemoryStore) GetAll() []*Memory {
 	return results
 }
 
+// GetStats returns the incrementally maintained store statistics.
 func (s *MemoryStore) GetStats() map[string]interface{} {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	typeCounts := make(map[string]int)
-	totalImportance := 0
-
-	for _, memory := range s.memories {
-		typeCounts[memory.Type]++
-		totalImportance += memory.Importance
-	}
-
-	avgImportance := 0.0
-	if len(s.memories) > 0 {
-		avgImportance = float64(totalImportance) / float64(len(s.memories))
-	}
-
-	return map[string]interface{}{
-		"total":           len(s.memories),
-		"by_type":         typeCounts,
-		"avg_importance":  avgImportance,
-		"storage_size_kb": s.estimateSize() / 1024,
-	}
+	return s.stats.snapshot()
 }
 
-func (s *MemoryStore) estimateSize() int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	totalSize := 0
-	for _, memory := range s.memories {
-		totalSize += len(memory.Content)
-		totalSize += len(memory.ID) * 2 // ID stored twice
-		totalSize += len(memory.Type)
-		for _, tag := range memory.Tags {
-			totalSize += len(tag)
-		}
-	}
-	return totalSize
+// Len returns the number of memories in the store.
+func (s *MemoryStore) Len() int {
+	entries, _ := s.stats.totals()
+	return entries
 }
 
-// Persistence
 func (s *MemoryStore) SaveToFile(filename string) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	return s.saveLocked(filename)
+}
+
+func (s *MemoryStore) saveLocked(filename string) error {
 	data, err := json.MarshalIndent(s.memories, "", "  ")
-	if err != nil {
-		return err
+	if err == nil {
+		data, err = s.sealer.seal(data)
 	}
-
-	// Ensure directory exists
-	if err := os.MkdirAll(s.storageDir, 0o755); err != nil {
+	if err != nil {
 		return err
 	}
 
 	path := filepath.Join(s.storageDir, filename)
-	return os.WriteFile(path, data, 0o644)
+	return fsutil.WriteFileAtomic(path, data, 0o644)
 }
 
+// LoadFromFile replaces the store contents with the memories in filename.
 func (s *MemoryStore) LoadFromFile(filename string) error {
 	path := filepath.Join(s.storageDir, filename)
 
 	data, err := os.ReadFile(path)
+	if err == nil {
+		data, err = s.sealer.open(data)
+	}
 	if err != nil {
 		return err
 	}
 
+	memories := make(map[string]*Memory)
+	if err := json.Unmarshal(data, &memories); err != nil {
+		return err
+	}
+	if memories == nil {
+		memories = make(map[string]*Memory)
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
-
-	return json.Unmarshal(data, &s.memories)
+	s.replaceLocked(memories)
+	return nil
 }
 
 type Service struct {
-	cfg    Config
-	store  *MemoryStore
-	logger *log.Logger
+	cfg      Config
+	store    *MemoryStore
+	logger   *log.Logger
+	semantic *semanticIndexer
+	backend  Backend
+	journal  *Journal
+	history  *History
+	backups  *Backups
+	sync     *dbSyncer
+	events   *eventPublisher
+
+	// metaDirty holds memories with MetaOnly changes not yet written to the
+	// backend.
+	metaDirty map[string]struct{}
+	metaLock  sync.Mutex
+
+	summarizer Summarizer
 }
 
 func NewService(cfg Config, logger *log.Logger) (*Service, error) {
@@ -288,52 +556,268 @@ func NewService(cfg Config, logger *log.Logger) (*Service, error) {
 		logger = log.New(os.Stdout, "[memory] ", log.LstdFlags|log.LUTC)
 	}
 
-	svc := &Service{cfg: cfg, store: store, logger: logger}
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = defaultMaxLimit
+	}
+	if cfg.BackupRetention <= 0 {
+		cfg.BackupRetention = defaultBackupRetention
+	}
+	cfg.Capacity.Policy = evictionPolicy(cfg.Capacity.Policy)
+	key := cfg.EncryptionKey
+	if key == "" && cfg.UseKeychain {
+		var err error
+		if key, err = keychainKey(); err != nil {
+			return nil, err
+		}
+	}
+	sealer := NewSealer(key)
+	store.sealer = sealer
+	if sealer != nil {
+		logger.Printf("[INFO] Memories are encrypted at rest")
+	}
 
-	if err := store.LoadFromFile("memories.json"); err != nil {
-		logger.Printf("[INFO] No existing memories found, starting fresh")
-	} else {
-		logger.Printf("[INFO] Loaded %d memories from disk", len(store.memories))
+	svc := &Service{cfg: cfg, store: store, logger: logger, backups: NewBackups(cfg.StorageDir, cfg.BackupRetention, sealer)}
+
+	if cfg.EmbedderURL != "" {
+		svc.semantic = newSemanticIndexer(&HTTPEmbedder{URL: cfg.EmbedderURL, Model: cfg.EmbedderModel, APIKey: cfg.EmbedderKey}, logger)
+		store.Observe(svc.semantic.observe)
+		go svc.semantic.run()
+		logger.Printf("[INFO] Semantic search enabled")
 	}
 
-	svc.startAutoSave()
+	history, err := OpenHistory(filepath.Join(cfg.StorageDir, historyFile), cfg.MaxRevisions, sealer)
+	if err != nil {
+		return nil, fmt.Errorf("Memory-Historie konnte nicht geladen werden: %w", err)
+	}
+	svc.history = history
+	store.history = history
+	store.Observe(history.observe)
+
+	backend, err := OpenBackend(cfg, sealer)
+	if err != nil {
+		return nil, fmt.Errorf("Memory-Backend konnte nicht geöffnet werden: %w", err)
+	}
+	svc.backend = backend
+
+	if backend == nil {
+		if err := store.LoadFromFile("memories.json"); errors.Is(err, os.ErrNotExist) {
+			logger.Printf("[INFO] No existing memories found, starting fresh")
+		} else if err != nil {
+			// Never start empty over a file we cannot read, e.g. with a
+			// missing or wrong JARVIS_MEMORY_KEY: the next save would wipe it.
+			return nil, fmt.Errorf("memories.json konnte nicht gelesen werden: %w", err)
+		} else {
+			logger.Printf("[INFO] Loaded %d memories from disk", store.Len())
+		}
+		if cfg.Journal {
+			if err := svc.openJournal(); err != nil {
+				return nil, err
+			}
+		}
+		svc.startAutoSave()
+	} else if err := svc.openBackendStore(); err != nil {
+		backend.Close()
+		return nil, err
+	}
+
+	if cfg.SyncURL != "" {
+		svc.sync = newDBSyncer(cfg, logger)
+		store.Observe(svc.sync.observe)
+		go svc.sync.run()
+		logger.Printf("[INFO] Mirroring memories to %s", cfg.SyncURL)
+	}
+
+	if cfg.GatewayURL != "" {
+		svc.events = newEventPublisher(cfg, logger)
+		go svc.events.run()
+	}
+	svc.enforceCapacity("")
+
+	svc.startExpiryJanitor()
+	if cfg.BackupInterval > 0 {
+		svc.startBackups()
+	}
+	if cfg.DecayPeriod > 0 {
+		svc.startDecay()
+	}
+
+	if cfg.SummarizerURL != "" {
+		svc.summarizer = &HTTPSummarizer{URL: cfg.SummarizerURL, APIKey: cfg.SummarizerKey}
+		svc.startConsolidation()
+	}
 
 	return svc, nil
 }
 
-func (s *Service) Routes(mux *http.ServeMux) {
+// openBackendStore loads the memories from the backend and persists every
+// further change. An empty backend imports an existing memories.json once.
+func (s *Service) openBackendStore() error {
+	memories, err := s.backend.Load()
+	if err != nil {
+		return fmt.Errorf("Memories konnten nicht geladen werden: %w", err)
+	}
+	s.store.Replace(memories)
+	s.metaDirty = make(map[string]struct{})
+	s.store.Observe(s.persist)
+	s.startMetaFlush()
+
+	if len(memories) == 0 {
+		if err := s.store.LoadFromFile("memories.json"); err == nil {
+			s.logger.Printf("[INFO] Imported %d memories from memories.json into %s", s.store.Len(), s.cfg.Backend)
+		}
+	}
+	s.logger.Printf("[INFO] Loaded %d memories from %s backend", s.store.Len(), s.cfg.Backend)
+	return nil
+}
+
+// openJournal replays changes logged since the last snapshot and starts
+// journaling new ones.
+func (s *Service) openJournal() error {
+	journal, err := OpenJournal(s.cfg.StorageDir, s.store.sealer)
+	if err != nil {
+		return fmt.Errorf("Memory-Journal konnte nicht geöffnet werden: %w", err)
+	}
+	replayed, err := journal.Replay(s.store)
+	if err != nil {
+		journal.Close()
+		return fmt.Errorf("Memory-Journal konnte nicht gelesen werden: %w", err)
+	}
+	if replayed > 0 {
+		s.logger.Printf("[INFO] Replayed %d journal entries", replayed)
+	}
+	s.journal = journal
+	s.store.Observe(func(change Change) {
+		if err := journal.observe(change); err != nil {
+			s.logger.Printf("[ERROR] Journal-Eintrag fehlgeschlagen: %v", err)
+		}
+	})
+	return nil
+}
+
+// save writes a snapshot of the JSON backend and compacts the journal.
+func (s *Service) save() error {
+	return s.store.snapshot("memories.json", s.journal)
+}
+
+// Close releases the storage backend; with the JSON backend it saves the file.
+func (s *Service) Close() error {
+	s.flushHistory()
+	if s.backend != nil {
+		s.flushMeta()
+		return s.backend.Close()
+	}
+	if err := s.save(); err != nil {
+		return err
+	}
+	if s.journal != nil {
+		return s.journal.Close()
+	}
+	return nil
+}
+
+func (s *Service) Routes(serveMux *http.ServeMux) {
 	router := mux.NewRouter()
 
 	router.HandleFunc("/health", s.healthHandler).Methods(http.MethodGet)
-	router.HandleFunc("/api/memory", s.addMemoryHandler).Methods(http.MethodPost)
-	router.HandleFunc("/api/memory/{id}", s.getMemoryHandler).Methods(http.MethodGet)
-	router.HandleFunc("/api/memory/{id}", s.updateMemoryHandler).Methods(http.MethodPut)
-	router.HandleFunc("/api/memory/{id}", s.deleteMemoryHandler).Methods(http.MethodDelete)
-	router.HandleFunc("/api/memory/search", s.searchMemoriesHandler).Methods(http.MethodGet)
-	router.HandleFunc("/api/memory/all", s.getAllMemoriesHandler).Methods(http.MethodGet)
-	router.HandleFunc("/api/memory/stats", s.getStatsHandler).Methods(http.MethodGet)
-	router.HandleFunc("/api/memory/save", s.saveMemoriesHandler).Methods(http.MethodPost)
-	router.HandleFunc("/api/memory/load", s.loadMemoriesHandler).Methods(http.MethodPost)
+	s.routesV1(router.PathPrefix("/api/v1/memory").Subrouter())
+	s.legacyRoutes(router.PathPrefix("/api/memory").Subrouter())
+
+	serveMux.Handle("/", cors.New(s.cfg.CORS).Handler(router))
+}
+
+// routesV1 registers the versioned API. Collections and actions live in
+// their own subresources, so no fixed path can collide with a memory ID.
+func (s *Service) routesV1(api *mux.Router) {
+	api.HandleFunc("/memories", s.addMemoryHandler).Methods(http.MethodPost)
+	api.HandleFunc("/memories", s.getAllMemoriesHandler).Methods(http.MethodGet)
+	api.HandleFunc("/memories/{id}", s.getMemoryHandler).Methods(http.MethodGet)
+	api.HandleFunc("/memories/{id}", s.updateMemoryHandler).Methods(http.MethodPut)
+	api.HandleFunc("/memories/{id}", s.deleteMemoryHandler).Methods(http.MethodDelete)
+	api.HandleFunc("/memories/{id}/pin", s.flagHandler(flagPinned, true)).Methods(http.MethodPost)
+	api.HandleFunc("/memories/{id}/pin", s.flagHandler(flagPinned, false)).Methods(http.MethodDelete)
+	api.HandleFunc("/memories/{id}/archive", s.flagHandler(flagArchived, true)).Methods(http.MethodPost)
+	api.HandleFunc("/memories/{id}/archive", s.flagHandler(flagArchived, false)).Methods(http.MethodDelete)
+	api.HandleFunc("/memories/{id}/revisions", s.listRevisionsHandler).Methods(http.MethodGet)
+	api.HandleFunc("/memories/{id}/revisions/{revision}/restore", s.restoreRevisionHandler).Methods(http.MethodPost)
+	api.HandleFunc("/kv/{key}", s.putKeyHandler).Methods(http.MethodPut)
+	api.HandleFunc("/kv/{key}", s.getKeyHandler).Methods(http.MethodGet)
+	api.HandleFunc("/kv/{key}", s.deleteKeyHandler).Methods(http.MethodDelete)
+	api.HandleFunc("/search", s.searchMemoriesHandler).Methods(http.MethodGet)
+	api.HandleFunc("/stats", s.getStatsHandler).Methods(http.MethodGet)
+	api.HandleFunc("/stats/most-used", s.mostUsedHandler).Methods(http.MethodGet)
+	api.HandleFunc("/categories", s.categoriesHandler).Methods(http.MethodGet)
+	api.HandleFunc("/storage/save", s.saveMemoriesHandler).Methods(http.MethodPost)
+	api.HandleFunc("/storage/load", s.loadMemoriesHandler).Methods(http.MethodPost)
+	api.HandleFunc("/backups", s.listBackupsHandler).Methods(http.MethodGet)
+	api.HandleFunc("/backups", s.createBackupHandler).Methods(http.MethodPost)
+	api.HandleFunc("/backups/{name}/restore", s.restoreBackupHandler).Methods(http.MethodPost)
+	api.HandleFunc("/storage/sync", s.syncHandler).Methods(http.MethodPost)
+	api.HandleFunc("/export", s.exportHandler).Methods(http.MethodGet)
+	api.HandleFunc("/export/stream", s.streamMemoriesHandler).Methods(http.MethodGet)
+	api.HandleFunc("/import", s.importHandler).Methods(http.MethodPost)
+	api.HandleFunc("/consolidate", s.consolidateHandler).Methods(http.MethodPost)
+}
 
-	router.Use(corsMiddleware)
+// legacyRoutes keeps the unversioned /api/memory paths working. The fixed
+// paths are registered before /{id} so they are no longer shadowed by it.
+func (s *Service) legacyRoutes(api *mux.Router) {
+	api.Use(deprecated)
+	api.HandleFunc("", s.addMemoryHandler).Methods(http.MethodPost)
+	api.HandleFunc("/search", s.searchMemoriesHandler).Methods(http.MethodGet)
+	api.HandleFunc("/all", s.getAllMemoriesHandler).Methods(http.MethodGet)
+	api.HandleFunc("/all/stream", s.streamMemoriesHandler).Methods(http.MethodGet)
+	api.HandleFunc("/stats", s.getStatsHandler).Methods(http.MethodGet)
+	api.HandleFunc("/most-used", s.mostUsedHandler).Methods(http.MethodGet)
+	api.HandleFunc("/categories", s.categoriesHandler).Methods(http.MethodGet)
+	api.HandleFunc("/save", s.saveMemoriesHandler).Methods(http.MethodPost)
+	api.HandleFunc("/load", s.loadMemoriesHandler).Methods(http.MethodPost)
+	api.HandleFunc("/backups", s.listBackupsHandler).Methods(http.MethodGet)
+	api.HandleFunc("/backups", s.createBackupHandler).Methods(http.MethodPost)
+	api.HandleFunc("/backups/{name}/restore", s.restoreBackupHandler).Methods(http.MethodPost)
+	api.HandleFunc("/export", s.exportHandler).Methods(http.MethodGet)
+	api.HandleFunc("/import", s.importHandler).Methods(http.MethodPost)
+	api.HandleFunc("/kv/{key}", s.putKeyHandler).Methods(http.MethodPut)
+	api.HandleFunc("/kv/{key}", s.getKeyHandler).Methods(http.MethodGet)
+	api.HandleFunc("/kv/{key}", s.deleteKeyHandler).Methods(http.MethodDelete)
+	api.HandleFunc("/{id}", s.getMemoryHandler).Methods(http.MethodGet)
+	api.HandleFunc("/{id}", s.updateMemoryHandler).Methods(http.MethodPut)
+	api.HandleFunc("/{id}", s.deleteMemoryHandler).Methods(http.MethodDelete)
+}
 
-	mux.Handle("/", router)
+func deprecated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", `</api/v1/memory>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
 }
 
 func (s *Service) startAutoSave() {
-	if s.cfg.AutoSaveInterval <= 0 {
+	var compact <-chan struct{}
+	if s.journal != nil {
+		compact = s.journal.compact
+	}
+	if s.cfg.AutoSaveInterval <= 0 && compact == nil {
 		return
 	}
 
 	go func() {
-		ticker := time.NewTicker(s.cfg.AutoSaveInterval)
-		defer ticker.Stop()
+		var tick <-chan time.Time
+		if s.cfg.AutoSaveInterval > 0 {
+			ticker := time.NewTicker(s.cfg.AutoSaveInterval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
 
-		for range ticker.C {
-			if err := s.store.SaveToFile("memories.json"); err != nil {
+		for {
+			select {
+			case <-tick:
+			case <-compact:
+			}
+			if err := s.save(); err != nil {
 				s.logger.Printf("[ERROR] Auto-save failed: %s", err)
 			} else {
-				s.logger.Printf("[INFO] Auto-saved %d memories", len(s.store.memories))
+				s.logger.Printf("[INFO] Auto-saved %d memories", s.store.Len())
 			}
 		}
 	}()
@@ -364,14 +848,18 @@ func (s *Service) addMemoryHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"Content is required"}`, http.StatusBadRequest)
 		return
 	}
-	if memory.Type == "" {
+	if normalizeCategory(memory.Type) == "" {
 		memory.Type = "note"
 	}
 	if memory.Importance == 0 {
 		memory.Importance = 5
 	}
+	if !s.admitNamespace(w, &memory, nil) {
+		return
+	}
 
 	id := s.store.Add(&memory)
+	s.enforceCapacity(id)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -385,7 +873,11 @@ func (s *Service) getMemoryHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	memory, exists := s.store.Get(id)
+	if _, exists := s.store.Get(id); !exists {
+		http.Error(w, `{"error":"Memory not found"}`, http.StatusNotFound)
+		return
+	}
+	memory, exists := s.store.Touch(id, s.cfg.AccessBoost, time.Now())
 	if !exists {
 		http.Error(w, `{"error":"Memory not found"}`, http.StatusNotFound)
 		return
@@ -409,6 +901,8 @@ func (s *Service) updateMemoryHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"Memory not found"}`, http.StatusNotFound)
 		return
 	}
+	s.flushHistory()
+	s.enforceCapacity(id)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -435,25 +929,94 @@ func (s *Service) deleteMemoryHandler(w http.ResponseWriter, r *http.Request) {
 
 func (s *Service) searchMemoriesHandler(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("query")
-	memoryType := r.URL.Query().Get("type")
-	tagsParam := r.URL.Query().Get("tags")
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
 
-	var tags []string
-	if tagsParam != "" {
-		tags = strings.Split(tagsParam, ",")
+	if r.URL.Query().Get("mode") == "semantic" {
+		s.semanticSearchHandler(w, r, query, filter)
+		return
 	}
 
-	results := s.store.Search(query, memoryType, tags)
+	defaultSort := SortImportance
+	if query != "" {
+		defaultSort = SortRelevance
+	}
+	opts, err := parsePageOptions(r, defaultSort, s.cfg.MaxLimit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	parsed, err := ParseQuery(query)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	results := s.store.Search(parsed, filter)
+	sortMemories(results, opts, parsed)
+	page := paginate(w, results, opts)
+	s.store.RecordHits(page, time.Now())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+func (s *Service) semanticSearchHandler(w http.ResponseWriter, r *http.Request, query string, filter Filter) {
+	if s.semantic == nil {
+		http.Error(w, `{"error":"Semantic search is not configured"}`, http.StatusServiceUnavailable)
+		return
+	}
+	if strings.TrimSpace(query) == "" {
+		http.Error(w, `{"error":"Query is required"}`, http.StatusBadRequest)
+		return
+	}
+	limit := 10
+	if value, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && value > 0 {
+		limit = min(value, s.cfg.MaxLimit)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+	results, err := s.semanticSearch(ctx, query, filter, limit)
+	if err != nil {
+		s.logger.Printf("[WARN] Semantic search failed: %v", err)
+		http.Error(w, `{"error":"Embedding failed"}`, http.StatusBadGateway)
+		return
+	}
+	hits := make([]*Memory, 0, len(results))
+	for _, result := range results {
+		hits = append(hits, result.Memory)
+	}
+	s.store.RecordHits(hits, time.Now())
+	for i := range results {
+		results[i].Memory = hits[i]
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(results)
 }
 
-func (s *Service) getAllMemoriesHandler(w http.ResponseWriter, _ *http.Request) {
-	memories := s.store.GetAll()
+func (s *Service) getAllMemoriesHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := parsePageOptions(r, SortUpdatedAt, s.cfg.MaxLimit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	memories := s.store.Search(nil, filter)
+	sortMemories(memories, opts, nil)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(memories)
+	json.NewEncoder(w).Encode(paginate(w, memories, opts))
 }
 
 func (s *Service) getStatsHandler(w http.ResponseWriter, _ *http.Request) {
@@ -464,7 +1027,15 @@ func (s *Service) getStatsHandler(w http.ResponseWriter, _ *http.Request) {
 }
 
 func (s *Service) saveMemoriesHandler(w http.ResponseWriter, _ *http.Request) {
-	if err := s.store.SaveToFile("memories.json"); err != nil {
+	if s.backend != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Memories are persisted by the " + s.cfg.Backend + " backend",
+		})
+		return
+	}
+	if err := s.save(); err != nil {
 		http.Error(w, fmt.Sprintf(`{"error":"Failed to save: %s"}`, err), http.StatusInternalServerError)
 		return
 	}
@@ -477,7 +1048,16 @@ func (s *Service) saveMemoriesHandler(w http.ResponseWriter, _ *http.Request) {
 }
 
 func (s *Service) loadMemoriesHandler(w http.ResponseWriter, _ *http.Request) {
-	if err := s.store.LoadFromFile("memories.json"); err != nil {
+	var err error
+	if s.backend != nil {
+		var memories map[string]*Memory
+		if memories, err = s.backend.Load(); err == nil {
+			s.store.Replace(memories)
+		}
+	} else {
+		err = s.store.LoadFromFile("memories.json")
+	}
+	if err != nil {
 		http.Error(w, fmt.Sprintf(`{"error":"Failed to load: %s"}`, err), http.StatusInternalServerError)
 		return
 	}
@@ -486,21 +1066,6 @@ func (s *Service) loadMemoriesHandler(w http.ResponseWriter, _ *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
 		"message": "Memories loaded from disk",
-		"count":   len(s.store.memories),
-	})
-}
-
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
+		"count":   s.store.Len(),
 	})
 }
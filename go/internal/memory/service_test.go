@@ -0,0 +1,236 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// newTestService returns a JSON backed service in a temporary directory
+// without background jobs.
+func newTestService(t *testing.T, cfg Config) *Service {
+	t.Helper()
+	if cfg.StorageDir == "" {
+		cfg.StorageDir = t.TempDir()
+	}
+	svc, err := NewService(cfg, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	t.Cleanup(func() { svc.Close() })
+	return svc
+}
+
+// serve sends a request with a JSON body (unless body is nil) through the
+// service routes.
+func serve(svc *Service, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		payload, _ := json.Marshal(body)
+		reader = bytes.NewReader(payload)
+	}
+	return serveRequest(svc, httptest.NewRequest(method, path, reader))
+}
+
+func serveRequest(svc *Service, req *http.Request) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	svc.Routes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+}
+
+// addMemory adds memory through the API and returns its id.
+func addMemory(t *testing.T, svc *Service, memory map[string]interface{}) string {
+	t.Helper()
+	rec := serve(svc, http.MethodPost, "/api/v1/memory/memories", memory)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("add: status %d (%s)", rec.Code, rec.Body)
+	}
+	var body struct {
+		ID string `json:"id"`
+	}
+	decode(t, rec, &body)
+	return body.ID
+}
+
+func TestMemoryCRUD(t *testing.T) {
+	svc := newTestService(t, Config{})
+
+	if rec := serve(svc, http.MethodPost, "/api/v1/memory/memories", map[string]interface{}{"type": "note"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("add without content: status %d, want 400", rec.Code)
+	}
+	id := addMemory(t, svc, map[string]interface{}{"content": "Anna mag Tee", "tags": []string{"anna"}})
+
+	var memory Memory
+	rec := serve(svc, http.MethodGet, "/api/v1/memory/memories/"+id, nil)
+	decode(t, rec, &memory)
+	if memory.Content != "Anna mag Tee" || memory.Type != "note" || memory.Importance != 5 {
+		t.Errorf("defaults not applied: %+v", memory)
+	}
+
+	if rec := serve(svc, http.MethodPut, "/api/v1/memory/memories/"+id, map[string]interface{}{"content": "Anna mag Kaffee", "importance": 8}); rec.Code != http.StatusOK {
+		t.Fatalf("update: status %d", rec.Code)
+	}
+	stored, _ := svc.store.Get(id)
+	if stored.Content != "Anna mag Kaffee" || stored.Importance != 8 {
+		t.Errorf("after update %+v", stored)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodDelete, "/api/v1/memory/memories/" + id, http.StatusOK},
+		{http.MethodDelete, "/api/v1/memory/memories/" + id, http.StatusNotFound},
+		{http.MethodGet, "/api/v1/memory/memories/" + id, http.StatusNotFound},
+		{http.MethodPut, "/api/v1/memory/memories/missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		body := interface{}(nil)
+		if tt.method == http.MethodPut {
+			body = map[string]interface{}{"content": "x"}
+		}
+		if rec := serve(svc, tt.method, tt.path, body); rec.Code != tt.status {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, rec.Code, tt.status)
+		}
+	}
+}
+
+func TestStoreReturnsCopies(t *testing.T) {
+	store := NewMemoryStore(t.TempDir())
+	id := store.Add(&Memory{Content: "original", Importance: 5})
+
+	got, _ := store.Get(id)
+	got.Content = "changed"
+	all := store.GetAll()
+	all[0].Importance = 1
+	found := store.Search(nil, Filter{})
+	found[0].Tags = []string{"changed"}
+
+	stored := store.memories[id]
+	if stored.Content != "original" || stored.Importance != 5 || stored.Tags != nil {
+		t.Errorf("caller modified the store: %+v", stored)
+	}
+}
+
+func TestLoadFromFileReportsChanges(t *testing.T) {
+	store := NewMemoryStore(t.TempDir())
+	kept := store.Add(&Memory{Content: "bleibt", Importance: 5})
+	edited := store.Add(&Memory{Content: "alt", Importance: 5})
+	if err := store.SaveToFile("memories.json"); err != nil {
+		t.Fatalf("SaveToFile: %v", err)
+	}
+	if err := store.LoadFromFile("memories.json"); err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+
+	var changes []Change
+	store.Observe(func(change Change) { changes = append(changes, change) })
+	if err := store.LoadFromFile("memories.json"); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("reloading an unchanged file reported %d changes", len(changes))
+	}
+
+	store.Update(edited, map[string]interface{}{"content": "neu"})
+	added := store.Add(&Memory{Content: "später", Importance: 5})
+	changes = nil
+	if err := store.LoadFromFile("memories.json"); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	reported := map[string]bool{}
+	for _, change := range changes {
+		reported[change.ID] = change.Deleted
+	}
+	want := map[string]bool{edited: false, added: true}
+	if !reflect.DeepEqual(reported, want) {
+		t.Errorf("reported %v, want %v", reported, want)
+	}
+	if store.Len() != 2 || mustGet(t, store, edited).Content != "alt" || mustGet(t, store, kept).Content != "bleibt" {
+		t.Errorf("store after reload: %d memories", store.Len())
+	}
+}
+
+func TestServicePersistsAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	first, err := NewService(Config{StorageDir: dir}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := addMemory(t, first, map[string]interface{}{"content": "bleibt erhalten"})
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	second := newTestService(t, Config{StorageDir: dir})
+	if memory, ok := second.store.Get(id); !ok || memory.Content != "bleibt erhalten" {
+		t.Errorf("after restart %+v, %v", memory, ok)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	svc := newTestService(t, Config{})
+	id := addMemory(t, svc, map[string]interface{}{"content": "Routing Test"})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		status     int
+		deprecated bool
+		isMemory   bool
+	}{
+		{"v1 memory", http.MethodGet, "/api/v1/memory/memories/" + id, http.StatusOK, false, true},
+		{"v1 search", http.MethodGet, "/api/v1/memory/search?query=routing", http.StatusOK, false, false},
+		{"v1 stats", http.MethodGet, "/api/v1/memory/stats", http.StatusOK, false, false},
+		{"legacy memory", http.MethodGet, "/api/memory/" + id, http.StatusOK, true, true},
+		{"legacy search not shadowed", http.MethodGet, "/api/memory/search?query=routing", http.StatusOK, true, false},
+		{"legacy stats not shadowed", http.MethodGet, "/api/memory/stats", http.StatusOK, true, false},
+		{"legacy all not shadowed", http.MethodGet, "/api/memory/all", http.StatusOK, true, false},
+		{"legacy unknown id", http.MethodGet, "/api/memory/missing", http.StatusNotFound, true, false},
+		{"v1 id outside collection", http.MethodGet, "/api/v1/memory/" + id, http.StatusNotFound, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(svc, tt.method, tt.path, nil)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Deprecation") == "true"; got != tt.deprecated {
+				t.Errorf("deprecated = %v, want %v", got, tt.deprecated)
+			}
+			if tt.isMemory {
+				var memory Memory
+				decode(t, rec, &memory)
+				if memory.ID != id {
+					t.Errorf("returned %+v, want memory %s", memory, id)
+				}
+			}
+		})
+	}
+}
+
+// mustGet returns the stored memory or fails the test.
+func mustGet(t *testing.T, store *MemoryStore, id string) *Memory {
+	t.Helper()
+	memory, ok := store.Get(id)
+	if !ok {
+		t.Fatalf("memory %s not found", id)
+	}
+	return memory
+}
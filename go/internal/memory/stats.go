@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"sync"
+)
+
+type statsEntry struct {
+	memoryType string
+	namespace  string
+	importance int
+	size       int
+}
+
+// storeStats keeps the numbers behind /stats up to date on every change, so
+// reading them neither scans the store nor takes the store lock.
+type storeStats struct {
+	entries     map[string]statsEntry
+	byType      map[string]int
+	byNamespace map[string]int
+	importance  int
+	bytes       int
+	mu          sync.Mutex
+}
+
+func newStoreStats() *storeStats {
+	return &storeStats{
+		entries:     make(map[string]statsEntry),
+		byType:      make(map[string]int),
+		byNamespace: make(map[string]int),
+	}
+}
+
+// estimateSize approximates the memory held by an entry.
+func estimateSize(memory *Memory) int {
+	size := len(memory.Content)
+	size += len(memory.ID) * 2 // ID stored twice
+	size += len(memory.Type)
+	for _, tag := range memory.Tags {
+		size += len(tag)
+	}
+	return size
+}
+
+func (st *storeStats) observe(change Change) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if previous, exists := st.entries[change.ID]; exists {
+		st.byType[previous.memoryType]--
+		if st.byType[previous.memoryType] == 0 {
+			delete(st.byType, previous.memoryType)
+		}
+		st.byNamespace[previous.namespace]--
+		if st.byNamespace[previous.namespace] == 0 {
+			delete(st.byNamespace, previous.namespace)
+		}
+		st.importance -= previous.importance
+		st.bytes -= previous.size
+		delete(st.entries, change.ID)
+	}
+	if change.Deleted {
+		return
+	}
+
+	entry := statsEntry{
+		memoryType: change.Memory.Type,
+		namespace:  namespaceOf(change.Memory),
+		importance: change.Memory.Importance,
+		size:       estimateSize(change.Memory),
+	}
+	st.entries[change.ID] = entry
+	st.byType[entry.memoryType]++
+	st.byNamespace[entry.namespace]++
+	st.importance += entry.importance
+	st.bytes += entry.size
+}
+
+func (st *storeStats) reset() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.entries = make(map[string]statsEntry)
+	st.byType = make(map[string]int)
+	st.byNamespace = make(map[string]int)
+	st.importance = 0
+	st.bytes = 0
+}
+
+func (st *storeStats) namespaceCount(namespace string) int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.byNamespace[namespace]
+}
+
+// totals returns the number of entries and their estimated size.
+func (st *storeStats) totals() (entries, bytes int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return len(st.entries), st.bytes
+}
+
+func (st *storeStats) snapshot() map[string]interface{} {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	typeCounts := make(map[string]int, len(st.byType))
+	for memoryType, count := range st.byType {
+		typeCounts[memoryType] = count
+	}
+	namespaceCounts := make(map[string]int, len(st.byNamespace))
+	for namespace, count := range st.byNamespace {
+		namespaceCounts[namespace] = count
+	}
+	avgImportance := 0.0
+	if len(st.entries) > 0 {
+		avgImportance = float64(st.importance) / float64(len(st.entries))
+	}
+
+	return map[string]interface{}{
+		"total":           len(st.entries),
+		"by_type":         typeCounts,
+		"by_namespace":    namespaceCounts,
+		"avg_importance":  avgImportance,
+		"storage_size_kb": st.bytes / 1024,
+	}
+}
@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestStoreStatsObserve(t *testing.T) {
+	st := newStoreStats()
+	steps := []struct {
+		name        string
+		change      Change
+		total       int
+		importance  int
+		byType      map[string]int
+		byNamespace map[string]int
+	}{
+		{
+			"add",
+			Change{ID: "a", Memory: &Memory{ID: "a", Type: "note", Importance: 4, Content: "Tee"}},
+			1, 4, map[string]int{"note": 1}, map[string]int{defaultNamespace: 1},
+		},
+		{
+			"add in namespace",
+			Change{ID: "b", Memory: &Memory{ID: "b", Type: "task", Namespace: "chat", Importance: 8}},
+			2, 12, map[string]int{"note": 1, "task": 1}, map[string]int{defaultNamespace: 1, "chat": 1},
+		},
+		{
+			"update moves counters",
+			Change{ID: "a", Memory: &Memory{ID: "a", Type: "task", Namespace: "chat", Importance: 6}},
+			2, 14, map[string]int{"task": 2}, map[string]int{"chat": 2},
+		},
+		{
+			"delete",
+			Change{ID: "b", Deleted: true, Memory: &Memory{ID: "b"}},
+			1, 6, map[string]int{"task": 1}, map[string]int{"chat": 1},
+		},
+		{
+			"delete unknown",
+			Change{ID: "x", Deleted: true, Memory: &Memory{ID: "x"}},
+			1, 6, map[string]int{"task": 1}, map[string]int{"chat": 1},
+		},
+	}
+	for _, step := range steps {
+		st.observe(step.change)
+		if len(st.entries) != step.total || st.importance != step.importance ||
+			!reflect.DeepEqual(st.byType, step.byType) || !reflect.DeepEqual(st.byNamespace, step.byNamespace) {
+			t.Errorf("%s: total %d importance %d types %v namespaces %v", step.name, len(st.entries), st.importance, st.byType, st.byNamespace)
+		}
+	}
+	if got := st.namespaceCount("chat"); got != 1 {
+		t.Errorf("namespaceCount = %d, want 1", got)
+	}
+
+	st.reset()
+	if entries, bytes := st.totals(); entries != 0 || bytes != 0 || len(st.byType) != 0 {
+		t.Errorf("after reset: %d entries, %d bytes, types %v", entries, bytes, st.byType)
+	}
+}
+
+// scanStats recomputes the counters the slow way.
+func scanStats(store *MemoryStore) (total int, byType map[string]int, bytes int) {
+	byType = map[string]int{}
+	for _, memory := range store.Dump() {
+		total++
+		byType[memory.Type]++
+		bytes += estimateSize(memory)
+	}
+	return total, byType, bytes
+}
+
+func TestStatsMatchScanUnderConcurrency(t *testing.T) {
+	store := NewMemoryStore(t.TempDir())
+	types := []string{"note", "task", "personal/music"}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				id := store.Add(&Memory{Content: fmt.Sprintf("w%d-%d", worker, i), Type: types[i%len(types)], Importance: i % 10})
+				switch i % 3 {
+				case 1:
+					store.Update(id, map[string]interface{}{"type": types[(i+1)%len(types)], "content": "geändert"})
+				case 2:
+					store.Delete(id)
+				}
+				store.GetStats()
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	total, byType, bytes := scanStats(store)
+	entries, counted := store.stats.totals()
+	if entries != total || counted != bytes || !reflect.DeepEqual(store.stats.byType, byType) {
+		t.Errorf("counters %d entries %d bytes %v, scan %d entries %d bytes %v", entries, counted, store.stats.byType, total, bytes, byType)
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	svc := newTestService(t, Config{})
+	addMemory(t, svc, map[string]interface{}{"content": "Tee", "type": "note", "importance": 4})
+	addMemory(t, svc, map[string]interface{}{"content": "Kaffee", "type": "note", "importance": 8, "namespace": "chat"})
+
+	var stats struct {
+		Total         int            `json:"total"`
+		ByType        map[string]int `json:"by_type"`
+		ByNamespace   map[string]int `json:"by_namespace"`
+		AvgImportance float64        `json:"avg_importance"`
+	}
+	decode(t, serve(svc, http.MethodGet, "/api/v1/memory/stats", nil), &stats)
+	if stats.Total != 2 || stats.ByType["note"] != 2 || stats.ByNamespace["chat"] != 1 || stats.AvgImportance != 6 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
@@ -0,0 +1,176 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	syncRetryDelay   = 10 * time.Second
+	databaseMemories = "/api/database/memories"
+	// syncMetaInterval is how often changes that only touched bookkeeping
+	// fields are sent; they don't wake the syncer on their own.
+	syncMetaInterval = time.Minute
+)
+
+// dbSyncer mirrors every change into the memories table of the database
+// service. Writes stay in memory first; the syncer catches up in the
+// background and retries while the database service is unreachable.
+type dbSyncer struct {
+	url    string
+	apiKey string
+	client *http.Client
+	logger *log.Logger
+
+	// pending holds the latest state per ID; nil means deleted.
+	pending map[string]*Memory
+	wake    chan struct{}
+	mu      sync.Mutex
+}
+
+func newDBSyncer(cfg Config, logger *log.Logger) *dbSyncer {
+	return &dbSyncer{
+		url:     strings.TrimRight(cfg.SyncURL, "/"),
+		apiKey:  cfg.SyncAPIKey,
+		client:  &http.Client{Timeout: 15 * time.Second},
+		logger:  logger,
+		pending: make(map[string]*Memory),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+func (d *dbSyncer) observe(change Change) {
+	var memory *Memory
+	if !change.Deleted && !change.Memory.Archived && !change.Memory.expired(time.Now()) {
+		copied := *change.Memory
+		memory = &copied
+	}
+
+	d.mu.Lock()
+	d.pending[change.ID] = memory
+	d.mu.Unlock()
+
+	if change.MetaOnly {
+		return
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// enqueueAll schedules every memory of store, e.g. to fill an empty table.
+func (d *dbSyncer) enqueueAll(store *MemoryStore) int {
+	memories := store.Dump()
+	for _, memory := range memories {
+		d.observe(Change{ID: memory.ID, Content: memory.Content, Memory: memory})
+	}
+	return len(memories)
+}
+
+func (d *dbSyncer) run() {
+	ticker := time.NewTicker(syncMetaInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.wake:
+		case <-ticker.C:
+		}
+		for {
+			d.mu.Lock()
+			var (
+				id     string
+				memory *Memory
+				found  bool
+			)
+			for id, memory = range d.pending {
+				delete(d.pending, id)
+				found = true
+				break
+			}
+			d.mu.Unlock()
+			if !found {
+				break
+			}
+
+			if err := d.send(id, memory); err != nil {
+				d.logger.Printf("[WARN] Datenbank-Sync für %s fehlgeschlagen: %v", id, err)
+				d.mu.Lock()
+				if _, newer := d.pending[id]; !newer {
+					d.pending[id] = memory
+				}
+				d.mu.Unlock()
+				time.Sleep(syncRetryDelay)
+			}
+		}
+	}
+}
+
+func (d *dbSyncer) send(id string, memory *Memory) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var req *http.Request
+	var err error
+	if memory == nil {
+		req, err = http.NewRequestWithContext(ctx, http.MethodDelete, d.url+databaseMemories+"/"+url.PathEscape(id), nil)
+	} else {
+		body, marshalErr := json.Marshal(map[string]interface{}{
+			"id":         memory.ID,
+			"content":    memory.Content,
+			"type":       memory.Type,
+			"tags":       memory.Tags,
+			"importance": min(max(memory.Importance, 1), 10),
+			"created_at": memory.CreatedAt,
+			"updated_at": memory.UpdatedAt,
+		})
+		if marshalErr != nil {
+			return marshalErr
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, d.url+databaseMemories, bytes.NewReader(body))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if d.apiKey != "" {
+		req.Header.Set("X-API-Key", d.apiKey)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("database service antwortete mit %s", resp.Status)
+	}
+	return nil
+}
+
+// syncHandler queues all memories for the database, e.g. after enabling the
+// sync on an existing store.
+func (s *Service) syncHandler(w http.ResponseWriter, _ *http.Request) {
+	if s.sync == nil {
+		http.Error(w, `{"error":"Database sync is not configured"}`, http.StatusServiceUnavailable)
+		return
+	}
+	queued := s.sync.enqueueAll(s.store)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"queued":  queued,
+	})
+}
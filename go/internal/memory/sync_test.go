@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type syncRequest struct {
+	method string
+	path   string
+	header http.Header
+	body   map[string]interface{}
+}
+
+// databaseStub records the requests the syncer sends and answers with status.
+func databaseStub(t *testing.T, status int) (*httptest.Server, chan syncRequest) {
+	t.Helper()
+	requests := make(chan syncRequest, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := syncRequest{method: r.Method, path: r.URL.EscapedPath(), header: r.Header.Clone()}
+		if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+			json.Unmarshal(data, &req.body)
+		}
+		requests <- req
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func receive(t *testing.T, requests chan syncRequest) syncRequest {
+	t.Helper()
+	select {
+	case req := <-requests:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("no request reached the database service")
+		return syncRequest{}
+	}
+}
+
+func TestSyncerObserve(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name    string
+		change  Change
+		mirror  bool
+		wakesUp bool
+	}{
+		{"write", Change{ID: "m1", Memory: &Memory{ID: "m1"}}, true, true},
+		{"delete", Change{ID: "m1", Deleted: true, Memory: &Memory{ID: "m1"}}, false, true},
+		{"archived", Change{ID: "m1", Memory: &Memory{ID: "m1", Archived: true}}, false, true},
+		{"expired", Change{ID: "m1", Memory: &Memory{ID: "m1", ExpiresAt: &past}}, false, true},
+		{"bookkeeping only", Change{ID: "m1", MetaOnly: true, Memory: &Memory{ID: "m1"}}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syncer := newDBSyncer(Config{SyncURL: "http://db"}, log.New(io.Discard, "", 0))
+			syncer.observe(tt.change)
+
+			memory, queued := syncer.pending["m1"]
+			if !queued || (memory != nil) != tt.mirror {
+				t.Errorf("pending = %v %v, want mirrored %v", memory, queued, tt.mirror)
+			}
+			if memory != nil && memory == tt.change.Memory {
+				t.Error("pending shares the store's memory")
+			}
+			if woken := len(syncer.wake) == 1; woken != tt.wakesUp {
+				t.Errorf("woken = %v, want %v", woken, tt.wakesUp)
+			}
+		})
+	}
+}
+
+func TestSyncerSend(t *testing.T) {
+	server, requests := databaseStub(t, http.StatusOK)
+	syncer := newDBSyncer(Config{SyncURL: server.URL + "/", SyncAPIKey: "db-key"}, log.New(io.Discard, "", 0))
+
+	if err := syncer.send("m1", &Memory{ID: "m1", Content: "Tee", Type: "note", Importance: 15}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	req := receive(t, requests)
+	if req.method != http.MethodPost || req.path != databaseMemories || req.header.Get("X-API-Key") != "db-key" {
+		t.Errorf("upsert = %s %s key %q", req.method, req.path, req.header.Get("X-API-Key"))
+	}
+	if req.body["content"] != "Tee" || req.body["importance"] != float64(10) {
+		t.Errorf("upsert body = %v", req.body)
+	}
+
+	if err := syncer.send("a/b", nil); err != nil {
+		t.Fatalf("send delete: %v", err)
+	}
+	if req := receive(t, requests); req.method != http.MethodDelete || req.path != databaseMemories+"/a%2Fb" {
+		t.Errorf("delete = %s %s", req.method, req.path)
+	}
+
+	failing, _ := databaseStub(t, http.StatusInternalServerError)
+	syncer.url = failing.URL
+	if err := syncer.send("m1", nil); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("send to failing service error = %v", err)
+	}
+}
+
+func TestServiceMirrorsWrites(t *testing.T) {
+	server, requests := databaseStub(t, http.StatusOK)
+	svc := newTestService(t, Config{SyncURL: server.URL})
+
+	id := addMemory(t, svc, map[string]interface{}{"content": "Tee"})
+	if req := receive(t, requests); req.method != http.MethodPost || req.body["id"] != id {
+		t.Errorf("after add: %s %v", req.method, req.body)
+	}
+	serve(svc, http.MethodDelete, "/api/v1/memory/memories/"+id, nil)
+	if req := receive(t, requests); req.method != http.MethodDelete || !strings.HasSuffix(req.path, "/"+id) {
+		t.Errorf("after delete: %s %s", req.method, req.path)
+	}
+
+	addMemory(t, svc, map[string]interface{}{"content": "Kaffee"})
+	receive(t, requests)
+	var body struct {
+		Queued int `json:"queued"`
+	}
+	decode(t, serve(svc, http.MethodPost, "/api/v1/memory/storage/sync", nil), &body)
+	if body.Queued != 1 {
+		t.Errorf("queued = %d, want 1", body.Queued)
+	}
+	receive(t, requests)
+}
+
+func TestSyncHandlerWithoutSync(t *testing.T) {
+	svc := newTestService(t, Config{})
+	if rec := serve(svc, http.MethodPost, "/api/v1/memory/storage/sync", nil); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status %d, want 503", rec.Code)
+	}
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const defaultMostUsedLimit = 10
+
+// RecordHits counts memories returned by a search as accessed. Unlike Touch
+// it doesn't raise their importance. The entries of memories are replaced
+// with copies of the updated memories, safe to use after the store lock is
+// released.
+func (s *MemoryStore) RecordHits(memories []*Memory, now time.Time) {
+	if len(memories) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, result := range memories {
+		memory, exists := s.memories[result.ID]
+		if !exists {
+			continue
+		}
+		accessed := now
+		memory.LastAccessed = &accessed
+		memory.Hits++
+		s.notifyMetaLocked(memory)
+		copied := *memory
+		memories[i] = &copied
+	}
+}
+
+// usageScore favours memories that are used often and were used recently.
+// It is added to the relevance score, so it mostly breaks ties between
+// similarly good text matches.
+func usageScore(memory *Memory, now time.Time) int {
+	score := int(math.Log2(float64(1+memory.Hits)) * 4)
+	if memory.LastAccessed != nil {
+		switch since := now.Sub(*memory.LastAccessed); {
+		case since < 24*time.Hour:
+			score += 6
+		case since < 7*24*time.Hour:
+			score += 3
+		}
+	}
+	return score
+}
+
+// MostUsed returns the limit memories with the most hits, most recently
+// accessed first on ties. Archived and expired memories are left out. The
+// results are copies, safe to use after the store lock is released.
+func (s *MemoryStore) MostUsed(limit int) []*Memory {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	results := []*Memory{}
+	for _, memory := range s.memories {
+		if memory.Hits > 0 && !memory.Archived && !memory.expired(now) {
+			copied := *memory
+			results = append(results, &copied)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Hits != b.Hits {
+			return a.Hits > b.Hits
+		}
+		return a.lastTouched().After(b.lastTouched())
+	})
+	return results[:min(limit, len(results))]
+}
+
+func (s *Service) mostUsedHandler(w http.ResponseWriter, r *http.Request) {
+	limit := defaultMostUsedLimit
+	if value, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && value > 0 {
+		limit = min(value, s.cfg.MaxLimit)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.store.MostUsed(limit))
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestUsageScore(t *testing.T) {
+	now := time.Now()
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+	tests := []struct {
+		name   string
+		memory Memory
+		want   int
+	}{
+		{"unused", Memory{}, 0},
+		{"one hit long ago", Memory{Hits: 1, LastAccessed: ago(30 * 24 * time.Hour)}, 4},
+		{"three hits this week", Memory{Hits: 3, LastAccessed: ago(3 * 24 * time.Hour)}, 11},
+		{"seven hits today", Memory{Hits: 7, LastAccessed: ago(time.Hour)}, 18},
+	}
+	for _, tt := range tests {
+		if got := usageScore(&tt.memory, now); got != tt.want {
+			t.Errorf("%s: usageScore = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRelevanceBlendsUsage(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-time.Hour)
+	used := &Memory{ID: "used", Content: "notiz zum tee", Hits: 15, LastAccessed: &recent}
+	unused := &Memory{ID: "unused", Content: "notiz zum tee"}
+	better := &Memory{ID: "better", Content: "tee, tee und tee", Tags: []string{"tee"}}
+
+	terms := []string{"tee"}
+	if relevance(used, terms, now) <= relevance(unused, terms, now) {
+		t.Error("usage does not break the tie between equal matches")
+	}
+	if relevance(better, terms, now) <= relevance(used, terms, now) {
+		t.Error("usage outweighs a clearly better text match")
+	}
+}
+
+func TestRecordHits(t *testing.T) {
+	store := NewMemoryStore(t.TempDir())
+	id := store.Add(&Memory{Content: "Tee", Importance: 5})
+	now := time.Now()
+
+	results := []*Memory{{ID: id}, {ID: "missing"}}
+	store.RecordHits(results, now)
+	store.RecordHits(results[:1], now)
+
+	memory := mustGet(t, store, id)
+	if memory.Hits != 2 || !memory.LastAccessed.Equal(now) || memory.Importance != 5 {
+		t.Errorf("memory after hits = %+v", memory)
+	}
+	if results[0].Hits != 2 || results[1].Hits != 0 {
+		t.Errorf("results not replaced with updated copies: %+v %+v", results[0], results[1])
+	}
+	results[0].Hits = 99
+	if mustGet(t, store, id).Hits != 2 {
+		t.Error("result shares the stored memory")
+	}
+}
+
+func TestMostUsed(t *testing.T) {
+	svc := newTestService(t, Config{MaxLimit: 3})
+	ids := map[string]string{}
+	for _, content := range []string{"selten", "oft", "mittel", "archiviert", "nie", "auch mittel"} {
+		ids[content] = addMemory(t, svc, map[string]interface{}{"content": content})
+	}
+	now := time.Now()
+	hits := map[string]int{"selten": 1, "oft": 5, "mittel": 3, "archiviert": 9, "auch mittel": 3}
+	for content, count := range hits {
+		at := now
+		if content == "auch mittel" {
+			at = now.Add(time.Minute)
+		}
+		for i := 0; i < count; i++ {
+			svc.store.RecordHits([]*Memory{{ID: ids[content]}}, at)
+		}
+	}
+	svc.store.SetFlag(ids["archiviert"], flagArchived, true)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"oft", "auch mittel", "mittel", "selten"}},
+		{"?limit=2", []string{"oft", "auch mittel"}},
+		{"?limit=50", []string{"oft", "auch mittel", "mittel"}},
+		{"?limit=abc", []string{"oft", "auch mittel", "mittel", "selten"}},
+	}
+	for _, tt := range tests {
+		var results []Memory
+		decode(t, serve(svc, http.MethodGet, "/api/v1/memory/stats/most-used"+tt.query, nil), &results)
+		var got []string
+		for _, memory := range results {
+			got = append(got, memory.Content)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%q: %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%q: %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestSearchRecordsHits(t *testing.T) {
+	svc := newTestService(t, Config{})
+	id := addMemory(t, svc, map[string]interface{}{"content": "Tee"})
+	addMemory(t, svc, map[string]interface{}{"content": "Kaffee"})
+
+	serve(svc, http.MethodGet, "/api/v1/memory/search?query=tee", nil)
+	serve(svc, http.MethodGet, "/api/v1/memory/search?query=tee", nil)
+	if memory := mustGet(t, svc.store, id); memory.Hits != 2 || memory.LastAccessed == nil {
+		t.Errorf("after two searches: hits %d, last accessed %v", memory.Hits, memory.LastAccessed)
+	}
+}
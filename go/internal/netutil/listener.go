@@ -0,0 +1,117 @@
+// Package netutil contains listener helpers shared by the Go services.
+package netutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// TLSConfig describes the optional (mutual) TLS setup of a service listener.
+type TLSConfig struct {
+	CertFile          string
+	KeyFile           string
+	ClientCAFile      string
+	RequireClientCert bool
+}
+
+// LoadTLSConfig reads <PREFIX>_TLS_CERT, <PREFIX>_TLS_KEY, <PREFIX>_TLS_CLIENT_CA
+// and <PREFIX>_TLS_REQUIRE_CLIENT_CERT from the environment.
+func LoadTLSConfig(prefix string) TLSConfig {
+	cfg := TLSConfig{
+		CertFile:     strings.TrimSpace(os.Getenv(prefix + "_TLS_CERT")),
+		KeyFile:      strings.TrimSpace(os.Getenv(prefix + "_TLS_KEY")),
+		ClientCAFile: strings.TrimSpace(os.Getenv(prefix + "_TLS_CLIENT_CA")),
+	}
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(prefix + "_TLS_REQUIRE_CLIENT_CERT"))) {
+	case "1", "true", "yes":
+		cfg.RequireClientCert = true
+	}
+	return cfg
+}
+
+// Enabled reports whether a server certificate is configured.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// ServerConfig builds the tls.Config for a listener. Client certificates are
+// verified against ClientCAFile when it is set; RequireClientCert rejects
+// connections without one.
+func (c TLSConfig) ServerConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("TLS-Zertifikat konnte nicht geladen werden: %w", err)
+	}
+	tlsCfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.ClientCAFile != "" {
+		pool, err := loadCertPool(c.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+		if c.RequireClientCert {
+			tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	} else if c.RequireClientCert {
+		return nil, fmt.Errorf("Client-Zertifikate verlangt, aber keine Client-CA konfiguriert")
+	}
+	return tlsCfg, nil
+}
+
+// ClientConfig builds a tls.Config for internal callers presenting a client
+// certificate and trusting the given CA for the server certificate.
+func ClientConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if certFile != "" && keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Client-Zertifikat konnte nicht geladen werden: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.RootCAs = pool
+	}
+	return tlsCfg, nil
+}
+
+// Listen opens a TCP listener on addr, wrapped in TLS when configured.
+func Listen(addr string, cfg TLSConfig) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Enabled() {
+		return listener, nil
+	}
+	tlsCfg, err := cfg.ServerConfig()
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return tls.NewListener(listener, tlsCfg), nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("CA-Datei konnte nicht gelesen werden: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(raw) {
+		return nil, fmt.Errorf("CA-Datei enthält keine gültigen Zertifikate: %s", path)
+	}
+	return pool, nil
+}
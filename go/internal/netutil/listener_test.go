@@ -0,0 +1,156 @@
+package netutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// issue creates a certificate for name signed by parent (self-signed if nil)
+// and writes it and its key as PEM files to dir.
+func issue(t *testing.T, dir, name string, parent *testCert, isCA bool) (*testCert, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return &testCert{cert: cert, key: key}, certFile, keyFile
+}
+
+func TestServerConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca, caFile, _ := issue(t, dir, "ca", nil, true)
+	_, certFile, keyFile := issue(t, dir, "server", ca, false)
+
+	tests := []struct {
+		name    string
+		cfg     TLSConfig
+		auth    tls.ClientAuthType
+		wantErr bool
+	}{
+		{"server only", TLSConfig{CertFile: certFile, KeyFile: keyFile}, tls.NoClientCert, false},
+		{"optional client cert", TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile}, tls.VerifyClientCertIfGiven, false},
+		{"required client cert", TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, RequireClientCert: true}, tls.RequireAndVerifyClientCert, false},
+		{"required without CA", TLSConfig{CertFile: certFile, KeyFile: keyFile, RequireClientCert: true}, 0, true},
+		{"missing certificate", TLSConfig{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: keyFile}, 0, true},
+		{"CA file without certificates", TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsCfg, err := tt.cfg.ServerConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tlsCfg.ClientAuth != tt.auth {
+				t.Errorf("ClientAuth = %v, want %v", tlsCfg.ClientAuth, tt.auth)
+			}
+		})
+	}
+}
+
+func TestListenMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caFile, _ := issue(t, dir, "ca", nil, true)
+	_, certFile, keyFile := issue(t, dir, "server", ca, false)
+	_, clientCert, clientKey := issue(t, dir, "memoryd", ca, false)
+	_, rogueCert, rogueKey := issue(t, dir, "rogue", nil, false)
+
+	listener, err := Listen("127.0.0.1:0", TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, RequireClientCert: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	peers := make(chan string, 8)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			tlsConn := conn.(*tls.Conn)
+			peer := ""
+			if err := tlsConn.Handshake(); err == nil {
+				peer = tlsConn.ConnectionState().PeerCertificates[0].Subject.CommonName
+			}
+			conn.Close()
+			peers <- peer
+		}
+	}()
+
+	tests := []struct {
+		name     string
+		cert     string
+		key      string
+		wantPeer string
+	}{
+		{"client certificate", clientCert, clientKey, "memoryd"},
+		{"no client certificate", "", "", ""},
+		{"certificate of another CA", rogueCert, rogueKey, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientCfg, err := ClientConfig(tt.cert, tt.key, caFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn, err := tls.Dial("tcp", listener.Addr().String(), clientCfg)
+			if err == nil {
+				// TLS 1.3 reports a rejected client certificate on the first read.
+				_, err = conn.Read(make([]byte, 1))
+				conn.Close()
+			}
+			select {
+			case peer := <-peers:
+				if peer != tt.wantPeer {
+					t.Errorf("server saw peer %q, want %q", peer, tt.wantPeer)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("server did not finish the handshake (client error %v)", err)
+			}
+		})
+	}
+}
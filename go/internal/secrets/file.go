@@ -0,0 +1,93 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// FileSource reads a JSON object of secrets from a file encrypted with
+// AES-256-GCM. The file holds base64(nonce || ciphertext); Key is either a
+// base64 encoded 32 byte key or a passphrase that is hashed with SHA-256.
+type FileSource struct {
+	Path string
+	Key  string
+}
+
+func (f *FileSource) Fetch(context.Context) (map[string]string, error) {
+	raw, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("Secret-Datei konnte nicht gelesen werden: %w", err)
+	}
+	plaintext, err := Decrypt(f.Key, raw)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, fmt.Errorf("Secret-Datei enthält kein gültiges JSON: %w", err)
+	}
+	return values, nil
+}
+
+func deriveKey(key string) []byte {
+	if decoded, err := base64.StdEncoding.DecodeString(key); err == nil && len(decoded) == 32 {
+		return decoded
+	}
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+func newGCM(key string) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(deriveKey(key))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt produces the file format read by FileSource.
+func Encrypt(key string, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(sealed)))
+	base64.StdEncoding.Encode(encoded, sealed)
+	return encoded, nil
+}
+
+// Decrypt reverses Encrypt.
+func Decrypt(key string, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed := make([]byte, base64.StdEncoding.DecodedLen(len(data)))
+	n, err := base64.StdEncoding.Decode(sealed, bytes.TrimSpace(data))
+	if err != nil {
+		return nil, fmt.Errorf("Secret-Datei ist nicht base64-kodiert: %w", err)
+	}
+	sealed = sealed[:n]
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("Secret-Datei ist zu kurz")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Secret-Datei konnte nicht entschlüsselt werden: %w", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,98 @@
+package secrets
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		t.Fatal(err)
+	}
+	rawKey := base64.StdEncoding.EncodeToString(raw)
+
+	tests := []struct {
+		name       string
+		key        string
+		decryptKey string
+		tamper     func([]byte) []byte
+		wantErr    string
+	}{
+		{name: "passphrase", key: "correct horse", decryptKey: "correct horse"},
+		{name: "raw key", key: rawKey, decryptKey: rawKey},
+		{name: "wrong key", key: "correct horse", decryptKey: "battery staple", wantErr: "entschlüsselt"},
+		{name: "tampered", key: "k", decryptKey: "k", tamper: flipLastByte, wantErr: "entschlüsselt"},
+		{name: "not base64", key: "k", decryptKey: "k", tamper: func([]byte) []byte { return []byte("%%%") }, wantErr: "base64"},
+		{name: "too short", key: "k", decryptKey: "k", tamper: func([]byte) []byte { return []byte("AAAA") }, wantErr: "zu kurz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext := []byte(`{"JARVIS_API_KEY":"s3cret"}`)
+			sealed, err := Encrypt(tt.key, plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if tt.tamper != nil {
+				sealed = tt.tamper(sealed)
+			}
+			opened, err := Decrypt(tt.decryptKey, append(sealed, '\n'))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decrypt: %v", err)
+			}
+			if string(opened) != string(plaintext) {
+				t.Errorf("plaintext = %q, want %q", opened, plaintext)
+			}
+		})
+	}
+}
+
+func flipLastByte(sealed []byte) []byte {
+	decoded, _ := base64.StdEncoding.DecodeString(string(sealed))
+	decoded[len(decoded)-1] ^= 0xff
+	return []byte(base64.StdEncoding.EncodeToString(decoded))
+}
+
+func TestEncryptUsesFreshNonce(t *testing.T) {
+	first, _ := Encrypt("k", []byte("same"))
+	second, _ := Encrypt("k", []byte("same"))
+	if string(first) == string(second) {
+		t.Error("two encryptions of the same plaintext are identical")
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	sealed, err := Encrypt("passphrase", []byte(`{"JARVIS_API_KEY":"s3cret","JARVIS_JWT_SECRET":"jwt"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	if err := os.WriteFile(path, sealed, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := (&FileSource{Path: path, Key: "passphrase"}).Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	want := map[string]string{"JARVIS_API_KEY": "s3cret", "JARVIS_JWT_SECRET": "jwt"}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %v, want %v", values, want)
+	}
+
+	if _, err := (&FileSource{Path: filepath.Join(t.TempDir(), "missing"), Key: "passphrase"}).Fetch(context.Background()); err == nil {
+		t.Error("missing file did not fail")
+	}
+}
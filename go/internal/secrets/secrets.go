@@ -0,0 +1,210 @@
+// Package secrets loads service secrets from the environment, an encrypted
+// local file, HashiCorp Vault or AWS Secrets Manager, and re-reads them
+// periodically so secrets can be rotated without a restart.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultRefreshInterval = 5 * time.Minute
+
+// Source names.
+const (
+	SourceEnv   = "env"
+	SourceFile  = "file"
+	SourceVault = "vault"
+	SourceAWS   = "aws"
+)
+
+// Source returns all secrets it knows as name/value pairs.
+type Source interface {
+	Fetch(ctx context.Context) (map[string]string, error)
+}
+
+type Config struct {
+	Source          string
+	RefreshInterval time.Duration
+
+	// Encrypted file
+	File    string
+	FileKey string
+
+	// HashiCorp Vault (KV v1 or v2)
+	VaultAddr  string
+	VaultToken string
+	VaultPath  string
+
+	// AWS Secrets Manager
+	AWSRegion   string
+	AWSSecretID string
+}
+
+// LoadConfig reads JARVIS_SECRETS_SOURCE and the source specific variables.
+func LoadConfig() Config {
+	cfg := Config{
+		Source:          SourceEnv,
+		RefreshInterval: defaultRefreshInterval,
+		File:            strings.TrimSpace(os.Getenv("JARVIS_SECRETS_FILE")),
+		FileKey:         strings.TrimSpace(os.Getenv("JARVIS_SECRETS_KEY")),
+		VaultAddr:       strings.TrimRight(strings.TrimSpace(os.Getenv("VAULT_ADDR")), "/"),
+		VaultToken:      strings.TrimSpace(os.Getenv("VAULT_TOKEN")),
+		VaultPath:       strings.Trim(strings.TrimSpace(os.Getenv("JARVIS_VAULT_PATH")), "/"),
+		AWSRegion:       strings.TrimSpace(os.Getenv("AWS_REGION")),
+		AWSSecretID:     strings.TrimSpace(os.Getenv("JARVIS_AWS_SECRET_ID")),
+	}
+
+	if value := strings.ToLower(strings.TrimSpace(os.Getenv("JARVIS_SECRETS_SOURCE"))); value != "" {
+		cfg.Source = value
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_SECRETS_REFRESH")); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			cfg.RefreshInterval = parsed
+		}
+	}
+	if cfg.AWSRegion == "" {
+		cfg.AWSRegion = strings.TrimSpace(os.Getenv("AWS_DEFAULT_REGION"))
+	}
+
+	return cfg
+}
+
+// External reports whether secrets come from somewhere other than the environment.
+func (c Config) External() bool {
+	return c.Source != "" && c.Source != SourceEnv
+}
+
+// NewSource builds the Source selected by cfg.
+func NewSource(cfg Config) (Source, error) {
+	switch cfg.Source {
+	case "", SourceEnv:
+		return envSource{}, nil
+	case SourceFile:
+		if cfg.File == "" || cfg.FileKey == "" {
+			return nil, fmt.Errorf("JARVIS_SECRETS_FILE und JARVIS_SECRETS_KEY müssen gesetzt sein")
+		}
+		return &FileSource{Path: cfg.File, Key: cfg.FileKey}, nil
+	case SourceVault:
+		if cfg.VaultAddr == "" || cfg.VaultToken == "" || cfg.VaultPath == "" {
+			return nil, fmt.Errorf("VAULT_ADDR, VAULT_TOKEN und JARVIS_VAULT_PATH müssen gesetzt sein")
+		}
+		return &VaultSource{Addr: cfg.VaultAddr, Token: cfg.VaultToken, Path: cfg.VaultPath}, nil
+	case SourceAWS:
+		if cfg.AWSRegion == "" || cfg.AWSSecretID == "" {
+			return nil, fmt.Errorf("AWS_REGION und JARVIS_AWS_SECRET_ID müssen gesetzt sein")
+		}
+		return NewAWSSource(cfg.AWSRegion, cfg.AWSSecretID)
+	default:
+		return nil, fmt.Errorf("unbekannte Secret-Quelle: %q", cfg.Source)
+	}
+}
+
+type envSource struct{}
+
+func (envSource) Fetch(context.Context) (map[string]string, error) {
+	values := map[string]string{}
+	for _, entry := range os.Environ() {
+		if name, value, ok := strings.Cut(entry, "="); ok && strings.HasPrefix(name, "JARVIS_") {
+			values[name] = value
+		}
+	}
+	return values, nil
+}
+
+// Manager keeps the current secrets and notifies subscribers on changes.
+type Manager struct {
+	source   Source
+	interval time.Duration
+	logger   *log.Logger
+	values   map[string]string
+	watchers []func(name, value string)
+	mu       sync.RWMutex
+}
+
+// NewManager creates a manager and performs the initial fetch.
+func NewManager(cfg Config, logger *log.Logger) (*Manager, error) {
+	source, err := NewSource(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if logger == nil {
+		logger = log.New(os.Stdout, "[secrets] ", log.LstdFlags|log.LUTC)
+	}
+	m := &Manager{source: source, interval: cfg.RefreshInterval, logger: logger, values: map[string]string{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := m.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Get returns the current value of a secret.
+func (m *Manager) Get(name string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.values[name]
+}
+
+// Watch registers fn to be called whenever a secret changes.
+func (m *Manager) Watch(fn func(name, value string)) {
+	m.mu.Lock()
+	m.watchers = append(m.watchers, fn)
+	m.mu.Unlock()
+}
+
+// Refresh re-reads the source and notifies watchers about changed values.
+func (m *Manager) Refresh(ctx context.Context) error {
+	values, err := m.source.Fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	changed := map[string]string{}
+	for name, value := range values {
+		if old, ok := m.values[name]; !ok || old != value {
+			changed[name] = value
+		}
+	}
+	m.values = values
+	watchers := append([]func(string, string){}, m.watchers...)
+	m.mu.Unlock()
+
+	for name, value := range changed {
+		for _, fn := range watchers {
+			fn(name, value)
+		}
+	}
+	return nil
+}
+
+// Start re-reads the secrets every RefreshInterval until stop is closed.
+func (m *Manager) Start(stop <-chan struct{}) {
+	if m.interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+				if err := m.Refresh(ctx); err != nil {
+					m.logger.Printf("[WARN] Secrets konnten nicht aktualisiert werden: %v", err)
+				}
+				cancel()
+			}
+		}
+	}()
+}
@@ -0,0 +1,119 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestNewSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "default env", cfg: Config{}},
+		{name: "env", cfg: Config{Source: SourceEnv}},
+		{name: "file", cfg: Config{Source: SourceFile, File: "secrets.enc", FileKey: "k"}},
+		{name: "file without key", cfg: Config{Source: SourceFile, File: "secrets.enc"}, wantErr: true},
+		{name: "vault", cfg: Config{Source: SourceVault, VaultAddr: "http://vault", VaultToken: "t", VaultPath: "secret/jarvis"}},
+		{name: "vault without token", cfg: Config{Source: SourceVault, VaultAddr: "http://vault", VaultPath: "secret/jarvis"}, wantErr: true},
+		{name: "aws without secret id", cfg: Config{Source: SourceAWS, AWSRegion: "eu-central-1"}, wantErr: true},
+		{name: "unknown", cfg: Config{Source: "consul"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSource(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("JARVIS_SECRETS_SOURCE", " Vault ")
+	t.Setenv("JARVIS_SECRETS_REFRESH", "30s")
+	t.Setenv("VAULT_ADDR", "http://vault:8200/")
+	t.Setenv("JARVIS_VAULT_PATH", "/secret/data/jarvis/")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "eu-west-1")
+
+	cfg := LoadConfig()
+	if cfg.Source != SourceVault || !cfg.External() {
+		t.Errorf("Source = %q, External = %v", cfg.Source, cfg.External())
+	}
+	if cfg.RefreshInterval.String() != "30s" {
+		t.Errorf("RefreshInterval = %v", cfg.RefreshInterval)
+	}
+	if cfg.VaultAddr != "http://vault:8200" || cfg.VaultPath != "secret/data/jarvis" {
+		t.Errorf("VaultAddr = %q, VaultPath = %q", cfg.VaultAddr, cfg.VaultPath)
+	}
+	if cfg.AWSRegion != "eu-west-1" {
+		t.Errorf("AWSRegion = %q", cfg.AWSRegion)
+	}
+}
+
+func TestEnvSource(t *testing.T) {
+	t.Setenv("JARVIS_TEST_SECRET", "value")
+	t.Setenv("OTHER_TEST_SECRET", "ignored")
+
+	values, err := envSource{}.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["JARVIS_TEST_SECRET"] != "value" {
+		t.Errorf("JARVIS_TEST_SECRET = %q", values["JARVIS_TEST_SECRET"])
+	}
+	if _, ok := values["OTHER_TEST_SECRET"]; ok {
+		t.Error("non-JARVIS variable was returned")
+	}
+}
+
+type fakeSource struct {
+	values map[string]string
+	err    error
+}
+
+func (f *fakeSource) Fetch(context.Context) (map[string]string, error) {
+	return f.values, f.err
+}
+
+func TestManagerRefresh(t *testing.T) {
+	source := &fakeSource{values: map[string]string{"A": "1", "B": "2"}}
+	m := &Manager{source: source, logger: log.New(io.Discard, "", 0), values: map[string]string{}}
+
+	var changed []string
+	m.Watch(func(name, value string) { changed = append(changed, name+"="+value) })
+
+	steps := []struct {
+		values  map[string]string
+		err     error
+		changed []string
+		a       string
+	}{
+		{values: map[string]string{"A": "1", "B": "2"}, changed: []string{"A=1", "B=2"}, a: "1"},
+		{values: map[string]string{"A": "1", "B": "2"}, a: "1"},
+		{values: map[string]string{"A": "rotated", "B": "2"}, changed: []string{"A=rotated"}, a: "rotated"},
+		{err: errors.New("unreachable"), a: "rotated"},
+	}
+	for i, step := range steps {
+		changed = nil
+		source.values, source.err = step.values, step.err
+		err := m.Refresh(context.Background())
+		if (err != nil) != (step.err != nil) {
+			t.Fatalf("step %d: err = %v", i, err)
+		}
+		sort.Strings(changed)
+		if !reflect.DeepEqual(changed, step.changed) {
+			t.Errorf("step %d: changed = %v, want %v", i, changed, step.changed)
+		}
+		if got := m.Get("A"); got != step.a {
+			t.Errorf("step %d: Get(A) = %q, want %q", i, got, step.a)
+		}
+	}
+}
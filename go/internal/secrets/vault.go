@@ -0,0 +1,69 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// VaultSource reads a KV secret from HashiCorp Vault. Both KV v1
+// ("secret/jarvis") and KV v2 ("secret/data/jarvis") paths are supported.
+type VaultSource struct {
+	Addr   string
+	Token  string
+	Path   string
+	Client *http.Client
+}
+
+func (v *VaultSource) Fetch(ctx context.Context) (map[string]string, error) {
+	client := v.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/v1/%s", v.Addr, v.Path), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Vault nicht erreichbar: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Vault antwortete mit Status %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("ungültige Vault-Antwort: %w", err)
+	}
+
+	data := payload.Data
+	if nested, ok := data["data"]; ok {
+		var inner map[string]json.RawMessage
+		if err := json.Unmarshal(nested, &inner); err == nil {
+			data = inner
+		}
+	}
+	return stringValues(data), nil
+}
+
+// stringValues keeps string values as-is and re-encodes everything else as JSON.
+func stringValues(data map[string]json.RawMessage) map[string]string {
+	values := make(map[string]string, len(data))
+	for name, raw := range data {
+		var value string
+		if err := json.Unmarshal(raw, &value); err == nil {
+			values[name] = value
+		} else {
+			values[name] = string(raw)
+		}
+	}
+	return values
+}
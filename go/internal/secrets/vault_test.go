@@ -0,0 +1,66 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestVaultSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		status  int
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "kv v1",
+			path: "secret/jarvis",
+			body: `{"data":{"JARVIS_API_KEY":"v1","JARVIS_PORT":8080}}`,
+			want: map[string]string{"JARVIS_API_KEY": "v1", "JARVIS_PORT": "8080"},
+		},
+		{
+			name: "kv v2",
+			path: "secret/data/jarvis",
+			body: `{"data":{"data":{"JARVIS_API_KEY":"v2"},"metadata":{"version":3}}}`,
+			want: map[string]string{"JARVIS_API_KEY": "v2"},
+		},
+		{name: "forbidden", path: "secret/jarvis", status: http.StatusForbidden, wantErr: true},
+		{name: "invalid json", path: "secret/jarvis", body: `{`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/"+tt.path || r.Header.Get("X-Vault-Token") != "root" {
+					http.Error(w, "unexpected request", http.StatusBadRequest)
+					return
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			source := &VaultSource{Addr: server.URL, Token: "root", Path: tt.path}
+			values, err := source.Fetch(context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("values = %v, want error", values)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if !reflect.DeepEqual(values, tt.want) {
+				t.Errorf("values = %v, want %v", values, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	alertQueueSize   = 64
+	alertTimeout     = 5 * time.Second
+	alertEventType   = "security_alert"
+	alertSignatureV1 = "sha256="
+)
+
+type alertEvent struct {
+	Type      string     `json:"type"`
+	Timestamp float64    `json:"timestamp"`
+	Payload   AuditEntry `json:"payload"`
+}
+
+// alertPublisher reports critical detections: as a security_alert event to
+// gatewayd (POST /api/events), which broadcasts it to the desktop app, and
+// to every configured webhook. Webhooks receive the same JSON, signed with
+// HMAC-SHA256 in X-Jarvis-Signature if a secret is set. Alerts are sent in
+// the background and dropped while the queue is full.
+type alertPublisher struct {
+	gatewayURL   string
+	gatewayToken string
+	webhooks     []string
+	secret       string
+	client       *http.Client
+	logger       *log.Logger
+	queue        chan alertEvent
+}
+
+// newAlertPublisher returns nil if no target is configured.
+func newAlertPublisher(cfg Config, logger *log.Logger) *alertPublisher {
+	if cfg.GatewayURL == "" && len(cfg.AlertWebhooks) == 0 {
+		return nil
+	}
+	p := &alertPublisher{
+		gatewayToken: cfg.GatewayToken,
+		webhooks:     cfg.AlertWebhooks,
+		secret:       cfg.AlertWebhookSecret,
+		client:       &http.Client{Timeout: alertTimeout},
+		logger:       logger,
+		queue:        make(chan alertEvent, alertQueueSize),
+	}
+	if cfg.GatewayURL != "" {
+		p.gatewayURL = strings.TrimRight(cfg.GatewayURL, "/") + "/api/events"
+	}
+	go p.run()
+	return p
+}
+
+// publish queues an alert. A nil publisher discards it.
+func (p *alertPublisher) publish(entry AuditEntry) {
+	if p == nil {
+		return
+	}
+	select {
+	case p.queue <- alertEvent{Type: alertEventType, Timestamp: float64(entry.Time.UnixNano()) / 1e9, Payload: entry}:
+	default:
+		p.logger.Printf("[WARN] Sicherheitsalarm verworfen: Warteschlange voll")
+	}
+}
+
+func (p *alertPublisher) run() {
+	for event := range p.queue {
+		body, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		if p.gatewayURL != "" {
+			p.send(p.gatewayURL, body, func(req *http.Request) {
+				if p.gatewayToken != "" {
+					req.Header.Set("X-API-Key", p.gatewayToken)
+				}
+			})
+		}
+		for _, webhook := range p.webhooks {
+			p.send(webhook, body, func(req *http.Request) {
+				if p.secret != "" {
+					req.Header.Set("X-Jarvis-Signature", alertSignatureV1+signAlert(p.secret, body))
+				}
+			})
+		}
+	}
+}
+
+func (p *alertPublisher) send(target string, body []byte, prepare func(*http.Request)) {
+	ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		p.logger.Printf("[WARN] Ungültiges Alarmziel %s: %v", target, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	prepare(req)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		p.logger.Printf("[WARN] Sicherheitsalarm an %s fehlgeschlagen: %v", target, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		p.logger.Printf("[WARN] Sicherheitsalarm an %s fehlgeschlagen: %d", target, resp.StatusCode)
+	}
+}
+
+func signAlert(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
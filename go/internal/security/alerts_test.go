@@ -0,0 +1,144 @@
+package security
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type receivedAlert struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// alertReceiver collects the alerts posted to it.
+func alertReceiver(t *testing.T) (*httptest.Server, chan receivedAlert) {
+	t.Helper()
+	received := make(chan receivedAlert, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedAlert{path: r.URL.Path, header: r.Header.Clone(), body: body}
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func nextAlert(t *testing.T, received chan receivedAlert) receivedAlert {
+	t.Helper()
+	select {
+	case alert := <-received:
+		return alert
+	case <-time.After(2 * time.Second):
+		t.Fatal("no alert received")
+		return receivedAlert{}
+	}
+}
+
+func TestAlertTargets(t *testing.T) {
+	server, received := alertReceiver(t)
+	entry := AuditEntry{Time: time.Unix(1700000000, 0).UTC(), InputHash: "abc", MatchedRules: []string{"prompt-override"}, Severity: "critical"}
+
+	tests := []struct {
+		name   string
+		cfg    Config
+		path   string
+		header string
+		value  func(body []byte) string
+	}{
+		{"gateway with token", Config{GatewayURL: server.URL + "/", GatewayToken: "geheim"}, "/api/events", "X-Api-Key", func([]byte) string { return "geheim" }},
+		{"gateway without token", Config{GatewayURL: server.URL}, "/api/events", "X-Api-Key", func([]byte) string { return "" }},
+		{"signed webhook", Config{AlertWebhooks: []string{server.URL + "/hook"}, AlertWebhookSecret: "s3cret"}, "/hook", "X-Jarvis-Signature",
+			func(body []byte) string { return alertSignatureV1 + signAlert("s3cret", body) }},
+		{"unsigned webhook", Config{AlertWebhooks: []string{server.URL + "/hook"}}, "/hook", "X-Jarvis-Signature", func([]byte) string { return "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newAlertPublisher(tt.cfg, log.New(io.Discard, "", 0)).publish(entry)
+			alert := nextAlert(t, received)
+			if alert.path != tt.path || alert.header.Get("Content-Type") != "application/json" {
+				t.Errorf("alert to %s with %v", alert.path, alert.header)
+			}
+			if got := alert.header.Get(tt.header); got != tt.value(alert.body) {
+				t.Errorf("%s = %q, want %q", tt.header, got, tt.value(alert.body))
+			}
+			var event alertEvent
+			if err := json.Unmarshal(alert.body, &event); err != nil {
+				t.Fatalf("decode %s: %v", alert.body, err)
+			}
+			if event.Type != alertEventType || event.Timestamp != 1700000000 || event.Payload.InputHash != "abc" {
+				t.Errorf("event = %+v", event)
+			}
+		})
+	}
+}
+
+func TestSignAlert(t *testing.T) {
+	body := []byte(`{"type":"security_alert"}`)
+	if signAlert("a", body) == signAlert("b", body) || signAlert("a", body) == signAlert("a", append(body, ' ')) {
+		t.Error("signature does not depend on secret and body")
+	}
+	if len(signAlert("a", body)) != 64 {
+		t.Errorf("signature %q is not hex SHA-256", signAlert("a", body))
+	}
+}
+
+func TestAlertPublisherWithoutTargets(t *testing.T) {
+	publisher := newAlertPublisher(Config{}, log.New(io.Discard, "", 0))
+	if publisher != nil {
+		t.Fatalf("publisher = %+v, want nil", publisher)
+	}
+	publisher.publish(AuditEntry{})
+}
+
+func TestAlertQueueFull(t *testing.T) {
+	var logs bytes.Buffer
+	publisher := &alertPublisher{logger: log.New(&logs, "", 0), queue: make(chan alertEvent, 1)}
+	publisher.publish(AuditEntry{})
+	publisher.publish(AuditEntry{})
+	if len(publisher.queue) != 1 || !strings.Contains(logs.String(), "Warteschlange voll") {
+		t.Errorf("queue %d, logs %q", len(publisher.queue), logs.String())
+	}
+}
+
+func TestCriticalValidationAlerts(t *testing.T) {
+	server, received := alertReceiver(t)
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	os.WriteFile(path, []byte(`
+rules:
+  - {id: harmless, pattern: 'apple', severity: high}
+  - {id: alarming, pattern: 'durian', severity: critical}
+`), 0o644)
+	svc := newTestService(t, Config{RulesFile: path, Locales: []string{}, AlertWebhooks: []string{server.URL}})
+
+	tests := []struct {
+		input string
+		alert bool
+	}{
+		{"nur ein apple", false},
+		{"eine durian", true},
+	}
+	for _, tt := range tests {
+		validate(t, svc, ValidateRequest{Input: tt.input})
+		select {
+		case alert := <-received:
+			if !tt.alert {
+				t.Errorf("%q: unexpected alert %s", tt.input, alert.body)
+			} else if strings.Contains(string(alert.body), tt.input) {
+				t.Errorf("%q: alert contains the input: %s", tt.input, alert.body)
+			}
+		case <-time.After(300 * time.Millisecond):
+			if tt.alert {
+				t.Errorf("%q: no alert", tt.input)
+			}
+		}
+	}
+}
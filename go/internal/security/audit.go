@@ -0,0 +1,248 @@
+package security
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"jarviscore/go/internal/authmw"
+	"jarviscore/go/internal/fsutil"
+)
+
+const (
+	defaultAuditFile       = "data/security/audit.jsonl"
+	defaultAuditMaxEntries = 10000
+	defaultAuditPageSize   = 50
+	maxAuditPageSize       = 500
+	auditHashLength        = 16
+)
+
+// AuditEntry records a rejected or critical validation. The input itself is
+// never stored, only a truncated SHA-256 so repeated inputs can be grouped.
+type AuditEntry struct {
+	Time         time.Time `json:"time"`
+	InputHash    string    `json:"input_hash"`
+	InputLength  int       `json:"input_length"`
+	MatchedRules []string  `json:"matched_rules"`
+	Severity     string    `json:"severity"`
+	Score        float64   `json:"score"`
+	Profile      string    `json:"profile,omitempty"`
+	Rejected     bool      `json:"rejected"`
+	Caller       string    `json:"caller"`
+}
+
+// AuditLog appends entries to a JSON lines file and keeps the newest
+// maxEntries in memory for queries. When the file grows to twice that size it
+// is rewritten with the kept entries.
+type AuditLog struct {
+	path       string
+	maxEntries int
+	entries    []AuditEntry
+	written    int
+	file       *os.File
+	mu         sync.Mutex
+}
+
+func OpenAuditLog(path string, maxEntries int) (*AuditLog, error) {
+	if maxEntries <= 0 {
+		maxEntries = defaultAuditMaxEntries
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	a := &AuditLog{path: path, maxEntries: maxEntries}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var entry AuditEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			a.entries = append(a.entries, entry)
+			a.written++
+		}
+	}
+	if len(a.entries) > maxEntries {
+		a.entries = append([]AuditEntry(nil), a.entries[len(a.entries)-maxEntries:]...)
+	}
+
+	a.file, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Record appends entry.
+func (a *AuditLog) Record(entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.entries = append(a.entries, entry)
+	if len(a.entries) > a.maxEntries {
+		a.entries = append([]AuditEntry(nil), a.entries[len(a.entries)-a.maxEntries:]...)
+	}
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	a.written++
+	if a.written >= 2*a.maxEntries {
+		return a.compactLocked()
+	}
+	return nil
+}
+
+func (a *AuditLog) compactLocked() error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, entry := range a.entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	if err := fsutil.WriteFileAtomic(a.path, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	a.file.Close()
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	a.file = file
+	a.written = len(a.entries)
+	return nil
+}
+
+// Query returns entries matching severity and rule (empty matches all),
+// newest first, and the total number of matches.
+func (a *AuditLog) Query(severity, rule string, offset, limit int) ([]AuditEntry, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	matches := []AuditEntry{}
+	for i := len(a.entries) - 1; i >= 0; i-- {
+		entry := a.entries[i]
+		if severity != "" && entry.Severity != severity {
+			continue
+		}
+		if rule != "" && !containsString(entry.MatchedRules, rule) {
+			continue
+		}
+		matches = append(matches, entry)
+	}
+	total := len(matches)
+	if offset >= total {
+		return []AuditEntry{}, total
+	}
+	return matches[offset:min(offset+limit, total)], total
+}
+
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.file.Close()
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func inputHash(input string) string {
+	sum := sha256.Sum256([]byte(input))
+	return hex.EncodeToString(sum[:])[:auditHashLength]
+}
+
+// callerOf names the client of r: the authenticated subject, a masked API
+// key or the remote IP.
+func callerOf(r *http.Request) string {
+	if identity, ok := authmw.FromContext(r.Context()); ok {
+		return identity.Subject
+	}
+	if key := authmw.APIKeyFromRequest(r); key != "" {
+		return authmw.MaskKey(key)
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// audit records result if it was rejected or critical.
+func (s *Service) audit(r *http.Request, input string, result ValidateResponse) {
+	s.record(r, inputHash(input), len(input), result)
+}
+
+// record is audit for inputs that are only known by hash and length.
+// Critical results are also sent as alerts.
+func (s *Service) record(r *http.Request, hash string, length int, result ValidateResponse) {
+	if !result.Rejected && result.Severity != "critical" {
+		return
+	}
+	entry := AuditEntry{
+		Time:         time.Now().UTC(),
+		InputHash:    hash,
+		InputLength:  length,
+		MatchedRules: result.MatchedRules,
+		Severity:     result.Severity,
+		Score:        result.Score,
+		Profile:      result.Profile,
+		Rejected:     result.Rejected,
+		Caller:       callerOf(r),
+	}
+	if result.Severity == "critical" {
+		s.alerts.publish(entry)
+	}
+	if s.auditLog == nil {
+		return
+	}
+	if err := s.auditLog.Record(entry); err != nil {
+		s.logger.Printf("[ERROR] Audit-Eintrag fehlgeschlagen: %v", err)
+	}
+}
+
+func (s *Service) auditHandler(w http.ResponseWriter, r *http.Request) {
+	if s.auditLog == nil {
+		http.Error(w, `{"error":"Audit log is disabled"}`, http.StatusServiceUnavailable)
+		return
+	}
+	query := r.URL.Query()
+	limit := defaultAuditPageSize
+	if value, err := strconv.Atoi(query.Get("limit")); err == nil && value > 0 {
+		limit = min(value, maxAuditPageSize)
+	}
+	offset := 0
+	if value, err := strconv.Atoi(query.Get("offset")); err == nil && value > 0 {
+		offset = value
+	}
+
+	entries, total := s.auditLog.Query(query.Get("severity"), query.Get("rule"), offset, limit)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entries,
+		"total":   total,
+		"offset":  offset,
+		"limit":   limit,
+	})
+}
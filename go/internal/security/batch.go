@@ -0,0 +1,81 @@
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// maxBatchSize limits the inputs of one batch request.
+const maxBatchSize = 100
+
+// BatchValidateRequest validates several inputs, e.g. the parts of a
+// multi-part prompt, with the same profile and thresholds.
+type BatchValidateRequest struct {
+	Inputs     []string    `json:"inputs"`
+	Strict     bool        `json:"strict"`
+	Profile    string      `json:"profile,omitempty"`
+	Thresholds *Thresholds `json:"thresholds,omitempty"`
+}
+
+// BatchValidateResponse holds one result per input, in request order.
+// IsSafe is false if any input was rejected.
+type BatchValidateResponse struct {
+	Results  []ValidateResponse `json:"results"`
+	IsSafe   bool               `json:"is_safe"`
+	Rejected int                `json:"rejected"`
+	Total    int                `json:"total"`
+}
+
+func (s *Service) validateBatchHandler(w http.ResponseWriter, r *http.Request) {
+	var req BatchValidateRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if len(req.Inputs) == 0 {
+		http.Error(w, `{"error":"inputs must not be empty"}`, http.StatusBadRequest)
+		return
+	}
+	if len(req.Inputs) > maxBatchSize {
+		http.Error(w, fmt.Sprintf(`{"error":"at most %d inputs per batch"}`, maxBatchSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	rules := s.rules()
+	profile, name, err := s.profile(r, rules, ValidateRequest{
+		Strict:     req.Strict,
+		Profile:    req.Profile,
+		Thresholds: req.Thresholds,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	s.statsLock.Lock()
+	s.stats.TotalValidations += len(req.Inputs)
+	s.statsLock.Unlock()
+
+	validator := s.validator(rules)
+	response := BatchValidateResponse{
+		Results: make([]ValidateResponse, 0, len(req.Inputs)),
+		IsSafe:  true,
+		Total:   len(req.Inputs),
+	}
+	for _, input := range req.Inputs {
+		result := validator.Validate(input, profile)
+		result.Profile = name
+		s.audit(r, input, result)
+		if result.Rejected {
+			response.Rejected++
+			response.IsSafe = false
+		}
+		response.Results = append(response.Results, result)
+	}
+	s.countCaller(r, len(req.Inputs), response.Results...)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,59 @@
+package security
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestValidateBatch(t *testing.T) {
+	svc := weightedService(t)
+
+	tests := []struct {
+		name     string
+		req      interface{}
+		code     int
+		safe     []bool
+		rejected int
+	}{
+		{"all safe", BatchValidateRequest{Inputs: []string{"apple", "hello"}}, http.StatusOK, []bool{true, true}, 0},
+		{"one rejected", BatchValidateRequest{Inputs: []string{"apple", "elder", "banana"}}, http.StatusOK, []bool{true, false, true}, 1},
+		{"strict profile", BatchValidateRequest{Inputs: []string{"apple", "hello"}, Strict: true}, http.StatusOK, []bool{false, true}, 1},
+		{"threshold override", BatchValidateRequest{Inputs: []string{"banana"}, Thresholds: &Thresholds{Reject: 3}}, http.StatusOK, []bool{false}, 1},
+		{"empty", BatchValidateRequest{}, http.StatusBadRequest, nil, 0},
+		{"too many", BatchValidateRequest{Inputs: make([]string, maxBatchSize+1)}, http.StatusRequestEntityTooLarge, nil, 0},
+		{"unknown profile", BatchValidateRequest{Inputs: []string{"x"}, Profile: "paranoid"}, http.StatusBadRequest, nil, 0},
+		{"invalid body", "inputs", http.StatusBadRequest, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(svc, http.MethodPost, "/api/security/validate/batch", tt.req, nil)
+			if rec.Code != tt.code {
+				t.Fatalf("status %d, want %d (%s)", rec.Code, tt.code, rec.Body)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			var response BatchValidateResponse
+			decode(t, rec, &response)
+			if response.Total != len(tt.safe) || response.Rejected != tt.rejected || response.IsSafe != (tt.rejected == 0) {
+				t.Errorf("response = total %d rejected %d safe %v", response.Total, response.Rejected, response.IsSafe)
+			}
+			for i, result := range response.Results {
+				if result.IsSafe != tt.safe[i] {
+					t.Errorf("result %d safe %v, want %v", i, result.IsSafe, tt.safe[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateBatchCountsEveryInput(t *testing.T) {
+	svc := weightedService(t)
+	serve(svc, http.MethodPost, "/api/security/validate/batch", BatchValidateRequest{Inputs: []string{"a", "b", "elder"}}, nil)
+
+	svc.statsLock.Lock()
+	defer svc.statsLock.Unlock()
+	if svc.stats.TotalValidations != 3 || svc.stats.Rejected != 1 {
+		t.Errorf("stats = %+v", svc.stats)
+	}
+}
@@ -0,0 +1,135 @@
+package security
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultScanBudget = 50 * time.Millisecond
+	// slowRuleThreshold is the recent time per call from which a rule
+	// counts as slow. Slow rules run after the others and are skipped once
+	// the scan budget is spent.
+	slowRuleThreshold = time.Millisecond
+	// recentWeight is the weight of the newest call in the recent time.
+	// Every skip decays it by the same factor, so a skipped rule eventually
+	// runs early again and is measured anew.
+	recentWeight = 0.125
+	// minRuleCalls is how often a rule must have run before it can count as
+	// slow, so one cold call does not defer it.
+	minRuleCalls = 5
+)
+
+// RuleTiming are the timing counters of one rule.
+type RuleTiming struct {
+	Rule    string        `json:"rule"`
+	Calls   int64         `json:"calls"`
+	Total   time.Duration `json:"total_ns"`
+	Max     time.Duration `json:"max_ns"`
+	Average time.Duration `json:"average_ns"`
+	// Recent is a moving average of the latest calls that decides whether
+	// the rule is slow.
+	Recent  time.Duration `json:"recent_ns"`
+	Skipped int64         `json:"skipped"`
+	Slow    bool          `json:"slow"`
+}
+
+// ruleTimings measures how long each rule takes.
+type ruleTimings struct {
+	rules map[string]*RuleTiming
+	mu    sync.Mutex
+}
+
+func newRuleTimings() *ruleTimings {
+	return &ruleTimings{rules: make(map[string]*RuleTiming)}
+}
+
+func (t *ruleTimings) timingLocked(id string) *RuleTiming {
+	timing, ok := t.rules[id]
+	if !ok {
+		timing = &RuleTiming{Rule: id}
+		t.rules[id] = timing
+	}
+	return timing
+}
+
+// observe records a call of rule id. A nil ruleTimings discards it.
+func (t *ruleTimings) observe(id string, elapsed time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timing := t.timingLocked(id)
+	if timing.Calls == 0 {
+		timing.Recent = elapsed
+	} else {
+		timing.Recent += time.Duration(recentWeight * float64(elapsed-timing.Recent))
+	}
+	timing.Calls++
+	timing.Total += elapsed
+	timing.Max = max(timing.Max, elapsed)
+}
+
+func (t *ruleTimings) skip(id string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timing := t.timingLocked(id)
+	timing.Skipped++
+	timing.Recent -= time.Duration(recentWeight * float64(timing.Recent))
+}
+
+// slow reports whether rule id recently took slowRuleThreshold or longer.
+func (t *ruleTimings) slow(id string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timing, ok := t.rules[id]
+	return ok && timing.slowLocked()
+}
+
+func (timing *RuleTiming) slowLocked() bool {
+	return timing.Calls >= minRuleCalls && timing.Recent >= slowRuleThreshold
+}
+
+// snapshot returns the counters, slowest on average first.
+func (t *ruleTimings) snapshot() []RuleTiming {
+	t.mu.Lock()
+	timings := make([]RuleTiming, 0, len(t.rules))
+	for _, timing := range t.rules {
+		copied := *timing
+		if copied.Calls > 0 {
+			copied.Average = copied.Total / time.Duration(copied.Calls)
+		}
+		copied.Slow = timing.slowLocked()
+		timings = append(timings, copied)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(timings, func(i, j int) bool {
+		if timings[i].Average != timings[j].Average {
+			return timings[i].Average > timings[j].Average
+		}
+		return timings[i].Rule < timings[j].Rule
+	})
+	return timings
+}
+
+// ruleMetricsHandler reports how long the rules take and how often slow ones
+// were skipped for exceeding the scan budget.
+func (s *Service) ruleMetricsHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"budget_ns":         s.cfg.ScanBudget,
+		"slow_threshold_ns": slowRuleThreshold,
+		"rules":             s.timings.snapshot(),
+	})
+}
@@ -0,0 +1,141 @@
+package security
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// calls returns n calls taking elapsed each.
+func calls(n int, elapsed time.Duration) []time.Duration {
+	durations := make([]time.Duration, n)
+	for i := range durations {
+		durations[i] = elapsed
+	}
+	return durations
+}
+
+func TestRuleTimings(t *testing.T) {
+	tests := []struct {
+		name    string
+		calls   []time.Duration
+		skips   int
+		slow    bool
+		maximum time.Duration
+	}{
+		{"fast", calls(5, 10*time.Microsecond), 0, false, 10 * time.Microsecond},
+		{"slow but too few calls", calls(minRuleCalls-1, 5*time.Millisecond), 0, false, 5 * time.Millisecond},
+		{"slow", calls(minRuleCalls, 5*time.Millisecond), 0, true, 5 * time.Millisecond},
+		{"one cold call", append(calls(1, 20*time.Millisecond), calls(30, 10*time.Microsecond)...), 0, false, 20 * time.Millisecond},
+		{"skips decay", calls(minRuleCalls, 2*time.Millisecond), 6, false, 2 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timings := newRuleTimings()
+			for _, elapsed := range tt.calls {
+				timings.observe("regel", elapsed)
+			}
+			for i := 0; i < tt.skips; i++ {
+				timings.skip("regel")
+			}
+			if got := timings.slow("regel"); got != tt.slow {
+				t.Errorf("slow = %v, want %v (%+v)", got, tt.slow, *timings.rules["regel"])
+			}
+			snapshot := timings.snapshot()
+			if len(snapshot) != 1 || snapshot[0].Calls != int64(len(tt.calls)) || snapshot[0].Skipped != int64(tt.skips) ||
+				snapshot[0].Max != tt.maximum || snapshot[0].Slow != tt.slow {
+				t.Errorf("snapshot = %+v", snapshot)
+			}
+		})
+	}
+
+	var none *ruleTimings
+	none.observe("regel", time.Second)
+	none.skip("regel")
+	if none.slow("regel") {
+		t.Error("nil timings report a slow rule")
+	}
+}
+
+func TestRuleTimingsSnapshotOrder(t *testing.T) {
+	timings := newRuleTimings()
+	timings.observe("b", 2*time.Millisecond)
+	timings.observe("a", 2*time.Millisecond)
+	timings.observe("c", 3*time.Millisecond)
+	timings.observe("c", 5*time.Millisecond)
+	timings.skip("d")
+
+	var order []string
+	for _, timing := range timings.snapshot() {
+		order = append(order, timing.Rule)
+	}
+	if len(order) != 4 || order[0] != "c" || order[1] != "a" || order[2] != "b" || order[3] != "d" {
+		t.Errorf("order = %v, want slowest average first", order)
+	}
+	if average := timings.snapshot()[0].Average; average != 4*time.Millisecond {
+		t.Errorf("average = %v, want 4ms", average)
+	}
+}
+
+// markSlow makes rule id count as slow.
+func markSlow(svc *Service, id string) {
+	for i := 0; i < minRuleCalls; i++ {
+		svc.timings.observe(id, 10*slowRuleThreshold)
+	}
+}
+
+func TestScanBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  time.Duration
+		profile string
+		skipped bool
+	}{
+		{"spent budget skips slow rule", time.Nanosecond, ModerateProfile, true},
+		{"budget left", time.Hour, ModerateProfile, false},
+		{"budget disabled", 0, ModerateProfile, false},
+		{"exhaustive profile", time.Nanosecond, StrictProfile, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := weightedService(t)
+			svc.cfg.ScanBudget = tt.budget
+			markSlow(svc, "heavy")
+
+			result := validate(t, svc, ValidateRequest{Input: "durian", Profile: tt.profile})
+			if skipped := containsString(result.SkippedRules, "heavy"); skipped != tt.skipped {
+				t.Fatalf("skipped %v, want heavy skipped %v", result.SkippedRules, tt.skipped)
+			}
+			if tt.skipped {
+				if containsString(result.MatchedRules, "heavy") || result.Score != weightScanBudget {
+					t.Errorf("result = %+v, want only the budget weight", result)
+				}
+				if svc.timings.rules["heavy"].Skipped != 1 {
+					t.Errorf("skip not counted: %+v", *svc.timings.rules["heavy"])
+				}
+			} else if !containsString(result.MatchedRules, "heavy") {
+				t.Errorf("matched %v, want heavy", result.MatchedRules)
+			}
+		})
+	}
+}
+
+func TestRuleMetricsHandler(t *testing.T) {
+	svc := weightedService(t)
+	svc.cfg.ScanBudget = defaultScanBudget
+	validate(t, svc, ValidateRequest{Input: "apple"})
+
+	var metrics struct {
+		Budget time.Duration `json:"budget_ns"`
+		Rules  []RuleTiming  `json:"rules"`
+	}
+	decode(t, serve(svc, http.MethodGet, "/api/security/rules/metrics", nil, nil), &metrics)
+	if metrics.Budget != defaultScanBudget || len(metrics.Rules) != len(svc.rules().Rules) {
+		t.Errorf("metrics = %+v", metrics)
+	}
+	for _, timing := range metrics.Rules {
+		if timing.Calls != 1 {
+			t.Errorf("rule %s ran %d times, want 1", timing.Rule, timing.Calls)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package security
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"time"
+
+	"jarviscore/go/internal/authmw"
+)
+
+const (
+	// maxTrackedCallers bounds the per-caller stats; further keys are not
+	// tracked.
+	maxTrackedCallers = 10000
+	defaultTopCallers = 10
+	maxTopCallers     = 100
+)
+
+// CallerStats are the counters of one API key. The key itself is not kept;
+// ID is derived from it like the key ids of the auth service.
+type CallerStats struct {
+	ID          string         `json:"id"`
+	Key         string         `json:"key"`
+	Validations int            `json:"validations"`
+	Rejected    int            `json:"rejected"`
+	Rules       map[string]int `json:"rules"`
+	LastSeen    time.Time      `json:"last_seen"`
+}
+
+func callerKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// countCaller adds validations of the request's API key, if it has one.
+func (s *Service) countCaller(r *http.Request, validations int, results ...ValidateResponse) {
+	key := authmw.APIKeyFromRequest(r)
+	if key == "" {
+		return
+	}
+	id := callerKeyID(key)
+
+	s.statsLock.Lock()
+	defer s.statsLock.Unlock()
+
+	caller, ok := s.callers[id]
+	if !ok {
+		if len(s.callers) >= maxTrackedCallers {
+			return
+		}
+		caller = &CallerStats{ID: id, Key: authmw.MaskKey(key), Rules: make(map[string]int)}
+		s.callers[id] = caller
+	}
+	caller.Validations += validations
+	caller.LastSeen = time.Now().UTC()
+	for _, result := range results {
+		if result.Rejected {
+			caller.Rejected++
+		}
+		for _, rule := range result.MatchedRules {
+			caller.Rules[rule]++
+		}
+	}
+}
+
+// callerStatsLocked returns a copy of the stats of key, given as the key
+// itself or its id.
+func (s *Service) callerStatsLocked(key string) (CallerStats, bool) {
+	caller, ok := s.callers[key]
+	if !ok {
+		caller, ok = s.callers[callerKeyID(key)]
+	}
+	if !ok {
+		return CallerStats{}, false
+	}
+	return caller.copy(), true
+}
+
+// topCallersLocked returns the limit callers with the most rejections.
+func (s *Service) topCallersLocked(limit int) []CallerStats {
+	top := make([]CallerStats, 0, len(s.callers))
+	for _, caller := range s.callers {
+		if caller.Rejected > 0 {
+			top = append(top, caller.copy())
+		}
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Rejected != top[j].Rejected {
+			return top[i].Rejected > top[j].Rejected
+		}
+		return top[i].ID < top[j].ID
+	})
+	if len(top) > limit {
+		top = top[:limit]
+	}
+	return top
+}
+
+func (c *CallerStats) copy() CallerStats {
+	copied := *c
+	copied.Rules = make(map[string]int, len(c.Rules))
+	for rule, count := range c.Rules {
+		copied.Rules[rule] = count
+	}
+	return copied
+}
@@ -0,0 +1,119 @@
+package security
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func apiKeyHeader(key string) http.Header {
+	return http.Header{"X-Api-Key": {key}}
+}
+
+func TestCallerStats(t *testing.T) {
+	svc := weightedService(t)
+	requests := []struct {
+		key   string
+		input string
+	}{
+		{"schluessel-anna", "apple"},
+		{"schluessel-anna", "elder"},
+		{"schluessel-anna", "elder apple"},
+		{"schluessel-ben", "elder"},
+		{"schluessel-ben", "hallo"},
+		{"schluessel-clara", "hallo"},
+		{"", "elder"},
+	}
+	for _, req := range requests {
+		serve(svc, http.MethodPost, "/api/security/validate", ValidateRequest{Input: req.input}, apiKeyHeader(req.key))
+	}
+	serve(svc, http.MethodPost, "/api/security/validate/batch", BatchValidateRequest{Inputs: []string{"elder", "banana"}}, apiKeyHeader("schluessel-clara"))
+
+	tests := []struct {
+		key         string
+		code        int
+		validations int
+		rejected    int
+		rules       map[string]int
+	}{
+		{"schluessel-anna", http.StatusOK, 3, 2, map[string]int{"low": 2, "blocker": 2}},
+		{callerKeyID("schluessel-ben"), http.StatusOK, 2, 1, map[string]int{"blocker": 1}},
+		{"schluessel-clara", http.StatusOK, 3, 1, map[string]int{"blocker": 1, "medium": 1}},
+		{"unbekannt", http.StatusNotFound, 0, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			rec := serve(svc, http.MethodGet, "/api/security/stats?key="+tt.key, nil, nil)
+			if rec.Code != tt.code {
+				t.Fatalf("status %d, want %d", rec.Code, tt.code)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			var caller CallerStats
+			decode(t, rec, &caller)
+			if caller.Validations != tt.validations || caller.Rejected != tt.rejected || len(caller.Rules) != len(tt.rules) {
+				t.Errorf("caller = %+v", caller)
+			}
+			for rule, count := range tt.rules {
+				if caller.Rules[rule] != count {
+					t.Errorf("rule %s counted %d, want %d", rule, caller.Rules[rule], count)
+				}
+			}
+			if caller.Key == tt.key || caller.LastSeen.IsZero() {
+				t.Errorf("key %q is not masked or last seen is missing", caller.Key)
+			}
+		})
+	}
+}
+
+func TestTopOffenders(t *testing.T) {
+	svc := weightedService(t)
+	for i, rejections := range []int{1, 3, 0, 2, 3} {
+		key := "schluessel-" + strconv.Itoa(i)
+		serve(svc, http.MethodPost, "/api/security/validate", ValidateRequest{Input: "hallo"}, apiKeyHeader(key))
+		for j := 0; j < rejections; j++ {
+			serve(svc, http.MethodPost, "/api/security/validate", ValidateRequest{Input: "elder"}, apiKeyHeader(key))
+		}
+	}
+	first, second := callerKeyID("schluessel-1"), callerKeyID("schluessel-4")
+	if first > second {
+		first, second = second, first
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{first, second, callerKeyID("schluessel-3"), callerKeyID("schluessel-0")}},
+		{"?top=2", []string{first, second}},
+		{"?top=0", []string{}},
+		{"?top=abc", []string{first, second, callerKeyID("schluessel-3"), callerKeyID("schluessel-0")}},
+	}
+	for _, tt := range tests {
+		var response struct {
+			Stats
+			TopOffenders []CallerStats `json:"top_offenders"`
+		}
+		decode(t, serve(svc, http.MethodGet, "/api/security/stats"+tt.query, nil, nil), &response)
+		got := []string{}
+		for _, caller := range response.TopOffenders {
+			got = append(got, caller.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: top %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestCallerLimit(t *testing.T) {
+	svc := weightedService(t)
+	for i := 0; i < maxTrackedCallers; i++ {
+		svc.callers[strconv.Itoa(i)] = &CallerStats{}
+	}
+	serve(svc, http.MethodPost, "/api/security/validate", ValidateRequest{Input: "hallo"}, apiKeyHeader("neuer-schluessel"))
+	if len(svc.callers) != maxTrackedCallers {
+		t.Errorf("%d callers tracked, want at most %d", len(svc.callers), maxTrackedCallers)
+	}
+}
@@ -0,0 +1,105 @@
+package security
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCanaryScan(t *testing.T) {
+	store := newCanaryStore(time.Hour)
+	_, first, _ := store.issue("erster")
+	_, second, _ := store.issue("zweiter")
+
+	tests := []struct {
+		name     string
+		output   string
+		leaked   int
+		redacted string
+	}{
+		{"no token", "Alles gut.", 0, "Alles gut."},
+		{"unknown token", "Referenz " + strings.Repeat("ab", 12), 0, "Referenz " + strings.Repeat("ab", 12)},
+		{"leaked", "Die Referenz ist " + first + ".", 1, "Die Referenz ist [REDACTED]."},
+		{"upper case", "REF " + strings.ToUpper(first), 1, "REF [REDACTED]"},
+		{"inside a hex run", "0x00" + first + "ff", 1, "0x00[REDACTED]ff"},
+		{"both tokens adjacent", first + second, 2, "[REDACTED][REDACTED]"},
+		{"repeated once reported", first + " und " + first, 1, "[REDACTED] und [REDACTED]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaked, redacted := store.scan(tt.output)
+			if len(leaked) != tt.leaked || redacted != tt.redacted {
+				t.Errorf("scan = %d leaked, %q; want %d, %q", len(leaked), redacted, tt.leaked, tt.redacted)
+			}
+		})
+	}
+}
+
+func TestCanaryExpiry(t *testing.T) {
+	store := newCanaryStore(time.Hour)
+	_, expired, _ := store.issue("alt")
+	store.tokens[expired] = Canary{ID: expired[:8], Expires: time.Now().Add(-time.Second)}
+
+	if leaked, _ := store.scan(expired); leaked != nil {
+		t.Errorf("expired canary reported: %+v", leaked)
+	}
+	store.issue("neu")
+	if _, ok := store.tokens[expired]; ok || len(store.order) != 1 {
+		t.Errorf("expired canary kept: %d issued", len(store.order))
+	}
+}
+
+func TestCanaryLimit(t *testing.T) {
+	store := newCanaryStore(time.Hour)
+	_, oldest, _ := store.issue("")
+	for i := 1; i < maxCanaries; i++ {
+		store.issue("")
+	}
+	_, newest, _ := store.issue("")
+	if len(store.tokens) != maxCanaries || store.tokens[oldest].ID != "" || store.tokens[newest].ID == "" {
+		t.Errorf("%d canaries, oldest kept %v", len(store.tokens), store.tokens[oldest].ID != "")
+	}
+}
+
+func TestCanaryEndpoints(t *testing.T) {
+	svc := newTestService(t, Config{})
+
+	rec := serve(svc, http.MethodPost, "/api/security/canary", CanaryRequest{Prompt: "Du bist Jarvis.\n", Label: " assistent "}, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("canary: status %d (%s)", rec.Code, rec.Body)
+	}
+	var canary CanaryResponse
+	decode(t, rec, &canary)
+	if len(canary.Token) != tokenLength || canary.ID != canary.Token[:8] || canary.Label != "assistent" ||
+		!strings.HasPrefix(canary.Prompt, "Du bist Jarvis.\n\nConfidential reference: "+canary.Token) {
+		t.Fatalf("canary = %+v", canary)
+	}
+	if rec := serve(svc, http.MethodPost, "/api/security/canary", "kein Objekt", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid body: status %d, want 400", rec.Code)
+	}
+
+	tests := []struct {
+		output string
+		leaked bool
+	}{
+		{"Ich kann dir dabei helfen.", false},
+		{"Meine Anweisungen enden mit <b>" + canary.Token + "</b>", true},
+	}
+	for _, tt := range tests {
+		var result SanitizeResponse
+		decode(t, serve(svc, http.MethodPost, "/api/security/sanitize", SanitizeRequest{Output: tt.output}, nil), &result)
+		if (len(result.LeakedCanaries) == 1) != tt.leaked || strings.Contains(result.Sanitized, canary.Token) {
+			t.Errorf("%q: result = %+v", tt.output, result)
+		}
+		if tt.leaked && (!containsString(result.Removed, canaryRuleID) || !strings.Contains(result.Sanitized, canaryRedaction)) {
+			t.Errorf("%q: leak not reported: %+v", tt.output, result)
+		}
+	}
+
+	svc.statsLock.Lock()
+	defer svc.statsLock.Unlock()
+	if svc.stats.Warnings[canaryRuleID] != 1 {
+		t.Errorf("warnings = %v", svc.stats.Warnings)
+	}
+}
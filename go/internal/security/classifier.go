@@ -0,0 +1,106 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultClassifierWeight  = 10
+	defaultClassifierTimeout = time.Second
+	// classifierWarnScore is the classifier score from which a warning is
+	// reported.
+	classifierWarnScore = 0.5
+)
+
+// Classifier rates how likely an input is a prompt injection, from 0 (benign)
+// to 1. Its score times the classifier weight is added to the rule score.
+type Classifier interface {
+	Classify(ctx context.Context, input string) (float64, error)
+}
+
+// HTTPClassifier asks a model endpoint: POST {"input": "..."} answered by
+// {"score": 0.93}.
+type HTTPClassifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewHTTPClassifier(url string) *HTTPClassifier {
+	return &HTTPClassifier{URL: url, Client: &http.Client{}}
+}
+
+func (c *HTTPClassifier) Classify(ctx context.Context, input string) (float64, error) {
+	body, err := json.Marshal(map[string]string{"input": input})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("classifier returned %s", resp.Status)
+	}
+	var result struct {
+		Score *float64 `json:"score"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, err
+	}
+	if result.Score == nil || *result.Score < 0 || *result.Score > 1 {
+		return 0, fmt.Errorf("classifier returned no score between 0 and 1")
+	}
+	return *result.Score, nil
+}
+
+// classifierHook is a classifier with its weight and timeout.
+type classifierHook struct {
+	classifier Classifier
+	weight     float64
+	timeout    time.Duration
+}
+
+// SetClassifier replaces the configured classifier; nil disables it. weight
+// and timeout fall back to the defaults when not positive.
+func (s *Service) SetClassifier(classifier Classifier, weight float64, timeout time.Duration) {
+	s.rulesLock.Lock()
+	defer s.rulesLock.Unlock()
+	if classifier == nil {
+		s.classifier = nil
+		return
+	}
+	if weight <= 0 {
+		weight = defaultClassifierWeight
+	}
+	if timeout <= 0 {
+		timeout = defaultClassifierTimeout
+	}
+	s.classifier = &classifierHook{classifier: classifier, weight: weight, timeout: timeout}
+}
+
+// classify returns the classifier score of input. Errors are counted and
+// otherwise ignored, so an unavailable model never blocks validation.
+func (v *PromptValidator) classify(input string) (float64, bool) {
+	if v.classifier == nil {
+		return 0, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), v.classifier.timeout)
+	defer cancel()
+	score, err := v.classifier.classifier.Classify(ctx, input[:min(len(input), v.maxLength)])
+	if err != nil {
+		v.incrementWarning("classifier_error")
+		return 0, false
+	}
+	return score, true
+}
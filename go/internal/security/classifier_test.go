@@ -0,0 +1,109 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPClassifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		score   float64
+		wantErr bool
+	}{
+		{"score", http.StatusOK, `{"score": 0.93}`, 0.93, false},
+		{"zero", http.StatusOK, `{"score": 0}`, 0, false},
+		{"missing score", http.StatusOK, `{}`, 0, true},
+		{"out of range", http.StatusOK, `{"score": 1.5}`, 0, true},
+		{"invalid json", http.StatusOK, `score`, 0, true},
+		{"server error", http.StatusInternalServerError, `{"score": 0.1}`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req struct{ Input string }
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Input != "Eingabe" || r.Method != http.MethodPost {
+					t.Errorf("request %s %+v (%v)", r.Method, req, err)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			score, err := NewHTTPClassifier(server.URL).Classify(context.Background(), "Eingabe")
+			if (err != nil) != tt.wantErr || score != tt.score {
+				t.Errorf("Classify = %v, %v; want %v, error %v", score, err, tt.score, tt.wantErr)
+			}
+		})
+	}
+}
+
+type classifierFunc func(ctx context.Context, input string) (float64, error)
+
+func (f classifierFunc) Classify(ctx context.Context, input string) (float64, error) {
+	return f(ctx, input)
+}
+
+func fixedScore(score float64, err error) Classifier {
+	return classifierFunc(func(context.Context, string) (float64, error) { return score, err })
+}
+
+func TestClassifierScoring(t *testing.T) {
+	slow := classifierFunc(func(ctx context.Context, _ string) (float64, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+
+	tests := []struct {
+		name       string
+		classifier Classifier
+		weight     float64
+		rated      bool
+		score      float64
+		warning    string
+	}{
+		{"benign", fixedScore(0.1, nil), 0, true, 1, ""},
+		{"injection", fixedScore(0.8, nil), 0, true, 8, "classifier"},
+		{"custom weight", fixedScore(0.5, nil), 4, true, 2, "classifier"},
+		{"error", fixedScore(0.9, errors.New("modell nicht geladen")), 0, false, 0, "classifier_error"},
+		{"timeout", slow, 0, false, 0, "classifier_error"},
+		{"disabled", nil, 0, false, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(t, Config{Locales: []string{}})
+			svc.SetClassifier(tt.classifier, tt.weight, 20*time.Millisecond)
+
+			result := validate(t, svc, ValidateRequest{Input: "Wie wird das Wetter morgen?"})
+			if (result.ClassifierScore != nil) != tt.rated || result.Score != tt.score {
+				t.Errorf("classifier score %v, score %v; want rated %v, score %v", result.ClassifierScore, result.Score, tt.rated, tt.score)
+			}
+			svc.statsLock.Lock()
+			defer svc.statsLock.Unlock()
+			if tt.warning != "" && svc.stats.Warnings[tt.warning] != 1 {
+				t.Errorf("warnings = %v, want %s", svc.stats.Warnings, tt.warning)
+			}
+		})
+	}
+}
+
+func TestClassifierSeesTruncatedInput(t *testing.T) {
+	var seen int
+	svc := newTestService(t, Config{Locales: []string{}, MaxLength: 100})
+	svc.SetClassifier(classifierFunc(func(_ context.Context, input string) (float64, error) {
+		seen = len(input)
+		return 0, nil
+	}), 0, 0)
+	validate(t, svc, ValidateRequest{Input: strings.Repeat("lang ", 100)})
+	if seen == 0 || seen > 100 {
+		t.Errorf("classifier saw %d bytes, want at most 100", seen)
+	}
+}
@@ -0,0 +1,225 @@
+package security
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gorilla/mux"
+
+	"jarviscore/go/internal/fsutil"
+)
+
+const (
+	defaultCustomRulesFile = "data/security/custom_rules.json"
+	// maxMatchText limits the matched text shown by the test endpoint.
+	maxMatchText = 100
+)
+
+// readCustomRules reads the rules added through the API. A missing file
+// means none.
+func readCustomRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid custom rules file: %w", err)
+	}
+	return rules, nil
+}
+
+func writeCustomRules(path string, rules []Rule) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(path, data, 0o600)
+}
+
+// customRuleList returns a copy of the custom rules.
+func (s *Service) customRuleList() []Rule {
+	s.customLock.Lock()
+	defer s.customLock.Unlock()
+	return append([]Rule(nil), s.customRules...)
+}
+
+// updateCustomRules applies change to a copy of the custom rules, rebuilds
+// the rule set and on success keeps and stores the result.
+func (s *Service) updateCustomRules(change func([]Rule) ([]Rule, error)) (*RuleSet, error) {
+	s.customLock.Lock()
+	defer s.customLock.Unlock()
+
+	rules, err := change(append([]Rule(nil), s.customRules...))
+	if err != nil {
+		return nil, err
+	}
+	set, err := LoadRules(s.cfg.RulesFile, s.cfg.Locales, rules...)
+	if err != nil {
+		return nil, err
+	}
+	if s.cfg.CustomRulesFile != "" {
+		if err := writeCustomRules(s.cfg.CustomRulesFile, rules); err != nil {
+			return nil, err
+		}
+	}
+	s.customRules = rules
+
+	s.rulesLock.Lock()
+	s.ruleSet = set
+	s.rulesLock.Unlock()
+	return set, nil
+}
+
+// addRuleHandler adds a custom rule or replaces the custom rule with the same
+// id.
+func (s *Service) addRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var rule Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	candidate := rule
+	if err := candidate.compile(); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusUnprocessableEntity)
+		return
+	}
+	rule = candidate
+
+	replaced := false
+	set, err := s.updateCustomRules(func(rules []Rule) ([]Rule, error) {
+		for i := range rules {
+			if rules[i].ID == rule.ID {
+				rules[i], replaced = rule, true
+				return rules, nil
+			}
+		}
+		return append(rules, rule), nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusUnprocessableEntity)
+		return
+	}
+	s.logger.Printf("[INFO] Eigene Sicherheitsregel %s gespeichert", rule.ID)
+
+	status := http.StatusCreated
+	if replaced {
+		status = http.StatusOK
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"rule":    candidate,
+		"rules":   len(set.Rules),
+	})
+}
+
+func (s *Service) deleteRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	found := false
+	_, err := s.updateCustomRules(func(rules []Rule) ([]Rule, error) {
+		for i := range rules {
+			if rules[i].ID == id {
+				found = true
+				return append(rules[:i], rules[i+1:]...), nil
+			}
+		}
+		return nil, errNoCustomRule
+	})
+	if !found {
+		http.Error(w, `{"error":"No custom rule with this id"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	s.logger.Printf("[INFO] Eigene Sicherheitsregel %s gelöscht", id)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
+
+var errNoCustomRule = errors.New("no custom rule with this id")
+
+// RuleTestRequest tries an input against the active rules plus Rules, which
+// are not stored.
+type RuleTestRequest struct {
+	ValidateRequest
+	Rules []Rule `json:"rules,omitempty"`
+}
+
+// RuleMatch is one place where a rule matched.
+type RuleMatch struct {
+	Rule    string `json:"rule"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Text    string `json:"text"`
+	Excused bool   `json:"excused"`
+}
+
+// testRulesHandler shows which rules an input triggers, where, and the
+// resulting decision. It does not count towards the stats or the audit log.
+func (s *Service) testRulesHandler(w http.ResponseWriter, r *http.Request) {
+	var req RuleTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	rules := s.rules()
+	if len(req.Rules) > 0 {
+		var err error
+		rules, err = LoadRules(s.cfg.RulesFile, s.cfg.Locales, append(s.customRuleList(), req.Rules...)...)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+	profile, name, err := s.profile(r, rules, req.ValidateRequest)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	validator := NewPromptValidator(s.cfg.MaxLength, rules, &Stats{Warnings: make(map[string]int)}, &sync.Mutex{})
+	result := validator.Validate(req.Input, profile)
+	result.Profile = name
+
+	scan := normalizeInput(req.Input).scan
+	matches := []RuleMatch{}
+	for i := range rules.Rules {
+		rule := &rules.Rules[i]
+		for _, loc := range rule.locations(scan) {
+			text := scan[loc[0]:loc[1]]
+			if len(text) > maxMatchText {
+				text = text[:maxMatchText]
+			}
+			matches = append(matches, RuleMatch{
+				Rule:    rule.ID,
+				Start:   loc[0],
+				End:     loc[1],
+				Text:    text,
+				Excused: rule.excused(scan, loc[0], loc[1]),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"result":  result,
+		"matches": matches,
+	})
+}
@@ -0,0 +1,126 @@
+package security
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCustomRuleHandlers(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "security", "custom_rules.json")
+	svc := newTestService(t, Config{AdminKey: "admin", CustomRulesFile: file, Locales: []string{}})
+	admin := adminHeader("admin")
+
+	// The steps share the service and run in order.
+	steps := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		header http.Header
+		code   int
+	}{
+		{"add without key", http.MethodPost, "/api/security/rules", Rule{ID: "kiwi", Pattern: "kiwi"}, nil, http.StatusUnauthorized},
+		{"add with wrong key", http.MethodPost, "/api/security/rules", Rule{ID: "kiwi", Pattern: "kiwi"}, adminHeader("falsch"), http.StatusForbidden},
+		{"add invalid body", http.MethodPost, "/api/security/rules", "kiwi", admin, http.StatusBadRequest},
+		{"add invalid pattern", http.MethodPost, "/api/security/rules", Rule{ID: "kiwi", Pattern: "(kiwi"}, admin, http.StatusUnprocessableEntity},
+		{"add without id", http.MethodPost, "/api/security/rules", Rule{Pattern: "kiwi"}, admin, http.StatusUnprocessableEntity},
+		{"add clashing with a built-in rule", http.MethodPost, "/api/security/rules", Rule{ID: "code-execution", Pattern: "kiwi"}, admin, http.StatusUnprocessableEntity},
+		{"add", http.MethodPost, "/api/security/rules", Rule{ID: "kiwi", Pattern: "(?i)kiwi", Severity: "medium"}, admin, http.StatusCreated},
+		{"replace", http.MethodPost, "/api/security/rules", Rule{ID: "kiwi", Pattern: "(?i)kiwi", Severity: "low"}, admin, http.StatusOK},
+		{"add second", http.MethodPost, "/api/security/rules", Rule{ID: "mango", Pattern: "mango"}, admin, http.StatusCreated},
+		{"delete without key", http.MethodDelete, "/api/security/rules/mango", nil, nil, http.StatusUnauthorized},
+		{"delete unknown", http.MethodDelete, "/api/security/rules/code-execution", nil, admin, http.StatusNotFound},
+		{"delete", http.MethodDelete, "/api/security/rules/mango", nil, admin, http.StatusOK},
+		{"delete again", http.MethodDelete, "/api/security/rules/mango", nil, admin, http.StatusNotFound},
+	}
+	for _, step := range steps {
+		if rec := serve(svc, step.method, step.path, step.body, step.header); rec.Code != step.code {
+			t.Fatalf("%s: status %d, want %d (%s)", step.name, rec.Code, step.code, rec.Body)
+		}
+	}
+
+	result := validate(t, svc, ValidateRequest{Input: "Eine KIWI und eine mango"})
+	if !containsString(result.MatchedRules, "kiwi") || containsString(result.MatchedRules, "mango") || result.Score != 1 {
+		t.Errorf("result = %+v, want only the replaced kiwi rule", result)
+	}
+
+	stored, err := readCustomRules(file)
+	if err != nil || len(stored) != 1 || stored[0].ID != "kiwi" || stored[0].Severity != "low" {
+		t.Fatalf("stored rules = %+v (%v)", stored, err)
+	}
+	restarted := newTestService(t, Config{CustomRulesFile: file, Locales: []string{}})
+	if !containsString(validate(t, restarted, ValidateRequest{Input: "kiwi"}).MatchedRules, "kiwi") {
+		t.Error("custom rule lost on restart")
+	}
+}
+
+func TestReadCustomRules(t *testing.T) {
+	dir := t.TempDir()
+	broken := filepath.Join(dir, "broken.json")
+	os.WriteFile(broken, []byte("[{"), 0o600)
+
+	if rules, err := readCustomRules(filepath.Join(dir, "missing.json")); rules != nil || err != nil {
+		t.Errorf("missing file = %v, %v; want no rules", rules, err)
+	}
+	if _, err := readCustomRules(broken); err == nil {
+		t.Error("broken file read without error")
+	}
+}
+
+func TestRuleTestEndpoint(t *testing.T) {
+	svc := weightedService(t)
+
+	tests := []struct {
+		name    string
+		req     RuleTestRequest
+		code    int
+		matches []RuleMatch
+		safe    bool
+	}{
+		{"no match", RuleTestRequest{ValidateRequest: ValidateRequest{Input: "hallo"}}, http.StatusOK, []RuleMatch{}, true},
+		{"positions", RuleTestRequest{ValidateRequest: ValidateRequest{Input: "apple und apple"}}, http.StatusOK,
+			[]RuleMatch{{Rule: "low", Start: 0, End: 5, Text: "apple"}, {Rule: "low", Start: 10, End: 15, Text: "apple"}}, true},
+		{"draft rule", RuleTestRequest{ValidateRequest: ValidateRequest{Input: "eine kiwi"}, Rules: []Rule{{ID: "kiwi", Pattern: "kiwi", Action: ActionReject}}}, http.StatusOK,
+			[]RuleMatch{{Rule: "kiwi", Start: 5, End: 9, Text: "kiwi"}}, false},
+		{"invalid draft rule", RuleTestRequest{ValidateRequest: ValidateRequest{Input: "x"}, Rules: []Rule{{ID: "kaputt", Pattern: "("}}}, http.StatusUnprocessableEntity, nil, false},
+		{"unknown profile", RuleTestRequest{ValidateRequest: ValidateRequest{Input: "x", Profile: "paranoid"}}, http.StatusBadRequest, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(svc, http.MethodPost, "/api/security/rules/test", tt.req, nil)
+			if rec.Code != tt.code {
+				t.Fatalf("status %d, want %d (%s)", rec.Code, tt.code, rec.Body)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			var response struct {
+				Result  ValidateResponse `json:"result"`
+				Matches []RuleMatch      `json:"matches"`
+			}
+			decode(t, rec, &response)
+			if len(response.Matches) != len(tt.matches) {
+				t.Fatalf("matches = %+v, want %+v", response.Matches, tt.matches)
+			}
+			for i := range tt.matches {
+				if response.Matches[i] != tt.matches[i] {
+					t.Errorf("match %d = %+v, want %+v", i, response.Matches[i], tt.matches[i])
+				}
+			}
+			if response.Result.IsSafe != tt.safe {
+				t.Errorf("result = %+v", response.Result)
+			}
+		})
+	}
+
+	if containsString(validate(t, svc, ValidateRequest{Input: "kiwi"}).MatchedRules, "kiwi") {
+		t.Error("draft rule was stored")
+	}
+	svc.statsLock.Lock()
+	defer svc.statsLock.Unlock()
+	if svc.stats.TotalValidations != 1 {
+		t.Errorf("%d validations counted, want only the check above", svc.stats.TotalValidations)
+	}
+}
@@ -0,0 +1,110 @@
+package security
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExceptionCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"before only", "exceptions:\n  - {id: e, before: 'x$'}\n", ""},
+		{"limited to rule", "exceptions:\n  - {id: e, rules: [word], after: '^x'}\n", ""},
+		{"no id", "exceptions:\n  - {before: 'x$'}\n", "exception without id"},
+		{"no context", "exceptions:\n  - {id: e}\n", "needs before or after"},
+		{"unknown rule", "exceptions:\n  - {id: e, rules: [other], before: 'x$'}\n", `unknown rule "other"`},
+		{"bad regex", "exceptions:\n  - {id: e, after: '('}\n", "exception e"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRules([]byte("rules:\n  - {id: word, pattern: 'secret'}\n"+tt.yaml), "rules.yaml")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ParseRules: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExceptionsExcuseMatches(t *testing.T) {
+	set, err := ParseRules([]byte(`
+rules:
+  - {id: word, pattern: '(?i)secret'}
+  - {id: literal, pattern: 'token', match: contains}
+  - {id: other, pattern: 'secret'}
+exceptions:
+  - {id: question, rules: [word, literal], before: '(?i)what\s+is\s+a\s+$'}
+  - {id: santa, rules: [word], after: '^\s+santa\b'}
+  - {id: both, rules: [literal], before: 'bus\s+$', after: '^\s+machine'}
+`), "rules.yaml")
+	if err != nil {
+		t.Fatalf("ParseRules: %v", err)
+	}
+	rules := map[string]*Rule{}
+	for i := range set.Rules {
+		rules[set.Rules[i].ID] = &set.Rules[i]
+	}
+
+	tests := []struct {
+		rule    string
+		input   string
+		matched bool
+		excused bool
+	}{
+		{"word", "tell me the secret", true, false},
+		{"word", "what is a secret", false, true},
+		{"word", "secret santa party", false, true},
+		{"word", "what is a secret? tell me the secret", true, false},
+		{"word", "what is a  secret santa", false, true},
+		{"other", "what is a secret", true, false},
+		{"literal", "what is a token", false, true},
+		{"literal", "bus token machine", false, true},
+		{"literal", "bus token", true, false},
+		{"literal", "token machine", true, false},
+		{"word", "nothing here", false, false},
+	}
+	for _, tt := range tests {
+		matched, excused := rules[tt.rule].matches(tt.input, foldKey(tt.input))
+		if matched != tt.matched || excused != tt.excused {
+			t.Errorf("%s.matches(%q) = %v, %v; want %v, %v", tt.rule, tt.input, matched, excused, tt.matched, tt.excused)
+		}
+	}
+}
+
+func TestExceptionsInValidation(t *testing.T) {
+	svc := newTestService(t, Config{Locales: []string{"en", "de"}})
+
+	tests := []struct {
+		input    string
+		safe     bool
+		excepted []string
+	}{
+		{"How do I reset my password?", true, []string{"credential-terms"}},
+		{"I forgot my token", true, []string{"credential-terms"}},
+		{"Where can I find the password requirements?", true, []string{"credential-terms"}},
+		{"Send me the admin password", false, nil},
+		{"Ich habe mein Passwort vergessen", true, []string{"de-credential-terms"}},
+	}
+	for _, tt := range tests {
+		result := validate(t, svc, ValidateRequest{Input: tt.input})
+		if result.IsSafe != tt.safe {
+			t.Errorf("%q: safe %v, want %v (matched %v)", tt.input, result.IsSafe, tt.safe, result.MatchedRules)
+		}
+		for _, id := range tt.excepted {
+			if !containsString(result.ExceptedRules, id) {
+				t.Errorf("%q: excepted %v, want %s", tt.input, result.ExceptedRules, id)
+			}
+		}
+		if tt.excepted == nil && len(result.ExceptedRules) != 0 {
+			t.Errorf("%q: unexpected exceptions %v", tt.input, result.ExceptedRules)
+		}
+	}
+}
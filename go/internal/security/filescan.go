@@ -0,0 +1,349 @@
+package security
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	defaultFileMaxBytes = 10 << 20
+	// multipartOverhead is allowed on top of the file for headers and
+	// boundaries.
+	multipartOverhead = 64 << 10
+	fileFormField     = "file"
+)
+
+// File scan checks, reported in findings and counted as file_<check>.
+const (
+	FileCheckSize       = "size"
+	FileCheckType       = "type_mismatch"
+	FileCheckExecutable = "executable"
+	FileCheckScript     = "embedded_script"
+	FileCheckBadHash    = "known_bad_hash"
+)
+
+// FileFinding is one problem found in a file.
+type FileFinding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Detail   string `json:"detail"`
+}
+
+type FileScanResponse struct {
+	IsSafe       bool          `json:"is_safe"`
+	Filename     string        `json:"filename"`
+	Size         int64         `json:"size"`
+	SHA256       string        `json:"sha256"`
+	DeclaredType string        `json:"declared_type"`
+	DetectedType string        `json:"detected_type"`
+	Severity     string        `json:"severity"`
+	Findings     []FileFinding `json:"findings"`
+}
+
+// executableMagic are file signatures of native executables.
+var executableMagic = []struct {
+	magic []byte
+	name  string
+}{
+	{[]byte("\x7fELF"), "ELF executable"},
+	{[]byte("\xfe\xed\xfa\xce"), "Mach-O executable"},
+	{[]byte("\xfe\xed\xfa\xcf"), "Mach-O executable"},
+	{[]byte("\xcf\xfa\xed\xfe"), "Mach-O executable"},
+	{[]byte("\xce\xfa\xed\xfe"), "Mach-O executable"},
+	{[]byte("\xca\xfe\xba\xbe"), "Mach-O universal binary or Java class"},
+}
+
+// executable names the kind of executable data is, with the severity of
+// finding it, or returns "".
+func executable(data []byte) (string, string) {
+	for _, signature := range executableMagic {
+		if bytes.HasPrefix(data, signature.magic) {
+			return signature.name, "critical"
+		}
+	}
+	// MZ alone is common in text; a PE file has the offset of its PE
+	// header at 0x3c.
+	if bytes.HasPrefix(data, []byte("MZ")) && len(data) >= 0x40 {
+		offset := int(binary.LittleEndian.Uint32(data[0x3c:]))
+		if offset >= 0x40 && offset+4 <= len(data) && bytes.Equal(data[offset:offset+4], []byte("PE\x00\x00")) {
+			return "Windows executable", "critical"
+		}
+	}
+	if bytes.HasPrefix(data, []byte("#!/")) {
+		return "script with interpreter line", "high"
+	}
+	return "", ""
+}
+
+// equivalentTypes lists detected types that legitimately carry a declared
+// type which content sniffing cannot tell apart.
+var equivalentTypes = map[string][]string{
+	"text/plain": {
+		"text/csv", "text/markdown", "application/json", "text/xml", "application/xml",
+		"image/svg+xml", "text/x-python", "application/x-yaml", "application/yaml", "text/yaml",
+	},
+	"text/xml": {"application/xml", "image/svg+xml"},
+	"application/zip": {
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		"application/epub+zip",
+	},
+}
+
+// readBadHashes reads SHA-256 hashes, one per line; # starts a comment.
+func readBadHashes(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hashes := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) > 0 {
+			line = strings.ToLower(fields[0])
+		}
+		if len(line) != sha256.Size*2 {
+			return nil, fmt.Errorf("invalid sha256 %q", line)
+		}
+		hashes[line] = true
+	}
+	return hashes, scanner.Err()
+}
+
+// scanFileHandler checks an uploaded file (multipart field "file"): size,
+// content type against its magic bytes, executables, scripts embedded in
+// SVG or HTML and known bad hashes.
+func (s *Service) scanFileHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.cfg.FileMaxBytes+multipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, `{"error":"Expected multipart/form-data"}`, http.StatusBadRequest)
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			http.Error(w, `{"error":"No file in field \"file\""}`, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		if part.FormName() != fileFormField {
+			part.Close()
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(part, s.cfg.FileMaxBytes+1))
+		part.Close()
+		var maxBytesErr *http.MaxBytesError
+		if err != nil && !errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		tooLarge := err != nil || int64(len(data)) > s.cfg.FileMaxBytes
+
+		result := s.scanFile(part.FileName(), part.Header.Get("Content-Type"), data, tooLarge)
+		s.recordFileScan(r, result)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+}
+
+// scanFile runs the file checks. A file over the size limit is only
+// reported, its content is not checked.
+func (s *Service) scanFile(filename, declared string, data []byte, tooLarge bool) FileScanResponse {
+	result := FileScanResponse{
+		Filename:     filepath.Base(filename),
+		Size:         int64(len(data)),
+		DeclaredType: mediaType(declared),
+		Findings:     []FileFinding{},
+	}
+	if tooLarge {
+		result.Findings = append(result.Findings, FileFinding{
+			Check:    FileCheckSize,
+			Severity: "high",
+			Detail:   fmt.Sprintf("File exceeds %d bytes", s.cfg.FileMaxBytes),
+		})
+		return result.finish()
+	}
+
+	sum := sha256.Sum256(data)
+	result.SHA256 = hex.EncodeToString(sum[:])
+	result.DetectedType = mediaType(http.DetectContentType(data))
+
+	s.rulesLock.RLock()
+	bad := s.badHashes[result.SHA256]
+	s.rulesLock.RUnlock()
+	if bad {
+		result.Findings = append(result.Findings, FileFinding{Check: FileCheckBadHash, Severity: "critical", Detail: "File matches a known bad hash"})
+	}
+
+	if kind, severity := executable(data); kind != "" {
+		result.Findings = append(result.Findings, FileFinding{Check: FileCheckExecutable, Severity: severity, Detail: "Executable content: " + kind})
+	}
+
+	if detail := typeMismatch(result.DeclaredType, result.DetectedType, filename); detail != "" {
+		result.Findings = append(result.Findings, FileFinding{Check: FileCheckType, Severity: "high", Detail: detail})
+	}
+
+	if isMarkup(result.DeclaredType, result.DetectedType, filename) {
+		for _, detail := range embeddedScripts(data) {
+			result.Findings = append(result.Findings, FileFinding{Check: FileCheckScript, Severity: "critical", Detail: detail})
+		}
+	}
+	return result.finish()
+}
+
+// finish derives the severity from the findings; high and critical findings
+// make the file unsafe.
+func (result FileScanResponse) finish() FileScanResponse {
+	result.Severity = "low"
+	for _, finding := range result.Findings {
+		if severityRank[finding.Severity] > severityRank[result.Severity] {
+			result.Severity = finding.Severity
+		}
+	}
+	result.IsSafe = severityRank[result.Severity] < severityRank["high"]
+	return result
+}
+
+func (s *Service) recordFileScan(r *http.Request, result FileScanResponse) {
+	checks := make([]string, 0, len(result.Findings))
+	s.statsLock.Lock()
+	for _, finding := range result.Findings {
+		s.stats.Warnings["file_"+finding.Check]++
+		if !containsString(checks, finding.Check) {
+			checks = append(checks, finding.Check)
+		}
+	}
+	if !result.IsSafe {
+		s.stats.Rejected++
+	}
+	s.statsLock.Unlock()
+
+	hash := result.SHA256
+	if len(hash) > auditHashLength {
+		hash = hash[:auditHashLength]
+	}
+	s.record(r, hash, int(result.Size), ValidateResponse{
+		Severity:     result.Severity,
+		MatchedRules: checks,
+		Rejected:     !result.IsSafe,
+	})
+}
+
+func mediaType(contentType string) string {
+	parsed, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+	return parsed
+}
+
+// typeMismatch explains why the declared type does not fit the content or
+// the file name, or returns "". Unknown types are not compared.
+func typeMismatch(declared, detected, filename string) string {
+	if declared == "" || declared == "application/octet-stream" {
+		return ""
+	}
+	if detected != "application/octet-stream" && !compatibleTypes(detected, declared) {
+		return fmt.Sprintf("Declared %s but content is %s", declared, detected)
+	}
+	byExtension := mediaType(mime.TypeByExtension(strings.ToLower(filepath.Ext(filename))))
+	if byExtension != "" && !compatibleTypes(byExtension, declared) {
+		return fmt.Sprintf("Declared %s but file name suggests %s", declared, byExtension)
+	}
+	return ""
+}
+
+// compatibleTypes reports whether content of type a may be declared as b.
+// Sniffing only tells plain text, so it fits every text type.
+func compatibleTypes(a, b string) bool {
+	if a == b || containsString(equivalentTypes[a], b) || containsString(equivalentTypes[b], a) {
+		return true
+	}
+	return a == "text/plain" && strings.HasPrefix(b, "text/")
+}
+
+func isMarkup(declared, detected, filename string) bool {
+	for _, contentType := range []string{declared, detected} {
+		switch contentType {
+		case "text/html", "image/svg+xml", "application/xhtml+xml", "text/xml", "application/xml":
+			return true
+		}
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".html", ".htm", ".xhtml", ".svg", ".xml":
+		return true
+	}
+	return false
+}
+
+// embeddedScripts lists active content in HTML, SVG or XML: script and
+// embedding elements, event handler attributes and unsafe URLs.
+func embeddedScripts(data []byte) []string {
+	tokenizer := html.NewTokenizer(bytes.NewReader(data))
+	found := &removedSet{items: []string{}}
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return found.items
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+		token := tokenizer.Token()
+		switch token.Data {
+		case "script", "iframe", "frame", "object", "embed", "applet", "foreignobject", "handler":
+			found.add("<" + token.Data + "> element")
+		}
+		for _, attr := range token.Attr {
+			name := strings.ToLower(attr.Key)
+			if strings.HasPrefix(name, "on") {
+				found.add(name + " handler")
+				continue
+			}
+			if (urlAttributes[name] || name == "xlink:href") && !safeFileURL(attr.Val) {
+				found.add("unsafe URL in " + name)
+			}
+		}
+	}
+}
+
+// safeFileURL is safeURL that also accepts inline raster images, which SVG
+// files commonly embed.
+func safeFileURL(raw string) bool {
+	lower := strings.ToLower(strings.TrimSpace(raw))
+	if strings.HasPrefix(lower, "data:image/") && !strings.HasPrefix(lower, "data:image/svg") {
+		return true
+	}
+	return safeURL(raw)
+}
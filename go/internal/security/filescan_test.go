@@ -0,0 +1,191 @@
+package security
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func peFile() []byte {
+	data := make([]byte, 0x84)
+	copy(data, "MZ")
+	binary.LittleEndian.PutUint32(data[0x3c:], 0x80)
+	copy(data[0x80:], "PE\x00\x00")
+	return data
+}
+
+func TestExecutable(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		severity string
+	}{
+		{"elf", []byte("\x7fELF\x02\x01"), "critical"},
+		{"mach-o", []byte("\xcf\xfa\xed\xfe...."), "critical"},
+		{"pe", peFile(), "critical"},
+		{"mz text", append([]byte("MZ ist eine Abkürzung"), make([]byte, 0x40)...), ""},
+		{"shebang", []byte("#!/bin/sh\nrm -rf /"), "high"},
+		{"text", []byte("Hallo Welt"), ""},
+	}
+	for _, tt := range tests {
+		if _, severity := executable(tt.data); severity != tt.severity {
+			t.Errorf("%s: severity %q, want %q", tt.name, severity, tt.severity)
+		}
+	}
+}
+
+func TestTypeMismatch(t *testing.T) {
+	tests := []struct {
+		declared, detected, filename string
+		mismatch                     bool
+	}{
+		{"image/png", "image/png", "bild.png", false},
+		{"image/png", "text/html", "bild.png", true},
+		{"image/png", "image/png", "bild.exe", true},
+		{"application/json", "text/plain", "daten.json", false},
+		{"text/csv", "text/plain", "liste.csv", false},
+		{"image/svg+xml", "text/xml", "logo.svg", false},
+		{"application/vnd.openxmlformats-officedocument.wordprocessingml.document", "application/zip", "brief.docx", false},
+		{"application/pdf", "application/octet-stream", "unbekannt", false},
+		{"", "text/html", "seite.html", false},
+		{"application/octet-stream", "image/png", "bild.png", false},
+	}
+	for _, tt := range tests {
+		if detail := typeMismatch(tt.declared, tt.detected, tt.filename); (detail != "") != tt.mismatch {
+			t.Errorf("typeMismatch(%s, %s, %s) = %q, want mismatch %v", tt.declared, tt.detected, tt.filename, detail, tt.mismatch)
+		}
+	}
+}
+
+func TestEmbeddedScripts(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		found []string
+	}{
+		{"clean svg", `<svg><circle r="4"/><image href="data:image/png;base64,AAAA"/></svg>`, nil},
+		{"script", `<svg><script>alert(1)</script></svg>`, []string{"<script> element"}},
+		{"handler", `<svg onload="alert(1)"><a xlink:href="javascript:x()"></a></svg>`, []string{"onload handler", "unsafe URL in xlink:href"}},
+		{"nested svg data url", `<img src="data:image/svg+xml;base64,AAAA">`, []string{"unsafe URL in src"}},
+		{"foreign object", `<svg><foreignObject><iframe src="https://example.com"></iframe></foreignObject></svg>`, []string{"<foreignobject> element", "<iframe> element"}},
+	}
+	for _, tt := range tests {
+		if found := embeddedScripts([]byte(tt.data)); strings.Join(found, ",") != strings.Join(tt.found, ",") {
+			t.Errorf("%s: found %q, want %q", tt.name, found, tt.found)
+		}
+	}
+}
+
+func TestReadBadHashes(t *testing.T) {
+	dir := t.TempDir()
+	hash := hex.EncodeToString(make([]byte, sha256.Size))
+	valid := filepath.Join(dir, "valid.txt")
+	os.WriteFile(valid, []byte("# bekannte Schadsoftware\n\n"+hash+"  boese.exe\n"+"AB"+hash[2:]+" # Kommentar\n"), 0o600)
+	invalid := filepath.Join(dir, "invalid.txt")
+	os.WriteFile(invalid, []byte("abc\n"), 0o600)
+
+	hashes, err := readBadHashes(valid)
+	if err != nil || len(hashes) != 2 || !hashes[hash] || !hashes["ab"+hash[2:]] {
+		t.Errorf("hashes = %v (%v)", hashes, err)
+	}
+	if _, err := readBadHashes(invalid); err == nil {
+		t.Error("invalid hash accepted")
+	}
+	if _, err := readBadHashes(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("missing file accepted")
+	}
+}
+
+// uploadFile posts data as the multipart field name.
+func uploadFile(svc *Service, field, filename, contentType string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("kommentar", "Anhang")
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="`+field+`"; filename="`+filename+`"`)
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	part, _ := writer.CreatePart(header)
+	part.Write(data)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/security/scan-file", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	mux := http.NewServeMux()
+	svc.Routes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestScanFileHandler(t *testing.T) {
+	bad := []byte("bekannt böse")
+	sum := sha256.Sum256(bad)
+	hashes := filepath.Join(t.TempDir(), "hashes.txt")
+	os.WriteFile(hashes, []byte(hex.EncodeToString(sum[:])+"\n"), 0o600)
+	svc := newTestService(t, Config{FileMaxBytes: 1024, BadHashesFile: hashes})
+
+	tests := []struct {
+		name        string
+		field       string
+		filename    string
+		contentType string
+		data        []byte
+		code        int
+		safe        bool
+		checks      []string
+	}{
+		{"png", "file", "bild.png", "image/png", pngHeader, http.StatusOK, true, nil},
+		{"text", "file", "notiz.txt", "text/plain; charset=utf-8", []byte("Hallo"), http.StatusOK, true, nil},
+		{"disguised html", "file", "bild.png", "image/png", []byte("<html><script>x</script></html>"), http.StatusOK, false, []string{FileCheckType, FileCheckScript}},
+		{"executable", "file", "tool.bin", "application/octet-stream", []byte("\x7fELF\x02\x01\x01"), http.StatusOK, false, []string{FileCheckExecutable}},
+		{"known bad hash", "file", "daten.txt", "text/plain", bad, http.StatusOK, false, []string{FileCheckBadHash}},
+		{"too large", "file", "gross.txt", "text/plain", bytes.Repeat([]byte("a"), 2048), http.StatusOK, false, []string{FileCheckSize}},
+		{"path in file name", "file", "../../etc/passwd", "", []byte("x"), http.StatusOK, true, nil},
+		{"wrong field", "anhang", "bild.png", "image/png", pngHeader, http.StatusBadRequest, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := uploadFile(svc, tt.field, tt.filename, tt.contentType, tt.data)
+			if rec.Code != tt.code {
+				t.Fatalf("status %d, want %d (%s)", rec.Code, tt.code, rec.Body)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			var result FileScanResponse
+			decode(t, rec, &result)
+			var checks []string
+			for _, finding := range result.Findings {
+				checks = append(checks, finding.Check)
+			}
+			if result.IsSafe != tt.safe || strings.Join(checks, ",") != strings.Join(tt.checks, ",") {
+				t.Errorf("result = %+v, want safe %v with %v", result, tt.safe, tt.checks)
+			}
+			if result.Filename == "" || filepath.Base(result.Filename) != result.Filename {
+				t.Errorf("file name %q", result.Filename)
+			}
+		})
+	}
+
+	if rec := serve(svc, http.MethodPost, "/api/security/scan-file", map[string]string{"file": "x"}, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("JSON body: status %d, want 400", rec.Code)
+	}
+	svc.statsLock.Lock()
+	defer svc.statsLock.Unlock()
+	if svc.stats.Rejected != 4 || svc.stats.Warnings["file_"+FileCheckScript] != 1 {
+		t.Errorf("stats = %+v", svc.stats)
+	}
+}
@@ -0,0 +1,138 @@
+package security
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// latencyBuckets are the upper bounds in seconds of the validation latency
+// histogram.
+var latencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// validationMetrics count rule hits and validation latency for /metrics.
+// The other metrics are read from the stats and rule timings.
+type validationMetrics struct {
+	ruleHits map[string]int64
+	buckets  []int64
+	count    int64
+	sum      float64
+	mu       sync.Mutex
+}
+
+func newValidationMetrics() *validationMetrics {
+	return &validationMetrics{ruleHits: make(map[string]int64), buckets: make([]int64, len(latencyBuckets))}
+}
+
+// observe records one validation. A nil validationMetrics discards it.
+func (m *validationMetrics) observe(matched []string, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	seconds := elapsed.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, rule := range matched {
+		m.ruleHits[rule]++
+	}
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			m.buckets[i]++
+		}
+	}
+	m.count++
+	m.sum += seconds
+}
+
+// metricsHandler writes the metrics in the Prometheus text format.
+func (s *Service) metricsHandler(w http.ResponseWriter, _ *http.Request) {
+	s.statsLock.Lock()
+	stats := s.stats.copy()
+	s.statsLock.Unlock()
+
+	w.Header().Set("Content-Type", metricsContentType)
+
+	writeMetric(w, "jarvis_security_validations_total", "counter", "Validated inputs.", float64(stats.TotalValidations))
+	writeMetric(w, "jarvis_security_rejections_total", "counter", "Rejected inputs and files.", float64(stats.Rejected))
+	ratio := 0.0
+	if stats.TotalValidations > 0 {
+		ratio = float64(stats.Rejected) / float64(stats.TotalValidations)
+	}
+	writeMetric(w, "jarvis_security_rejection_ratio", "gauge", "Rejected share of all validations.", ratio)
+	writeLabeled(w, "jarvis_security_warnings_total", "counter", "Warnings by category.", "category", intValues(stats.Warnings))
+
+	s.metrics.mu.Lock()
+	hits := make(map[string]float64, len(s.metrics.ruleHits))
+	for rule, count := range s.metrics.ruleHits {
+		hits[rule] = float64(count)
+	}
+	buckets := append([]int64(nil), s.metrics.buckets...)
+	count, sum := s.metrics.count, s.metrics.sum
+	s.metrics.mu.Unlock()
+
+	writeLabeled(w, "jarvis_security_rule_hits_total", "counter", "Matches by rule.", "rule", hits)
+
+	fmt.Fprintf(w, "# HELP jarvis_security_validation_duration_seconds Time to validate one input.\n")
+	fmt.Fprintf(w, "# TYPE jarvis_security_validation_duration_seconds histogram\n")
+	for i, bound := range latencyBuckets {
+		fmt.Fprintf(w, "jarvis_security_validation_duration_seconds_bucket{le=\"%s\"} %d\n", formatFloat(bound), buckets[i])
+	}
+	fmt.Fprintf(w, "jarvis_security_validation_duration_seconds_bucket{le=\"+Inf\"} %d\n", count)
+	fmt.Fprintf(w, "jarvis_security_validation_duration_seconds_sum %s\n", formatFloat(sum))
+	fmt.Fprintf(w, "jarvis_security_validation_duration_seconds_count %d\n", count)
+
+	durations := map[string]float64{}
+	skipped := map[string]float64{}
+	for _, timing := range s.timings.snapshot() {
+		durations[timing.Rule] = timing.Total.Seconds()
+		skipped[timing.Rule] = float64(timing.Skipped)
+	}
+	writeLabeled(w, "jarvis_security_rule_duration_seconds_total", "counter", "Time spent matching each rule.", "rule", durations)
+	writeLabeled(w, "jarvis_security_rule_skipped_total", "counter", "Slow rules skipped for exceeding the scan budget.", "rule", skipped)
+
+	rules := s.rules()
+	writeMetric(w, "jarvis_security_rules_loaded", "gauge", "Active validation rules.", float64(len(rules.Rules)))
+	writeMetric(w, "jarvis_security_rules_loaded_timestamp_seconds", "gauge", "When the rules were last loaded.", float64(rules.LoadedAt.Unix()))
+}
+
+func writeMetric(w io.Writer, name, kind, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", name, help, name, kind, name, formatFloat(value))
+}
+
+// writeLabeled writes one sample per label value, sorted by label.
+func writeLabeled(w io.Writer, name, kind, help, label string, values map[string]float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %s\n", name, label, escapeLabel(key), formatFloat(values[key]))
+	}
+}
+
+func intValues(values map[string]int) map[string]float64 {
+	converted := make(map[string]float64, len(values))
+	for key, value := range values {
+		converted[key] = float64(value)
+	}
+	return converted
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
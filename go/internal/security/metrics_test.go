@@ -0,0 +1,84 @@
+package security
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidationMetricsObserve(t *testing.T) {
+	metrics := newValidationMetrics()
+	metrics.observe([]string{"low", "high"}, 300*time.Microsecond)
+	metrics.observe([]string{"low"}, 3*time.Millisecond)
+	metrics.observe(nil, 2*time.Second)
+
+	tests := []struct {
+		bound float64
+		count int64
+	}{
+		{0.0005, 1},
+		{0.0025, 1},
+		{0.005, 2},
+		{1, 2},
+	}
+	for _, tt := range tests {
+		for i, bound := range latencyBuckets {
+			if bound == tt.bound && metrics.buckets[i] != tt.count {
+				t.Errorf("bucket le=%v: %d, want %d", tt.bound, metrics.buckets[i], tt.count)
+			}
+		}
+	}
+	if metrics.count != 3 || metrics.ruleHits["low"] != 2 || metrics.ruleHits["high"] != 1 {
+		t.Errorf("count %d, hits %v", metrics.count, metrics.ruleHits)
+	}
+
+	var none *validationMetrics
+	none.observe([]string{"low"}, time.Second)
+}
+
+func TestWriteLabeled(t *testing.T) {
+	var out bytes.Buffer
+	writeLabeled(&out, "m", "counter", "Hilfe.", "rule", map[string]float64{"b": 2, `a"\` + "\n": 0.5})
+	want := "# HELP m Hilfe.\n# TYPE m counter\n" + `m{rule="a\"\\\n"} 0.5` + "\nm{rule=\"b\"} 2\n"
+	if out.String() != want {
+		t.Errorf("output\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	svc := weightedService(t)
+	for _, input := range []string{"apple", "apple banana", "elder", "hallo"} {
+		validate(t, svc, ValidateRequest{Input: input})
+	}
+
+	rec := serve(svc, http.MethodGet, "/metrics", nil, nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != metricsContentType {
+		t.Fatalf("status %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"jarvis_security_validations_total 4\n",
+		"jarvis_security_rejections_total 1\n",
+		"jarvis_security_rejection_ratio 0.25\n",
+		`jarvis_security_rule_hits_total{rule="low"} 2` + "\n",
+		`jarvis_security_rule_hits_total{rule="blocker"} 1` + "\n",
+		`jarvis_security_validation_duration_seconds_bucket{le="+Inf"} 4` + "\n",
+		"jarvis_security_validation_duration_seconds_count 4\n",
+		`jarvis_security_rule_skipped_total{rule="heavy"} 0` + "\n",
+		"jarvis_security_rules_loaded 5\n",
+		"# TYPE jarvis_security_rule_duration_seconds_total counter\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics miss %q", want)
+		}
+	}
+}
+
+func TestMetricsWithoutValidations(t *testing.T) {
+	body := serve(newTestService(t, Config{}), http.MethodGet, "/metrics", nil, nil).Body.String()
+	if !strings.Contains(body, "jarvis_security_rejection_ratio 0\n") || strings.Contains(body, "NaN") {
+		t.Errorf("metrics without validations:\n%s", body)
+	}
+}
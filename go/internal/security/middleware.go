@@ -0,0 +1,290 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"jarviscore/go/internal/authmw"
+)
+
+const (
+	defaultMiddlewareTimeout = 2 * time.Second
+	defaultMiddlewareMaxBody = 1 << 20
+)
+
+// defaultMiddlewareFields are the JSON fields validated when none are
+// configured.
+var defaultMiddlewareFields = []string{"input", "text", "message", "prompt", "command", "query"}
+
+// MiddlewareConfig configures a Middleware.
+type MiddlewareConfig struct {
+	// URL is the security service; empty disables the middleware.
+	URL string
+	// Token is sent as bearer token if securityd requires authentication.
+	Token string
+	// Profile is the validation profile; empty lets securityd choose, e.g.
+	// by the caller's API key, which is forwarded.
+	Profile string
+	// Fields are the top-level JSON body fields validated. Strings and
+	// arrays of strings are checked.
+	Fields []string
+	// FailOpen passes requests on when securityd cannot be reached or
+	// answers with a server error, instead of answering 503. Requests it
+	// refuses to validate are always answered with 422.
+	FailOpen bool
+	Timeout  time.Duration
+	// MaxBodyBytes limits the bodies read for validation; larger ones are
+	// answered with 413.
+	MaxBodyBytes int64
+}
+
+// LoadMiddlewareConfig reads <PREFIX>_SECURITY_URL, <PREFIX>_SECURITY_TOKEN,
+// <PREFIX>_SECURITY_PROFILE, <PREFIX>_SECURITY_FIELDS (comma separated),
+// <PREFIX>_SECURITY_FAIL_OPEN, <PREFIX>_SECURITY_TIMEOUT (seconds) and
+// <PREFIX>_SECURITY_MAX_BODY_BYTES from the environment.
+func LoadMiddlewareConfig(prefix string) MiddlewareConfig {
+	cfg := MiddlewareConfig{
+		URL:          strings.TrimRight(strings.TrimSpace(os.Getenv(prefix+"_SECURITY_URL")), "/"),
+		Token:        strings.TrimSpace(os.Getenv(prefix + "_SECURITY_TOKEN")),
+		Profile:      strings.ToLower(strings.TrimSpace(os.Getenv(prefix + "_SECURITY_PROFILE"))),
+		Fields:       defaultMiddlewareFields,
+		Timeout:      defaultMiddlewareTimeout,
+		MaxBodyBytes: defaultMiddlewareMaxBody,
+	}
+	if value := strings.TrimSpace(os.Getenv(prefix + "_SECURITY_FIELDS")); value != "" {
+		cfg.Fields = nil
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				cfg.Fields = append(cfg.Fields, field)
+			}
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(prefix + "_SECURITY_FAIL_OPEN"))) {
+	case "1", "true", "yes":
+		cfg.FailOpen = true
+	}
+	if value := strings.TrimSpace(os.Getenv(prefix + "_SECURITY_TIMEOUT")); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil && parsed > 0 {
+			cfg.Timeout = time.Duration(parsed * float64(time.Second))
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv(prefix + "_SECURITY_MAX_BODY_BYTES")); value != "" {
+		if parsed, err := strconv.ParseInt(value, 10, 64); err == nil && parsed > 0 {
+			cfg.MaxBodyBytes = parsed
+		}
+	}
+	return cfg
+}
+
+// Middleware validates the text fields of inbound JSON requests with the
+// security service before they reach a handler. Rejected requests are
+// answered with 422; the results of accepted ones are stored in the request
+// context.
+type Middleware struct {
+	cfg    MiddlewareConfig
+	client *http.Client
+	logger *log.Logger
+}
+
+func NewMiddleware(cfg MiddlewareConfig, logger *log.Logger) *Middleware {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultMiddlewareTimeout
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = defaultMiddlewareMaxBody
+	}
+	if len(cfg.Fields) == 0 {
+		cfg.Fields = defaultMiddlewareFields
+	}
+	if logger == nil {
+		logger = log.New(os.Stdout, "[security] ", log.LstdFlags|log.LUTC)
+	}
+	return &Middleware{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}, logger: logger}
+}
+
+type resultKey struct{}
+
+// ResultFromContext returns the validation result of the request's text,
+// set by the middleware when the body contained any.
+func ResultFromContext(ctx context.Context) (*BatchValidateResponse, bool) {
+	result, ok := ctx.Value(resultKey{}).(*BatchValidateResponse)
+	return result, ok && result != nil
+}
+
+// Handler wraps next. Without a configured URL it returns next unchanged.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	if m.cfg.URL == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil || !hasBody(r.Method) || !isJSON(r.Header.Get("Content-Type")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, m.cfg.MaxBodyBytes+1))
+		r.Body.Close()
+		if err != nil {
+			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+		if int64(len(body)) > m.cfg.MaxBodyBytes {
+			http.Error(w, `{"error":"Request body too large"}`, http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		inputs := m.texts(body)
+		if len(inputs) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		result, err := m.validate(r, inputs)
+		if err != nil && !errors.Is(err, errSecurityUnavailable) {
+			m.logger.Printf("[WARN] Eingabe konnte nicht geprüft werden, Anfrage abgelehnt: %v", err)
+			http.Error(w, `{"error":"Input could not be validated"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			if m.cfg.FailOpen {
+				m.logger.Printf("[WARN] Sicherheitsprüfung nicht möglich, Anfrage wird durchgelassen: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			m.logger.Printf("[ERROR] Sicherheitsprüfung nicht möglich, Anfrage abgelehnt: %v", err)
+			http.Error(w, `{"error":"Security validation unavailable"}`, http.StatusServiceUnavailable)
+			return
+		}
+		if !result.IsSafe {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":    "Input rejected by security policy",
+				"warnings": rejectedWarnings(result),
+			})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), resultKey{}, result)))
+	})
+}
+
+// texts returns the configured fields of a JSON object body.
+func (m *Middleware) texts(body []byte) []string {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return nil
+	}
+	var texts []string
+	for _, name := range m.cfg.Fields {
+		raw, ok := fields[name]
+		if !ok {
+			continue
+		}
+		var text string
+		if json.Unmarshal(raw, &text) == nil {
+			if text != "" {
+				texts = append(texts, text)
+			}
+			continue
+		}
+		var list []string
+		if json.Unmarshal(raw, &list) == nil {
+			for _, text := range list {
+				if text != "" {
+					texts = append(texts, text)
+				}
+			}
+		}
+	}
+	return texts
+}
+
+// errSecurityUnavailable marks errors after which FailOpen applies: the
+// security service could not be reached or failed itself.
+var errSecurityUnavailable = errors.New("security service unavailable")
+
+// validate asks the batch endpoint of the security service, at most
+// maxBatchSize inputs per call, and stops at the first rejected batch.
+func (m *Middleware) validate(r *http.Request, inputs []string) (*BatchValidateResponse, error) {
+	result := &BatchValidateResponse{Results: make([]ValidateResponse, 0, len(inputs)), IsSafe: true}
+	for start := 0; start < len(inputs); start += maxBatchSize {
+		batch, err := m.validateBatch(r, inputs[start:min(start+maxBatchSize, len(inputs))])
+		if err != nil {
+			return nil, err
+		}
+		result.Results = append(result.Results, batch.Results...)
+		result.Rejected += batch.Rejected
+		result.Total += batch.Total
+		if !batch.IsSafe {
+			result.IsSafe = false
+			break
+		}
+	}
+	return result, nil
+}
+
+// validateBatch asks about one batch. The caller's API key is forwarded, so
+// its profile and stats apply.
+func (m *Middleware) validateBatch(r *http.Request, inputs []string) (*BatchValidateResponse, error) {
+	payload, err := json.Marshal(BatchValidateRequest{Inputs: inputs, Profile: m.cfg.Profile})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, m.cfg.URL+"/api/security/validate/batch", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if key := authmw.APIKeyFromRequest(r); key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	if m.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+m.cfg.Token)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errSecurityUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: %s", errSecurityUnavailable, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("security service returned %s", resp.Status)
+	}
+	var result BatchValidateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("%w: %v", errSecurityUnavailable, err)
+	}
+	return &result, nil
+}
+
+// rejectedWarnings collects the warnings of the rejected inputs.
+func rejectedWarnings(result *BatchValidateResponse) []string {
+	warnings := []string{}
+	for _, item := range result.Results {
+		if item.Rejected {
+			warnings = append(warnings, item.Warnings...)
+		}
+	}
+	return warnings
+}
+
+func hasBody(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+func isJSON(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return mediaType == "" || mediaType == "application/json"
+}
@@ -0,0 +1,185 @@
+package security
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// securityServer serves the routes of svc.
+func securityServer(t *testing.T, svc *Service) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	svc.Routes(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// protected returns a handler behind the middleware that echoes the body and
+// reports how many inputs the middleware validated.
+func protected(cfg MiddlewareConfig) http.Handler {
+	return NewMiddleware(cfg, log.New(io.Discard, "", 0)).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if result, ok := ResultFromContext(r.Context()); ok {
+			w.Header().Set("X-Validated", strings.Repeat("v", result.Total))
+		}
+		w.Write(body)
+	}))
+}
+
+func TestMiddleware(t *testing.T) {
+	server := securityServer(t, weightedService(t))
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		code        int
+		validated   int
+	}{
+		{"safe text", http.MethodPost, "application/json", `{"text":"hallo"}`, http.StatusOK, 1},
+		{"rejected text", http.MethodPost, "application/json", `{"message":"elder"}`, http.StatusUnprocessableEntity, 0},
+		{"string array", http.MethodPut, "application/json; charset=utf-8", `{"input":["apple","elder"]}`, http.StatusUnprocessableEntity, 0},
+		{"several fields", http.MethodPatch, "", `{"prompt":"apple","query":"banana","other":"elder"}`, http.StatusOK, 2},
+		{"no text fields", http.MethodPost, "application/json", `{"id":7,"text":""}`, http.StatusOK, 0},
+		{"not an object", http.MethodPost, "application/json", `["elder"]`, http.StatusOK, 0},
+		{"other content type", http.MethodPost, "text/plain", `{"text":"elder"}`, http.StatusOK, 0},
+		{"get request", http.MethodGet, "application/json", `{"text":"elder"}`, http.StatusOK, 0},
+		{"body too large", http.MethodPost, "application/json", `{"text":"` + strings.Repeat("a", 200) + `"}`, http.StatusRequestEntityTooLarge, 0},
+	}
+	handler := protected(MiddlewareConfig{URL: server.URL, MaxBodyBytes: 100})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/command", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.code {
+				t.Fatalf("status %d, want %d (%s)", rec.Code, tt.code, rec.Body)
+			}
+			if tt.code == http.StatusOK && rec.Body.String() != tt.body {
+				t.Errorf("handler got body %q, want the original", rec.Body)
+			}
+			if got := len(rec.Header().Get("X-Validated")); got != tt.validated {
+				t.Errorf("%d inputs validated, want %d", got, tt.validated)
+			}
+			if tt.code == http.StatusUnprocessableEntity {
+				var response struct{ Warnings []string }
+				json.Unmarshal(rec.Body.Bytes(), &response)
+				if len(response.Warnings) == 0 {
+					t.Errorf("rejection without warnings: %s", rec.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareFailure(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "kaputt", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	refusing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nein", http.StatusUnauthorized)
+	}))
+	defer refusing.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	tests := []struct {
+		name     string
+		url      string
+		failOpen bool
+		code     int
+	}{
+		{"unreachable, fail closed", down.URL, false, http.StatusServiceUnavailable},
+		{"unreachable, fail open", down.URL, true, http.StatusOK},
+		{"server error, fail open", failing.URL, true, http.StatusOK},
+		{"timeout, fail closed", slow.URL, false, http.StatusServiceUnavailable},
+		{"refused, fail open", refusing.URL, true, http.StatusUnprocessableEntity},
+		{"disabled", "", false, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := protected(MiddlewareConfig{URL: tt.url, FailOpen: tt.failOpen, Timeout: 50 * time.Millisecond})
+			req := httptest.NewRequest(http.MethodPost, "/api/command", strings.NewReader(`{"text":"hallo"}`))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.code {
+				t.Errorf("status %d, want %d", rec.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestMiddlewareForwarding(t *testing.T) {
+	var calls atomic.Int32
+	var got http.Header
+	var batch BatchValidateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		got = r.Header.Clone()
+		json.NewDecoder(r.Body).Decode(&batch)
+		results := make([]ValidateResponse, len(batch.Inputs))
+		for i := range results {
+			results[i].IsSafe = true
+		}
+		json.NewEncoder(w).Encode(BatchValidateResponse{Results: results, IsSafe: true, Total: len(results)})
+	}))
+	defer server.Close()
+
+	inputs := make([]string, maxBatchSize+1)
+	for i := range inputs {
+		inputs[i] = "text"
+	}
+	body, _ := json.Marshal(map[string][]string{"input": inputs})
+	req := httptest.NewRequest(http.MethodPost, "/api/command", strings.NewReader(string(body)))
+	req.Header.Set("X-API-Key", "schluessel-anna")
+	rec := httptest.NewRecorder()
+	protected(MiddlewareConfig{URL: server.URL, Token: "dienst", Profile: StrictProfile, MaxBodyBytes: 1 << 20}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || len(rec.Header().Get("X-Validated")) != len(inputs) {
+		t.Fatalf("status %d, validated %d", rec.Code, len(rec.Header().Get("X-Validated")))
+	}
+	if calls.Load() != 2 || len(batch.Inputs) != 1 || batch.Profile != StrictProfile {
+		t.Errorf("%d calls, last batch %+v", calls.Load(), batch)
+	}
+	if got.Get("X-API-Key") != "schluessel-anna" || got.Get("Authorization") != "Bearer dienst" {
+		t.Errorf("headers %v", got)
+	}
+}
+
+func TestLoadMiddlewareConfig(t *testing.T) {
+	t.Setenv("COMMAND_SECURITY_URL", " http://localhost:8095/ ")
+	t.Setenv("COMMAND_SECURITY_PROFILE", "Strict")
+	t.Setenv("COMMAND_SECURITY_FIELDS", "text, ,befehl")
+	t.Setenv("COMMAND_SECURITY_FAIL_OPEN", "yes")
+	t.Setenv("COMMAND_SECURITY_TIMEOUT", "0.5")
+	t.Setenv("COMMAND_SECURITY_MAX_BODY_BYTES", "abc")
+
+	cfg := LoadMiddlewareConfig("COMMAND")
+	want := MiddlewareConfig{
+		URL:          "http://localhost:8095",
+		Profile:      StrictProfile,
+		Fields:       []string{"text", "befehl"},
+		FailOpen:     true,
+		Timeout:      500 * time.Millisecond,
+		MaxBodyBytes: defaultMiddlewareMaxBody,
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("config = %+v, want %+v", cfg, want)
+	}
+}
@@ -0,0 +1,126 @@
+package security
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxReportedHomoglyphs limits the words named in the warning.
+const maxReportedHomoglyphs = 5
+
+// homoglyphs maps Cyrillic and Greek letters to the Latin letters they look
+// like, so "pаssword" with a Cyrillic а matches the rules.
+var homoglyphs = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p',
+	'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd',
+	'ԛ': 'q', 'ԝ': 'w', 'ӏ': 'l', 'һ': 'h', 'ү': 'y',
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O', 'Р': 'P',
+	'С': 'C', 'Т': 'T', 'У': 'Y', 'Х': 'X', 'І': 'I', 'Ј': 'J', 'Ѕ': 'S', 'Ԁ': 'D',
+	'Ԛ': 'Q', 'Ԝ': 'W', 'Һ': 'H', 'Ү': 'Y',
+	// Greek
+	'α': 'a', 'ο': 'o', 'ρ': 'p', 'ν': 'v', 'κ': 'k', 'ι': 'i', 'τ': 't', 'υ': 'u',
+	'χ': 'x', 'ε': 'e', 'ϲ': 'c',
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K', 'Μ': 'M',
+	'Ν': 'N', 'Ο': 'O', 'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+}
+
+// normalizedInput is an input prepared for the checks.
+type normalizedInput struct {
+	// visible is the NFKC normalized input without invisible characters.
+	visible string
+	// scan is visible with homoglyphs replaced; the rules run on it.
+	scan string
+	// invisible counts the removed invisible characters.
+	invisible int
+	// homoglyphWords are words mixing Latin letters with look-alikes.
+	homoglyphWords []string
+}
+
+func normalizeInput(input string) normalizedInput {
+	if isASCII(input) {
+		return normalizedInput{visible: input, scan: input}
+	}
+	var result normalizedInput
+	runes := []rune(input)
+	var builder strings.Builder
+	builder.Grow(len(input))
+	for i, r := range runes {
+		if isInvisible(r) && !joinsSymbols(runes, i) {
+			result.invisible++
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	result.visible = norm.NFKC.String(builder.String())
+
+	result.scan = strings.Map(func(r rune) rune {
+		if latin, ok := homoglyphs[r]; ok {
+			return latin
+		}
+		return r
+	}, result.visible)
+	if result.scan != result.visible {
+		result.homoglyphWords = mixedScriptWords(result.visible)
+	}
+	return result
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// isInvisible reports zero-width, bidi control and similar characters that
+// can hide inside keywords.
+func isInvisible(r rune) bool {
+	switch {
+	case r >= 0x200B && r <= 0x200F, // zero-width space, joiners, direction marks
+		r >= 0x202A && r <= 0x202E, // bidi embeddings and overrides
+		r >= 0x2060 && r <= 0x2064, // word joiner, invisible operators
+		r >= 0x2066 && r <= 0x2069, // bidi isolates
+		r == 0x00AD, r == 0x034F, r == 0x061C, r == 0x115F, r == 0x1160,
+		r == 0x180E, r == 0x3164, r == 0xFEFF, r == 0xFFA0:
+		return true
+	}
+	return unicode.Is(unicode.Variation_Selector, r) && r != 0xFE0F
+}
+
+// joinsSymbols keeps a zero-width joiner between two non-letters, where it
+// forms emoji sequences.
+func joinsSymbols(runes []rune, i int) bool {
+	if runes[i] != 0x200D || i == 0 || i == len(runes)-1 {
+		return false
+	}
+	return !unicode.IsLetter(runes[i-1]) && !unicode.IsLetter(runes[i+1])
+}
+
+// mixedScriptWords returns the words of text that contain both Latin letters
+// and homoglyphs of them.
+func mixedScriptWords(text string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		latin, lookalike := false, false
+		for _, r := range word {
+			if _, ok := homoglyphs[r]; ok {
+				lookalike = true
+			} else if unicode.Is(unicode.Latin, r) {
+				latin = true
+			}
+		}
+		if latin && lookalike {
+			words = append(words, word)
+			if len(words) == maxReportedHomoglyphs {
+				break
+			}
+		}
+	}
+	return words
+}
@@ -0,0 +1,124 @@
+package security
+
+import (
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// maxPrefilterLiterals bounds the literals kept per rule; rules that would
+// need more are always run.
+const maxPrefilterLiterals = 64
+
+// Go's regexp falls back to its NFA on long inputs, which costs around
+// 100ns per byte for the case-insensitive rule patterns. Most patterns can
+// only match where one of a few literals occurs, so each rule keeps these
+// literals and its regex only runs if one of them is found in the input.
+//
+// Literals and input are compared in case-folded form (foldKey), which
+// agrees with (?i) matching, including folds such as ſ/s and K/k.
+
+// requiredLiterals returns literals of which every match of pattern contains
+// at least one, in foldKey form. ok is false if there are none.
+func requiredLiterals(pattern string) (literals []string, ok bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, false
+	}
+	literals, ok = prefixLiterals(re.Simplify())
+	if !ok || len(literals) == 0 {
+		return nil, false
+	}
+	for i, literal := range literals {
+		if literal == "" {
+			return nil, false
+		}
+		literals[i] = foldKey(literal)
+	}
+	return literals, true
+}
+
+// prefixLiterals returns the literals every match of re starts with.
+func prefixLiterals(re *syntax.Regexp) ([]string, bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return []string{string(re.Rune)}, true
+	case syntax.OpCapture:
+		return prefixLiterals(re.Sub[0])
+	case syntax.OpAlternate:
+		var literals []string
+		for _, sub := range re.Sub {
+			subLiterals, ok := prefixLiterals(sub)
+			if !ok {
+				return nil, false
+			}
+			literals = append(literals, subLiterals...)
+		}
+		return literals, len(literals) <= maxPrefilterLiterals
+	case syntax.OpConcat:
+		subs := re.Sub
+		for len(subs) > 0 && emptyWidth(subs[0]) {
+			subs = subs[1:]
+		}
+		if len(subs) == 0 {
+			return nil, false
+		}
+		literals, ok := prefixLiterals(subs[0])
+		if !ok || subs[0].Op != syntax.OpLiteral || len(subs) == 1 {
+			return literals, ok
+		}
+		// Extend a leading literal by what follows, so "e(?:xec|val)"
+		// yields "exec" and "eval" instead of just "e".
+		rest := &syntax.Regexp{Op: syntax.OpConcat, Sub: subs[1:], Flags: re.Flags}
+		suffixes, ok := prefixLiterals(rest)
+		if !ok || len(literals)*len(suffixes) > maxPrefilterLiterals {
+			return literals, true
+		}
+		extended := make([]string, 0, len(literals)*len(suffixes))
+		for _, literal := range literals {
+			for _, suffix := range suffixes {
+				extended = append(extended, literal+suffix)
+			}
+		}
+		return extended, true
+	default:
+		return nil, false
+	}
+}
+
+func emptyWidth(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText,
+		syntax.OpEndText, syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return true
+	}
+	return false
+}
+
+// foldKey maps every rune to the smallest rune of its case folding orbit.
+func foldKey(s string) string {
+	if isASCII(s) {
+		return strings.ToUpper(s)
+	}
+	return strings.Map(func(r rune) rune {
+		smallest := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			smallest = min(smallest, f)
+		}
+		return smallest
+	}, s)
+}
+
+// mayMatch reports whether the rule's regex can match an input whose foldKey
+// is folded.
+func (r *Rule) mayMatch(folded string) bool {
+	if len(r.literals) == 0 {
+		return true
+	}
+	for _, literal := range r.literals {
+		if strings.Contains(folded, literal) {
+			return true
+		}
+	}
+	return false
+}
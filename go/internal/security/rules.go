@@ -0,0 +1,420 @@
+package security
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed default_rules.yaml
+var defaultRules []byte
+
+// rulePacks hold the language specific rules, one file per locale.
+//
+//go:embed rules_*.yaml
+var rulePacks embed.FS
+
+const (
+	rulesWatchInterval = 2 * time.Second
+	defaultRulesSource = "builtin"
+	exceptionContext   = 80
+)
+
+// Rule actions.
+const (
+	ActionWarn   = "warn"
+	ActionStrip  = "strip"
+	ActionReject = "reject"
+)
+
+// Rule matching modes.
+const (
+	MatchRegex    = "regex"
+	MatchContains = "contains"
+)
+
+var severityRank = map[string]int{"low": 0, "medium": 1, "high": 2, "critical": 3}
+
+// Rule is one entry of the rules file.
+type Rule struct {
+	ID       string `json:"id" yaml:"id"`
+	Pattern  string `json:"pattern" yaml:"pattern"`
+	Match    string `json:"match,omitempty" yaml:"match,omitempty"`
+	Severity string `json:"severity" yaml:"severity"`
+	Action   string `json:"action" yaml:"action"`
+	Category string `json:"category,omitempty" yaml:"category,omitempty"`
+	// Group is the rule group profiles enable or disable; it defaults to
+	// the category.
+	Group string `json:"group,omitempty" yaml:"group,omitempty"`
+	// Weight is added to the risk score on a match; it defaults to the
+	// weight of the severity.
+	Weight      float64 `json:"weight" yaml:"weight"`
+	Description string  `json:"description,omitempty" yaml:"description,omitempty"`
+	// Custom marks rules added through the API.
+	Custom bool `json:"custom,omitempty" yaml:"-"`
+
+	re         *regexp.Regexp
+	literals   []string
+	exceptions []*Exception
+}
+
+// Exception excuses matches of Rules (all rules if empty) when the text
+// right before the match fits Before or the text after it fits After. Both
+// are regular expressions applied to at most exceptionContext characters,
+// so anchor them with $ and ^ to demand adjacency.
+type Exception struct {
+	ID     string   `json:"id" yaml:"id"`
+	Rules  []string `json:"rules,omitempty" yaml:"rules,omitempty"`
+	Before string   `json:"before,omitempty" yaml:"before,omitempty"`
+	After  string   `json:"after,omitempty" yaml:"after,omitempty"`
+
+	before *regexp.Regexp
+	after  *regexp.Regexp
+}
+
+// RuleSet is a validated, compiled rules file.
+type RuleSet struct {
+	Rules      []Rule      `json:"rules" yaml:"rules"`
+	Exceptions []Exception `json:"exceptions,omitempty" yaml:"exceptions"`
+	// Profiles are named thresholds and rule groups, e.g. strict and
+	// moderate.
+	Profiles map[string]Profile `json:"profiles,omitempty" yaml:"profiles"`
+	Domains  DomainLists        `json:"domains" yaml:"domains"`
+	Source   string             `json:"source" yaml:"-"`
+	// Locales lists the rule packs added to the rules file.
+	Locales  []string  `json:"locales" yaml:"-"`
+	LoadedAt time.Time `json:"loaded_at" yaml:"-"`
+}
+
+// ParseRules reads a YAML rules document, or JSON if source ends in .json.
+func ParseRules(data []byte, source string) (*RuleSet, error) {
+	set, err := decodeRules(data, source)
+	if err != nil {
+		return nil, err
+	}
+	if err := set.compile(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+func decodeRules(data []byte, source string) (*RuleSet, error) {
+	set := &RuleSet{Source: source}
+	var err error
+	if strings.EqualFold(filepath.Ext(source), ".json") {
+		err = json.Unmarshal(data, set)
+	} else {
+		err = yaml.Unmarshal(data, set)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules file: %w", err)
+	}
+	return set, nil
+}
+
+func (set *RuleSet) compile() error {
+	seen := map[string]bool{}
+	for i := range set.Rules {
+		rule := &set.Rules[i]
+		if err := rule.compile(); err != nil {
+			return err
+		}
+		if seen[rule.ID] {
+			return fmt.Errorf("duplicate rule id %q", rule.ID)
+		}
+		seen[rule.ID] = true
+	}
+	for i := range set.Exceptions {
+		exception := &set.Exceptions[i]
+		if err := exception.compile(seen); err != nil {
+			return err
+		}
+		for j := range set.Rules {
+			if exception.appliesTo(set.Rules[j].ID) {
+				set.Rules[j].exceptions = append(set.Rules[j].exceptions, exception)
+			}
+		}
+	}
+	for name, profile := range set.Profiles {
+		if err := profile.validate(); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+	}
+	set.Domains.Block = normalizeDomains(set.Domains.Block)
+	set.Domains.Allow = normalizeDomains(set.Domains.Allow)
+	set.LoadedAt = time.Now()
+	return nil
+}
+
+// LoadRules reads the rules file at path, or the built-in rules if path is
+// empty, and adds the rule packs of locales and the custom rules.
+func LoadRules(path string, locales []string, custom ...Rule) (*RuleSet, error) {
+	data, source := defaultRules, defaultRulesSource
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+		source = path
+	}
+	set, err := decodeRules(data, source)
+	if err != nil {
+		return nil, err
+	}
+
+	set.Locales = []string{}
+	for _, locale := range locales {
+		pack, err := loadRulePack(locale)
+		if err != nil {
+			return nil, err
+		}
+		set.Rules = append(set.Rules, pack.Rules...)
+		set.Exceptions = append(set.Exceptions, pack.Exceptions...)
+		set.Locales = append(set.Locales, locale)
+	}
+	for _, rule := range custom {
+		rule.Custom = true
+		set.Rules = append(set.Rules, rule)
+	}
+	if err := set.compile(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+func loadRulePack(locale string) (*RuleSet, error) {
+	name := "rules_" + locale + ".yaml"
+	data, err := rulePacks.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no rule pack for locale %q", locale)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeRules(data, name)
+}
+
+func (r *Rule) compile() error {
+	r.ID = strings.TrimSpace(r.ID)
+	if r.ID == "" {
+		return fmt.Errorf("rule without id")
+	}
+	if r.Pattern == "" {
+		return fmt.Errorf("rule %s: empty pattern", r.ID)
+	}
+	if r.Severity = strings.ToLower(r.Severity); r.Severity == "" {
+		r.Severity = "medium"
+	}
+	if _, ok := severityRank[r.Severity]; !ok {
+		return fmt.Errorf("rule %s: unknown severity %q", r.ID, r.Severity)
+	}
+	if r.Weight < 0 {
+		return fmt.Errorf("rule %s: negative weight", r.ID)
+	}
+	if r.Weight == 0 {
+		r.Weight = severityWeight[r.Severity]
+	}
+	switch r.Action = strings.ToLower(r.Action); r.Action {
+	case "":
+		r.Action = ActionWarn
+	case ActionWarn, ActionStrip, ActionReject:
+	default:
+		return fmt.Errorf("rule %s: unknown action %q", r.ID, r.Action)
+	}
+	if r.Category == "" {
+		r.Category = r.ID
+	}
+	if r.Group = strings.ToLower(strings.TrimSpace(r.Group)); r.Group == "" {
+		r.Group = r.Category
+	}
+
+	switch r.Match = strings.ToLower(r.Match); r.Match {
+	case "", MatchRegex:
+		r.Match = MatchRegex
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("rule %s: %w", r.ID, err)
+		}
+		r.re = re
+		r.literals, _ = requiredLiterals(r.Pattern)
+	case MatchContains:
+	default:
+		return fmt.Errorf("rule %s: unknown match %q", r.ID, r.Match)
+	}
+	return nil
+}
+
+func (e *Exception) compile(rules map[string]bool) error {
+	if e.ID = strings.TrimSpace(e.ID); e.ID == "" {
+		return fmt.Errorf("exception without id")
+	}
+	if e.Before == "" && e.After == "" {
+		return fmt.Errorf("exception %s: needs before or after", e.ID)
+	}
+	for _, id := range e.Rules {
+		if !rules[id] {
+			return fmt.Errorf("exception %s: unknown rule %q", e.ID, id)
+		}
+	}
+	var err error
+	if e.Before != "" {
+		if e.before, err = regexp.Compile(e.Before); err != nil {
+			return fmt.Errorf("exception %s: %w", e.ID, err)
+		}
+	}
+	if e.After != "" {
+		if e.after, err = regexp.Compile(e.After); err != nil {
+			return fmt.Errorf("exception %s: %w", e.ID, err)
+		}
+	}
+	return nil
+}
+
+func (e *Exception) appliesTo(rule string) bool {
+	if len(e.Rules) == 0 {
+		return true
+	}
+	for _, id := range e.Rules {
+		if id == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// excuses reports whether the match input[start:end] is allowed.
+func (e *Exception) excuses(input string, start, end int) bool {
+	if e.before != nil && !e.before.MatchString(input[max(0, start-exceptionContext):start]) {
+		return false
+	}
+	if e.after != nil && !e.after.MatchString(input[end:min(len(input), end+exceptionContext)]) {
+		return false
+	}
+	return true
+}
+
+// matches reports whether the rule matches input, whose foldKey is folded.
+// excused is set when it matched only in places allowed by an exception, in
+// which case matched is false.
+func (r *Rule) matches(input, folded string) (matched, excused bool) {
+	if !r.mayMatch(folded) {
+		return false, false
+	}
+	if len(r.exceptions) == 0 {
+		if r.re != nil {
+			return r.re.MatchString(input), false
+		}
+		return strings.Contains(input, r.Pattern), false
+	}
+
+	for _, loc := range r.locations(input) {
+		if !r.excused(input, loc[0], loc[1]) {
+			return true, false
+		}
+		excused = true
+	}
+	return false, excused
+}
+
+func (r *Rule) locations(input string) [][]int {
+	if r.re != nil {
+		return r.re.FindAllStringIndex(input, -1)
+	}
+	var locations [][]int
+	for offset := 0; ; {
+		i := strings.Index(input[offset:], r.Pattern)
+		if i < 0 {
+			return locations
+		}
+		start := offset + i
+		locations = append(locations, []int{start, start + len(r.Pattern)})
+		offset = start + len(r.Pattern)
+	}
+}
+
+func (r *Rule) excused(input string, start, end int) bool {
+	for _, exception := range r.exceptions {
+		if exception.excuses(input, start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) strip(input string) string {
+	if r.re != nil {
+		return r.re.ReplaceAllString(input, "")
+	}
+	return strings.ReplaceAll(input, r.Pattern, "")
+}
+
+func (r *Rule) warning() string {
+	if r.Match == MatchContains {
+		return fmt.Sprintf("Detected suspicious string: %s", r.Pattern)
+	}
+	return fmt.Sprintf("Detected injection pattern: %s", r.ID)
+}
+
+// rules returns the active rule set.
+func (s *Service) rules() *RuleSet {
+	s.rulesLock.RLock()
+	defer s.rulesLock.RUnlock()
+	return s.ruleSet
+}
+
+// reloadRules loads the rules file again. On error the current rules stay
+// active.
+func (s *Service) reloadRules() (*RuleSet, error) {
+	set, err := LoadRules(s.cfg.RulesFile, s.cfg.Locales, s.customRuleList()...)
+	if err != nil {
+		return nil, err
+	}
+	s.rulesLock.Lock()
+	s.ruleSet = set
+	s.rulesLock.Unlock()
+	s.logger.Printf("[INFO] %d Sicherheitsregeln geladen aus %s (Sprachen: %s)", len(set.Rules), set.Source, strings.Join(set.Locales, ", "))
+	return set, nil
+}
+
+// watchRules reloads the rules file whenever its modification time or size
+// changes, until the service is closed.
+func (s *Service) watchRules() {
+	stat := func() (time.Time, int64) {
+		info, err := os.Stat(s.cfg.RulesFile)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return info.ModTime(), info.Size()
+	}
+
+	modTime, size := stat()
+	go func() {
+		ticker := time.NewTicker(rulesWatchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+			currentTime, currentSize := stat()
+			if currentSize < 0 || (currentTime.Equal(modTime) && currentSize == size) {
+				continue
+			}
+			modTime, size = currentTime, currentSize
+			if _, err := s.reloadRules(); err != nil {
+				s.logger.Printf("[ERROR] Sicherheitsregeln konnten nicht neu geladen werden: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,348 @@
+package security
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Sanitizer policies.
+const (
+	// PolicyText removes all markup.
+	PolicyText = "text"
+	// PolicyHTML keeps basic formatting tags.
+	PolicyHTML = "html"
+	// PolicyMarkdown is PolicyHTML for Markdown: fenced code blocks and
+	// inline code are left untouched, since renderers show them verbatim.
+	// Inside raw HTML they are not code, so output with any markup outside
+	// code is sanitized as a whole.
+	PolicyMarkdown = "markdown"
+)
+
+const defaultSanitizePolicy = PolicyMarkdown
+
+// Policy decides which elements and attributes survive sanitizing. Elements
+// that are not allowed are removed but their text is kept, except for
+// dropContent elements, which are removed entirely.
+type Policy struct {
+	// Elements maps allowed tags to their allowed attributes.
+	Elements map[string][]string
+	// PreserveCode leaves Markdown code untouched.
+	PreserveCode bool
+}
+
+// dropContent elements are removed with everything inside them.
+var dropContent = map[string]bool{
+	"script": true, "style": true, "iframe": true, "frame": true, "frameset": true,
+	"object": true, "embed": true, "applet": true, "noscript": true, "template": true,
+	"svg": true, "math": true, "textarea": true, "select": true, "title": true,
+	"head": true, "base": true, "meta": true, "link": true,
+}
+
+// urlAttributes must hold http(s), mailto or relative URLs.
+var urlAttributes = map[string]bool{"href": true, "src": true, "cite": true}
+
+var safeSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+var formattingElements = map[string][]string{
+	"p": nil, "br": nil, "hr": nil, "b": nil, "strong": nil, "i": nil, "em": nil,
+	"u": nil, "s": nil, "del": nil, "ins": nil, "mark": nil, "small": nil, "sub": nil,
+	"sup": nil, "code": nil, "pre": nil, "kbd": nil, "blockquote": {"cite"},
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"ul": nil, "ol": {"start"}, "li": nil, "dl": nil, "dt": nil, "dd": nil,
+	"table": nil, "thead": nil, "tbody": nil, "tr": nil, "th": {"colspan", "rowspan"},
+	"td": {"colspan", "rowspan"}, "a": {"href", "title"}, "img": {"src", "alt", "title"},
+	"span": nil, "div": nil, "details": nil, "summary": nil,
+}
+
+var policies = map[string]*Policy{
+	PolicyText:     {Elements: map[string][]string{}},
+	PolicyHTML:     {Elements: formattingElements},
+	PolicyMarkdown: {Elements: formattingElements, PreserveCode: true},
+}
+
+// SanitizeOutput removes unsafe markup from model output with the named
+// policy.
+func (v *PromptValidator) SanitizeOutput(output, policyName string) (SanitizeResponse, error) {
+	if policyName == "" {
+		policyName = defaultSanitizePolicy
+	}
+	policy, ok := policies[strings.ToLower(policyName)]
+	if !ok {
+		return SanitizeResponse{}, fmt.Errorf("unknown policy %q", policyName)
+	}
+
+	removed := &removedSet{items: []string{}}
+	var sanitized strings.Builder
+	segments := []codeSegment{{text: output}}
+	if policy.PreserveCode {
+		segments = splitCode(output)
+		if proseHasMarkup(segments) {
+			segments = []codeSegment{{text: output}}
+		}
+	}
+	for _, segment := range segments {
+		if segment.code {
+			sanitized.WriteString(segment.text)
+			continue
+		}
+		sanitized.WriteString(policy.sanitize(segment.text, removed))
+	}
+	return SanitizeResponse{Sanitized: sanitized.String(), Removed: removed.items, Policy: strings.ToLower(policyName)}, nil
+}
+
+// removedSet collects what was removed, each item once.
+type removedSet struct {
+	items []string
+}
+
+func (r *removedSet) add(item string) {
+	for _, existing := range r.items {
+		if existing == item {
+			return
+		}
+	}
+	r.items = append(r.items, item)
+}
+
+func (p *Policy) sanitize(fragment string, removed *removedSet) string {
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+	var out strings.Builder
+	var dropping string
+	dropDepth := 0
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return out.String()
+		}
+		token := tokenizer.Token()
+
+		if dropDepth > 0 {
+			switch {
+			case tokenType == html.StartTagToken && token.Data == dropping:
+				dropDepth++
+			case tokenType == html.EndTagToken && token.Data == dropping:
+				dropDepth--
+			}
+			continue
+		}
+
+		switch tokenType {
+		case html.TextToken:
+			// The tokenizer decoded entities; escape only what could start
+			// markup, so Markdown such as "> quote" survives.
+			out.WriteString(strings.ReplaceAll(token.Data, "<", "&lt;"))
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if dropContent[token.Data] {
+				removed.add("<" + token.Data + ">")
+				if tokenType == html.StartTagToken && !voidElement(token.Data) {
+					dropping, dropDepth = token.Data, 1
+				}
+				continue
+			}
+			allowed, ok := p.Elements[token.Data]
+			if !ok {
+				removed.add("<" + token.Data + ">")
+				continue
+			}
+			out.WriteString(renderStartTag(token, allowed, removed, tokenType == html.SelfClosingTagToken))
+		case html.EndTagToken:
+			if _, ok := p.Elements[token.Data]; ok {
+				out.WriteString("</" + token.Data + ">")
+			}
+		case html.CommentToken:
+			removed.add("<!-- -->")
+		case html.DoctypeToken:
+			removed.add("<!DOCTYPE>")
+		}
+	}
+}
+
+func renderStartTag(token html.Token, allowed []string, removed *removedSet, selfClosing bool) string {
+	var b strings.Builder
+	b.WriteString("<" + token.Data)
+	for _, attr := range token.Attr {
+		name := strings.ToLower(attr.Key)
+		if attr.Namespace != "" || !containsString(allowed, name) {
+			if strings.HasPrefix(name, "on") {
+				removed.add(name + "=")
+			} else {
+				removed.add(name + " attribute")
+			}
+			continue
+		}
+		if urlAttributes[name] && !safeURL(attr.Val) {
+			removed.add("unsafe " + name)
+			continue
+		}
+		fmt.Fprintf(&b, ` %s="%s"`, name, html.EscapeString(attr.Val))
+	}
+	if selfClosing {
+		b.WriteString(" /")
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// safeURL accepts relative URLs and the safe schemes. Control characters and
+// whitespace are removed first, as browsers ignore them in schemes.
+func safeURL(raw string) bool {
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, raw)
+	parsed, err := url.Parse(cleaned)
+	if err != nil {
+		return false
+	}
+	return parsed.Scheme == "" || safeSchemes[strings.ToLower(parsed.Scheme)]
+}
+
+func voidElement(tag string) bool {
+	switch tag {
+	case "base", "meta", "link", "embed", "img", "br", "hr", "input", "source", "track", "wbr":
+		return true
+	}
+	return false
+}
+
+// codeSegment is a part of a Markdown document; code segments are fenced
+// code blocks or inline code spans including their delimiters.
+type codeSegment struct {
+	text string
+	code bool
+}
+
+// splitCode splits Markdown into code and other text. An unclosed fence
+// extends to the end, as in CommonMark.
+func splitCode(text string) []codeSegment {
+	var segments []codeSegment
+	var prose strings.Builder
+	flush := func() {
+		if prose.Len() > 0 {
+			segments = append(segments, splitInlineCode(prose.String())...)
+			prose.Reset()
+		}
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		fence := fenceOf(lines[i])
+		if fence == "" {
+			prose.WriteString(lines[i])
+			continue
+		}
+		flush()
+		var block strings.Builder
+		block.WriteString(lines[i])
+		for i++; i < len(lines); i++ {
+			block.WriteString(lines[i])
+			if closing := fenceOf(lines[i]); closing != "" && closing[0] == fence[0] && len(closing) >= len(fence) &&
+				strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(lines[i]), fence[:1])) == "" {
+				break
+			}
+		}
+		segments = append(segments, codeSegment{text: block.String(), code: true})
+	}
+	flush()
+	return segments
+}
+
+// proseHasMarkup reports whether a segment outside code contains a tag,
+// comment or doctype. In CommonMark such markup may open a raw HTML block,
+// in which backticks and fences are not code.
+func proseHasMarkup(segments []codeSegment) bool {
+	for _, segment := range segments {
+		if segment.code {
+			continue
+		}
+		tokenizer := html.NewTokenizer(strings.NewReader(segment.text))
+		for {
+			tokenType := tokenizer.Next()
+			if tokenType == html.ErrorToken {
+				break
+			}
+			if tokenType != html.TextToken {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fenceOf returns the fence opening line starts with (``` or ~~~, at least
+// three, indented by up to three spaces), or "".
+func fenceOf(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 {
+		return ""
+	}
+	marker := trimmed[0]
+	if marker != '`' && marker != '~' {
+		return ""
+	}
+	n := 0
+	for n < len(trimmed) && trimmed[n] == marker {
+		n++
+	}
+	if n < 3 || (marker == '`' && strings.Contains(trimmed[n:], "`")) {
+		return ""
+	}
+	return trimmed[:n]
+}
+
+// splitInlineCode separates `code` spans: a run of backticks up to the next
+// run of the same length.
+func splitInlineCode(text string) []codeSegment {
+	var segments []codeSegment
+	start := 0
+	for i := 0; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		n := 0
+		for i+n < len(text) && text[i+n] == '`' {
+			n++
+		}
+		end := closingBackticks(text, i+n, n)
+		if end < 0 {
+			i += n
+			continue
+		}
+		if i > start {
+			segments = append(segments, codeSegment{text: text[start:i]})
+		}
+		segments = append(segments, codeSegment{text: text[i:end], code: true})
+		start, i = end, end
+	}
+	if start < len(text) {
+		segments = append(segments, codeSegment{text: text[start:]})
+	}
+	return segments
+}
+
+// closingBackticks returns the end of the first run of exactly n backticks
+// from offset, or -1.
+func closingBackticks(text string, offset, n int) int {
+	for i := offset; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		run := 0
+		for i+run < len(text) && text[i+run] == '`' {
+			run++
+		}
+		if run == n {
+			return i + run
+		}
+		i += run
+	}
+	return -1
+}
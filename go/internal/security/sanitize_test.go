@@ -0,0 +1,97 @@
+package security
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestSanitizePolicies(t *testing.T) {
+	validator := newTestService(t, Config{}).validator(builtinRules(t))
+
+	tests := []struct {
+		name    string
+		policy  string
+		output  string
+		want    string
+		removed []string
+	}{
+		{"text strips tags", PolicyText, "<b>fett</b> und <i>kursiv</i>", "fett und kursiv", []string{"<b>", "<i>"}},
+		{"html keeps formatting", PolicyHTML, "<p><b>fett</b><br/></p>", "<p><b>fett</b><br /></p>", []string{}},
+		{"script dropped with content", PolicyHTML, "vor<script>alert(1)</script>nach", "vornach", []string{"<script>"}},
+		{"nested drop elements", PolicyHTML, "<svg><svg></svg>x</svg>rest", "rest", []string{"<svg>"}},
+		{"event handler removed", PolicyHTML, `<img src="a.png" onerror="alert(1)">`, `<img src="a.png">`, []string{"onerror="}},
+		{"javascript url removed", PolicyHTML, `<a href=" java\tscript:alert(1)">Link</a>`, `<a>Link</a>`, []string{"unsafe href"}},
+		{"safe url kept", PolicyHTML, `<a href="https://example.com/?a=1&amp;b=2" style="x">Link</a>`, `<a href="https://example.com/?a=1&amp;b=2">Link</a>`, []string{"style attribute"}},
+		{"comment and doctype", PolicyHTML, "<!DOCTYPE html><!-- geheim -->Text", "Text", []string{"<!DOCTYPE>", "<!-- -->"}},
+		{"escaped text", PolicyText, "1 &lt; 2 > 0", "1 &lt; 2 > 0", []string{}},
+		{"markdown keeps inline code", PolicyMarkdown, "Nutze `<script>` nie", "Nutze `<script>` nie", []string{}},
+		{"markdown keeps fenced code", PolicyMarkdown, "```html\n<script>x</script>\n```\n> Zitat", "```html\n<script>x</script>\n```\n> Zitat", []string{}},
+		{"markdown with raw html", PolicyMarkdown, "<div>`<script>x</script>`</div>", "<div>``</div>", []string{"<script>"}},
+		{"default is markdown", "", "`<b>`", "`<b>`", []string{}},
+		{"policy is case-insensitive", "TEXT", "<b>x</b>", "x", []string{"<b>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := validator.SanitizeOutput(tt.output, tt.policy)
+			if err != nil {
+				t.Fatalf("SanitizeOutput: %v", err)
+			}
+			if result.Sanitized != tt.want || !reflect.DeepEqual(result.Removed, tt.removed) {
+				t.Errorf("sanitized %q, removed %q; want %q, %q", result.Sanitized, result.Removed, tt.want, tt.removed)
+			}
+		})
+	}
+}
+
+func TestSplitCode(t *testing.T) {
+	tests := []struct {
+		text string
+		code []string
+	}{
+		{"kein Code", nil},
+		{"a `b` c ``d`e`` f", []string{"`b`", "``d`e``"}},
+		{"offen ` bleibt", nil},
+		{"~~~\ncode\n~~~~\nText", []string{"~~~\ncode\n~~~~\n"}},
+		{"````\n```\nnoch Code\n````\n", []string{"````\n```\nnoch Code\n````\n"}},
+		{"```\nnie geschlossen", []string{"```\nnie geschlossen"}},
+		{"    ```\nzu tief eingerückt", nil},
+	}
+	for _, tt := range tests {
+		var code []string
+		for _, segment := range splitCode(tt.text) {
+			if segment.code {
+				code = append(code, segment.text)
+			}
+		}
+		if !reflect.DeepEqual(code, tt.code) {
+			t.Errorf("splitCode(%q) code = %q, want %q", tt.text, code, tt.code)
+		}
+	}
+}
+
+func TestSanitizeHandler(t *testing.T) {
+	svc := newTestService(t, Config{})
+	tests := []struct {
+		body interface{}
+		code int
+	}{
+		{SanitizeRequest{Output: "<b>x</b>", Policy: PolicyText}, http.StatusOK},
+		{SanitizeRequest{Output: "x", Policy: "pdf"}, http.StatusBadRequest},
+		{"kein Objekt", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := serve(svc, http.MethodPost, "/api/security/sanitize", tt.body, nil)
+		if rec.Code != tt.code {
+			t.Errorf("%v: status %d, want %d", tt.body, rec.Code, tt.code)
+			continue
+		}
+		if tt.code == http.StatusOK {
+			var result SanitizeResponse
+			decode(t, rec, &result)
+			if result.Sanitized != "x" || result.Policy != PolicyText {
+				t.Errorf("result = %+v", result)
+			}
+		}
+	}
+}
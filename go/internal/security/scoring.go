@@ -0,0 +1,167 @@
+package security
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"jarviscore/go/internal/authmw"
+)
+
+// Default rule weights by severity, used when a rule has no weight.
+var severityWeight = map[string]float64{"low": 1, "medium": 3, "high": 6, "critical": 10}
+
+// Weights of the built-in heuristics.
+const (
+	weightTooLong    = 3
+	weightRepetition = 3
+	weightBase64     = 1
+	weightEncoding   = 1
+	weightInvisible  = 1
+	weightHomoglyph  = 3
+
+	weightBlockedDomain    = 10
+	weightSuspiciousDomain = 3
+
+	weightCanaryLeak = 10
+
+	// weightScanBudget is added when slow rules were skipped, so an input
+	// that exhausts the scan budget is at least reported.
+	weightScanBudget = 3
+)
+
+// Built-in profiles. "default" is the former name of moderate and is kept
+// for existing callers and rules files.
+const (
+	StrictProfile     = "strict"
+	ModerateProfile   = "moderate"
+	PermissiveProfile = "permissive"
+	DefaultProfile    = ModerateProfile
+	legacyProfile     = "default"
+)
+
+// Rule groups of the built-in heuristics. Rules are grouped by their group
+// field.
+const (
+	GroupObfuscation = "obfuscation"
+	GroupLinks       = "links"
+	GroupClassifier  = "classifier"
+)
+
+// Thresholds turn a risk score into a decision: from Warn on the input is
+// reported with severity medium, from Reject on it is rejected.
+type Thresholds struct {
+	Warn   float64 `json:"warn" yaml:"warn"`
+	Reject float64 `json:"reject" yaml:"reject"`
+}
+
+// Profile is a validation policy: thresholds and the rule groups checked.
+type Profile struct {
+	Thresholds `yaml:",inline"`
+	// Groups lists the enabled rule groups; empty enables all.
+	Groups []string `json:"groups,omitempty" yaml:"groups,omitempty"`
+	// Exhaustive runs every rule regardless of the scan budget.
+	Exhaustive bool `json:"exhaustive,omitempty" yaml:"exhaustive,omitempty"`
+}
+
+// enabled reports whether the profile checks group.
+func (p Profile) enabled(group string) bool {
+	return len(p.Groups) == 0 || containsString(p.Groups, group)
+}
+
+// defaultProfiles apply when the rules file defines none of that name.
+// Moderate reproduces the former behaviour: any critical rule rejects, and
+// in strict mode any warning does. Permissive is meant for trusted callers
+// and only checks for attacks on the model itself.
+var defaultProfiles = map[string]Profile{
+	StrictProfile:   {Thresholds: Thresholds{Warn: 1, Reject: 1}, Exhaustive: true},
+	ModerateProfile: {Thresholds: Thresholds{Warn: 1, Reject: 10}},
+	legacyProfile:   {Thresholds: Thresholds{Warn: 1, Reject: 10}},
+	PermissiveProfile: {
+		Thresholds: Thresholds{Warn: 3, Reject: 20},
+		Groups:     []string{"prompt_injection", "jailbreak", "code_injection", GroupLinks},
+	},
+}
+
+// severityFor maps a score to the severity reported to callers.
+func (t Thresholds) severityFor(score float64) string {
+	switch {
+	case score >= t.Reject:
+		return "critical"
+	case score >= t.Warn:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+func (t Thresholds) validate() error {
+	if t.Warn < 0 || t.Reject <= 0 {
+		return fmt.Errorf("thresholds must be positive")
+	}
+	if t.Warn > t.Reject {
+		return fmt.Errorf("warn threshold above reject threshold")
+	}
+	return nil
+}
+
+// profile returns the profile name from the rules file, falling back to the
+// built-in profiles.
+func (set *RuleSet) profile(name string) (Profile, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if profile, ok := set.Profiles[name]; ok {
+		return profile, true
+	}
+	profile, ok := defaultProfiles[name]
+	return profile, ok
+}
+
+// profile resolves the profile of a request: the one assigned to its API key,
+// else the one it names, else strict or the configured default. A key's
+// profile can't be swapped for a weaker one by the caller. Per-request
+// threshold overrides are applied on top.
+func (s *Service) profile(r *http.Request, rules *RuleSet, req ValidateRequest) (Profile, string, error) {
+	name := s.keyProfile(r)
+	if name == "" {
+		name = req.Profile
+	}
+	if name == "" {
+		name = s.cfg.Profile
+		if req.Strict {
+			name = StrictProfile
+		}
+	}
+	profile, ok := rules.profile(name)
+	if !ok {
+		return Profile{}, "", fmt.Errorf("unknown profile %q", name)
+	}
+	// Overrides can only tighten the profile; higher values are ignored.
+	if req.Thresholds != nil {
+		if req.Thresholds.Warn > 0 {
+			profile.Warn = min(profile.Warn, req.Thresholds.Warn)
+		}
+		if req.Thresholds.Reject > 0 {
+			profile.Reject = min(profile.Reject, req.Thresholds.Reject)
+		}
+	}
+	if err := profile.validate(); err != nil {
+		return Profile{}, "", err
+	}
+	return profile, strings.ToLower(name), nil
+}
+
+// keyProfile returns the profile assigned to the API key of r, given in
+// KeyProfiles as the key or its id.
+func (s *Service) keyProfile(r *http.Request) string {
+	if len(s.cfg.KeyProfiles) == 0 || r == nil {
+		return ""
+	}
+	key := authmw.APIKeyFromRequest(r)
+	if key == "" {
+		return ""
+	}
+	if name, ok := s.cfg.KeyProfiles[key]; ok {
+		return name
+	}
+	return s.cfg.KeyProfiles[callerKeyID(key)]
+}
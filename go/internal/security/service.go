@@ -1,6 +1,7 @@
 package security
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -14,25 +15,236 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+
+	"jarviscore/go/internal/authmw"
+	"jarviscore/go/internal/cors"
 )
 
 const defaultListenAddr = ":8081"
 const defaultMaxLength = 50000
 
+var defaultLocales = []string{"en", "de"}
+
 type Config struct {
 	ListenAddr string
 	MaxLength  int
+	CORS       cors.Config
+
+	// AdminKey guards the audit log and the routes that change the rules
+	// (JARVIS_SECURITY_ADMIN_KEY, sent as X-Admin-Key). Without it they
+	// accept the credentials of Auth (JARVIS_AUTH_SECRET/JARVIS_AUTH_KEYS);
+	// with neither they are disabled.
+	AdminKey string
+	Auth     authmw.Config
+
+	// Profile names the profile used when a request selects none
+	// (JARVIS_SECURITY_PROFILE, default "moderate"), KeyProfiles the profile
+	// of single API keys, given as the key or its id; it overrides the
+	// profile a request names (JARVIS_SECURITY_KEY_PROFILES, comma separated
+	// key=profile pairs).
+	Profile     string
+	KeyProfiles map[string]string
+
+	// AuditFile stores rejected and critical validations
+	// (JARVIS_SECURITY_AUDIT_FILE, "off" disables); the newest
+	// AuditMaxEntries can be queried by admins on /api/security/audit.
+	AuditFile       string
+	AuditMaxEntries int
+
+	// ScanBudget bounds the time a validation spends on rules that are slow
+	// on average (JARVIS_SECURITY_SCAN_BUDGET_MS, default 50, 0 disables);
+	// they run after the others and are skipped once it is spent.
+	ScanBudget time.Duration
+
+	// FileMaxBytes limits uploads to /api/security/scan-file
+	// (JARVIS_SECURITY_FILE_MAX_BYTES, default 10 MiB). BadHashesFile lists
+	// SHA-256 hashes of known bad files, one per line
+	// (JARVIS_SECURITY_BAD_HASHES_FILE).
+	FileMaxBytes  int64
+	BadHashesFile string
+
+	// StatsFile keeps the counters across restarts (JARVIS_SECURITY_STATS_FILE,
+	// "off" disables), with daily counters of the last StatsHistoryDays
+	// (JARVIS_SECURITY_STATS_HISTORY_DAYS, default 90).
+	StatsFile        string
+	StatsHistoryDays int
+
+	// StreamMaxBytes limits inputs of /api/security/validate/stream, which
+	// scans them in windows of MaxLength (JARVIS_SECURITY_STREAM_MAX_BYTES).
+	StreamMaxBytes int64
+
+	// RulesFile replaces the built-in validation rules with a YAML or JSON
+	// file (JARVIS_SECURITY_RULES_FILE) that is reloaded when it changes.
+	RulesFile string
+	// CustomRulesFile stores the rules added through POST
+	// /api/security/rules (JARVIS_SECURITY_CUSTOM_RULES_FILE, "off" keeps
+	// them in memory only).
+	CustomRulesFile string
+
+	// ReputationURL is asked about linked domains that are on neither
+	// domain list of the rules file (JARVIS_SECURITY_REPUTATION_URL,
+	// JARVIS_SECURITY_REPUTATION_TIMEOUT in seconds, shared by all lookups
+	// of one input).
+	ReputationURL     string
+	ReputationTimeout time.Duration
+
+	// GatewayURL receives a security_alert event for every critical
+	// detection (POST /api/events), as do AlertWebhooks
+	// (JARVIS_SECURITY_ALERT_WEBHOOKS, comma separated). Webhook requests are
+	// signed with AlertWebhookSecret (JARVIS_SECURITY_ALERT_WEBHOOK_SECRET).
+	GatewayURL         string
+	GatewayToken       string
+	AlertWebhooks      []string
+	AlertWebhookSecret string
+
+	// ClassifierURL is a model endpoint scoring inputs as prompt injections
+	// from 0 to 1 (JARVIS_SECURITY_CLASSIFIER_URL). The score times
+	// ClassifierWeight (JARVIS_SECURITY_CLASSIFIER_WEIGHT, default 10) is
+	// added to the rule score; ClassifierTimeout
+	// (JARVIS_SECURITY_CLASSIFIER_TIMEOUT in seconds, default 1) bounds the
+	// call.
+	ClassifierURL     string
+	ClassifierWeight  float64
+	ClassifierTimeout time.Duration
+
+	// CanaryTTL is how long issued canary tokens are recognized in outputs
+	// (JARVIS_SECURITY_CANARY_TTL in hours, default 24).
+	CanaryTTL time.Duration
+
+	// Locales selects the language rule packs added to the rules
+	// (JARVIS_SECURITY_LOCALES, comma separated, default "en,de").
+	Locales []string
 }
 
 func LoadConfig() Config {
 	cfg := Config{
 		ListenAddr: defaultListenAddr,
 		MaxLength:  defaultMaxLength,
+		CORS:       cors.LoadConfig("JARVIS_SECURITY_CORS_ORIGINS"),
+		AdminKey:   strings.TrimSpace(os.Getenv("JARVIS_SECURITY_ADMIN_KEY")),
+		Auth:       authmw.LoadConfig().ForService("security"),
+		RulesFile:  strings.TrimSpace(os.Getenv("JARVIS_SECURITY_RULES_FILE")),
+		Profile:    DefaultProfile,
+		Locales:    defaultLocales,
+
+		GatewayURL:         strings.TrimSpace(os.Getenv("JARVIS_GATEWAYD_URL")),
+		GatewayToken:       strings.TrimSpace(os.Getenv("JARVIS_GATEWAYD_TOKEN")),
+		AlertWebhookSecret: strings.TrimSpace(os.Getenv("JARVIS_SECURITY_ALERT_WEBHOOK_SECRET")),
+
+		ClassifierURL:     strings.TrimSpace(os.Getenv("JARVIS_SECURITY_CLASSIFIER_URL")),
+		ClassifierWeight:  defaultClassifierWeight,
+		ClassifierTimeout: defaultClassifierTimeout,
+		AuditFile:         defaultAuditFile,
+
+		CustomRulesFile: defaultCustomRulesFile,
+		AuditMaxEntries: defaultAuditMaxEntries,
+		StreamMaxBytes:  defaultStreamMaxBytes,
+		CanaryTTL:       defaultCanaryTTL,
+		ScanBudget:      defaultScanBudget,
+		FileMaxBytes:    defaultFileMaxBytes,
+		BadHashesFile:   strings.TrimSpace(os.Getenv("JARVIS_SECURITY_BAD_HASHES_FILE")),
+
+		StatsFile:        defaultStatsFile,
+		StatsHistoryDays: defaultStatsHistoryDays,
 	}
+	cfg.CORS.AllowedMethods = "GET, POST, DELETE, OPTIONS"
 
 	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_ADDR")); value != "" {
 		cfg.ListenAddr = value
 	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_AUDIT_FILE")); value != "" {
+		cfg.AuditFile = value
+		if strings.EqualFold(value, "off") {
+			cfg.AuditFile = ""
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_CUSTOM_RULES_FILE")); value != "" {
+		cfg.CustomRulesFile = value
+		if strings.EqualFold(value, "off") {
+			cfg.CustomRulesFile = ""
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_AUDIT_MAX_ENTRIES")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			cfg.AuditMaxEntries = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_SCAN_BUDGET_MS")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			cfg.ScanBudget = time.Duration(parsed) * time.Millisecond
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_FILE_MAX_BYTES")); value != "" {
+		if parsed, err := strconv.ParseInt(value, 10, 64); err == nil && parsed > 0 {
+			cfg.FileMaxBytes = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_STATS_FILE")); value != "" {
+		cfg.StatsFile = value
+		if strings.EqualFold(value, "off") {
+			cfg.StatsFile = ""
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_STATS_HISTORY_DAYS")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			cfg.StatsHistoryDays = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_STREAM_MAX_BYTES")); value != "" {
+		if parsed, err := strconv.ParseInt(value, 10, 64); err == nil && parsed > 0 {
+			cfg.StreamMaxBytes = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_REPUTATION_URL")); value != "" {
+		cfg.ReputationURL = value
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_REPUTATION_TIMEOUT")); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil && parsed > 0 {
+			cfg.ReputationTimeout = time.Duration(parsed * float64(time.Second))
+		}
+	}
+	for _, webhook := range strings.Split(os.Getenv("JARVIS_SECURITY_ALERT_WEBHOOKS"), ",") {
+		if webhook = strings.TrimSpace(webhook); webhook != "" {
+			cfg.AlertWebhooks = append(cfg.AlertWebhooks, webhook)
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_CLASSIFIER_WEIGHT")); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil && parsed > 0 {
+			cfg.ClassifierWeight = parsed
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_CLASSIFIER_TIMEOUT")); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil && parsed > 0 {
+			cfg.ClassifierTimeout = time.Duration(parsed * float64(time.Second))
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_CANARY_TTL")); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil && parsed > 0 {
+			cfg.CanaryTTL = time.Duration(parsed * float64(time.Hour))
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_LOCALES")); value != "" {
+		cfg.Locales = nil
+		for _, locale := range strings.Split(value, ",") {
+			if locale = strings.ToLower(strings.TrimSpace(locale)); locale != "" {
+				cfg.Locales = append(cfg.Locales, locale)
+			}
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_PROFILE")); value != "" {
+		cfg.Profile = strings.ToLower(value)
+	}
+	for _, pair := range strings.Split(os.Getenv("JARVIS_SECURITY_KEY_PROFILES"), ",") {
+		key, profile, ok := strings.Cut(pair, "=")
+		key, profile = strings.TrimSpace(key), strings.ToLower(strings.TrimSpace(profile))
+		if !ok || key == "" || profile == "" {
+			continue
+		}
+		if cfg.KeyProfiles == nil {
+			cfg.KeyProfiles = make(map[string]string)
+		}
+		cfg.KeyProfiles[key] = profile
+	}
 	if value := strings.TrimSpace(os.Getenv("JARVIS_SECURITY_MAX_LENGTH")); value != "" {
 		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
 			cfg.MaxLength = parsed
@@ -42,68 +254,79 @@ func LoadConfig() Config {
 	return cfg
 }
 
-// Dangerous patterns that indicate injection attempts.
-var dangerousPatterns = []*regexp.Regexp{
-	// System prompt manipulation
-	regexp.MustCompile(`(?i)(system\s*:|ignore\s+previous|forget\s+that|pretend\s+you\s+are)`),
-	regexp.MustCompile(`(?i)(new\s+instructions|override\s+instructions|disregard)`),
-
-	// Code execution attempts
-	regexp.MustCompile(`(?i)(execute|eval|__import__|subprocess|os\.system)`),
-	regexp.MustCompile(`(?i)(exec\s*\(|eval\s*\(|compile\s*\()`),
-
-	// Sensitive data extraction
-	regexp.MustCompile(`(?i)(password|secret|token|api[_-]?key|credentials)`),
-	regexp.MustCompile(`(?i)(private[_-]?key|access[_-]?token|auth[_-]?token)`),
-
-	// Injection patterns
-	regexp.MustCompile(`(?i)(sql\s+injection|command\s+injection|code\s+injection)`),
-	regexp.MustCompile(`(?i)(\bUNION\s+SELECT|DROP\s+TABLE|DELETE\s+FROM)`),
-
-	// Path traversal
-	regexp.MustCompile(`\.\.[\\/]`),
-	regexp.MustCompile(`(?i)(\.\.%2f|\.\.%5c)`),
+// maxRepeatedRun is the longest run of one character accepted before the
+// input is flagged as a repetition attack.
+const maxRepeatedRun = 100
 
-	// Jailbreak attempts
-	regexp.MustCompile(`(?i)(DAN\s+mode|developer\s+mode|god\s+mode)`),
-	regexp.MustCompile(`(?i)(unrestricted|uncensored|no\s+filter)`),
-}
+// Patterns of the built-in checks, compiled once. Rule patterns are compiled
+// when the rules file is loaded.
+var (
+	base64Pattern = regexp.MustCompile(`[A-Za-z0-9+/]{40,}={0,2}`)
+)
 
-// Suspicious strings.
-var suspiciousStrings = []string{
-	"<!--", "-->",
-	"{{", "}}",
-	"${", "}",
-	"\\x", "\\u",
-	"\x00",
-	"<script>", "</script>",
-	"javascript:",
-	"data:text/html",
-	"onerror=", "onload=",
+// hasRepeatedRun reports whether a character (other than a newline) is
+// followed by limit or more copies of itself. Go's regexp has no
+// backreferences, so this replaces the pattern (.)\1{100,}.
+func hasRepeatedRun(input string, limit int) bool {
+	var previous rune = -1
+	run := 0
+	for _, r := range input {
+		if r == previous && r != '\n' {
+			run++
+			if run >= limit {
+				return true
+			}
+			continue
+		}
+		previous, run = r, 0
+	}
+	return false
 }
 
 // Request/Response Models.
 type ValidateRequest struct {
-	Input  string `json:"input"`
-	Strict bool   `json:"strict"`
+	Input string `json:"input"`
+	// Strict selects the strict profile; it predates profiles and is kept
+	// for existing callers.
+	Strict bool `json:"strict"`
+	// Profile selects a named profile; Thresholds lowers single values of it.
+	Profile    string      `json:"profile,omitempty"`
+	Thresholds *Thresholds `json:"thresholds,omitempty"`
 }
 
 type ValidateResponse struct {
-	IsSafe        bool     `json:"is_safe"`
-	CleanedInput  string   `json:"cleaned_input"`
-	Warnings      []string `json:"warnings"`
-	Severity      string   `json:"severity"`
-	Rejected      bool     `json:"rejected"`
-	RejectedCount int      `json:"rejected_count"`
+	IsSafe        bool       `json:"is_safe"`
+	CleanedInput  string     `json:"cleaned_input"`
+	Warnings      []string   `json:"warnings"`
+	Severity      string     `json:"severity"`
+	Score         float64    `json:"score"`
+	Profile       string     `json:"profile,omitempty"`
+	Thresholds    Thresholds `json:"thresholds"`
+	MatchedRules  []string   `json:"matched_rules,omitempty"`
+	ExceptedRules []string   `json:"excepted_rules,omitempty"`
+	// SkippedRules are slow rules not checked because the scan budget was
+	// spent.
+	SkippedRules []string     `json:"skipped_rules,omitempty"`
+	URLs         []URLVerdict `json:"urls,omitempty"`
+	// ClassifierScore is set when a classifier rated the input.
+	ClassifierScore *float64 `json:"classifier_score,omitempty"`
+	Rejected        bool     `json:"rejected"`
+	RejectedCount   int      `json:"rejected_count"`
 }
 
 type SanitizeRequest struct {
 	Output string `json:"output"`
+	// Policy is text, html or markdown (default).
+	Policy string `json:"policy,omitempty"`
 }
 
 type SanitizeResponse struct {
 	Sanitized string   `json:"sanitized"`
 	Removed   []string `json:"removed"`
+	Policy    string   `json:"policy"`
+	// LeakedCanaries are the canaries found in the output; their tokens are
+	// redacted from Sanitized.
+	LeakedCanaries []Canary `json:"leaked_canaries,omitempty"`
 }
 
 type Stats struct {
@@ -114,93 +337,196 @@ type Stats struct {
 
 // PromptValidator.
 type PromptValidator struct {
-	maxLength int
-	stats     *Stats
-	mu        *sync.Mutex
+	maxLength  int
+	rules      *RuleSet
+	stats      *Stats
+	mu         *sync.Mutex
+	reputation *reputationClient
+	classifier *classifierHook
+	// budget bounds the time spent on slow rules; timings tells which rules
+	// are slow.
+	budget  time.Duration
+	timings *ruleTimings
+	metrics *validationMetrics
 }
 
-func NewPromptValidator(maxLength int, stats *Stats, mu *sync.Mutex) *PromptValidator {
+func NewPromptValidator(maxLength int, rules *RuleSet, stats *Stats, mu *sync.Mutex) *PromptValidator {
 	return &PromptValidator{
 		maxLength: maxLength,
+		rules:     rules,
 		stats:     stats,
 		mu:        mu,
 	}
 }
 
-func (v *PromptValidator) Validate(input string, strict bool) ValidateResponse {
+// Validate scores input against the rule groups of profile and rejects it
+// once the score reaches the profile's reject threshold.
+func (v *PromptValidator) Validate(input string, profile Profile) ValidateResponse {
+	started := time.Now()
 	warnings := []string{}
-	cleanedInput := input
-	severity := "low"
+	score := 0.0
+
+	// Normalize the input, so invisible characters and look-alike letters
+	// cannot hide keywords from the checks below
+	normalized := normalizeInput(input)
+	cleanedInput, scan := normalized.visible, normalized.scan
+	obfuscation := profile.enabled(GroupObfuscation)
+	if obfuscation && normalized.invisible > 0 {
+		warnings = append(warnings, fmt.Sprintf("Removed %d invisible characters", normalized.invisible))
+		v.incrementWarning("invisible")
+		score += weightInvisible
+	}
+	if obfuscation && len(normalized.homoglyphWords) > 0 {
+		warnings = append(warnings, fmt.Sprintf("Detected homoglyph obfuscation: %s", strings.Join(normalized.homoglyphWords, ", ")))
+		v.incrementWarning("homoglyph")
+		score += weightHomoglyph
+	}
 
 	// Check length
 	if len(input) > v.maxLength {
 		warnings = append(warnings, fmt.Sprintf("Input exceeds maximum length (%d chars)", v.maxLength))
-		cleanedInput = cleanedInput[:v.maxLength]
-		severity = "medium"
+		cleanedInput = cleanedInput[:min(len(cleanedInput), v.maxLength)]
+		score += weightTooLong
 	}
 
-	// Check for dangerous patterns
-	for _, pattern := range dangerousPatterns {
-		if pattern.MatchString(input) {
-			warning := fmt.Sprintf("Detected injection pattern: %s", pattern.String())
-			warnings = append(warnings, warning)
-			v.incrementWarning("dangerous_pattern")
-			severity = "critical"
+	// Check the configured rules. Slow rules run last and, unless the
+	// profile is exhaustive, are skipped once the scan budget is spent.
+	matched := []string{}
+	excepted := []string{}
+	skipped := []string{}
+	forceReject := false
+	folded := foldKey(scan)
+	deadline := time.Now().Add(v.budget)
+	var deferred []*Rule
+	check := func(rule *Rule) {
+		started := time.Now()
+		hit, excused := rule.matches(scan, folded)
+		v.timings.observe(rule.ID, time.Since(started))
+		if excused {
+			excepted = append(excepted, rule.ID)
+			v.incrementWarning("excepted")
+		}
+		if !hit {
+			return
+		}
+		warnings = append(warnings, rule.warning())
+		matched = append(matched, rule.ID)
+		v.incrementWarning(rule.Category)
+		if rule.Action == ActionStrip {
+			cleanedInput = rule.strip(cleanedInput)
+		}
+		if rule.Action == ActionReject {
+			forceReject = true
+		}
+		score += rule.Weight
+	}
+	for i := range v.rules.Rules {
+		rule := &v.rules.Rules[i]
+		if !profile.enabled(rule.Group) {
+			continue
+		}
+		if v.budget > 0 && !profile.Exhaustive && v.timings.slow(rule.ID) {
+			deferred = append(deferred, rule)
+			continue
+		}
+		check(rule)
+	}
+	for _, rule := range deferred {
+		if time.Now().After(deadline) {
+			skipped = append(skipped, rule.ID)
+			v.timings.skip(rule.ID)
+			continue
+		}
+		check(rule)
+	}
+	if len(skipped) > 0 {
+		warnings = append(warnings, fmt.Sprintf("Scan budget exceeded, skipped slow rules: %s", strings.Join(skipped, ", ")))
+		v.incrementWarning("scan_budget")
+		score += weightScanBudget
+	}
+
+	// Check linked domains
+	var urls []URLVerdict
+	if profile.enabled(GroupLinks) {
+		urls = v.checkURLs(scan)
+	}
+	for _, link := range urls {
+		switch link.Verdict {
+		case VerdictBlocked, VerdictMalicious:
+			warnings = append(warnings, fmt.Sprintf("Detected %s domain: %s", link.Verdict, link.Domain))
+			v.incrementWarning("blocked_domain")
+			score += weightBlockedDomain
+		case VerdictSuspicious:
+			warnings = append(warnings, fmt.Sprintf("Detected suspicious domain: %s", link.Domain))
+			v.incrementWarning("suspicious_domain")
+			score += weightSuspiciousDomain
 		}
 	}
 
-	// Check for suspicious strings
-	for _, suspicious := range suspiciousStrings {
-		if strings.Contains(input, suspicious) {
-			warnings = append(warnings, fmt.Sprintf("Detected suspicious string: %s", suspicious))
-			cleanedInput = strings.ReplaceAll(cleanedInput, suspicious, "")
-			v.incrementWarning("suspicious_string")
-			if severity == "low" {
-				severity = "medium"
+	// Ask the classifier
+	var classifierScore *float64
+	if profile.enabled(GroupClassifier) {
+		if value, ok := v.classify(normalized.visible); ok {
+			classifierScore = &value
+			if value >= classifierWarnScore {
+				warnings = append(warnings, fmt.Sprintf("Classifier rated input as injection (%.2f)", value))
+				v.incrementWarning("classifier")
 			}
+			score += value * v.classifier.weight
 		}
 	}
 
 	// Check for excessive character repetition (e.g., "aaaaaaa..." to DoS)
-	repeatPattern := regexp.MustCompile(`(.)\1{100,}`)
-	if repeatPattern.MatchString(input) {
+	if obfuscation && hasRepeatedRun(scan, maxRepeatedRun) {
 		warnings = append(warnings, "Detected excessive character repetition")
 		v.incrementWarning("repetition")
-		if severity == "low" {
-			severity = "medium"
-		}
+		score += weightRepetition
 	}
 
 	// Check for base64 encoding attempts (often used to hide payloads)
-	base64Pattern := regexp.MustCompile(`(?i)[A-Za-z0-9+/]{40,}={0,2}`)
-	if base64Pattern.MatchString(input) {
+	if obfuscation && base64Pattern.MatchString(scan) {
 		warnings = append(warnings, "Detected potential base64 encoded payload")
 		v.incrementWarning("base64")
+		score += weightBase64
 	}
 
 	// Check for unicode/encoding tricks
-	if strings.Contains(input, "\\u") || strings.Contains(input, "\\x") {
+	if obfuscation && (strings.Contains(scan, "\\u") || strings.Contains(scan, "\\x")) {
 		warnings = append(warnings, "Detected unicode/hex encoding")
 		v.incrementWarning("encoding")
+		score += weightEncoding
 	}
 
 	// Determine if safe
-	isSafe := len(warnings) == 0 || (!strict && severity != "critical")
-	rejected := !isSafe
+	rejected := forceReject || score >= profile.Reject
+	severity := profile.severityFor(score)
+	if forceReject {
+		severity = "critical"
+	}
 
+	v.mu.Lock()
 	if rejected {
-		v.mu.Lock()
 		v.stats.Rejected++
-		v.mu.Unlock()
 	}
+	rejectedCount := v.stats.Rejected
+	v.mu.Unlock()
+	v.metrics.observe(matched, time.Since(started))
 
 	return ValidateResponse{
-		IsSafe:        isSafe,
+		IsSafe:        !rejected,
 		CleanedInput:  cleanedInput,
 		Warnings:      warnings,
 		Severity:      severity,
-		Rejected:      rejected,
-		RejectedCount: v.stats.Rejected,
+		Score:         score,
+		Thresholds:    profile.Thresholds,
+		MatchedRules:  matched,
+		ExceptedRules: excepted,
+		SkippedRules:  skipped,
+		URLs:          urls,
+
+		ClassifierScore: classifierScore,
+		Rejected:        rejected,
+		RejectedCount:   rejectedCount,
 	}
 }
 
@@ -210,49 +536,32 @@ func (v *PromptValidator) incrementWarning(key string) {
 	v.mu.Unlock()
 }
 
-func (v *PromptValidator) SanitizeOutput(output string) SanitizeResponse {
-	removed := []string{}
-	sanitized := output
-
-	// Remove potential code execution attempts in output
-	codePatterns := []string{
-		"exec(", "eval(", "__import__(",
-		"subprocess.", "os.system(",
-		"open(", "read(", "write(",
-	}
-
-	for _, pattern := range codePatterns {
-		if strings.Contains(sanitized, pattern) {
-			sanitized = strings.ReplaceAll(sanitized, pattern, "[REDACTED]")
-			removed = append(removed, pattern)
-		}
-	}
-
-	// Remove potential XSS in output
-	xssPatterns := []string{
-		"<script", "</script>",
-		"javascript:",
-		"onerror=", "onload=",
-	}
-
-	for _, pattern := range xssPatterns {
-		if strings.Contains(strings.ToLower(sanitized), strings.ToLower(pattern)) {
-			sanitized = strings.ReplaceAll(sanitized, pattern, "")
-			removed = append(removed, pattern)
-		}
-	}
-
-	return SanitizeResponse{
-		Sanitized: sanitized,
-		Removed:   removed,
-	}
-}
-
 type Service struct {
 	cfg       Config
 	logger    *log.Logger
 	stats     Stats
 	statsLock sync.Mutex
+	callers   map[string]*CallerStats
+	// history holds the daily counters; bucketed are the totals already
+	// counted into it.
+	history   []DailyStats
+	bucketed  Stats
+	ruleSet   *RuleSet
+	rulesLock sync.RWMutex
+	// customRules are the rules added through the API.
+	customRules []Rule
+	customLock  sync.Mutex
+	auditLog    *AuditLog
+	reputation  *reputationClient
+	alerts      *alertPublisher
+	classifier  *classifierHook
+	canaries    *canaryStore
+	timings     *ruleTimings
+	metrics     *validationMetrics
+	// badHashes are the SHA-256 hashes of known bad files.
+	badHashes map[string]bool
+	verifier  *authmw.Verifier
+	stop      chan struct{}
 }
 
 func NewService(cfg Config, logger *log.Logger) *Service {
@@ -260,30 +569,151 @@ func NewService(cfg Config, logger *log.Logger) *Service {
 		logger = log.New(os.Stdout, "[security] ", log.LstdFlags|log.LUTC)
 	}
 
-	return &Service{
+	svc := &Service{
 		cfg:    cfg,
 		logger: logger,
 		stats: Stats{
 			Warnings: make(map[string]int),
 		},
+		callers:  make(map[string]*CallerStats),
+		canaries: newCanaryStore(cfg.CanaryTTL),
+		timings:  newRuleTimings(),
+		metrics:  newValidationMetrics(),
+		verifier: authmw.NewVerifier(cfg.Auth),
+		stop:     make(chan struct{}),
+	}
+	svc.bucketed = svc.stats.copy()
+
+	if cfg.StatsFile != "" {
+		if err := svc.loadStats(); err != nil {
+			logger.Printf("[ERROR] Sicherheitsstatistik aus %s konnte nicht geladen werden: %v", cfg.StatsFile, err)
+		}
+		svc.persistStatsPeriodically()
+	}
+
+	if cfg.CustomRulesFile != "" {
+		custom, err := readCustomRules(cfg.CustomRulesFile)
+		if err != nil {
+			logger.Printf("[ERROR] Eigene Sicherheitsregeln aus %s ungültig: %v", cfg.CustomRulesFile, err)
+		}
+		svc.customRules = custom
+	}
+
+	if _, err := svc.reloadRules(); err != nil {
+		// Never run without rules: fall back to the built-in set and keep
+		// watching, so a fixed file is picked up.
+		logger.Printf("[ERROR] Sicherheitsregeln aus %s ungültig, verwende Standardregeln: %v", cfg.RulesFile, err)
+		if svc.ruleSet, err = LoadRules("", cfg.Locales, svc.customRuleList()...); err != nil {
+			svc.ruleSet, _ = LoadRules("", defaultLocales)
+		}
+	}
+	if cfg.RulesFile != "" {
+		svc.watchRules()
+	}
+
+	if cfg.BadHashesFile != "" {
+		hashes, err := readBadHashes(cfg.BadHashesFile)
+		if err != nil {
+			logger.Printf("[ERROR] Hash-Liste %s konnte nicht geladen werden: %v", cfg.BadHashesFile, err)
+		}
+		svc.badHashes = hashes
+	}
+
+	if cfg.AdminKey == "" && !cfg.Auth.Enabled() {
+		logger.Println("[WARN] No JARVIS_SECURITY_ADMIN_KEY or JARVIS_AUTH_SECRET/JARVIS_AUTH_KEYS configured, the audit log and rule changes are disabled")
+	}
+	svc.alerts = newAlertPublisher(cfg, logger)
+	if cfg.ClassifierURL != "" {
+		svc.SetClassifier(NewHTTPClassifier(cfg.ClassifierURL), cfg.ClassifierWeight, cfg.ClassifierTimeout)
+	}
+	if cfg.ReputationURL != "" {
+		svc.reputation = newReputationClient(cfg.ReputationURL, cfg.ReputationTimeout)
+	}
+
+	if cfg.AuditFile != "" {
+		auditLog, err := OpenAuditLog(cfg.AuditFile, cfg.AuditMaxEntries)
+		if err != nil {
+			logger.Printf("[ERROR] Audit-Log %s konnte nicht geöffnet werden: %v", cfg.AuditFile, err)
+		} else {
+			svc.auditLog = auditLog
+		}
 	}
+
+	return svc
+}
+
+// validator returns a validator for rules that shares the service stats.
+func (s *Service) validator(rules *RuleSet) *PromptValidator {
+	validator := NewPromptValidator(s.cfg.MaxLength, rules, &s.stats, &s.statsLock)
+	validator.reputation = s.reputation
+	validator.budget = s.cfg.ScanBudget
+	validator.timings = s.timings
+	validator.metrics = s.metrics
+	s.rulesLock.RLock()
+	validator.classifier = s.classifier
+	s.rulesLock.RUnlock()
+	return validator
 }
 
 func Listen(addr string) (net.Listener, error) {
 	return net.Listen("tcp", addr)
 }
 
-func (s *Service) Routes(mux *http.ServeMux) {
+func (s *Service) Routes(serveMux *http.ServeMux) {
 	router := mux.NewRouter()
 
 	router.HandleFunc("/health", s.healthHandler).Methods(http.MethodGet)
+	router.HandleFunc("/metrics", s.metricsHandler).Methods(http.MethodGet)
 	router.HandleFunc("/api/security/validate", s.validateHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/security/validate/batch", s.validateBatchHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/security/validate/stream", s.validateStreamHandler).Methods(http.MethodPost)
 	router.HandleFunc("/api/security/sanitize", s.sanitizeHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/security/canary", s.canaryHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/security/scan-file", s.scanFileHandler).Methods(http.MethodPost)
 	router.HandleFunc("/api/security/stats", s.statsHandler).Methods(http.MethodGet)
+	router.HandleFunc("/api/security/stats/history", s.statsHistoryHandler).Methods(http.MethodGet)
+	router.HandleFunc("/api/security/audit", s.requireAdmin(s.auditHandler)).Methods(http.MethodGet)
+	router.HandleFunc("/api/security/rules", s.rulesHandler).Methods(http.MethodGet)
+	router.HandleFunc("/api/security/rules", s.requireAdmin(s.addRuleHandler)).Methods(http.MethodPost)
+	router.HandleFunc("/api/security/rules/reload", s.requireAdmin(s.reloadRulesHandler)).Methods(http.MethodPost)
+	router.HandleFunc("/api/security/rules/test", s.testRulesHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/security/rules/metrics", s.ruleMetricsHandler).Methods(http.MethodGet)
+	router.HandleFunc("/api/security/rules/{id}", s.requireAdmin(s.deleteRuleHandler)).Methods(http.MethodDelete)
+
+	serveMux.Handle("/", cors.New(s.cfg.CORS).Handler(router))
+}
 
-	router.Use(corsMiddleware)
-
-	mux.Handle("/", router)
+// requireAdmin guards the audit log and handlers that change the service:
+// with AdminKey set the request must carry it as X-Admin-Key, otherwise
+// valid credentials of Auth. Unauthenticated requests get 401, wrong admin
+// keys 403.
+func (s *Service) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case s.cfg.AdminKey != "":
+			headerKey := strings.TrimSpace(r.Header.Get("X-Admin-Key"))
+			if headerKey == "" {
+				http.Error(w, `{"error":"X-Admin-Key required"}`, http.StatusUnauthorized)
+				return
+			}
+			if subtle.ConstantTimeCompare([]byte(headerKey), []byte(s.cfg.AdminKey)) != 1 {
+				http.Error(w, `{"error":"Admin access required"}`, http.StatusForbidden)
+				return
+			}
+		case s.cfg.Auth.Enabled():
+			identity, err := s.verifier.Authenticate(r)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="jarvis"`)
+				http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
+			r = r.WithContext(authmw.WithIdentity(r.Context(), identity))
+		default:
+			http.Error(w, `{"error":"Admin access not configured"}`, http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
 }
 
 // HTTP Handlers
@@ -306,12 +736,22 @@ func (s *Service) validateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	rules := s.rules()
+	profile, name, err := s.profile(r, rules, req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
 	s.statsLock.Lock()
 	s.stats.TotalValidations++
 	s.statsLock.Unlock()
 
-	validator := NewPromptValidator(s.cfg.MaxLength, &s.stats, &s.statsLock)
-	result := validator.Validate(req.Input, req.Strict)
+	validator := s.validator(rules)
+	result := validator.Validate(req.Input, profile)
+	result.Profile = name
+	s.audit(r, req.Input, result)
+	s.countCaller(r, 1, result)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
@@ -325,33 +765,69 @@ func (s *Service) sanitizeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	validator := NewPromptValidator(s.cfg.MaxLength, &s.stats, &s.statsLock)
-	result := validator.SanitizeOutput(req.Output)
+	validator := s.validator(s.rules())
+	result, err := validator.SanitizeOutput(req.Output, req.Policy)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	s.checkCanaries(r, req.Output, &result)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
-func (s *Service) statsHandler(w http.ResponseWriter, _ *http.Request) {
+// statsHandler returns the global counters and the callers with the most
+// rejections (top, default 10), or with ?key= the counters of one API key,
+// given as the key or its id.
+func (s *Service) statsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if key := strings.TrimSpace(query.Get("key")); key != "" {
+		s.statsLock.Lock()
+		caller, ok := s.callerStatsLocked(key)
+		s.statsLock.Unlock()
+		if !ok {
+			http.Error(w, `{"error":"No stats for this key"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(caller)
+		return
+	}
+
+	limit := defaultTopCallers
+	if value, err := strconv.Atoi(query.Get("top")); err == nil && value >= 0 {
+		limit = min(value, maxTopCallers)
+	}
+
 	s.statsLock.Lock()
-	statsCopy := s.stats
+	statsCopy := s.stats.copy()
+	top := s.topCallersLocked(limit)
 	s.statsLock.Unlock()
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(statsCopy)
+	json.NewEncoder(w).Encode(struct {
+		Stats
+		TopOffenders []CallerStats `json:"top_offenders"`
+	}{statsCopy, top})
 }
 
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+func (s *Service) rulesHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.rules())
+}
 
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+func (s *Service) reloadRulesHandler(w http.ResponseWriter, _ *http.Request) {
+	set, err := s.reloadRules()
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusUnprocessableEntity)
+		return
+	}
 
-		next.ServeHTTP(w, r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"rules":   len(set.Rules),
+		"source":  set.Source,
 	})
 }
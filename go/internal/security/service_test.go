@@ -0,0 +1,71 @@
+package security
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestService returns a service with the built-in rules that keeps no
+// files unless cfg names them.
+func newTestService(t *testing.T, cfg Config) *Service {
+	t.Helper()
+	if cfg.MaxLength == 0 {
+		cfg.MaxLength = defaultMaxLength
+	}
+	if cfg.Locales == nil {
+		cfg.Locales = defaultLocales
+	}
+	if cfg.Profile == "" {
+		cfg.Profile = DefaultProfile
+	}
+	svc := NewService(cfg, log.New(io.Discard, "", 0))
+	t.Cleanup(svc.Close)
+	return svc
+}
+
+// serve sends a request with a JSON body (unless body is nil) through the
+// service routes.
+func serve(svc *Service, method, path string, body interface{}, header http.Header) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		payload, _ := json.Marshal(body)
+		reader = bytes.NewReader(payload)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	mux := http.NewServeMux()
+	svc.Routes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+}
+
+// validate runs input through the validate endpoint.
+func validate(t *testing.T, svc *Service, req ValidateRequest) ValidateResponse {
+	t.Helper()
+	rec := serve(svc, http.MethodPost, "/api/security/validate", req, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("validate: status %d (%s)", rec.Code, rec.Body)
+	}
+	var result ValidateResponse
+	decode(t, rec, &result)
+	return result
+}
+
+func adminHeader(key string) http.Header {
+	return http.Header{"X-Admin-Key": {key}}
+}
@@ -0,0 +1,194 @@
+package security
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"jarviscore/go/internal/fsutil"
+)
+
+const (
+	defaultStatsFile        = "data/security/stats.json"
+	defaultStatsHistoryDays = 90
+	statsPersistInterval    = time.Minute
+	statsDateLayout         = "2006-01-02"
+)
+
+// DailyStats are the counters of one day (UTC).
+type DailyStats struct {
+	Date             string         `json:"date"`
+	TotalValidations int            `json:"total_validations"`
+	Rejected         int            `json:"rejected"`
+	Warnings         map[string]int `json:"warnings"`
+}
+
+// statsFile is the persisted form of the counters.
+type statsFile struct {
+	Saved   time.Time               `json:"saved"`
+	Totals  Stats                   `json:"totals"`
+	Callers map[string]*CallerStats `json:"callers"`
+	History []DailyStats            `json:"history"`
+}
+
+// loadStats restores the counters from the stats file. A missing file
+// leaves them empty.
+func (s *Service) loadStats() error {
+	data, err := os.ReadFile(s.cfg.StatsFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var stored statsFile
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("invalid stats file: %w", err)
+	}
+
+	s.statsLock.Lock()
+	defer s.statsLock.Unlock()
+	s.stats = stored.Totals
+	if s.stats.Warnings == nil {
+		s.stats.Warnings = make(map[string]int)
+	}
+	s.bucketed = s.stats.copy()
+	for id, caller := range stored.Callers {
+		if caller.Rules == nil {
+			caller.Rules = make(map[string]int)
+		}
+		s.callers[id] = caller
+	}
+	s.history = stored.History
+	return nil
+}
+
+// rollStatsLocked adds the counts since the last call to today's bucket and
+// drops buckets older than the history length. Counting into buckets this
+// way keeps the validation paths unaware of the history.
+func (s *Service) rollStatsLocked(now time.Time) {
+	date := now.UTC().Format(statsDateLayout)
+	if len(s.history) == 0 || s.history[len(s.history)-1].Date != date {
+		s.history = append(s.history, DailyStats{Date: date, Warnings: make(map[string]int)})
+	}
+	today := &s.history[len(s.history)-1]
+	today.TotalValidations += s.stats.TotalValidations - s.bucketed.TotalValidations
+	today.Rejected += s.stats.Rejected - s.bucketed.Rejected
+	for key, count := range s.stats.Warnings {
+		if delta := count - s.bucketed.Warnings[key]; delta > 0 {
+			today.Warnings[key] += delta
+		}
+	}
+	s.bucketed = s.stats.copy()
+
+	oldest := now.UTC().AddDate(0, 0, -s.cfg.StatsHistoryDays).Format(statsDateLayout)
+	keep := 0
+	for keep < len(s.history) && s.history[keep].Date <= oldest {
+		keep++
+	}
+	if keep > 0 {
+		s.history = append([]DailyStats(nil), s.history[keep:]...)
+	}
+}
+
+// persistStats writes the counters to the stats file.
+func (s *Service) persistStats() error {
+	s.statsLock.Lock()
+	s.rollStatsLocked(time.Now())
+	stored := statsFile{
+		Saved:   time.Now().UTC(),
+		Totals:  s.stats,
+		Callers: s.callers,
+		History: s.history,
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	s.statsLock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.cfg.StatsFile), 0o755); err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(s.cfg.StatsFile, data, 0o600)
+}
+
+// persistStatsPeriodically saves the counters every statsPersistInterval
+// until Close.
+func (s *Service) persistStatsPeriodically() {
+	go func() {
+		ticker := time.NewTicker(statsPersistInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if err := s.persistStats(); err != nil {
+					s.logger.Printf("[WARN] Sicherheitsstatistik konnte nicht gespeichert werden: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops background work and saves the counters. Call it on shutdown.
+func (s *Service) Close() {
+	select {
+	case <-s.stop:
+		return
+	default:
+		close(s.stop)
+	}
+	if s.cfg.StatsFile != "" {
+		if err := s.persistStats(); err != nil {
+			s.logger.Printf("[WARN] Sicherheitsstatistik konnte nicht gespeichert werden: %v", err)
+		}
+	}
+	if s.auditLog != nil {
+		s.auditLog.Close()
+	}
+}
+
+// statsHistoryHandler returns the daily counters of the last days (default
+// all kept), oldest first.
+func (s *Service) statsHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	days := s.cfg.StatsHistoryDays
+	if value, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && value > 0 {
+		days = min(value, days)
+	}
+
+	s.statsLock.Lock()
+	s.rollStatsLocked(time.Now())
+	history := s.history[max(len(s.history)-days, 0):]
+	copied := make([]DailyStats, len(history))
+	for i, day := range history {
+		copied[i] = day
+		copied[i].Warnings = make(map[string]int, len(day.Warnings))
+		for key, count := range day.Warnings {
+			copied[i].Warnings[key] = count
+		}
+	}
+	s.statsLock.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":    copied,
+		"persist": s.cfg.StatsFile != "",
+	})
+}
+
+func (st Stats) copy() Stats {
+	copied := st
+	copied.Warnings = make(map[string]int, len(st.Warnings))
+	for key, count := range st.Warnings {
+		copied.Warnings[key] = count
+	}
+	return copied
+}
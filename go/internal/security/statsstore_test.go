@@ -0,0 +1,125 @@
+package security
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRollStats(t *testing.T) {
+	svc := newTestService(t, Config{StatsHistoryDays: 3})
+	day := time.Date(2026, 3, 10, 23, 0, 0, 0, time.UTC)
+
+	// Each step adds counts and rolls at the given time.
+	steps := []struct {
+		at          time.Time
+		validations int
+		rejected    int
+		warning     int
+		days        []string
+		today       DailyStats
+	}{
+		{day, 4, 1, 2, []string{"2026-03-10"}, DailyStats{TotalValidations: 4, Rejected: 1}},
+		{day.Add(30 * time.Minute), 2, 0, 1, []string{"2026-03-10"}, DailyStats{TotalValidations: 6, Rejected: 1}},
+		{day.Add(2 * time.Hour), 1, 1, 0, []string{"2026-03-10", "2026-03-11"}, DailyStats{TotalValidations: 1, Rejected: 1}},
+		{day.AddDate(0, 0, 3), 5, 0, 0, []string{"2026-03-11", "2026-03-13"}, DailyStats{TotalValidations: 5}},
+	}
+	for i, step := range steps {
+		svc.statsLock.Lock()
+		svc.stats.TotalValidations += step.validations
+		svc.stats.Rejected += step.rejected
+		svc.stats.Warnings["base64"] += step.warning
+		svc.rollStatsLocked(step.at)
+		var days []string
+		for _, bucket := range svc.history {
+			days = append(days, bucket.Date)
+		}
+		today := svc.history[len(svc.history)-1]
+		svc.statsLock.Unlock()
+
+		if !reflect.DeepEqual(days, step.days) {
+			t.Fatalf("step %d: days %v, want %v", i, days, step.days)
+		}
+		if today.TotalValidations != step.today.TotalValidations || today.Rejected != step.today.Rejected {
+			t.Errorf("step %d: today = %+v, want %+v", i, today, step.today)
+		}
+	}
+	if first := svc.history[0]; first.Warnings["base64"] != 0 {
+		t.Errorf("warnings of %s = %v", first.Date, first.Warnings)
+	}
+}
+
+func TestStatsPersistence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "security", "stats.json")
+	cfg := Config{StatsFile: file, StatsHistoryDays: 30, Locales: []string{}}
+
+	first := newTestService(t, cfg)
+	validate(t, first, ValidateRequest{Input: "eval(x)"})
+	serve(first, http.MethodPost, "/api/security/validate", ValidateRequest{Input: "hallo"}, apiKeyHeader("schluessel-anna"))
+	first.Close()
+
+	second := newTestService(t, cfg)
+	second.statsLock.Lock()
+	stats, callers := second.stats.copy(), len(second.callers)
+	second.statsLock.Unlock()
+	if stats.TotalValidations != 2 || callers != 1 {
+		t.Fatalf("restored %d validations and %d callers, want 2 and 1", stats.TotalValidations, callers)
+	}
+
+	validate(t, second, ValidateRequest{Input: "hallo"})
+	var history struct {
+		Days    []DailyStats `json:"days"`
+		Persist bool         `json:"persist"`
+	}
+	decode(t, serve(second, http.MethodGet, "/api/security/stats/history", nil, nil), &history)
+	if !history.Persist || len(history.Days) != 1 || history.Days[0].TotalValidations != 3 {
+		t.Errorf("history = %+v, want today's 3 validations counted once", history)
+	}
+}
+
+func TestInvalidStatsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "stats.json")
+	os.WriteFile(file, []byte("{kaputt"), 0o600)
+	svc := newTestService(t, Config{StatsFile: file, StatsHistoryDays: 30})
+
+	validate(t, svc, ValidateRequest{Input: "hallo"})
+	svc.statsLock.Lock()
+	defer svc.statsLock.Unlock()
+	if svc.stats.TotalValidations != 1 {
+		t.Errorf("%d validations, want counting to start from zero", svc.stats.TotalValidations)
+	}
+}
+
+func TestStatsHistoryDays(t *testing.T) {
+	svc := newTestService(t, Config{StatsHistoryDays: 30})
+	now := time.Now().UTC()
+	for i := 5; i > 0; i-- {
+		svc.history = append(svc.history, DailyStats{Date: now.AddDate(0, 0, -i).Format(statsDateLayout), TotalValidations: i, Warnings: map[string]int{}})
+	}
+
+	tests := []struct {
+		query string
+		days  int
+	}{
+		{"", 6},
+		{"?days=2", 2},
+		{"?days=0", 6},
+		{"?days=100", 6},
+	}
+	for _, tt := range tests {
+		var history struct {
+			Days    []DailyStats `json:"days"`
+			Persist bool         `json:"persist"`
+		}
+		decode(t, serve(svc, http.MethodGet, "/api/security/stats/history"+tt.query, nil, nil), &history)
+		if len(history.Days) != tt.days || history.Persist {
+			t.Errorf("%q: %d days (persist %v), want %d", tt.query, len(history.Days), history.Persist, tt.days)
+		}
+		if len(history.Days) > 0 && history.Days[len(history.Days)-1].Date != now.Format(statsDateLayout) {
+			t.Errorf("%q: newest day %s", tt.query, history.Days[len(history.Days)-1].Date)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package security
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultStreamMaxBytes = 10 << 20
+	// streamOverlap is how much of a window is scanned again at the start of
+	// the next one, so matches across a window boundary are found. It covers
+	// the exception context on both sides of typical matches.
+	streamOverlap = 512
+	// minStreamWindow keeps windows larger than the overlap plus a cut
+	// rune, so every window reaches past what was already scanned.
+	minStreamWindow = 4 * utf8.UTFMax
+)
+
+// StreamWindow is the result of one window of a streamed input. The cleaned
+// input is left out.
+type StreamWindow struct {
+	Type   string `json:"type"`
+	Index  int    `json:"index"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+	ValidateResponse
+}
+
+// StreamSummary is the last line of a stream response. The input is rejected
+// if any window was; Score and Severity are those of the worst window.
+type StreamSummary struct {
+	Type         string     `json:"type"`
+	IsSafe       bool       `json:"is_safe"`
+	Rejected     bool       `json:"rejected"`
+	Severity     string     `json:"severity"`
+	Score        float64    `json:"score"`
+	Profile      string     `json:"profile,omitempty"`
+	Thresholds   Thresholds `json:"thresholds"`
+	MatchedRules []string   `json:"matched_rules"`
+	Windows      int        `json:"windows"`
+	Length       int        `json:"length"`
+}
+
+// windowScanner cuts a stream into windows of at most size bytes, but no
+// less than minStreamWindow, each starting overlap bytes before the end of
+// the previous one. Cuts fall on rune boundaries.
+type windowScanner struct {
+	source  io.Reader
+	size    int
+	overlap int
+	buf     []byte
+	offset  int // stream offset of buf[0]
+	scanned int // bytes at the start of buf that were already scanned
+	eof     bool
+}
+
+func newWindowScanner(source io.Reader, size int) *windowScanner {
+	size = max(size, minStreamWindow)
+	return &windowScanner{
+		source:  source,
+		size:    size,
+		overlap: min(streamOverlap, size/4),
+		buf:     make([]byte, 0, size),
+	}
+}
+
+// next returns the next window and its byte offset in the stream, or io.EOF.
+func (ws *windowScanner) next() (string, int, error) {
+	for len(ws.buf) < ws.size && !ws.eof {
+		n, err := ws.source.Read(ws.buf[len(ws.buf):ws.size])
+		ws.buf = ws.buf[:len(ws.buf)+n]
+		if errors.Is(err, io.EOF) {
+			ws.eof = true
+		} else if err != nil {
+			return "", 0, err
+		}
+	}
+	if len(ws.buf) == ws.scanned {
+		return "", 0, io.EOF
+	}
+
+	end, keep := len(ws.buf), len(ws.buf)
+	if !ws.eof {
+		end = runeStart(ws.buf, end)
+		keep = runeStart(ws.buf, max(0, end-ws.overlap))
+	}
+	window, offset := string(ws.buf[:end]), ws.offset
+	ws.buf = append(ws.buf[:0], ws.buf[keep:]...)
+	ws.offset += keep
+	ws.scanned = end - keep
+	return window, offset, nil
+}
+
+// runeStart moves i back to the start of a rune cut off at i, so that
+// buf[:i] ends with a complete rune.
+func runeStart(buf []byte, i int) int {
+	for j := i - 1; j >= 0 && j >= i-utf8.UTFMax; j-- {
+		if utf8.RuneStart(buf[j]) {
+			if utf8.FullRune(buf[j:i]) {
+				return i
+			}
+			return j
+		}
+	}
+	return i
+}
+
+// ndjsonInput joins the "input" fields of NDJSON lines into one stream.
+type ndjsonInput struct {
+	scanner *bufio.Scanner
+	pending string
+}
+
+func newNDJSONInput(source io.Reader, maxLine int) *ndjsonInput {
+	scanner := bufio.NewScanner(source)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLine)
+	return &ndjsonInput{scanner: scanner}
+}
+
+func (in *ndjsonInput) Read(p []byte) (int, error) {
+	for in.pending == "" {
+		if !in.scanner.Scan() {
+			if err := in.scanner.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		line := strings.TrimSpace(in.scanner.Text())
+		if line == "" {
+			continue
+		}
+		var chunk struct {
+			Input string `json:"input"`
+		}
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return 0, fmt.Errorf("invalid NDJSON line: %w", err)
+		}
+		in.pending = chunk.Input
+	}
+	n := copy(p, in.pending)
+	in.pending = in.pending[n:]
+	return n, nil
+}
+
+// hashingReader hashes and counts what is read through it.
+type hashingReader struct {
+	source io.Reader
+	hash   hash.Hash
+	length int
+}
+
+func (h *hashingReader) Read(p []byte) (int, error) {
+	n, err := h.source.Read(p)
+	h.hash.Write(p[:n])
+	h.length += n
+	return n, err
+}
+
+// validateStreamHandler validates inputs longer than MaxLength window by
+// window instead of truncating them. The body is the raw text, or with
+// Content-Type application/x-ndjson lines of {"input": "..."} whose inputs
+// are concatenated. The profile is selected with the query parameters
+// profile and strict. The response is NDJSON: one line per window as it is
+// scanned, then a summary.
+func (s *Service) validateStreamHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	strict, _ := strconv.ParseBool(query.Get("strict"))
+	rules := s.rules()
+	profile, name, err := s.profile(r, rules, ValidateRequest{Strict: strict, Profile: query.Get("profile")})
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, r.Body, s.cfg.StreamMaxBytes)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-ndjson") {
+		body = newNDJSONInput(body, int(s.cfg.StreamMaxBytes))
+	}
+	input := &hashingReader{source: body, hash: sha256.New()}
+	scanner := newWindowScanner(input, s.cfg.MaxLength)
+
+	validator := s.validator(rules)
+	summary := StreamSummary{
+		Type:         "summary",
+		IsSafe:       true,
+		Severity:     "low",
+		Profile:      name,
+		Thresholds:   profile.Thresholds,
+		MatchedRules: []string{},
+	}
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+
+	for {
+		window, offset, err := scanner.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if summary.Windows == 0 {
+				http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+				return
+			}
+			// The status is already sent; end the stream with the error.
+			encoder.Encode(map[string]interface{}{"type": "error", "error": err.Error()})
+			return
+		}
+		if summary.Windows == 0 {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+
+		s.statsLock.Lock()
+		s.stats.TotalValidations++
+		s.statsLock.Unlock()
+
+		result := validator.Validate(window, profile)
+		result.Profile = name
+		result.CleanedInput = ""
+		encoder.Encode(StreamWindow{Type: "window", Index: summary.Windows, Offset: offset, Length: len(window), ValidateResponse: result})
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		summary.Windows++
+		if result.Rejected {
+			summary.Rejected = true
+			summary.IsSafe = false
+		}
+		summary.Score = max(summary.Score, result.Score)
+		if severityRank[result.Severity] > severityRank[summary.Severity] {
+			summary.Severity = result.Severity
+		}
+		for _, id := range result.MatchedRules {
+			if !containsString(summary.MatchedRules, id) {
+				summary.MatchedRules = append(summary.MatchedRules, id)
+			}
+		}
+	}
+	if summary.Windows == 0 {
+		http.Error(w, `{"error":"empty input"}`, http.StatusBadRequest)
+		return
+	}
+
+	summary.Length = input.length
+	combined := ValidateResponse{
+		MatchedRules: summary.MatchedRules,
+		Severity:     summary.Severity,
+		Score:        summary.Score,
+		Profile:      name,
+		Rejected:     summary.Rejected,
+	}
+	s.record(r, hex.EncodeToString(input.hash.Sum(nil))[:auditHashLength], input.length, combined)
+	s.countCaller(r, 1, combined)
+	encoder.Encode(summary)
+}
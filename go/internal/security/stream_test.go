@@ -0,0 +1,186 @@
+package security
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+)
+
+func TestWindowScanner(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		size  int
+	}{
+		{"single window", "kurz", 64},
+		{"exact size", strings.Repeat("a", 64), 64},
+		{"many windows", strings.Repeat("abcdefghij", 50), 64},
+		{"multibyte runes", strings.Repeat("Grüße aus Köln ", 40), 37},
+		{"below minimum size", strings.Repeat("ü", 100), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := newWindowScanner(iotest.OneByteReader(strings.NewReader(tt.input)), tt.size)
+			covered := 0
+			for {
+				window, offset, err := scanner.next()
+				if err != nil {
+					break
+				}
+				if len(window) > scanner.size {
+					t.Fatalf("window of %d bytes, size %d", len(window), scanner.size)
+				}
+				if offset > covered || tt.input[offset:offset+len(window)] != window {
+					t.Fatalf("window at %d does not continue the stream at %d", offset, covered)
+				}
+				if !utf8.ValidString(window) {
+					t.Fatalf("window %q cuts a rune", window)
+				}
+				if offset+len(window) <= covered {
+					t.Fatalf("window at %d adds nothing past %d", offset, covered)
+				}
+				covered = offset + len(window)
+			}
+			if covered != len(tt.input) {
+				t.Errorf("covered %d bytes, want %d", covered, len(tt.input))
+			}
+		})
+	}
+}
+
+// streamService returns a service with small windows and the rules of
+// weightedService.
+func streamService(t *testing.T) *Service {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	os.WriteFile(path, []byte(`
+rules:
+  - {id: low, pattern: 'apple', severity: low}
+  - {id: blocker, pattern: 'elderberry', severity: low, action: reject}
+`), 0o644)
+	return newTestService(t, Config{RulesFile: path, Locales: []string{}, MaxLength: 64, StreamMaxBytes: 4096})
+}
+
+func serveStream(svc *Service, query, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/security/validate/stream"+query, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	mux := http.NewServeMux()
+	svc.Routes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// readStream splits a stream response into its windows and summary.
+func readStream(t *testing.T, rec *httptest.ResponseRecorder) ([]StreamWindow, StreamSummary) {
+	t.Helper()
+	var windows []StreamWindow
+	var summary StreamSummary
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var line struct{ Type string }
+		json.Unmarshal(scanner.Bytes(), &line)
+		switch line.Type {
+		case "window":
+			var window StreamWindow
+			json.Unmarshal(scanner.Bytes(), &window)
+			windows = append(windows, window)
+		case "summary":
+			json.Unmarshal(scanner.Bytes(), &summary)
+		default:
+			t.Fatalf("unexpected line %s", scanner.Bytes())
+		}
+	}
+	return windows, summary
+}
+
+func TestValidateStream(t *testing.T) {
+	padding := strings.Repeat("x", 60)
+	ndjson := `{"input":"` + padding + `elder"}` + "\n\n" + `{"input":"berry ` + padding + `"}` + "\n"
+
+	tests := []struct {
+		name        string
+		query       string
+		contentType string
+		body        string
+		code        int
+		rejected    bool
+		matched     []string
+		length      int
+	}{
+		{"safe text", "", "text/plain", strings.Repeat("Hallo Welt. ", 20), http.StatusOK, false, []string{}, 240},
+		{"match in a later window", "", "", padding + padding + "apple", http.StatusOK, false, []string{"low"}, 125},
+		{"match across a boundary", "", "", padding + "elderberry" + padding, http.StatusOK, true, []string{"blocker"}, 130},
+		{"ndjson chunks are joined", "", "application/x-ndjson", ndjson, http.StatusOK, true, []string{"blocker"}, 131},
+		{"repeated matches listed once", "", "", strings.Repeat("apple ", 40), http.StatusOK, false, []string{"low"}, 240},
+		{"empty", "", "", "", http.StatusBadRequest, false, nil, 0},
+		{"invalid ndjson", "", "application/x-ndjson", "{broken\n", http.StatusBadRequest, false, nil, 0},
+		{"unknown profile", "?profile=paranoid", "", "apple", http.StatusBadRequest, false, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveStream(streamService(t), tt.query, tt.contentType, tt.body)
+			if rec.Code != tt.code {
+				t.Fatalf("status %d, want %d (%s)", rec.Code, tt.code, rec.Body)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			if rec.Header().Get("Content-Type") != "application/x-ndjson" {
+				t.Errorf("Content-Type %q", rec.Header().Get("Content-Type"))
+			}
+			windows, summary := readStream(t, rec)
+			if summary.Windows != len(windows) || len(windows) == 0 {
+				t.Fatalf("summary counts %d windows, stream has %d", summary.Windows, len(windows))
+			}
+			for i, window := range windows {
+				if window.Index != i || window.CleanedInput != "" {
+					t.Errorf("window %d = %+v", i, window)
+				}
+			}
+			if summary.Rejected != tt.rejected || summary.IsSafe == tt.rejected || summary.Length != tt.length {
+				t.Errorf("summary = %+v", summary)
+			}
+			if strings.Join(summary.MatchedRules, ",") != strings.Join(tt.matched, ",") {
+				t.Errorf("matched %v, want %v", summary.MatchedRules, tt.matched)
+			}
+		})
+	}
+}
+
+func TestValidateStreamCountsWindows(t *testing.T) {
+	svc := streamService(t)
+	rec := serveStream(svc, "?strict=true", "", strings.Repeat("Hallo Welt. ", 20))
+	windows, summary := readStream(t, rec)
+	if summary.Profile != StrictProfile {
+		t.Errorf("profile = %q, want %q", summary.Profile, StrictProfile)
+	}
+
+	svc.statsLock.Lock()
+	defer svc.statsLock.Unlock()
+	if svc.stats.TotalValidations != len(windows) {
+		t.Errorf("%d validations counted, want one per window (%d)", svc.stats.TotalValidations, len(windows))
+	}
+}
+
+func TestValidateStreamTooLarge(t *testing.T) {
+	rec := serveStream(streamService(t), "", "", strings.Repeat("Hallo Welt. ", 400))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200 once windows were sent", rec.Code)
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	var last struct{ Type, Error string }
+	json.Unmarshal([]byte(lines[len(lines)-1]), &last)
+	if last.Type != "error" || !strings.Contains(last.Error, "too large") {
+		t.Errorf("last line = %s, want an error instead of a summary", lines[len(lines)-1])
+	}
+}
@@ -0,0 +1,239 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultReputationTimeout bounds all lookups of one input; it stays
+	// below the default timeout of the Middleware.
+	defaultReputationTimeout = time.Second
+	reputationCacheTTL       = time.Hour
+	reputationCacheSize      = 10000
+	// maxCheckedURLs limits the URLs of one input that are checked.
+	maxCheckedURLs = 20
+)
+
+// URL verdicts.
+const (
+	VerdictAllowed    = "allowed"
+	VerdictBlocked    = "blocked"
+	VerdictMalicious  = "malicious"
+	VerdictSuspicious = "suspicious"
+	VerdictClean      = "clean"
+	VerdictUnknown    = "unknown"
+)
+
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|ftp://|www\.)[^\s<>"'` + "`" + `]+`)
+
+// DomainLists of the rules file. Entries match the domain and its
+// subdomains; the allowlist wins over the blocklist and skips the
+// reputation check.
+type DomainLists struct {
+	Block []string `json:"block,omitempty" yaml:"block"`
+	Allow []string `json:"allow,omitempty" yaml:"allow"`
+}
+
+// URLVerdict is the result of checking one URL of an input.
+type URLVerdict struct {
+	URL     string `json:"url"`
+	Domain  string `json:"domain"`
+	Verdict string `json:"verdict"`
+	// Source is allowlist, blocklist or reputation.
+	Source string `json:"source,omitempty"`
+}
+
+// extractURLs returns the distinct URLs of input with their lower-case host.
+func extractURLs(input string) []URLVerdict {
+	var urls []URLVerdict
+	seen := map[string]bool{}
+	for _, raw := range urlPattern.FindAllString(input, -1) {
+		raw = strings.TrimRight(raw, ".,;:!?)]}")
+		target := raw
+		if !strings.Contains(target, "://") {
+			target = "http://" + target
+		}
+		parsed, err := url.Parse(target)
+		if err != nil || parsed.Hostname() == "" || seen[raw] {
+			continue
+		}
+		seen[raw] = true
+		urls = append(urls, URLVerdict{URL: raw, Domain: strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")})
+		if len(urls) == maxCheckedURLs {
+			break
+		}
+	}
+	return urls
+}
+
+// normalizeDomains lower-cases the entries and drops leading dots and
+// wildcards.
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.TrimLeft(strings.ToLower(strings.TrimSpace(domain)), "*.")
+		if domain != "" {
+			normalized = append(normalized, domain)
+		}
+	}
+	return normalized
+}
+
+// domainListed reports whether domain or one of its parent domains is in list.
+func domainListed(domain string, list []string) bool {
+	for _, entry := range list {
+		if domain == entry || strings.HasSuffix(domain, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// reputationClient asks a remote service about domains:
+// GET endpoint?domain=example.com answering {"verdict": "clean"|"suspicious"|
+// "malicious"}. Answers are cached; failures yield VerdictUnknown, so an
+// unavailable service never blocks validation.
+type reputationClient struct {
+	endpoint string
+	client   *http.Client
+	timeout  time.Duration
+	cache    map[string]reputationEntry
+	mu       sync.Mutex
+}
+
+type reputationEntry struct {
+	verdict string
+	expires time.Time
+}
+
+func newReputationClient(endpoint string, timeout time.Duration) *reputationClient {
+	if timeout <= 0 {
+		timeout = defaultReputationTimeout
+	}
+	return &reputationClient{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: timeout},
+		timeout:  timeout,
+		cache:    make(map[string]reputationEntry),
+	}
+}
+
+// verdicts rates domains concurrently. All lookups share one deadline of the
+// client timeout, so an input with many links waits no longer than one.
+func (c *reputationClient) verdicts(domains []string) map[string]string {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	verdicts := make(map[string]string, len(domains))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, domain := range domains {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			verdict := c.verdict(ctx, domain)
+			mu.Lock()
+			verdicts[domain] = verdict
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return verdicts
+}
+
+func (c *reputationClient) verdict(ctx context.Context, domain string) string {
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.cache[domain]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.verdict
+	}
+
+	verdict, err := c.lookup(ctx, domain)
+	if err != nil {
+		return VerdictUnknown
+	}
+	c.mu.Lock()
+	if len(c.cache) >= reputationCacheSize {
+		c.cache = make(map[string]reputationEntry)
+	}
+	c.cache[domain] = reputationEntry{verdict: verdict, expires: now.Add(reputationCacheTTL)}
+	c.mu.Unlock()
+	return verdict
+}
+
+func (c *reputationClient) lookup(ctx context.Context, domain string) (string, error) {
+	endpoint, err := url.Parse(c.endpoint)
+	if err != nil {
+		return "", err
+	}
+	query := endpoint.Query()
+	query.Set("domain", domain)
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("reputation service returned %s", resp.Status)
+	}
+	var body struct {
+		Verdict string `json:"verdict"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	switch verdict := strings.ToLower(body.Verdict); verdict {
+	case VerdictClean, VerdictSuspicious, VerdictMalicious:
+		return verdict, nil
+	default:
+		return "", fmt.Errorf("unknown verdict %q", body.Verdict)
+	}
+}
+
+// checkURLs extracts the URLs of input and rates their domains. Domains on
+// neither list are looked up together.
+func (v *PromptValidator) checkURLs(input string) []URLVerdict {
+	urls := extractURLs(input)
+	var lookups []string
+	for i := range urls {
+		verdict := &urls[i]
+		switch {
+		case domainListed(verdict.Domain, v.rules.Domains.Allow):
+			verdict.Verdict, verdict.Source = VerdictAllowed, "allowlist"
+		case domainListed(verdict.Domain, v.rules.Domains.Block):
+			verdict.Verdict, verdict.Source = VerdictBlocked, "blocklist"
+		case v.reputation != nil:
+			verdict.Source = "reputation"
+			if !containsString(lookups, verdict.Domain) {
+				lookups = append(lookups, verdict.Domain)
+			}
+		default:
+			verdict.Verdict = VerdictUnknown
+		}
+	}
+	if len(lookups) > 0 {
+		verdicts := v.reputation.verdicts(lookups)
+		for i := range urls {
+			if urls[i].Source == "reputation" {
+				urls[i].Verdict = verdicts[urls[i].Domain]
+			}
+		}
+	}
+	return urls
+}
@@ -0,0 +1,167 @@
+package security
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExtractURLs(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"kein Link hier", nil},
+		{"Siehe https://Example.COM/pfad?q=1.", []string{"example.com"}},
+		{"www.beispiel.de, und ftp://files.example.org/x)", []string{"www.beispiel.de", "files.example.org"}},
+		{"https://a.example https://a.example http://b.example:8080/", []string{"a.example", "b.example"}},
+		{"<a href=\"https://quoted.example/\">", []string{"quoted.example"}},
+		{"https://", nil},
+	}
+	for _, tt := range tests {
+		var domains []string
+		for _, link := range extractURLs(tt.input) {
+			if !containsString(domains, link.Domain) {
+				domains = append(domains, link.Domain)
+			}
+		}
+		if !reflect.DeepEqual(domains, tt.want) {
+			t.Errorf("extractURLs(%.40q) domains = %v, want %v", tt.input, domains, tt.want)
+		}
+	}
+	var many strings.Builder
+	for i := 0; i < maxCheckedURLs+5; i++ {
+		fmt.Fprintf(&many, "https://example.com/%d ", i)
+	}
+	if got := len(extractURLs(many.String())); got != maxCheckedURLs {
+		t.Errorf("%d URLs checked, want at most %d", got, maxCheckedURLs)
+	}
+}
+
+func TestDomainListed(t *testing.T) {
+	list := normalizeDomains([]string{" Evil.COM ", "*.phish.example", "", "."})
+	if !reflect.DeepEqual(list, []string{"evil.com", "phish.example"}) {
+		t.Fatalf("normalizeDomains = %q", list)
+	}
+
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{"evil.com", true},
+		{"login.evil.com", true},
+		{"notevil.com", false},
+		{"evil.com.example", false},
+		{"phish.example", true},
+		{"a.b.phish.example", true},
+	}
+	for _, tt := range tests {
+		if got := domainListed(tt.domain, list); got != tt.want {
+			t.Errorf("domainListed(%q) = %v, want %v", tt.domain, got, tt.want)
+		}
+	}
+}
+
+// reputationStub answers with the verdicts of the domain query parameter
+// and counts its requests.
+func reputationStub(t *testing.T, verdicts map[string]string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		domain := r.URL.Query().Get("domain")
+		switch verdict := verdicts[domain]; verdict {
+		case "":
+			http.Error(w, "unbekannt", http.StatusNotFound)
+		case "slow":
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte(`{"verdict":"clean"}`))
+		default:
+			w.Write([]byte(`{"verdict":"` + verdict + `"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestReputationClient(t *testing.T) {
+	server, requests := reputationStub(t, map[string]string{
+		"clean.example": "clean",
+		"shady.example": "SUSPICIOUS",
+		"bad.example":   "malicious",
+		"odd.example":   "maybe",
+		"slow.example":  "slow",
+	})
+	client := newReputationClient(server.URL+"/check?source=jarvis", 50*time.Millisecond)
+
+	want := map[string]string{
+		"clean.example":   VerdictClean,
+		"shady.example":   VerdictSuspicious,
+		"bad.example":     VerdictMalicious,
+		"odd.example":     VerdictUnknown,
+		"missing.example": VerdictUnknown,
+		"slow.example":    VerdictUnknown,
+	}
+	domains := make([]string, 0, len(want))
+	for domain := range want {
+		domains = append(domains, domain)
+	}
+	started := time.Now()
+	if got := client.verdicts(domains); !reflect.DeepEqual(got, want) {
+		t.Errorf("verdicts = %v, want %v", got, want)
+	}
+	if elapsed := time.Since(started); elapsed > 150*time.Millisecond {
+		t.Errorf("lookups took %v, want them bounded by the shared timeout", elapsed)
+	}
+
+	// Only definite answers are cached.
+	before := requests.Load()
+	client.verdicts([]string{"clean.example", "bad.example", "missing.example"})
+	if got := requests.Load() - before; got != 1 {
+		t.Errorf("%d requests after caching, want 1 for the unknown domain", got)
+	}
+}
+
+func TestURLVerdictsInValidation(t *testing.T) {
+	server, _ := reputationStub(t, map[string]string{"bad.example": "malicious", "shady.example": "suspicious", "fine.example": "clean"})
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	os.WriteFile(path, []byte(`
+domains:
+  block: [evil.com]
+  allow: [trusted.evil.com]
+rules: []
+`), 0o644)
+	svc := newTestService(t, Config{RulesFile: path, Locales: []string{}, ReputationURL: server.URL})
+
+	tests := []struct {
+		input   string
+		verdict string
+		source  string
+		score   float64
+	}{
+		{"Lies https://login.evil.com/konto", VerdictBlocked, "blocklist", weightBlockedDomain},
+		{"Lies https://trusted.evil.com/", VerdictAllowed, "allowlist", 0},
+		{"Lies https://bad.example/", VerdictMalicious, "reputation", weightBlockedDomain},
+		{"Lies https://shady.example/", VerdictSuspicious, "reputation", weightSuspiciousDomain},
+		{"Lies https://fine.example/", VerdictClean, "reputation", 0},
+		{"Lies https://nobody.example/", VerdictUnknown, "reputation", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := validate(t, svc, ValidateRequest{Input: tt.input})
+			if len(result.URLs) != 1 || result.URLs[0].Verdict != tt.verdict || result.URLs[0].Source != tt.source {
+				t.Fatalf("urls = %+v, want %s from %s", result.URLs, tt.verdict, tt.source)
+			}
+			if result.Score != tt.score {
+				t.Errorf("score = %v, want %v", result.Score, tt.score)
+			}
+		})
+	}
+}